@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/netip"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -14,7 +17,14 @@ import (
 	"github.com/go-chi/cors"
 
 	"github.com/snowmerak/open-librarian/lib/aggregator/api"
+	"github.com/snowmerak/open-librarian/lib/client/blob"
+	"github.com/snowmerak/open-librarian/lib/client/llm"
+	"github.com/snowmerak/open-librarian/lib/client/mail"
+	"github.com/snowmerak/open-librarian/lib/client/ocr"
+	"github.com/snowmerak/open-librarian/lib/client/web"
 	"github.com/snowmerak/open-librarian/lib/util/logger"
+	"github.com/snowmerak/open-librarian/lib/util/parser"
+	"go.mongodb.org/mongo-driver/v2/bson"
 
 	_ "github.com/snowmerak/open-librarian/lib/util/logger"
 )
@@ -41,11 +51,26 @@ func main() {
 	}
 	llmKey := getEnv("LLM_API_KEY", "")
 	llmModel := getEnv("LLM_MODEL", "gemma3:12b")
+	llmRouterConfig := getEnv("LLM_ROUTER_CONFIG", "")
+	llmContextTokensStr := getEnv("LLM_CONTEXT_TOKENS", "")
+	llmCacheMode := getEnv("LLM_CACHE", "memory")
+	llmCacheSizeStr := getEnv("LLM_CACHE_SIZE_MB", "64")
+	llmCacheTTLStr := getEnv("LLM_CACHE_TTL", "24h")
+	embeddingCacheTTLStr := getEnv("EMBEDDING_CACHE_TTL", "168h")
 
 	qdrantHost := getEnv("QDRANT_HOST", "localhost")
 	qdrantPortStr := getEnv("QDRANT_PORT", "6334")
 	mongoURI := getEnv("MONGODB_URI", "mongodb://localhost:27017/open_librarian")
 	jwtSecret := getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-this-in-production")
+	publicBaseURL := getEnv("PUBLIC_BASE_URL", "http://localhost:"+port)
+
+	mailProvider := getEnv("MAIL_PROVIDER", "log")
+	mailLogFile := getEnv("MAIL_LOG_FILE", "")
+	smtpHost := getEnv("SMTP_HOST", "")
+	smtpPortStr := getEnv("SMTP_PORT", "587")
+	smtpUsername := getEnv("SMTP_USERNAME", "")
+	smtpPassword := getEnv("SMTP_PASSWORD", "")
+	mailFrom := getEnv("MAIL_FROM", "")
 
 	configLogger.Info().
 		Str("port", port).
@@ -53,10 +78,14 @@ func main() {
 		Str("ollama_url", ollamaURL).
 		Str("llm_url", llmURL).
 		Str("llm_model", llmModel).
+		Str("llm_router_config", llmRouterConfig).
+		Str("llm_cache", llmCacheMode).
 		Str("qdrant_host", qdrantHost).
 		Str("qdrant_port", qdrantPortStr).
 		Str("mongo_uri", mongoURI).
 		Bool("jwt_secret_default", jwtSecret == "your-super-secret-jwt-key-change-this-in-production").
+		Str("public_base_url", publicBaseURL).
+		Str("mail_provider", mailProvider).
 		Msg("Configuration loaded")
 	configLogger.EndWithMsg("Configuration loading complete")
 
@@ -69,9 +98,164 @@ func main() {
 		mainLogger.Warn().Err(err).Str("qdrant_port_str", qdrantPortStr).Int("default_port", 6334).Msg("Invalid QDRANT_PORT, using default")
 	}
 
+	// Initialize mail sender
+	var mailSender mail.Sender
+	switch mailProvider {
+	case "smtp":
+		smtpPort, err := parsePort(smtpPortStr)
+		if err != nil {
+			mainLogger.Error().Err(err).Str("smtp_port_str", smtpPortStr).Msg("Invalid SMTP_PORT")
+			os.Exit(1)
+		}
+		smtpSender, err := mail.NewSMTPSender(mail.SMTPConfig{
+			Host:     smtpHost,
+			Port:     smtpPort,
+			Username: smtpUsername,
+			Password: smtpPassword,
+			From:     mailFrom,
+		})
+		if err != nil {
+			mainLogger.Error().Err(err).Msg("Failed to create SMTP mail sender")
+			os.Exit(1)
+		}
+		mailSender = smtpSender
+	default:
+		mailSender = mail.NewLogSender(mailLogFile)
+	}
+
+	serverOpts := []api.ServerOption{
+		api.WithMailSender(mailSender),
+		api.WithPublicBaseURL(publicBaseURL),
+	}
+
+	// Initialize the default LLM client; LLM_URL/LLM_MODEL serve every task without a route
+	defaultLLM := llm.NewClient(llmURL, llmKey, llmModel, ollamaURL)
+	if llmContextTokensStr != "" {
+		contextTokens, err := strconv.Atoi(llmContextTokensStr)
+		if err != nil || contextTokens <= 0 {
+			mainLogger.Error().Str("llm_context_tokens", llmContextTokensStr).Msg("Invalid LLM_CONTEXT_TOKENS")
+			os.Exit(1)
+		}
+		defaultLLM.SetContextLimit(contextTokens)
+	}
+
+	// Initialize per-task LLM routing
+	llmRouter := llm.NewRouter(defaultLLM)
+	if llmRouterConfig != "" {
+		routerConfig, err := llm.LoadRouterConfig(llmRouterConfig)
+		if err != nil {
+			mainLogger.Error().Err(err).Str("path", llmRouterConfig).Msg("Failed to load LLM router config")
+			os.Exit(1)
+		}
+		llmRouter, err = llm.NewRouterFromConfig(routerConfig, defaultLLM, ollamaURL)
+		if err != nil {
+			mainLogger.Error().Err(err).Msg("Failed to build LLM router")
+			os.Exit(1)
+		}
+	}
+	mainLogger.Info().Str("routes", llmRouter.Describe()).Int("default_context_tokens", defaultLLM.ContextLimit()).Msg("LLM router configured")
+	serverOpts = append(serverOpts, api.WithLLMRouter(llmRouter))
+
+	// Initialize the LLM cache; "mongo" adds a persistent tier shared between instances
+	if llmCacheMode != "off" {
+		cacheConfig, err := parseLLMCacheConfig(llmCacheSizeStr, llmCacheTTLStr, embeddingCacheTTLStr)
+		if err != nil {
+			mainLogger.Error().Err(err).Msg("Invalid LLM cache configuration")
+			os.Exit(1)
+		}
+		serverOpts = append(serverOpts, api.WithLLMCache(llm.NewCache(cacheConfig), llmCacheMode == "mongo"))
+		mainLogger.Info().Str("mode", llmCacheMode).Int64("max_bytes", cacheConfig.MaxBytes).Msg("LLM cache configured")
+	}
+
+	// Configure the bulk ingestion pipeline
+	ingestConfig, err := parseIngestConfig()
+	if err != nil {
+		mainLogger.Error().Err(err).Msg("Invalid ingestion configuration")
+		os.Exit(1)
+	}
+	serverOpts = append(serverOpts, api.WithIngestConfig(ingestConfig))
+
+	// Configure file upload limits
+	uploadConfig, err := parseUploadConfig()
+	if err != nil {
+		mainLogger.Error().Err(err).Msg("Invalid upload configuration")
+		os.Exit(1)
+	}
+	serverOpts = append(serverOpts, api.WithUploadConfig(uploadConfig))
+
+	// Configure OCR for scanned PDF pages and image uploads
+	ocrEngine, err := newOCREngine(llmRouter)
+	if err != nil {
+		mainLogger.Error().Err(err).Msg("Invalid OCR configuration")
+		os.Exit(1)
+	}
+	if ocrEngine != nil {
+		serverOpts = append(serverOpts, api.WithOCR(ocrEngine))
+	}
+	mainLogger.Info().Str("backend", getEnv("OCR_BACKEND", "none")).Msg("OCR configured")
+
+	// Configure storage of original uploaded files
+	blobStore, err := newBlobStore()
+	if err != nil {
+		mainLogger.Error().Err(err).Msg("Invalid file storage configuration")
+		os.Exit(1)
+	}
+	if blobStore != nil {
+		serverOpts = append(serverOpts, api.WithBlobStore(blobStore))
+	}
+	switch fileAccess := getEnv("FILE_DOWNLOAD_ACCESS", api.FileAccessUsers); fileAccess {
+	case api.FileAccessUsers, api.FileAccessRegistrar:
+		serverOpts = append(serverOpts, api.WithFileAccess(fileAccess))
+	default:
+		mainLogger.Error().Str("value", fileAccess).Msg("Invalid FILE_DOWNLOAD_ACCESS, expected users or registrar")
+		os.Exit(1)
+	}
+	mainLogger.Info().Str("backend", getEnv("BLOB_BACKEND", "none")).Msg("File storage configured")
+
+	// Configure fetching articles from URLs
+	fetcherConfig, err := parseFetcherConfig()
+	if err != nil {
+		mainLogger.Error().Err(err).Msg("Invalid URL fetcher configuration")
+		os.Exit(1)
+	}
+	serverOpts = append(serverOpts, api.WithFetcher(web.NewHTTPFetcher(fetcherConfig)))
+
+	// Configure rerankers per search path
+	rerankOpts, err := parseRerankOptions()
+	if err != nil {
+		mainLogger.Error().Err(err).Msg("Invalid reranker configuration")
+		os.Exit(1)
+	}
+	serverOpts = append(serverOpts, rerankOpts...)
+
+	// Configure cross-lingual query expansion
+	expansionOpts, err := parseQueryExpansionOptions()
+	if err != nil {
+		mainLogger.Error().Err(err).Msg("Invalid query expansion configuration")
+		os.Exit(1)
+	}
+	serverOpts = append(serverOpts, expansionOpts...)
+
+	// Configure how answer claims are checked against their sources
+	switch groundingMode := getEnv("ANSWER_GROUNDING", api.GroundingLLM); groundingMode {
+	case api.GroundingLLM, api.GroundingLexical:
+		serverOpts = append(serverOpts, api.WithGroundingMode(groundingMode))
+	default:
+		mainLogger.Error().Str("mode", groundingMode).Msg("Invalid ANSWER_GROUNDING, expected llm or lexical")
+		os.Exit(1)
+	}
+
+	// Configure periodic topic clustering
+	topicConfig, err := parseTopicConfig()
+	if err != nil {
+		mainLogger.Error().Err(err).Msg("Invalid topic clustering configuration")
+		os.Exit(1)
+	}
+	serverOpts = append(serverOpts, api.WithTopicConfig(topicConfig))
+
 	// Initialize API server
 	apiInitLogger := logger.NewLogger("api_init").StartWithMsg("Initializing API server")
-	apiServer, err := api.NewServer(llmURL, llmKey, llmModel, ollamaURL, opensearchURL, qdrantHost, mongoURI, jwtSecret, qdrantPort)
+	apiServer, err := api.NewServer(llmURL, llmKey, llmModel, ollamaURL, opensearchURL, qdrantHost, mongoURI, jwtSecret, qdrantPort, serverOpts...)
 	if err != nil {
 		apiInitLogger.EndWithError(err)
 		mainLogger.Error().Err(err).Msg("Failed to create API server")
@@ -80,6 +264,11 @@ func main() {
 	httpServer := api.NewHTTPServer(apiServer)
 	apiInitLogger.EndWithMsg("API server initialization complete")
 
+	// Cluster articles into topics in the background until shutdown
+	topicCtx, stopTopics := context.WithCancel(context.Background())
+	defer stopTopics()
+	apiServer.StartTopicClustering(topicCtx)
+
 	// Setup router with middleware
 	routerLogger := logger.NewLogger("router_setup").StartWithMsg("Setting up router and middleware")
 	router := setupRouter(httpServer)
@@ -129,6 +318,7 @@ func main() {
 
 	// Graceful shutdown with timeout
 	shutdownLogger.Info().Msg("Starting graceful shutdown")
+	stopTopics()
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
@@ -156,7 +346,7 @@ func setupRouter(httpServer *api.HTTPServer) *chi.Mux {
 	router.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-API-Key"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-API-Key", "Cache-Control"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: false,
 		MaxAge:           300,
@@ -207,3 +397,427 @@ func parsePort(portStr string) (int, error) {
 	}
 	return port, nil
 }
+
+// parseLLMCacheConfig builds the LLM cache configuration from environment values
+func parseLLMCacheConfig(sizeMBStr, ttlStr, embeddingTTLStr string) (llm.CacheConfig, error) {
+	config := llm.DefaultCacheConfig()
+
+	sizeMB, err := strconv.Atoi(sizeMBStr)
+	if err != nil || sizeMB <= 0 {
+		return config, fmt.Errorf("invalid LLM_CACHE_SIZE_MB %q", sizeMBStr)
+	}
+	config.MaxBytes = int64(sizeMB) << 20
+
+	ttl, err := time.ParseDuration(ttlStr)
+	if err != nil {
+		return config, fmt.Errorf("invalid LLM_CACHE_TTL %q: %w", ttlStr, err)
+	}
+	embeddingTTL, err := time.ParseDuration(embeddingTTLStr)
+	if err != nil {
+		return config, fmt.Errorf("invalid EMBEDDING_CACHE_TTL %q: %w", embeddingTTLStr, err)
+	}
+
+	config.TTLs[llm.CacheKindText] = ttl
+	config.TTLs[llm.CacheKindJSON] = ttl
+	config.TTLs[llm.CacheKindRelevance] = ttl
+	config.TTLs[llm.CacheKindEmbedding] = embeddingTTL
+	return config, nil
+}
+
+// parseIngestConfig reads the bulk ingestion pipeline settings from the environment
+func parseIngestConfig() (api.IngestConfig, error) {
+	config := api.DefaultIngestConfig()
+	settings := []struct {
+		env   string
+		value *int
+	}{
+		{"INGEST_LLM_CONCURRENCY", &config.LLMConcurrency},
+		{"INGEST_EMBEDDING_CONCURRENCY", &config.EmbeddingConcurrency},
+		{"INGEST_EMBEDDING_BATCH_SIZE", &config.EmbeddingBatchSize},
+		{"INGEST_STORAGE_CONCURRENCY", &config.StorageConcurrency},
+		{"INGEST_STORAGE_BATCH_SIZE", &config.StorageBatchSize},
+		{"INGEST_ARCHIVE_CONCURRENCY", &config.ArchiveConcurrency},
+	}
+
+	for _, setting := range settings {
+		raw := getEnv(setting.env, "")
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return config, fmt.Errorf("invalid %s %q", setting.env, raw)
+		}
+		*setting.value = n
+	}
+	return config, nil
+}
+
+// parseUploadConfig reads per-type upload size limits and parsing protections from the environment.
+// Sizes are given in megabytes.
+func parseUploadConfig() (api.UploadConfig, error) {
+	config := api.DefaultUploadConfig()
+	config.TempDir = getEnv("UPLOAD_TEMP_DIR", "")
+
+	sizes := []struct {
+		env      string
+		fileType string
+	}{
+		{"UPLOAD_MAX_PDF_MB", parser.TypePDF},
+		{"UPLOAD_MAX_DOCX_MB", parser.TypeDocx},
+		{"UPLOAD_MAX_XLSX_MB", parser.TypeExcel},
+		{"UPLOAD_MAX_CSV_MB", parser.TypeCSV},
+		{"UPLOAD_MAX_TEXT_MB", parser.TypeText},
+		{"UPLOAD_MAX_HTML_MB", parser.TypeHTML},
+		{"UPLOAD_MAX_PPTX_MB", parser.TypePptx},
+		{"UPLOAD_MAX_ODT_MB", parser.TypeOdt},
+		{"UPLOAD_MAX_ODS_MB", parser.TypeOds},
+		{"UPLOAD_MAX_EPUB_MB", parser.TypeEPUB},
+		{"UPLOAD_MAX_RTF_MB", parser.TypeRTF},
+		{"UPLOAD_MAX_IMAGE_MB", parser.TypeImage},
+	}
+	for _, size := range sizes {
+		raw := getEnv(size.env, "")
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || n <= 0 {
+			return config, fmt.Errorf("invalid %s %q", size.env, raw)
+		}
+		config.MaxSizes[size.fileType] = n << 20
+	}
+
+	archiveSizes := []struct {
+		env   string
+		value *int64
+	}{
+		{"UPLOAD_MAX_ARCHIVE_MB", &config.MaxArchiveSize},
+		{"ARCHIVE_MAX_EXPANDED_MB", &config.MaxArchiveExpandedSize},
+	}
+	for _, size := range archiveSizes {
+		raw := getEnv(size.env, "")
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || n <= 0 {
+			return config, fmt.Errorf("invalid %s %q", size.env, raw)
+		}
+		*size.value = n << 20
+	}
+	if raw := getEnv("ARCHIVE_MAX_FILES", ""); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return config, fmt.Errorf("invalid ARCHIVE_MAX_FILES %q", raw)
+		}
+		config.MaxArchiveFiles = n
+	}
+
+	if raw := getEnv("PARSE_MAX_UNCOMPRESSED_MB", ""); raw != "" {
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || n <= 0 {
+			return config, fmt.Errorf("invalid PARSE_MAX_UNCOMPRESSED_MB %q", raw)
+		}
+		config.Limits.MaxUncompressedSize = n << 20
+	}
+	counts := []struct {
+		env   string
+		value *int
+	}{
+		{"PARSE_MAX_ZIP_ENTRIES", &config.Limits.MaxZipEntries},
+		{"PARSE_MAX_PDF_PAGES", &config.Limits.MaxPDFPages},
+		{"PARSE_MAX_SHEET_ROWS", &config.Limits.MaxSheetRows},
+		{"PARSE_MAX_SHEET_COLUMNS", &config.Limits.MaxSheetColumns},
+	}
+	for _, count := range counts {
+		raw := getEnv(count.env, "")
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return config, fmt.Errorf("invalid %s %q", count.env, raw)
+		}
+		*count.value = n
+	}
+	if raw := getEnv("PARSE_MAX_OCR_PAGES", ""); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return config, fmt.Errorf("invalid PARSE_MAX_OCR_PAGES %q, expected a page count or 0", raw)
+		}
+		config.Limits.MaxOCRPages = n
+	}
+	if raw := getEnv("PARSE_TIMEOUT", ""); raw != "" {
+		timeout, err := time.ParseDuration(raw)
+		if err != nil || timeout < 0 {
+			return config, fmt.Errorf("invalid PARSE_TIMEOUT %q, expected a duration such as 5m or 0", raw)
+		}
+		config.Limits.Timeout = timeout
+	}
+	if raw := getEnv("PARSE_OCR_TIMEOUT", ""); raw != "" {
+		timeout, err := time.ParseDuration(raw)
+		if err != nil || timeout < 0 {
+			return config, fmt.Errorf("invalid PARSE_OCR_TIMEOUT %q, expected a duration such as 15m or 0", raw)
+		}
+		config.Limits.OCRTimeout = timeout
+	}
+
+	config.Sheets.Split = getEnv("SHEET_SPLIT", config.Sheets.Split)
+	if raw := getEnv("SHEET_CHUNK_ROWS", ""); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return config, fmt.Errorf("invalid SHEET_CHUNK_ROWS %q, expected a row count or 0", raw)
+		}
+		config.Sheets.ChunkRows = n
+	}
+	if err := config.Sheets.Validate(); err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+// newOCREngine creates the OCR engine selected by OCR_BACKEND: none, tesseract or vision.
+// The vision backend sends images to the model routed for the ocr task; it returns nil for none.
+func newOCREngine(router *llm.Router) (parser.OCR, error) {
+	switch backend := getEnv("OCR_BACKEND", "none"); backend {
+	case "none":
+		return nil, nil
+	case "tesseract":
+		config := ocr.DefaultTesseractConfig()
+		config.Path = getEnv("TESSERACT_PATH", config.Path)
+		config.Languages = getEnv("OCR_LANGUAGES", config.Languages)
+		if raw := getEnv("OCR_TIMEOUT", ""); raw != "" {
+			timeout, err := time.ParseDuration(raw)
+			if err != nil || timeout < 0 {
+				return nil, fmt.Errorf("invalid OCR_TIMEOUT %q, expected a duration such as 2m or 0", raw)
+			}
+			config.Timeout = timeout
+		}
+		tesseract, err := ocr.NewTesseract(config)
+		if err != nil {
+			return nil, err
+		}
+		return tesseract, nil
+	case "vision":
+		return ocr.NewVision(router), nil
+	default:
+		return nil, fmt.Errorf("invalid OCR_BACKEND %q, expected none, tesseract or vision", backend)
+	}
+}
+
+// newBlobStore creates the store for original uploaded files selected by BLOB_BACKEND: none, local or s3.
+// It returns nil for none, in which case originals are discarded after parsing.
+func newBlobStore() (blob.Store, error) {
+	switch backend := getEnv("BLOB_BACKEND", "none"); backend {
+	case "none":
+		return nil, nil
+	case "local":
+		local, err := blob.NewLocal(getEnv("BLOB_LOCAL_DIR", "./data/files"))
+		if err != nil {
+			return nil, err
+		}
+		return local, nil
+	case "s3":
+		config := blob.DefaultS3Config()
+		config.Endpoint = getEnv("S3_ENDPOINT", "")
+		config.Region = getEnv("S3_REGION", config.Region)
+		config.Bucket = getEnv("S3_BUCKET", "")
+		config.AccessKeyID = getEnv("S3_ACCESS_KEY_ID", "")
+		config.SecretAccessKey = getEnv("S3_SECRET_ACCESS_KEY", "")
+		if raw := getEnv("S3_PATH_STYLE", ""); raw != "" {
+			pathStyle, err := strconv.ParseBool(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid S3_PATH_STYLE %q, expected true or false", raw)
+			}
+			config.PathStyle = pathStyle
+		}
+		s3, err := blob.NewS3(config)
+		if err != nil {
+			return nil, err
+		}
+		return s3, nil
+	default:
+		return nil, fmt.Errorf("invalid BLOB_BACKEND %q, expected none, local or s3", backend)
+	}
+}
+
+// parseFetcherConfig reads the timeout, user agent, redirect limit and address policy used to fetch web pages.
+// UPLOAD_MAX_HTML_MB bounds the size of fetched pages.
+func parseFetcherConfig() (web.HTTPFetcherConfig, error) {
+	config := web.DefaultHTTPFetcherConfig()
+	config.UserAgent = getEnv("FETCH_USER_AGENT", config.UserAgent)
+
+	if raw := getEnv("FETCH_TIMEOUT", ""); raw != "" {
+		timeout, err := time.ParseDuration(raw)
+		if err != nil || timeout <= 0 {
+			return config, fmt.Errorf("invalid FETCH_TIMEOUT %q, expected a duration such as 30s", raw)
+		}
+		config.Timeout = timeout
+	}
+	if raw := getEnv("FETCH_MAX_REDIRECTS", ""); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return config, fmt.Errorf("invalid FETCH_MAX_REDIRECTS %q", raw)
+		}
+		config.MaxRedirects = n
+	}
+	for _, host := range strings.Split(getEnv("FETCH_ALLOWED_HOSTS", ""), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			config.AllowedHosts = append(config.AllowedHosts, host)
+		}
+	}
+	for _, raw := range strings.Split(getEnv("FETCH_ALLOWED_NETWORKS", ""), ",") {
+		if raw = strings.TrimSpace(raw); raw == "" {
+			continue
+		}
+		network, err := netip.ParsePrefix(raw)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(raw)
+			if addrErr != nil {
+				return config, fmt.Errorf("invalid FETCH_ALLOWED_NETWORKS entry %q, expected a CIDR such as 10.0.0.0/8 or an address", raw)
+			}
+			network = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		config.AllowedNetworks = append(config.AllowedNetworks, network.Masked())
+	}
+	return config, nil
+}
+
+// parseTopicConfig reads the topic clustering schedule and cluster counts from the environment.
+// TOPIC_CLUSTERING_INTERVAL=0 disables periodic clustering and TOPIC_CLUSTERS=0 derives the count from the library size.
+// TOPIC_ADMINS lists the IDs of the users who may start a run with POST /api/v1/topics/refresh.
+func parseTopicConfig() (api.TopicConfig, error) {
+	config := api.DefaultTopicConfig()
+
+	if raw := getEnv("TOPIC_CLUSTERING_INTERVAL", ""); raw != "" {
+		interval, err := time.ParseDuration(raw)
+		if err != nil || interval < 0 {
+			return config, fmt.Errorf("invalid TOPIC_CLUSTERING_INTERVAL %q, expected a duration such as 24h or 0", raw)
+		}
+		config.Interval = interval
+	}
+	if raw := getEnv("TOPIC_CLUSTERS", ""); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 || n == 1 {
+			return config, fmt.Errorf("invalid TOPIC_CLUSTERS %q, expected 0 or at least 2", raw)
+		}
+		config.Clusters = n
+	}
+	if raw := getEnv("TOPIC_MAX_CLUSTERS", ""); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 2 {
+			return config, fmt.Errorf("invalid TOPIC_MAX_CLUSTERS %q, expected at least 2", raw)
+		}
+		config.MaxClusters = n
+	}
+	for _, id := range strings.Split(getEnv("TOPIC_ADMINS", ""), ",") {
+		if id = strings.TrimSpace(id); id == "" {
+			continue
+		}
+		if _, err := bson.ObjectIDFromHex(id); err != nil {
+			return config, fmt.Errorf("invalid TOPIC_ADMINS entry %q, expected a user ID", id)
+		}
+		config.Admins = append(config.Admins, id)
+	}
+	return config, nil
+}
+
+// parseRerankOptions reads the reranker of each search path from the environment.
+// RERANK_AGENT and RERANK_HYBRID select "llm", "http" or "none".
+func parseRerankOptions() ([]api.ServerOption, error) {
+	var httpReranker *api.HTTPReranker
+	if rerankURL := getEnv("RERANK_URL", ""); rerankURL != "" {
+		minScore := 0.0
+		if raw := getEnv("RERANK_MIN_SCORE", ""); raw != "" {
+			parsed, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RERANK_MIN_SCORE %q", raw)
+			}
+			minScore = parsed
+		}
+
+		httpReranker = api.NewHTTPReranker(api.HTTPRerankerConfig{
+			URL:      rerankURL,
+			Model:    getEnv("RERANK_MODEL", ""),
+			APIKey:   getEnv("RERANK_API_KEY", ""),
+			Format:   getEnv("RERANK_FORMAT", "tei"),
+			MinScore: minScore,
+		})
+	}
+
+	paths := []struct {
+		env      string
+		path     string
+		fallback string
+	}{
+		{"RERANK_AGENT", api.RerankPathAgent, api.RerankerLLMJudge},
+		{"RERANK_HYBRID", api.RerankPathHybrid, api.RerankerNone},
+	}
+
+	var opts []api.ServerOption
+	for _, p := range paths {
+		switch name := getEnv(p.env, p.fallback); name {
+		case api.RerankerLLMJudge:
+			opts = append(opts, api.WithLLMJudgeReranker(p.path))
+		case api.RerankerHTTP:
+			if httpReranker == nil {
+				return nil, fmt.Errorf("%s is %q but RERANK_URL is not set", p.env, name)
+			}
+			opts = append(opts, api.WithReranker(p.path, httpReranker))
+		case api.RerankerNone:
+			opts = append(opts, api.WithReranker(p.path, api.NoopReranker{}))
+		default:
+			return nil, fmt.Errorf("invalid %s %q, expected llm, http or none", p.env, name)
+		}
+	}
+	return opts, nil
+}
+
+// parseQueryExpansionOptions reads cross-lingual query expansion settings from the environment.
+// QUERY_EXPANSION selects "llm", "dictionary" (with QUERY_EXPANSION_DICTIONARY) or "off".
+func parseQueryExpansionOptions() ([]api.ServerOption, error) {
+	config := api.DefaultQueryExpansionConfig()
+
+	if raw := getEnv("QUERY_EXPANSION_MIN_SHARE", ""); raw != "" {
+		share, err := strconv.ParseFloat(raw, 64)
+		if err != nil || share < 0 || share > 1 {
+			return nil, fmt.Errorf("invalid QUERY_EXPANSION_MIN_SHARE %q, expected a number between 0 and 1", raw)
+		}
+		config.MinLanguageShare = share
+	}
+	if raw := getEnv("QUERY_EXPANSION_MAX_LANGUAGES", ""); raw != "" {
+		maxLanguages, err := strconv.Atoi(raw)
+		if err != nil || maxLanguages < 1 {
+			return nil, fmt.Errorf("invalid QUERY_EXPANSION_MAX_LANGUAGES %q", raw)
+		}
+		config.MaxLanguages = maxLanguages
+	}
+	if raw := getEnv("QUERY_EXPANSION_WEIGHT", ""); raw != "" {
+		weight, err := strconv.ParseFloat(raw, 64)
+		if err != nil || weight <= 0 {
+			return nil, fmt.Errorf("invalid QUERY_EXPANSION_WEIGHT %q", raw)
+		}
+		config.TranslationWeight = weight
+	}
+
+	switch mode := getEnv("QUERY_EXPANSION", "off"); mode {
+	case api.QueryTranslatorLLM:
+		return []api.ServerOption{api.WithQueryExpansion(config), api.WithLLMQueryTranslator()}, nil
+	case api.QueryTranslatorDictionary:
+		path := getEnv("QUERY_EXPANSION_DICTIONARY", "")
+		if path == "" {
+			return nil, fmt.Errorf("QUERY_EXPANSION is %q but QUERY_EXPANSION_DICTIONARY is not set", mode)
+		}
+		translator, err := api.LoadDictionaryQueryTranslator(path)
+		if err != nil {
+			return nil, err
+		}
+		config.Translator = translator
+		return []api.ServerOption{api.WithQueryExpansion(config)}, nil
+	case "off":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("invalid QUERY_EXPANSION %q, expected llm, dictionary or off", mode)
+	}
+}
@@ -64,6 +64,44 @@ async function deleteArticle(articleId, articleTitle) {
     }
 }
 
+// Opens the original uploaded file of an article. PDFs and images open in a new tab; other
+// types are downloaded, since a blob URL would render them with this page's origin.
+async function openArticleFile(articleId, filename) {
+    const token = getJWTToken();
+    if (!token) {
+        alert(t('fileLoginRequired'));
+        return;
+    }
+
+    try {
+        const response = await fetch(`${API_BASE_URL}/api/v1/articles/${encodeURIComponent(articleId)}/file`, {
+            headers: { 'Authorization': `Bearer ${token}` }
+        });
+        if (response.status === 403) {
+            alert(t('filePermissionDenied'));
+            return;
+        }
+        if (!response.ok) {
+            throw new Error(`HTTP error! status: ${response.status}`);
+        }
+
+        const blob = await response.blob();
+        const url = URL.createObjectURL(blob);
+        if (blob.type === 'application/pdf' || blob.type.startsWith('image/')) {
+            window.open(url, '_blank');
+        } else {
+            const link = document.createElement('a');
+            link.href = url;
+            link.download = filename || 'original';
+            link.click();
+        }
+        setTimeout(() => URL.revokeObjectURL(url), 60000);
+    } catch (error) {
+        console.error('Open article file error:', error);
+        alert(t('fileOpenError'));
+    }
+}
+
 // 검색 결과에서 article 제거
 function removeArticleFromSearchResults(articleId) {
     // 모든 source-card에서 해당 article 제거
@@ -125,3 +163,4 @@ document.addEventListener('DOMContentLoaded', function() {
 window.deleteArticle = deleteArticle;
 window.setJWTToken = setJWTToken;
 window.tempLogin = tempLogin;
+window.openArticleFile = openArticleFile;
@@ -22,7 +22,8 @@ function initArticleForm() {
             const metadata = {
                 title: formData.get('title'),
                 original_url: formData.get('original_url') || '',
-                author: formData.get('author') || ''
+                author: formData.get('author') || '',
+                split: formData.get('split') || ''
             };
             
             // Handle created_date conversion from datetime-local to RFC3339
@@ -139,6 +140,8 @@ function initArticleForm() {
                         };
                         reader.readAsText(file);
                      });
+                } else if (isArchiveFile(fileName)) {
+                     window.uploadQueue.push({ type: 'archive', file: file, name: file.name });
                 } else {
                      window.uploadQueue.push({ type: 'file', file: file, name: file.name });
                 }
@@ -153,8 +156,9 @@ function initArticleForm() {
     }
 }
 
-// Function to upload a single file (returns Promise)
-async function uploadSingleFile(file, metadata = {}) {
+// Function to upload a single file (returns Promise of the article, or of the bulk job when a
+// spreadsheet was split into several articles)
+async function uploadSingleFile(file, metadata = {}, onProgress = () => {}) {
     const formData = new FormData();
     formData.append('file', file);
     
@@ -162,6 +166,7 @@ async function uploadSingleFile(file, metadata = {}) {
     if (metadata.author) formData.append('author', metadata.author);
     if (metadata.original_url) formData.append('original_url', metadata.original_url);
     if (metadata.created_date) formData.append('created_date', metadata.created_date);
+    if (metadata.split) formData.append('split', metadata.split);
 
     const token = getJWTToken();
     if (!token) throw new Error('Login Required');
@@ -177,9 +182,53 @@ async function uploadSingleFile(file, metadata = {}) {
         try { errMsg += ` - ${await response.text()}`; } catch (e) {}
         throw new Error(errMsg);
     }
+    if (response.status === 202) {
+        return await pollBulkJob(await response.json(), onProgress);
+    }
     return await response.json();
 }
 
+function isArchiveFile(fileName) {
+    return ['.zip', '.tar', '.tgz', '.tar.gz'].some(ext => fileName.endsWith(ext));
+}
+
+// Uploads an archive, then polls its bulk job until it finishes (returns Promise of the job)
+async function uploadArchiveFile(file, metadata = {}, onProgress = () => {}) {
+    const formData = new FormData();
+    formData.append('file', file);
+    if (metadata.split) formData.append('split', metadata.split);
+
+    const token = getJWTToken();
+    if (!token) throw new Error('Login Required');
+
+    const response = await fetch(`${API_BASE_URL}/api/v1/articles/upload/archive`, {
+        method: 'POST',
+        headers: { 'Authorization': `Bearer ${token}` },
+        body: formData
+    });
+    if (!response.ok) {
+        let errMsg = `Status: ${response.status}`;
+        try { errMsg += ` - ${await response.text()}`; } catch (e) {}
+        throw new Error(errMsg);
+    }
+    return await pollBulkJob(await response.json(), onProgress);
+}
+
+// Polls a bulk job until it finishes (returns Promise of the job)
+async function pollBulkJob(job, onProgress = () => {}) {
+    while (job.status !== 'completed' && job.status !== 'failed') {
+        onProgress(job);
+        await new Promise(r => setTimeout(r, 2000));
+        const poll = await fetch(`${API_BASE_URL}/api/v1/articles/jobs/${encodeURIComponent(job.id)}`, {
+            headers: { 'Authorization': `Bearer ${getJWTToken()}` }
+        });
+        if (!poll.ok) throw new Error(`Status: ${poll.status}`);
+        job = await poll.json();
+    }
+    if (job.status === 'failed') throw new Error(job.error || 'Bulk job failed');
+    return job;
+}
+
 // 대량 업로드 처리
 async function handleUnifiedUpload(queue, metadata) {
     if (!queue || queue.length === 0) return;
@@ -203,7 +252,7 @@ async function handleUnifiedUpload(queue, metadata) {
         const item = queue[i];
         if (currentProgress) currentProgress.textContent = i + 1;
         
-        const itemName = item.type === 'file' ? item.name : (item.name || 'Untitled');
+        const itemName = item.type !== 'data' ? item.name : (item.name || 'Untitled');
         if (currentItem) {
             currentItem.innerHTML = `
                 <div class="flex items-center">
@@ -214,6 +263,7 @@ async function handleUnifiedUpload(queue, metadata) {
         }
         
         try {
+            let warnings = [];
             if (item.type === 'data') {
                 const articleData = {
                     title: item.data.title || metadata.title || 'Untitled',
@@ -228,8 +278,37 @@ async function handleUnifiedUpload(queue, metadata) {
                 }
                 
                 await processIndividualArticleWithWebSocket(articleData, articleData.title);
-            } else if (item.type === 'file') {
-                await uploadSingleFile(item.file, metadata);
+            } else {
+                const showJob = job => {
+                    if (!currentItem) return;
+                    let status = t('archiveReading', { files: job.files_read });
+                    if (job.status === 'ingesting') {
+                        status = t(item.type === 'archive' ? 'archiveIngesting' : 'jobIngesting', { processed: job.processed, total: job.total });
+                    }
+                    currentItem.innerHTML = `
+                        <div class="flex items-center">
+                            <div class="spinner mr-2"></div>
+                            <span><strong>${escapeHtml(itemName.substring(0, 50))}</strong> - ${escapeHtml(status)}</span>
+                        </div>
+                    `;
+                };
+                const result = item.type === 'archive'
+                    ? await uploadArchiveFile(item.file, metadata, showJob)
+                    : await uploadSingleFile(item.file, metadata, showJob);
+                if (result.status === 'completed') {
+                    // A bulk job: an archive or a spreadsheet split into several articles
+                    const job = result;
+                    warnings = [
+                        item.type === 'archive'
+                            ? t('archiveComplete', { success: job.success_count, failed: job.error_count, skipped: (job.skipped || []).length })
+                            : t('jobComplete', { success: job.success_count, failed: job.error_count }),
+                        ...(job.results || []).filter(r => !r.success).map(r => `${r.title || r.path}: ${r.error}`),
+                        ...(job.skipped || []).map(s => `${s.path}: ${s.message}`),
+                        ...(job.warnings || []).map(w => `${w.path}: ${w.message}`)
+                    ];
+                } else {
+                    warnings = result.warnings || [];
+                }
             }
 
             successCount++;
@@ -238,6 +317,12 @@ async function handleUnifiedUpload(queue, metadata) {
                 div.className = 'text-sm text-green-600 mb-1';
                 div.innerHTML = `✓ ${escapeHtml(itemName.substring(0, 60))}`;
                 uploadLog.appendChild(div);
+                warnings.forEach(warning => {
+                    const warn = document.createElement('div');
+                    warn.className = 'text-xs text-amber-600 mb-1 ml-4';
+                    warn.textContent = `⚠ ${warning}`;
+                    uploadLog.appendChild(warn);
+                });
             }
         } catch (error) {
             errorCount++;
@@ -67,7 +67,11 @@ const TRANSLATIONS = {
         // JSONL Upload
         selectJsonlFile: 'Select JSONL File',
         selectFile: 'Select File (JSONL, PDF, Excel, Word, Markdown)',
-        fileFormatHelp: 'Supported: JSONL, PDF, Excel(.xlsx), Word(.docx), Markdown(.md)',
+        fileFormatHelp: 'Supported: JSONL, PDF, Excel(.xlsx), Word(.docx), PowerPoint(.pptx), OpenDocument(.odt/.ods), EPUB, RTF, CSV/TSV, Markdown(.md), HTML, Images(OCR), Archives(.zip/.tar.gz)',
+        sheetSplit: 'Spreadsheets (Excel, CSV)',
+        sheetSplitNone: 'One article per file',
+        sheetSplitSheet: 'One article per sheet',
+        sheetSplitRows: 'One article per block of rows',
         uploadFileButton: 'Upload File',
         jsonlFormat: 'Each line must be a JSON object in the following format:',
         filePreview: 'File Preview',
@@ -84,6 +88,11 @@ const TRANSLATIONS = {
         processing: 'Processing...',
         uploading: 'Uploading...',
         uploadComplete: 'Upload complete! Success: {success}, Failed: {failed}',
+        archiveReading: 'Reading archive: {files} files',
+        archiveIngesting: 'Indexing archive: {processed} / {total}',
+        archiveComplete: '{success} articles indexed, {failed} failed, {skipped} files skipped',
+        jobIngesting: 'Indexing articles: {processed} / {total}',
+        jobComplete: '{success} articles indexed, {failed} failed',
         articleAddedSuccess: 'Article added successfully!',
         articleAddError: 'An error occurred while adding the article. Please try again.',
         noUploadData: 'No data to upload.',
@@ -138,6 +147,10 @@ const TRANSLATIONS = {
         editArticle: 'Edit',
         deleteArticle: 'Delete',
         viewOriginalUrl: 'View Original',
+        viewOriginalFile: 'Open Original File',
+        fileLoginRequired: 'Please log in to open the original file.',
+        filePermissionDenied: 'Only the user who added this article can open its file.',
+        fileOpenError: 'The original file could not be opened.',
         noArticlesFound: 'No articles found',
         noArticlesFoundDesc: 'You haven\'t registered any articles yet.',
         
@@ -257,7 +270,11 @@ const TRANSLATIONS = {
         // JSONL Upload
         selectJsonlFile: 'JSONL 파일 선택',
         selectFile: '파일 선택 (JSONL, PDF, Excel, Word, Markdown)',
-        fileFormatHelp: '지원 형식: JSONL, PDF, Excel(.xlsx), Word(.docx), Markdown(.md)',
+        fileFormatHelp: '지원 형식: JSONL, PDF, Excel(.xlsx), Word(.docx), PowerPoint(.pptx), OpenDocument(.odt/.ods), EPUB, RTF, CSV/TSV, Markdown(.md), HTML, 이미지(OCR), 압축 파일(.zip/.tar.gz)',
+        sheetSplit: '스프레드시트 (Excel, CSV)',
+        sheetSplitNone: '파일당 아티클 1개',
+        sheetSplitSheet: '시트마다 아티클 1개',
+        sheetSplitRows: '행 묶음마다 아티클 1개',
         uploadFileButton: '파일 업로드',
         jsonlFormat: '각 줄은 다음 형식의 JSON 객체여야 합니다:',
         filePreview: '파일 미리보기',
@@ -274,6 +291,11 @@ const TRANSLATIONS = {
         processing: '처리 중...',
         uploading: '업로드 중...',
         uploadComplete: '업로드 완료! 성공: {success}개, 실패: {failed}개',
+        archiveReading: '압축 파일 읽는 중: {files}개 파일',
+        archiveIngesting: '압축 파일 색인 중: {processed} / {total}',
+        archiveComplete: '아티클 {success}개 색인, {failed}개 실패, 파일 {skipped}개 건너뜀',
+        jobIngesting: '아티클 색인 중: {processed} / {total}',
+        jobComplete: '아티클 {success}개 색인, {failed}개 실패',
         articleAddedSuccess: '아티클이 성공적으로 추가되었습니다!',
         articleAddError: '아티클 추가 중 오류가 발생했습니다. 다시 시도해주세요.',
         noUploadData: '업로드할 데이터가 없습니다.',
@@ -328,6 +350,10 @@ const TRANSLATIONS = {
         editArticle: '수정',
         deleteArticle: '삭제',
         viewOriginalUrl: '원본 보기',
+        viewOriginalFile: '원본 파일 열기',
+        fileLoginRequired: '원본 파일을 열려면 로그인이 필요합니다.',
+        filePermissionDenied: '이 아티클을 등록한 사용자만 파일을 열 수 있습니다.',
+        fileOpenError: '원본 파일을 열 수 없습니다.',
         noArticlesFound: '아티클이 없습니다',
         noArticlesFoundDesc: '아직 등록한 아티클이 없습니다.',
         
@@ -28,7 +28,7 @@ async function handleStreamSearch() {
 
     // 2. AI 응답 준비 (스피너 표시)
     const messageIds = appendAiMessage();
-    const { contentId, statusId, sourcesId } = messageIds;
+    const { contentId, statusId, sourcesId, citationsId } = messageIds;
     scrollToBottom();
 
     try {
@@ -62,7 +62,11 @@ async function handleStreamSearch() {
                         break;
                     case 'answer':
                         fullAnswer = message.data;
-                        updateAiContent(contentId, fullAnswer);
+                        updateAiContent(contentId, fullAnswer, `live-${sourcesId}`);
+                        scrollToBottom();
+                        break;
+                    case 'citations':
+                        renderCitations(citationsId, message.data.citations, message.data.unsupported_claims, `live-${sourcesId}`);
                         scrollToBottom();
                         break;
                     case 'error':
@@ -129,11 +133,12 @@ function clearChatInterface() {
 }
 
 // 정적 AI 메시지 렌더링 (히스토리 로드용)
-function renderStaticAiMessage(content, sources) {
+function renderStaticAiMessage(content, sources, citations, unsupportedClaims) {
     const container = document.getElementById('chat-container');
     const id = messageIdCounter++;
     const contentId = `ai-content-${id}`;
     const sourcesId = `ai-sources-${id}`;
+    const citationsId = `ai-citations-${id}`;
     
     const msgDiv = document.createElement('div');
     msgDiv.className = 'flex w-full mt-6 space-x-3 max-w-4xl mx-auto ai-bubble-container';
@@ -164,6 +169,7 @@ function renderStaticAiMessage(content, sources) {
             <div class="ai-bubble">
                 <div id="${sourcesId}" class="flex flex-wrap gap-2 mb-3">${sourcesHtml}</div>
                 <div id="${contentId}" class="markdown-content text-slate-800"></div>
+                <div id="${citationsId}"></div>
             </div>
         </div>
     `;
@@ -171,7 +177,8 @@ function renderStaticAiMessage(content, sources) {
     container.appendChild(msgDiv);
     
     // Markdown 렌더링
-    updateAiContent(contentId, content);
+    updateAiContent(contentId, content, `src-${id}`);
+    renderCitations(citationsId, citations, unsupportedClaims, `src-${id}`);
 }
 
 // 사용자 메시지 추가
@@ -196,6 +203,7 @@ function appendAiMessage() {
     const contentId = `ai-content-${id}`;
     const statusId = `ai-status-${id}`;
     const sourcesId = `ai-sources-${id}`;
+    const citationsId = `ai-citations-${id}`;
     
     const msgDiv = document.createElement('div');
     msgDiv.className = 'flex w-full mt-6 space-x-3 max-w-4xl mx-auto ai-bubble-container';
@@ -215,16 +223,17 @@ function appendAiMessage() {
                 </div>
                 <div id="${sourcesId}" class="flex flex-wrap gap-2 mb-3"></div>
                 <div id="${contentId}" class="markdown-content text-slate-800"></div>
+                <div id="${citationsId}"></div>
             </div>
         </div>
     `;
     
     container.appendChild(msgDiv);
-    return { contentId, statusId, sourcesId };
+    return { contentId, statusId, sourcesId, citationsId };
 }
 
 // AI 콘텐츠 업데이트 (Markdown 렌더링)
-function updateAiContent(elementId, text) {
+function updateAiContent(elementId, text, sourceKeyPrefix) {
     const element = document.getElementById(elementId);
     if (!element) return;
     
@@ -234,6 +243,91 @@ function updateAiContent(elementId, text) {
     } else {
         element.textContent = text;
     }
+    linkCitationMarkers(element, sourceKeyPrefix);
+}
+
+// 답변의 [n] 인용 표시를 출처 링크로 변환 (코드 블록과 링크 제외)
+function linkCitationMarkers(element, sourceKeyPrefix) {
+    if (!sourceKeyPrefix) return;
+
+    const walker = document.createTreeWalker(element, NodeFilter.SHOW_TEXT);
+    const nodes = [];
+    while (walker.nextNode()) {
+        const node = walker.currentNode;
+        if (node.parentElement.closest('code, pre, a')) continue;
+        if (/\[\d+\]/.test(node.nodeValue)) nodes.push(node);
+    }
+
+    nodes.forEach(node => {
+        const text = node.nodeValue;
+        const fragment = document.createDocumentFragment();
+        let last = 0;
+        text.replace(/\[(\d+)\]/g, (match, num, offset) => {
+            fragment.appendChild(document.createTextNode(text.slice(last, offset)));
+            const sourceKey = `${sourceKeyPrefix}-${Number(num) - 1}`;
+            if (sourceDataCache.has(sourceKey)) {
+                const sup = document.createElement('sup');
+                sup.className = 'citation-marker text-indigo-600 font-semibold cursor-pointer hover:underline';
+                sup.textContent = match;
+                sup.addEventListener('click', () => openSourceModal(sourceKey));
+                fragment.appendChild(sup);
+            } else {
+                fragment.appendChild(document.createTextNode(match));
+            }
+            last = offset + match.length;
+            return match;
+        });
+        fragment.appendChild(document.createTextNode(text.slice(last)));
+        node.parentNode.replaceChild(fragment, node);
+    });
+}
+
+// 근거 없는 주장 사유 표시 문구
+const claimReasonLabels = {
+    uncited: 'no citation',
+    invalid_source: 'cites a source that was not retrieved',
+    not_supported: 'not supported by the cited source',
+    unverified: 'could not be verified'
+};
+
+// 인용 목록과 근거 없는 주장 렌더링
+function renderCitations(elementId, citations, unsupportedClaims, sourceKeyPrefix) {
+    const element = document.getElementById(elementId);
+    if (!element) return;
+
+    let html = '';
+    if (citations && citations.length > 0) {
+        html += `
+            <details class="mt-4 text-sm">
+                <summary class="cursor-pointer font-semibold text-slate-600">Citations (${citations.length})</summary>
+                <ul class="mt-2 space-y-2">
+        `;
+        citations.forEach(citation => {
+            html += `
+                <li class="bg-slate-50 p-3 rounded-lg border border-slate-100 cursor-pointer hover:bg-slate-100 transition-colors" onclick="openSourceModal('${sourceKeyPrefix}-${citation.marker - 1}')">
+                    <span class="font-semibold text-indigo-600 mr-1">[${citation.marker}]</span>
+                    <span class="text-slate-700">“${escapeHtml(citation.quote)}”</span>
+                    <span class="block text-xs text-slate-400 mt-1">${escapeHtml(citation.title)}${citation.page ? `, p. ${citation.page}` : ''}</span>
+                </li>
+            `;
+        });
+        html += '</ul></details>';
+    }
+
+    if (unsupportedClaims && unsupportedClaims.length > 0) {
+        html += `
+            <div class="mt-4 text-sm bg-amber-50 border border-amber-200 rounded-lg p-3">
+                <p class="font-semibold text-amber-700 mb-1">Claims not backed by the sources</p>
+                <ul class="list-disc pl-5 space-y-1 text-amber-800">
+        `;
+        unsupportedClaims.forEach(claim => {
+            const reason = claimReasonLabels[claim.reason] || claim.reason;
+            html += `<li>${escapeHtml(claim.text)} <span class="text-xs text-amber-600">(${escapeHtml(reason)})</span></li>`;
+        });
+        html += '</ul></div>';
+    }
+
+    element.innerHTML = html;
 }
 
 // AI 상태 메시지 업데이트
@@ -309,6 +403,49 @@ function escapeHtml(text) {
         .replace(/'/g, "&#039;");
 }
 
+// 코드 포인트 단위로 자르기 (한글/일본어/이모지가 깨지지 않도록)
+function truncateText(text, maxChars) {
+    const chars = Array.from(text || '');
+    if (chars.length <= maxChars) return text || '';
+    return chars.slice(0, maxChars).join('') + '...';
+}
+
+// 하이라이트 구간을 <mark>로 감싼 HTML 생성 (오프셋은 코드 포인트 기준)
+function highlightFragment(fragment, matches) {
+    const chars = Array.from(fragment || '');
+    let html = '';
+    let pos = 0;
+    (matches || []).forEach(match => {
+        if (match.start < pos || match.end > chars.length) return;
+        html += escapeHtml(chars.slice(pos, match.start).join(''));
+        html += `<mark class="bg-yellow-200 rounded px-0.5">${escapeHtml(chars.slice(match.start, match.end).join(''))}</mark>`;
+        pos = match.end;
+    });
+    html += escapeHtml(chars.slice(pos).join(''));
+    return html;
+}
+
+// 매칭된 본문/요약 구간 표시
+function renderMatchedPassages(highlights) {
+    const container = document.getElementById('source-modal-passages');
+    const list = document.getElementById('source-modal-passages-list');
+    if (!container || !list) return;
+
+    const passages = (highlights || []).filter(h => h.field !== 'title');
+    if (passages.length === 0) {
+        container.classList.add('hidden');
+        list.innerHTML = '';
+        return;
+    }
+
+    list.innerHTML = passages.map(h => `
+        <p class="text-slate-700 text-sm leading-relaxed whitespace-pre-wrap bg-white p-3 rounded-lg border border-slate-100">
+            <span class="text-xs font-semibold text-slate-400 mr-1">${escapeHtml(h.field)}</span>…${highlightFragment(h.fragment, h.matches)}…
+        </p>
+    `).join('');
+    container.classList.remove('hidden');
+}
+
 // 검색 엔터키 처리
 document.addEventListener('DOMContentLoaded', function() {
     const input = document.getElementById('search-input');
@@ -338,7 +475,8 @@ function openSourceModal(sourceId) {
 
     titleEl.textContent = article.title || "No Title";
     // Check if summary exists, some implementation might not have it.
-    summaryEl.textContent = article.summary || article.content?.substring(0, 500) + "..." || "No Summary";
+    summaryEl.textContent = article.summary || (article.content ? truncateText(article.content, 500) : "No Summary");
+    renderMatchedPassages(source.highlights);
     
     // Score handling
     const score = source.score ? Math.round(source.score * 100) / 100 : 0; // Fix floating point
@@ -355,6 +493,17 @@ function openSourceModal(sourceId) {
         linkEl.classList.add('hidden');
     }
 
+    const fileEl = document.getElementById('source-modal-file');
+    if (fileEl) {
+        if (article.file && article.id) {
+            fileEl.onclick = () => openArticleFile(article.id, article.file.filename);
+            fileEl.classList.remove('hidden');
+        } else {
+            fileEl.onclick = null;
+            fileEl.classList.add('hidden');
+        }
+    }
+
     modal.classList.remove('hidden');
 }
 
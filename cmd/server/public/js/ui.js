@@ -104,7 +104,7 @@ async function loadChatSession(sessionId) {
                 if (msg.role === 'user') {
                     appendUserMessage(msg.content);
                 } else if (msg.role === 'assistant') {
-                    renderStaticAiMessage(msg.content, msg.sources);
+                    renderStaticAiMessage(msg.content, msg.sources, msg.citations, msg.unsupported_claims);
                 }
             });
             scrollToBottom();
@@ -91,7 +91,7 @@ class UserArticleManager {
         const articlesHtml = this.currentArticles.map(article => {
             const createdDate = formatCreatedDate(article.created_date);
             const summary = article.summary || article.content || '';
-            const truncatedSummary = summary.length > 200 ? summary.substring(0, 200) + '...' : summary;
+            const truncatedSummary = Array.from(summary).length > 200 ? Array.from(summary).slice(0, 200).join('') + '...' : summary;
             
             return `
                 <div class="article-item bg-white border border-slate-200 rounded-lg p-6 hover:border-indigo-300 hover:shadow-md transition-all cursor-pointer" data-article-id="${article.id}">
@@ -234,6 +234,10 @@ class UserArticleManager {
                             <span class="mx-2">•</span>
                             <a href="${escapeHtml(article.original_url)}" target="_blank" class="text-indigo-600 hover:text-indigo-800">원문 보기</a>
                         ` : ''}
+                        ${article.file ? `
+                            <span class="mx-2">•</span>
+                            <button type="button" data-file-article-id="${escapeHtml(article.id)}" data-file-name="${escapeHtml(article.file.filename || '')}" class="text-indigo-600 hover:text-indigo-800">원본 파일</button>
+                        ` : ''}
                     </div>
                 </div>
                 
@@ -262,11 +266,67 @@ class UserArticleManager {
                             </div>
                         </div>
                     ` : ''}
+
+                    <div class="mt-6 pt-6 border-t border-slate-200">
+                        <h3 class="text-sm font-semibold text-slate-700 mb-2">관련 아티클</h3>
+                        <div class="related-articles space-y-2 text-sm text-slate-500">불러오는 중...</div>
+                    </div>
                 </div>
             </div>
         `;
 
         document.body.appendChild(modal);
+        const fileButton = modal.querySelector('[data-file-article-id]');
+        if (fileButton) {
+            fileButton.addEventListener('click', () => openArticleFile(fileButton.dataset.fileArticleId, fileButton.dataset.fileName));
+        }
+        this.loadRelatedArticles(article.id, modal.querySelector('.related-articles'));
+    }
+
+    // 관련 아티클 불러오기
+    async loadRelatedArticles(articleId, container) {
+        if (!container) return;
+
+        try {
+            const response = await fetch(`${API_BASE_URL}/api/v1/articles/${articleId}/related?size=5`);
+            if (!response.ok) {
+                throw new Error(`Failed to fetch related articles: ${response.status}`);
+            }
+
+            const data = await response.json();
+            if (!data.results || data.results.length === 0) {
+                container.textContent = '관련 아티클이 없습니다.';
+                return;
+            }
+
+            container.innerHTML = data.results.map(related => {
+                const summary = related.article.summary || '';
+                const truncatedSummary = Array.from(summary).length > 120 ? Array.from(summary).slice(0, 120).join('') + '...' : summary;
+                return `
+                <button type="button" data-article-id="${escapeHtml(related.article.id)}"
+                        class="w-full text-left p-3 rounded-lg border border-slate-100 bg-slate-50 hover:bg-slate-100 transition-colors">
+                    <div class="font-medium text-slate-800">${escapeHtml(related.article.title)}</div>
+                    ${truncatedSummary ? `<div class="text-slate-500 mt-1">${escapeHtml(truncatedSummary)}</div>` : ''}
+                    ${related.shared_tags && related.shared_tags.length > 0 ? `
+                        <div class="flex flex-wrap gap-1 mt-2">
+                            ${related.shared_tags.map(tag => `<span class="px-2 py-0.5 text-xs bg-white text-slate-500 rounded-full border border-slate-200">${escapeHtml(tag)}</span>`).join('')}
+                        </div>
+                    ` : ''}
+                </button>
+            `;
+            }).join('');
+
+            // 관련 아티클 클릭 시 해당 아티클 상세보기로 이동
+            container.querySelectorAll('[data-article-id]').forEach(button => {
+                button.addEventListener('click', () => {
+                    container.closest('.fixed').remove();
+                    this.viewArticleDetail(button.dataset.articleId);
+                });
+            });
+        } catch (error) {
+            console.error('Error fetching related articles:', error);
+            container.textContent = '관련 아티클을 불러오는데 실패했습니다.';
+        }
     }
 
     // 아티클 삭제
@@ -0,0 +1,315 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/snowmerak/open-librarian/lib/client/mail"
+	"github.com/snowmerak/open-librarian/lib/client/mongo"
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+)
+
+const (
+	emailVerificationTTL = 24 * time.Hour
+	passwordResetTTL     = 1 * time.Hour
+)
+
+// ErrEmailNotVerified is returned when an unverified account tries to perform a restricted action
+var ErrEmailNotVerified = errors.New("email verification required")
+
+// EmailRequest represents a request that only carries an email address
+type EmailRequest struct {
+	Email string `json:"email"`
+}
+
+// TokenRequest represents a request that only carries an action token
+type TokenRequest struct {
+	Token string `json:"token"`
+}
+
+// PasswordResetConfirmRequest represents a password reset confirmation
+type PasswordResetConfirmRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// requireVerifiedUser extracts the user from context and ensures their email is verified
+func requireVerifiedUser(ctx context.Context) (*mongo.User, error) {
+	user, ok := ctx.Value(UserContextKey).(*mongo.User)
+	if !ok {
+		return nil, fmt.Errorf("authentication required")
+	}
+	if !user.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+	return user, nil
+}
+
+// issueActionToken creates, stores and returns a single-use token for the user
+func (s *Server) issueActionToken(ctx context.Context, user *mongo.User, purpose string, ttl time.Duration) (string, error) {
+	token, tokenID, expiresAt, err := s.jwtService.GenerateActionToken(user, purpose, ttl)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	if err := s.mongoClient.SaveUserToken(ctx, &mongo.UserToken{
+		ID:        tokenID,
+		UserID:    user.ID,
+		Purpose:   purpose,
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return "", fmt.Errorf("failed to store token: %w", err)
+	}
+
+	return token, nil
+}
+
+// consumeActionToken validates the token signature, marks it as used and returns its user.
+// A token issued for an address the user has since changed is rejected.
+func (s *Server) consumeActionToken(ctx context.Context, token, purpose string) (*mongo.User, error) {
+	claims, err := s.jwtService.ValidateActionToken(token, purpose)
+	if err != nil {
+		return nil, mongo.ErrTokenInvalid
+	}
+
+	stored, err := s.mongoClient.ConsumeUserToken(ctx, claims.ID, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	if stored.UserID.Hex() != claims.UserID {
+		return nil, mongo.ErrTokenInvalid
+	}
+
+	user, err := s.mongoClient.GetUserByID(ctx, stored.UserID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrUserNotFound) {
+			return nil, mongo.ErrTokenInvalid
+		}
+		return nil, err
+	}
+	if claims.Email != user.Email {
+		return nil, mongo.ErrTokenInvalid
+	}
+
+	return user, nil
+}
+
+// SendEmailVerification issues a verification token and emails it to the user
+func (s *Server) SendEmailVerification(ctx context.Context, user *mongo.User) error {
+	verifyLogger := logger.NewLogger("send_email_verification").StartWithMsg("Sending email verification")
+	verifyLogger.Info().Str("user_id", user.ID.Hex()).Msg("Issuing verification token")
+
+	token, err := s.issueActionToken(ctx, user, mongo.TokenPurposeEmailVerification, emailVerificationTTL)
+	if err != nil {
+		verifyLogger.EndWithError(err)
+		return err
+	}
+
+	link := fmt.Sprintf("%s/api/v1/users/verify-email/confirm?token=%s", s.publicBaseURL, url.QueryEscape(token))
+	body := fmt.Sprintf(`Hello %s,
+
+Please confirm your email address for Open Librarian by opening the link below:
+
+%s
+
+This link expires in %d hours. If you did not create an account, you can ignore this email.
+`, user.Username, link, int(emailVerificationTTL.Hours()))
+
+	if err := s.mailSender.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your Open Librarian email address",
+		Body:    body,
+	}); err != nil {
+		verifyLogger.EndWithError(err)
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+
+	verifyLogger.EndWithMsg("Email verification sent")
+	return nil
+}
+
+// ConfirmEmailVerification consumes a verification token and marks the email as verified
+func (s *Server) ConfirmEmailVerification(ctx context.Context, token string) error {
+	user, err := s.consumeActionToken(ctx, token, mongo.TokenPurposeEmailVerification)
+	if err != nil {
+		return err
+	}
+
+	if err := s.mongoClient.MarkEmailVerified(ctx, user.ID, user.Email); err != nil {
+		// The address changed or the account was deleted since the token was checked
+		if errors.Is(err, mongo.ErrUserNotFound) {
+			return mongo.ErrTokenInvalid
+		}
+		return err
+	}
+	return nil
+}
+
+// SendPasswordReset issues a password reset token and emails it.
+// Unknown addresses are ignored silently so the endpoint cannot be used to enumerate accounts.
+func (s *Server) SendPasswordReset(ctx context.Context, email string) error {
+	resetLogger := logger.NewLogger("send_password_reset").StartWithMsg("Sending password reset")
+
+	user, err := s.mongoClient.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, mongo.ErrUserNotFound) {
+			resetLogger.Info().Msg("Password reset requested for unknown email")
+			resetLogger.EndWithMsg("Password reset skipped")
+			return nil
+		}
+		resetLogger.EndWithError(err)
+		return err
+	}
+
+	token, err := s.issueActionToken(ctx, user, mongo.TokenPurposePasswordReset, passwordResetTTL)
+	if err != nil {
+		resetLogger.EndWithError(err)
+		return err
+	}
+
+	body := fmt.Sprintf(`Hello %s,
+
+A password reset was requested for your Open Librarian account.
+Use the following token with POST %s/api/v1/users/password-reset/confirm:
+
+%s
+
+This token expires in %d minutes. If you did not request a reset, you can ignore this email.
+`, user.Username, s.publicBaseURL, token, int(passwordResetTTL.Minutes()))
+
+	if err := s.mailSender.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your Open Librarian password",
+		Body:    body,
+	}); err != nil {
+		resetLogger.EndWithError(err)
+		return fmt.Errorf("failed to send password reset email: %w", err)
+	}
+
+	resetLogger.EndWithMsg("Password reset sent")
+	return nil
+}
+
+// ConfirmPasswordReset consumes a reset token and sets the new password
+func (s *Server) ConfirmPasswordReset(ctx context.Context, token, newPassword string) error {
+	user, err := s.consumeActionToken(ctx, token, mongo.TokenPurposePasswordReset)
+	if err != nil {
+		return err
+	}
+
+	return s.mongoClient.ResetPassword(ctx, user.ID, newPassword)
+}
+
+// requestEmailVerificationHandler re-sends the verification email for an address
+func (s *Server) requestEmailVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	var req EmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Email == "" {
+		http.Error(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	user, err := s.mongoClient.GetUserByEmail(r.Context(), req.Email)
+	if err == nil && !user.EmailVerified {
+		if err := s.SendEmailVerification(r.Context(), user); err != nil {
+			http.Error(w, "Failed to send verification email", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Always respond the same way so the endpoint does not reveal which emails exist
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// confirmEmailVerificationHandler verifies an email address from a token.
+// Accepts the token as a query parameter (link in email) or in a JSON body.
+func (s *Server) confirmEmailVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" && r.Method == http.MethodPost {
+		var req TokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		token = req.Token
+	}
+
+	if token == "" {
+		http.Error(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.ConfirmEmailVerification(r.Context(), token); err != nil {
+		if errors.Is(err, mongo.ErrTokenInvalid) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to verify email", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Email verified successfully",
+	})
+}
+
+// requestPasswordResetHandler sends a password reset email
+func (s *Server) requestPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	var req EmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Email == "" {
+		http.Error(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.SendPasswordReset(r.Context(), req.Email); err != nil {
+		http.Error(w, "Failed to send password reset email", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// confirmPasswordResetHandler sets a new password using a reset token
+func (s *Server) confirmPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	var req PasswordResetConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Token == "" || req.NewPassword == "" {
+		http.Error(w, "Token and new password are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.ConfirmPasswordReset(r.Context(), req.Token, req.NewPassword); err != nil {
+		if errors.Is(err, mongo.ErrTokenInvalid) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, mongo.ErrUserNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
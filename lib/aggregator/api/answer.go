@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/snowmerak/open-librarian/lib/client/llm"
 	"github.com/snowmerak/open-librarian/lib/client/opensearch"
 	"github.com/snowmerak/open-librarian/lib/util/logger"
 )
@@ -179,9 +180,15 @@ Answer (Markdown format):`
 	contentUsageCount := 0
 	summaryUsageCount := 0
 
+	// Share the answer model's prompt budget evenly between articles
+	budget := s.newPromptBudget(llm.TaskAnswer, answerOutputTokens)
+	perArticleTokens := budget.available(func(materials string) string {
+		return fmt.Sprintf(promptTemplate, query, contextIntro+materials)
+	}) / max(1, len(articles))
+
 	for i, article := range articles {
-		// Determine whether to use content or summary based on content length
-		useContent := len(article.Content) < 12000
+		// Use full content only when it fits the article's share of the budget
+		useContent := article.Content != "" && budget.count(article.Content) <= perArticleTokens
 		contentText := article.Summary
 		contentLabel := ""
 
@@ -191,6 +198,7 @@ Answer (Markdown format):`
 		} else {
 			summaryUsageCount++
 		}
+		contentText = truncateToTokens(budget.counter, contentText, perArticleTokens)
 
 		switch queryLang {
 		case "ko":
@@ -259,7 +267,7 @@ Answer (Markdown format):`
 	}
 
 	answerLogger.Info().Msg("Sending prompt to LLM for answer generation")
-	answer, err := s.llmClient.GenerateText(ctx, prompt)
+	answer, err := s.llmClient.GenerateText(ctx, llm.TaskAnswer, prompt)
 	if err != nil {
 		answerLogger.EndWithError(fmt.Errorf("failed to generate answer: %w", err))
 		return "", fmt.Errorf("failed to generate answer: %w", err)
@@ -437,9 +445,15 @@ Answer (Markdown format):`
 	contentUsageCount := 0
 	summaryUsageCount := 0
 
+	// Share the answer model's prompt budget evenly between articles
+	budget := s.newPromptBudget(llm.TaskAnswer, answerOutputTokens)
+	perArticleTokens := budget.available(func(materials string) string {
+		return fmt.Sprintf(promptTemplate, query, contextIntro+materials)
+	}) / max(1, len(articles))
+
 	for i, article := range articles {
-		// Determine whether to use content or summary based on content length
-		useContent := len(article.Content) < 4000
+		// Use full content only when it fits the article's share of the budget
+		useContent := article.Content != "" && budget.count(article.Content) <= perArticleTokens
 		contentText := article.Summary
 		contentLabel := ""
 
@@ -449,6 +463,7 @@ Answer (Markdown format):`
 		} else {
 			summaryUsageCount++
 		}
+		contentText = truncateToTokens(budget.counter, contentText, perArticleTokens)
 
 		switch queryLang {
 		case "ko":
@@ -514,5 +529,5 @@ Answer (Markdown format):`
 		prompt = fmt.Sprintf(promptTemplate, query, context)
 	}
 
-	return s.llmClient.GenerateTextStream(ctx, prompt, callback)
+	return s.llmClient.GenerateTextStream(ctx, llm.TaskAnswer, prompt, callback)
 }
@@ -0,0 +1,360 @@
+package api
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/snowmerak/open-librarian/lib/client/opensearch"
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+	"github.com/snowmerak/open-librarian/lib/util/parser"
+)
+
+// archiveIngestBatch is the number of articles read from an archive before they are passed to
+// the bulk pipeline
+const archiveIngestBatch = 50
+
+// Archive formats accepted by the archive upload
+const (
+	archiveZip   = "zip"
+	archiveTar   = "tar"
+	archiveTarGz = "tar.gz"
+)
+
+var (
+	// ErrUnsupportedArchive is returned for uploads that are not zip, tar or tar.gz archives
+	ErrUnsupportedArchive = errors.New("unsupported archive format")
+	// ErrArchiveTooLarge is returned when an archive has too many files or expands to too many bytes
+	ErrArchiveTooLarge = errors.New("archive exceeds the upload limits")
+)
+
+// archiveFormat detects the format of an archive from its first bytes
+func archiveFormat(r io.ReaderAt) (string, error) {
+	header := make([]byte, 512)
+	n, err := r.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read archive: %w", err)
+	}
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")), bytes.HasPrefix(header, []byte("PK\x05\x06")):
+		return archiveZip, nil
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		return archiveTarGz, nil
+	case len(header) >= 262 && string(header[257:262]) == "ustar":
+		return archiveTar, nil
+	}
+	return "", fmt.Errorf("%w: expected a zip, tar or tar.gz file", ErrUnsupportedArchive)
+}
+
+// checkArchive rejects zip archives whose central directory already exceeds the limits, before
+// a job is started for them. Tar archives have no directory, so they are checked while read.
+func checkArchive(r io.ReaderAt, size int64, format string, config UploadConfig) error {
+	if format != archiveZip {
+		return nil
+	}
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("failed to open zip archive: %w", err)
+	}
+
+	var (
+		files    int
+		expanded uint64
+	)
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		files++
+		expanded += f.UncompressedSize64
+	}
+	if config.MaxArchiveFiles > 0 && files > config.MaxArchiveFiles {
+		return fmt.Errorf("%w: archive has %d files, limit is %d", ErrArchiveTooLarge, files, config.MaxArchiveFiles)
+	}
+	if config.MaxArchiveExpandedSize > 0 && expanded > uint64(config.MaxArchiveExpandedSize) {
+		return fmt.Errorf("%w: archive expands to more than %d MB", ErrArchiveTooLarge, config.MaxArchiveExpandedSize>>20)
+	}
+	return nil
+}
+
+// archiveBudget counts the bytes decompressed from an archive and fails reads once they pass
+// the limit. Declared sizes can be forged, so only the bytes actually produced are trusted.
+type archiveBudget struct {
+	limit int64
+	read  int64
+}
+
+type budgetReader struct {
+	budget *archiveBudget
+	r      io.Reader
+}
+
+func (b *archiveBudget) reader(r io.Reader) io.Reader {
+	return &budgetReader{budget: b, r: r}
+}
+
+func (r *budgetReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.budget.read += int64(n)
+	if r.budget.limit > 0 && r.budget.read > r.budget.limit {
+		return n, fmt.Errorf("%w: archive expands to more than %d MB", ErrArchiveTooLarge, r.budget.limit>>20)
+	}
+	return n, err
+}
+
+// errorReader fails every read, for archive entries that cannot be opened
+type errorReader struct {
+	err error
+}
+
+func (r errorReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// walkArchive calls fn with the name and content of each regular file of an archive, in archive
+// order. Directories, links and devices are skipped. The walk stops with ErrArchiveTooLarge when
+// the archive has more files than MaxArchiveFiles or expands to more than MaxArchiveExpandedSize
+// bytes, and with the first error fn returns.
+func walkArchive(r io.ReaderAt, size int64, format string, config UploadConfig, fn func(name string, r io.Reader) error) error {
+	budget := &archiveBudget{limit: config.MaxArchiveExpandedSize}
+	files := 0
+	countFile := func() error {
+		files++
+		if config.MaxArchiveFiles > 0 && files > config.MaxArchiveFiles {
+			return fmt.Errorf("%w: archive has more than %d files", ErrArchiveTooLarge, config.MaxArchiveFiles)
+		}
+		return nil
+	}
+
+	if format == archiveZip {
+		zr, err := zip.NewReader(r, size)
+		if err != nil {
+			return fmt.Errorf("failed to open zip archive: %w", err)
+		}
+		for _, f := range zr.File {
+			if !f.Mode().IsRegular() {
+				continue
+			}
+			if err := countFile(); err != nil {
+				return err
+			}
+
+			var entry io.Reader
+			rc, err := f.Open()
+			if err != nil {
+				entry = errorReader{fmt.Errorf("failed to open archive entry: %w", err)}
+			} else {
+				entry = budget.reader(rc)
+			}
+			err = fn(f.Name, entry)
+			if rc != nil {
+				rc.Close()
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Tar entries are read in sequence, so skipped entries are still decompressed and counted
+	var stream io.Reader = io.NewSectionReader(r, 0, size)
+	if format == archiveTarGz {
+		gz, err := gzip.NewReader(stream)
+		if err != nil {
+			return fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		defer gz.Close()
+		stream = budget.reader(gz)
+	}
+
+	tr := tar.NewReader(stream)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if errors.Is(err, ErrArchiveTooLarge) {
+				return err
+			}
+			return fmt.Errorf("failed to read tar archive: %w", err)
+		}
+		if !header.FileInfo().Mode().IsRegular() {
+			continue
+		}
+		if err := countFile(); err != nil {
+			return err
+		}
+		if err := fn(header.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// archivePath cleans the path of an archive entry. Paths are only used for titles and tags and
+// entries are never written under their own name, but absolute paths and paths that climb out
+// of the archive ("zip slip") are still rejected since no honest archive contains them.
+func archivePath(name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(name, "/") || (len(name) >= 2 && name[1] == ':') {
+		return "", fmt.Errorf("absolute path is not allowed")
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("path leaves the archive")
+		}
+	}
+	if strings.ContainsRune(name, 0) {
+		return "", fmt.Errorf("path contains a NUL byte")
+	}
+
+	cleaned := path.Clean(name)
+	if cleaned == "." {
+		return "", fmt.Errorf("empty path")
+	}
+	// Zip files from older tools store names in a legacy code page
+	if !utf8.ValidString(cleaned) {
+		cleaned = strings.ToValidUTF8(cleaned, "�")
+	}
+	return cleaned, nil
+}
+
+// ignoredArchivePath reports whether an entry is operating system or tool clutter, such as
+// macOS resource forks, .DS_Store files or a .git directory, rather than a document
+func ignoredArchivePath(entryPath string) bool {
+	for _, segment := range strings.Split(entryPath, "/") {
+		if strings.HasPrefix(segment, ".") || segment == "__MACOSX" {
+			return true
+		}
+	}
+	return strings.EqualFold(path.Base(entryPath), "Thumbs.db") || strings.EqualFold(path.Base(entryPath), "desktop.ini")
+}
+
+// archiveFolderTag returns the folder of an archive entry, which is kept as a tag so the files of
+// one folder can be found together, or "" for files at the top of the archive
+func archiveFolderTag(entryPath string) string {
+	if folder := path.Dir(entryPath); folder != "." {
+		return folder
+	}
+	return ""
+}
+
+// readArchive parses every supported file of an archive into article requests and passes them
+// to ingest in batches of about archiveIngestBatch articles, so the archive is ingested while it
+// is read rather than held in memory. The articles of one file are always in the same batch, since
+// they share its stored original. Files that cannot be read are recorded in the job as skipped
+// rather than failing it; paths are the archive paths of the requests. tags are added to every
+// article.
+func (s *Server) readArchive(ctx context.Context, jobID string, archive *spooledUpload, format string, tags []string, ingest func(articles []ArticleRequest, paths []string)) error {
+	log := logger.NewLogger("archive-read")
+	parseCtx := parser.WithOCR(ctx, s.ocr)
+
+	var (
+		articles []ArticleRequest
+		paths    []string
+	)
+	err := walkArchive(archive.File, archive.Size, format, s.uploadConfig, func(name string, r io.Reader) error {
+		if err := context.Cause(ctx); err != nil {
+			return err
+		}
+
+		entryPath, err := archivePath(name)
+		if err != nil {
+			s.jobs.update(jobID, func(job *BulkJob) {
+				job.Skipped = append(job.Skipped, ArchiveEntryIssue{Path: name, Message: err.Error()})
+			})
+			return nil
+		}
+		if ignoredArchivePath(entryPath) {
+			return nil
+		}
+
+		doc, file, err := s.readArchiveEntry(parseCtx, entryPath, r)
+		if errors.Is(err, ErrArchiveTooLarge) {
+			return err
+		}
+		s.jobs.update(jobID, func(job *BulkJob) {
+			job.FilesRead++
+			if err != nil {
+				job.Skipped = append(job.Skipped, ArchiveEntryIssue{Path: entryPath, Message: err.Error()})
+				return
+			}
+			for _, warning := range doc.Warnings {
+				job.Warnings = append(job.Warnings, ArchiveEntryIssue{Path: entryPath, Message: warning})
+			}
+		})
+		if err != nil {
+			log.Warn().Err(err).Str("path", entryPath).Msg("Skipped archive file")
+			return nil
+		}
+
+		req := articleFromDocument(doc)
+		req.File = file
+		var folder []string
+		if tag := archiveFolderTag(entryPath); tag != "" {
+			folder = []string{tag}
+		}
+		req.Tags = slices.Concat(tags, folder, req.Tags)
+		for _, article := range sectionArticles(req, doc.Sections) {
+			articles = append(articles, article)
+			paths = append(paths, entryPath)
+		}
+
+		if len(articles) >= archiveIngestBatch {
+			ingest(articles, paths)
+			articles, paths = nil, nil
+		}
+		return nil
+	})
+	if err != nil {
+		s.discardOriginals(ctx, articles)
+		return err
+	}
+	if len(articles) > 0 {
+		ingest(articles, paths)
+	}
+	return nil
+}
+
+// readArchiveEntry spools an archive file to disk, detects its format, parses it and keeps the
+// original in blob storage when one is configured
+func (s *Server) readArchiveEntry(ctx context.Context, entryPath string, r io.Reader) (*parser.Document, *opensearch.FileRef, error) {
+	upload := &spooledUpload{Filename: path.Base(entryPath)}
+	defer upload.Close()
+	if err := upload.spool(r, s.uploadConfig, s.uploadConfig.maxUploadSize()); err != nil {
+		return nil, nil, err
+	}
+
+	p, err := upload.detect(s.uploadConfig, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	doc, err := parser.ParseAs(ctx, p.Type(), upload.File, upload.Size, upload.Filename, s.uploadConfig.Limits)
+	if err != nil {
+		return nil, nil, err
+	}
+	if doc.Content == "" {
+		if len(doc.Warnings) > 0 {
+			return nil, nil, fmt.Errorf("no readable text: %s", strings.Join(doc.Warnings, "; "))
+		}
+		return nil, nil, fmt.Errorf("no readable text")
+	}
+
+	file, err := s.storeOriginal(ctx, upload, p)
+	if err != nil {
+		doc.Warnings = append(doc.Warnings, "the original file could not be kept: "+err.Error())
+	}
+	return doc, file, nil
+}
@@ -0,0 +1,274 @@
+package api
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"hash/crc32"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestArchivePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "docs/guide.md", want: "docs/guide.md"},
+		{name: "./docs//guide.md", want: "docs/guide.md"},
+		{name: "docs\\guides\\setup.docx", want: "docs/guides/setup.docx"},
+		{name: "docs/./a/../b.txt", wantErr: true},
+		{name: "../etc/passwd", wantErr: true},
+		{name: "docs/../../etc/passwd", wantErr: true},
+		{name: "..\\windows\\win.ini", wantErr: true},
+		{name: "..", wantErr: true},
+		{name: "/etc/passwd", wantErr: true},
+		{name: "\\server\\share\\file.txt", wantErr: true},
+		{name: "C:\\Windows\\win.ini", wantErr: true},
+		{name: "c:/boot.ini", wantErr: true},
+		{name: "docs/report.pdf\x00.txt", wantErr: true},
+		{name: "", wantErr: true},
+		{name: ".", wantErr: true},
+		{name: "docs/\xb9\xae\xbc\xad.txt", want: "docs/\ufffd.txt"},
+		{name: "문서/보고서.pdf", want: "문서/보고서.pdf"},
+	}
+	for _, tt := range tests {
+		got, err := archivePath(tt.name)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("archivePath(%q) = %q, want an error", tt.name, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("archivePath(%q) = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+type archiveFile struct {
+	name string
+	data []byte
+}
+
+func buildZip(t *testing.T, files []archiveFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range files {
+		w, err := zw.Create(file.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(file.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// buildForgedZip writes one deflated entry whose headers declare declaredSize bytes instead
+// of the real size of data
+func buildForgedZip(t *testing.T, name string, data []byte, declaredSize uint64) []byte {
+	t.Helper()
+	var compressed bytes.Buffer
+	fw, err := flate.NewWriter(&compressed, flate.BestCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(data)
+	fw.Close()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               name,
+		Method:             zip.Deflate,
+		CRC32:              crc32.ChecksumIEEE(data),
+		CompressedSize64:   uint64(compressed.Len()),
+		UncompressedSize64: declaredSize,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(compressed.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func buildTarGz(t *testing.T, files []archiveFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for _, file := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: file.name, Mode: 0o644, Size: int64(len(file.data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(file.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// walkAll walks an archive reading every file in full, or none of them when skip is set, and
+// returns the names seen and the bytes read
+func walkAll(data []byte, config UploadConfig, skip bool) ([]string, int64, error) {
+	format, err := archiveFormat(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, err
+	}
+	var (
+		names []string
+		read  int64
+	)
+	err = walkArchive(bytes.NewReader(data), int64(len(data)), format, config, func(name string, r io.Reader) error {
+		names = append(names, name)
+		if skip {
+			return nil
+		}
+		n, err := io.Copy(io.Discard, r)
+		read += n
+		return err
+	})
+	return names, read, err
+}
+
+// readSlack is how far past a limit a read may go: the read that crosses it returns its bytes
+// along with the error, and io.Copy reads 32 KB at a time
+const readSlack = 32 << 10
+
+func archiveLimits(files int, expanded int64) UploadConfig {
+	config := DefaultUploadConfig()
+	config.MaxArchiveFiles = files
+	config.MaxArchiveExpandedSize = expanded
+	return config
+}
+
+func TestWalkArchiveReadsFiles(t *testing.T) {
+	files := []archiveFile{{"a.txt", []byte("first")}, {"docs/b.md", []byte("second")}}
+	for format, data := range map[string][]byte{"zip": buildZip(t, files), "tar.gz": buildTarGz(t, files)} {
+		names, read, err := walkAll(data, archiveLimits(10, 1<<20), false)
+		if err != nil {
+			t.Fatalf("%s: walkArchive failed: %v", format, err)
+		}
+		if strings.Join(names, ",") != "a.txt,docs/b.md" || read != 11 {
+			t.Fatalf("%s: read %v (%d bytes)", format, names, read)
+		}
+	}
+}
+
+func TestZipBombIsRejected(t *testing.T) {
+	// 4 MB of zeros deflates to a few kilobytes
+	data := buildZip(t, []archiveFile{{"bomb.txt", make([]byte, 4<<20)}})
+	config := archiveLimits(10, 1<<20)
+
+	if err := checkArchive(bytes.NewReader(data), int64(len(data)), archiveZip, config); !errors.Is(err, ErrArchiveTooLarge) {
+		t.Fatalf("checkArchive = %v, want ErrArchiveTooLarge", err)
+	}
+	_, read, err := walkAll(data, config, false)
+	if !errors.Is(err, ErrArchiveTooLarge) {
+		t.Fatalf("walkArchive = %v, want ErrArchiveTooLarge", err)
+	}
+	if read > config.MaxArchiveExpandedSize+readSlack {
+		t.Fatalf("read %d bytes past a limit of %d", read, config.MaxArchiveExpandedSize)
+	}
+}
+
+func TestZipWithForgedSizeIsRejected(t *testing.T) {
+	// The headers claim 1 KB, so the directory check passes, but the entry expands to 4 MB
+	data := buildForgedZip(t, "forged.txt", make([]byte, 4<<20), 1<<10)
+	config := archiveLimits(10, 1<<20)
+
+	if err := checkArchive(bytes.NewReader(data), int64(len(data)), archiveZip, config); err != nil {
+		t.Fatalf("checkArchive = %v, expected the forged size to pass the directory check", err)
+	}
+	_, read, err := walkAll(data, config, false)
+	if err == nil {
+		t.Fatal("walkArchive read a forged entry without an error")
+	}
+	if !errors.Is(err, ErrArchiveTooLarge) && !errors.Is(err, zip.ErrFormat) {
+		t.Fatalf("walkArchive = %v, want ErrArchiveTooLarge or zip.ErrFormat", err)
+	}
+	if read > config.MaxArchiveExpandedSize {
+		t.Fatalf("read %d bytes past a limit of %d", read, config.MaxArchiveExpandedSize)
+	}
+}
+
+func TestZipBudgetIsSharedByEntries(t *testing.T) {
+	// Each entry is under the limit on its own; together they are not
+	files := make([]archiveFile, 4)
+	for i := range files {
+		files[i] = archiveFile{name: strings.Repeat("x", i+1) + ".txt", data: make([]byte, 400<<10)}
+	}
+	data := buildZip(t, files)
+
+	names, read, err := walkAll(data, archiveLimits(10, 1<<20), false)
+	if !errors.Is(err, ErrArchiveTooLarge) {
+		t.Fatalf("walkArchive = %v, want ErrArchiveTooLarge", err)
+	}
+	if len(names) != 3 || read > 1<<20+readSlack {
+		t.Fatalf("expected the third entry to exhaust the budget, stopped at %d after %d bytes", len(names), read)
+	}
+}
+
+func TestTarGzOverExpandedSizeIsRejected(t *testing.T) {
+	files := []archiveFile{
+		{"a.txt", make([]byte, 600<<10)},
+		{"b.txt", make([]byte, 600<<10)},
+		{"c.txt", []byte("never reached")},
+	}
+	data := buildTarGz(t, files)
+	config := archiveLimits(10, 1<<20)
+
+	// Tar archives have no directory to check up front
+	if err := checkArchive(bytes.NewReader(data), int64(len(data)), archiveTarGz, config); err != nil {
+		t.Fatalf("checkArchive = %v, want nil", err)
+	}
+
+	for _, skip := range []bool{false, true} {
+		names, _, err := walkAll(data, config, skip)
+		if !errors.Is(err, ErrArchiveTooLarge) {
+			t.Fatalf("skip=%v: walkArchive = %v, want ErrArchiveTooLarge", skip, err)
+		}
+		// Entries that are not read are still decompressed to reach the next header
+		if len(names) == len(files) {
+			t.Fatalf("skip=%v: every file was walked despite the limit", skip)
+		}
+	}
+}
+
+func TestArchiveFileCountLimit(t *testing.T) {
+	files := []archiveFile{{"a.txt", []byte("a")}, {"b.txt", []byte("b")}, {"c.txt", []byte("c")}}
+	config := archiveLimits(2, 1<<20)
+
+	zipData := buildZip(t, files)
+	if err := checkArchive(bytes.NewReader(zipData), int64(len(zipData)), archiveZip, config); !errors.Is(err, ErrArchiveTooLarge) {
+		t.Fatalf("checkArchive = %v, want ErrArchiveTooLarge", err)
+	}
+	for format, data := range map[string][]byte{"zip": zipData, "tar.gz": buildTarGz(t, files)} {
+		names, _, err := walkAll(data, config, false)
+		if !errors.Is(err, ErrArchiveTooLarge) || len(names) != 2 {
+			t.Fatalf("%s: walkArchive = %v after %v, want ErrArchiveTooLarge after 2 files", format, err, names)
+		}
+	}
+}
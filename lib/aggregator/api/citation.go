@@ -0,0 +1,629 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/snowmerak/open-librarian/lib/client/llm"
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+	"github.com/snowmerak/open-librarian/lib/util/parser"
+)
+
+// Grounding modes for checking answer claims against their sources
+const (
+	GroundingLLM     = "llm"     // The grounding model verifies each claim and quotes its support
+	GroundingLexical = "lexical" // Claims are matched to passages by character overlap only
+)
+
+// Reasons a claim is reported as unsupported
+const (
+	ClaimUncited       = "uncited"        // States something without citing a source
+	ClaimInvalidSource = "invalid_source" // Cites only numbers that match no retrieved source
+	ClaimNotSupported  = "not_supported"  // The grounding model found no support in the cited sources
+	ClaimUnverified    = "unverified"     // Shares little text with the cited sources and was not checked by the model
+)
+
+const (
+	// maxGroundedClaims bounds the claims checked per answer
+	maxGroundedClaims = 20
+	// groundingPassageRunes is the size of the source passages claims are checked against
+	groundingPassageRunes = 400
+	// groundingPassagesPerClaim is the number of candidate passages shown per claim
+	groundingPassagesPerClaim = 3
+	// groundingFieldRunes is the length of the opening of a summary or content that claims are
+	// checked against; the rest of the content is only searched around its highlights
+	groundingFieldRunes = 4000
+	// maxGroundingHighlights bounds the highlights of a source whose surroundings are checked
+	maxGroundingHighlights = 5
+	// groundingOutputTokens is reserved in the context window for grounding verdicts
+	groundingOutputTokens = 1024
+	// lexicalSupportThreshold is the share of a claim's character bigrams a passage must contain to support it
+	lexicalSupportThreshold = 0.3
+	// minClaimRunes is the length below which uncited sentences are not treated as claims
+	minClaimRunes = 20
+)
+
+var (
+	// citationMarkerPattern matches [1] and [1, 2]
+	citationMarkerPattern = regexp.MustCompile(`\[(\d{1,3}(?:\s*,\s*\d{1,3})*)\]`)
+	// leadingMarkerPattern matches a citation marker at the start of text
+	leadingMarkerPattern = regexp.MustCompile(`^\[\d{1,3}(?:\s*,\s*\d{1,3})*\]`)
+	// listMarkerPattern matches Markdown list and quote prefixes
+	listMarkerPattern = regexp.MustCompile(`^(?:[-*+>]|\d+[.)])\s+`)
+	// spaceBeforePunctuation matches the gap left where a marker stood before punctuation
+	spaceBeforePunctuation = regexp.MustCompile(`\s+([.,;:!?。])`)
+)
+
+// citationMarker is a citation marker in a text
+type citationMarker struct {
+	start, end int // Byte offsets
+	numbers    []int
+}
+
+// answerClaim is a sentence of the answer with the source numbers it cites
+type answerClaim struct {
+	text    string // Without markers
+	markers []int  // Every cited number, valid or not
+}
+
+// groundingPassage is a passage of a source that a claim may be checked against
+type groundingPassage struct {
+	marker  int
+	field   string
+	text    string
+	start   int             // Offset in the field in code points
+	bigrams map[string]bool // Character bigrams of text, computed once for every claim
+	overlap float64
+}
+
+// claimCheck is a cited claim with the passages of its sources that best match it
+type claimCheck struct {
+	position   int // Index of the claim in the answer
+	claim      answerClaim
+	markers    []int // Valid, de-duplicated source numbers
+	candidates []groundingPassage
+}
+
+// groundingResult is an answer with validated markers, its citations and the claims that lack support
+type groundingResult struct {
+	answer      string
+	citations   []Citation
+	unsupported []Claim
+}
+
+// findCitationMarkers returns the citation markers in text, skipping Markdown links such as [1](url)
+func findCitationMarkers(text string) []citationMarker {
+	var markers []citationMarker
+	for _, match := range citationMarkerPattern.FindAllStringSubmatchIndex(text, -1) {
+		if match[1] < len(text) && text[match[1]] == '(' {
+			continue
+		}
+
+		var numbers []int
+		for _, part := range strings.Split(text[match[2]:match[3]], ",") {
+			if n, err := strconv.Atoi(strings.TrimSpace(part)); err == nil {
+				numbers = append(numbers, n)
+			}
+		}
+		markers = append(markers, citationMarker{start: match[0], end: match[1], numbers: numbers})
+	}
+	return markers
+}
+
+// rewriteCitationMarkers writes every marker as separate [n] markers and drops numbers that match no source or repeat
+func rewriteCitationMarkers(answer string, sourceCount int) string {
+	var sb strings.Builder
+	last := 0
+	for _, marker := range findCitationMarkers(answer) {
+		text := answer[last:marker.start]
+		valid := validMarkers(marker.numbers, sourceCount)
+		if len(valid) == 0 {
+			// Drop the space that separated the removed marker from the text
+			text = strings.TrimRight(text, " \t")
+		}
+		sb.WriteString(text)
+		for _, n := range valid {
+			fmt.Fprintf(&sb, "[%d]", n)
+		}
+		last = marker.end
+	}
+	sb.WriteString(answer[last:])
+	return sb.String()
+}
+
+// splitAnswerClaims splits a Markdown answer into sentences. Headings, code blocks and table rules are skipped.
+func splitAnswerClaims(answer string) []answerClaim {
+	var claims []answerClaim
+	inCode := false
+	for _, line := range strings.Split(answer, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode || line == "" || strings.HasPrefix(line, "#") || strings.Trim(line, "|-: ") == "" {
+			continue
+		}
+		line = listMarkerPattern.ReplaceAllString(line, "")
+
+		for _, sentence := range splitSentences(line) {
+			var claim answerClaim
+			var sb strings.Builder
+			last := 0
+			for _, marker := range findCitationMarkers(sentence) {
+				sb.WriteString(sentence[last:marker.start])
+				claim.markers = append(claim.markers, marker.numbers...)
+				last = marker.end
+			}
+			sb.WriteString(sentence[last:])
+
+			claim.text = spaceBeforePunctuation.ReplaceAllString(strings.Join(strings.Fields(sb.String()), " "), "$1")
+			if claim.text != "" {
+				claims = append(claims, claim)
+			}
+		}
+	}
+	return claims
+}
+
+// splitSentences splits text after sentence punctuation.
+// Markers following the punctuation stay with the sentence before them.
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		if !isSentenceEnd(r, text[i:]) {
+			continue
+		}
+
+		for {
+			rest := strings.TrimLeft(text[i:], " \t")
+			loc := leadingMarkerPattern.FindStringIndex(rest)
+			if loc == nil {
+				break
+			}
+			i = len(text) - len(rest) + loc[1]
+		}
+		sentences = append(sentences, text[start:i])
+		start = i
+	}
+	if strings.TrimSpace(text[start:]) != "" {
+		sentences = append(sentences, text[start:])
+	}
+	return sentences
+}
+
+// isSentenceEnd reports whether r ends a sentence, given the text after it
+func isSentenceEnd(r rune, rest string) bool {
+	switch r {
+	case '。', '！', '？':
+		return true
+	case '.', '!', '?':
+		return rest == "" || rest[0] == ' ' || rest[0] == '\t' || rest[0] == '['
+	}
+	return false
+}
+
+// isStatement reports whether an uncited sentence asserts something worth citing,
+// as opposed to a short remark, a lead-in to a list or a question back to the user
+func isStatement(text string) bool {
+	if utf8.RuneCountInString(text) < minClaimRunes {
+		return false
+	}
+	last, _ := utf8.DecodeLastRuneInString(text)
+	return last != ':' && last != '?' && last != '？'
+}
+
+// validMarkers returns the de-duplicated numbers that refer to a source
+func validMarkers(numbers []int, sourceCount int) []int {
+	var valid []int
+	for _, n := range numbers {
+		if n >= 1 && n <= sourceCount && !slices.Contains(valid, n) {
+			valid = append(valid, n)
+		}
+	}
+	return valid
+}
+
+// characterBigrams returns the lowercased letter and digit bigrams of text.
+// Bigrams work for languages written without spaces and tolerate inflection better than whole words.
+func characterBigrams(text string) map[string]bool {
+	bigrams := make(map[string]bool)
+	prev := rune(-1)
+	for _, r := range strings.ToLower(text) {
+		if !unicode.IsLetter(r) && !unicode.IsNumber(r) {
+			prev = -1
+			continue
+		}
+		if prev >= 0 {
+			bigrams[string([]rune{prev, r})] = true
+		}
+		prev = r
+	}
+	return bigrams
+}
+
+// lexicalOverlap returns the share of the claim's bigrams that occur in text
+func lexicalOverlap(claim map[string]bool, text string) float64 {
+	return bigramOverlap(claim, characterBigrams(text))
+}
+
+// bigramOverlap returns the share of the claim's bigrams that are in bigrams
+func bigramOverlap(claim, bigrams map[string]bool) float64 {
+	if len(claim) == 0 {
+		return 0
+	}
+	found := 0
+	for bigram := range claim {
+		if bigrams[bigram] {
+			found++
+		}
+	}
+	return float64(found) / float64(len(claim))
+}
+
+// splitPassages cuts a window of a field that starts offset code points into it into passages
+// of about groundingPassageRunes, ending them at a line or sentence break where possible
+func splitPassages(marker int, field, text string, offset int) []groundingPassage {
+	runes := []rune(text)
+	var passages []groundingPassage
+	for start := 0; start < len(runes); {
+		for start < len(runes) && unicode.IsSpace(runes[start]) {
+			start++
+		}
+		if start == len(runes) {
+			break
+		}
+
+		end := min(start+groundingPassageRunes, len(runes))
+		if end < len(runes) {
+			if cut := passageBreak(runes[start:end]); cut > groundingPassageRunes/2 {
+				end = start + cut
+			}
+		}
+
+		passageText := strings.TrimRightFunc(string(runes[start:end]), unicode.IsSpace)
+		passages = append(passages, groundingPassage{
+			marker:  marker,
+			field:   field,
+			text:    passageText,
+			start:   offset + start,
+			bigrams: characterBigrams(passageText),
+		})
+		start = end
+	}
+	return passages
+}
+
+// passageBreak returns the position after the last line or sentence break in a window, -1 if there is none
+func passageBreak(window []rune) int {
+	for i := len(window) - 1; i > 0; i-- {
+		switch window[i-1] {
+		case '\n', '。', '！', '？':
+			return i
+		case '.', '!', '?':
+			if unicode.IsSpace(window[i]) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// sourcePassages returns the passages of a source that claims are checked against: the opening
+// of its summary and content and the surroundings of its content highlights. Contents can be
+// whole books, so the rest of them is left out to keep grounding cheap.
+func sourcePassages(marker int, source SearchResultWithScore) []groundingPassage {
+	passages := splitPassages(marker, "summary", runeWindow(source.Article.Summary, 0, groundingFieldRunes), 0)
+
+	windows := [][2]int{{0, groundingFieldRunes}}
+	for _, highlight := range source.Highlights {
+		if highlight.Field != "content" || highlight.Start < 0 {
+			continue
+		}
+		if len(windows) > maxGroundingHighlights {
+			break
+		}
+		windows = append(windows, [2]int{max(highlight.Start-groundingPassageRunes, 0), highlight.End + groundingPassageRunes})
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i][0] < windows[j][0] })
+
+	// Overlapping windows are merged so no passage is checked twice
+	merged := windows[:1]
+	for _, window := range windows[1:] {
+		last := &merged[len(merged)-1]
+		if window[0] <= last[1] {
+			last[1] = max(last[1], window[1])
+			continue
+		}
+		merged = append(merged, window)
+	}
+	for _, window := range merged {
+		text := runeWindow(source.Article.Content, window[0], window[1])
+		passages = append(passages, splitPassages(marker, "content", text, window[0])...)
+	}
+	return passages
+}
+
+// runeWindow returns the code points of text from start to end without converting all of it
+func runeWindow(text string, start, end int) string {
+	from, n := len(text), 0
+	for i := range text {
+		if n == start {
+			from = i
+		}
+		if n == end {
+			return text[from:i]
+		}
+		n++
+	}
+	return text[from:]
+}
+
+// bestQuote returns the sentence of a passage that shares the most text with the claim,
+// with its offsets in the field
+func bestQuote(claim map[string]bool, passage groundingPassage) (string, int, int) {
+	quote, bestOverlap := passage.text, -1.0
+	for _, line := range strings.Split(passage.text, "\n") {
+		for _, sentence := range splitSentences(line) {
+			sentence = strings.TrimSpace(sentence)
+			if overlap := lexicalOverlap(claim, sentence); sentence != "" && overlap > bestOverlap {
+				quote, bestOverlap = sentence, overlap
+			}
+		}
+	}
+	start, end := locateQuote(passage, quote)
+	return quote, start, end
+}
+
+// locateQuote returns the offsets of a quote from a passage in the passage's field, -1 if it is not in the passage
+func locateQuote(passage groundingPassage, quote string) (int, int) {
+	index := strings.Index(passage.text, quote)
+	if quote == "" || index < 0 {
+		return -1, -1
+	}
+	start := passage.start + utf8.RuneCountInString(passage.text[:index])
+	return start, start + utf8.RuneCountInString(quote)
+}
+
+// groundAnswer validates the citation markers of an answer against the sources, quotes the passage
+// backing each cited claim and reports the claims that are uncited or unsupported
+func (s *Server) groundAnswer(ctx context.Context, answer string, sources []SearchResultWithScore) *groundingResult {
+	result := &groundingResult{answer: rewriteCitationMarkers(answer, len(sources))}
+	if answer == "" || len(sources) == 0 {
+		// Without sources the answer can only say nothing was found, so there is nothing to ground
+		return result
+	}
+
+	groundingLogger := logger.NewLogger("grounding")
+
+	passagesBySource := make(map[int][]groundingPassage)
+	var checks []claimCheck
+	// flagged holds the unsupported claims by their position in the answer, so they are reported in order
+	flagged := make(map[int]Claim)
+	claims := splitAnswerClaims(answer)
+	for i, claim := range claims {
+		valid := validMarkers(claim.markers, len(sources))
+		switch {
+		case len(claim.markers) == 0:
+			if isStatement(claim.text) {
+				flagged[i] = Claim{Text: claim.text, Reason: ClaimUncited}
+			}
+		case len(valid) == 0:
+			flagged[i] = Claim{Text: claim.text, Markers: claim.markers, Reason: ClaimInvalidSource}
+		case len(checks) < maxGroundedClaims:
+			for _, marker := range valid {
+				if _, ok := passagesBySource[marker]; !ok {
+					passagesBySource[marker] = sourcePassages(marker, sources[marker-1])
+				}
+			}
+			checks = append(checks, newClaimCheck(i, claim, valid, passagesBySource))
+		}
+	}
+
+	// Claims the model did not judge are checked lexically
+	verdicts := make(map[int]GroundingVerdict)
+	if s.groundingMode == GroundingLLM && len(checks) > 0 {
+		var err error
+		if verdicts, err = s.verifyClaims(ctx, checks); err != nil {
+			groundingLogger.Warn().Err(err).Int("claims", len(checks)).Msg("Claim verification failed, falling back to lexical grounding")
+		}
+	}
+
+	for i, check := range checks {
+		var citations []Citation
+		var reason string
+		if verdict, ok := verdicts[i+1]; ok {
+			citations, reason = modelCitations(check, verdict)
+		} else {
+			citations, reason = lexicalCitations(check)
+		}
+		if reason != "" {
+			flagged[check.position] = Claim{Text: check.claim.text, Markers: check.markers, Reason: reason}
+			continue
+		}
+		for _, citation := range citations {
+			citation.SourceID = sources[citation.Marker-1].Article.ID
+			citation.Title = sources[citation.Marker-1].Article.Title
+			if citation.Field == "content" {
+				citation.Page = parser.PageAt(sources[citation.Marker-1].Article.Content, citation.Start)
+			}
+			result.citations = append(result.citations, citation)
+		}
+	}
+
+	positions := make([]int, 0, len(flagged))
+	for i := range flagged {
+		positions = append(positions, i)
+	}
+	sort.Ints(positions)
+	for _, i := range positions {
+		result.unsupported = append(result.unsupported, flagged[i])
+	}
+
+	groundingLogger.Info().
+		Int("claims", len(claims)).
+		Int("citations", len(result.citations)).
+		Int("unsupported", len(result.unsupported)).
+		Str("mode", s.groundingMode).
+		Msg("Grounded answer in sources")
+	return result
+}
+
+// newClaimCheck picks the candidate passages of a claim: the best match of every cited source,
+// then the next best matches overall
+func newClaimCheck(position int, claim answerClaim, markers []int, passagesBySource map[int][]groundingPassage) claimCheck {
+	bigrams := characterBigrams(claim.text)
+	check := claimCheck{position: position, claim: claim, markers: markers}
+
+	var rest []groundingPassage
+	for _, marker := range markers {
+		scored := make([]groundingPassage, len(passagesBySource[marker]))
+		for i, passage := range passagesBySource[marker] {
+			passage.overlap = bigramOverlap(bigrams, passage.bigrams)
+			scored[i] = passage
+		}
+		sort.SliceStable(scored, func(i, j int) bool {
+			return scored[i].overlap > scored[j].overlap
+		})
+		if len(scored) > 0 {
+			check.candidates = append(check.candidates, scored[0])
+			rest = append(rest, scored[1:]...)
+		}
+	}
+
+	sort.SliceStable(rest, func(i, j int) bool {
+		return rest[i].overlap > rest[j].overlap
+	})
+	for _, passage := range rest {
+		if len(check.candidates) >= groundingPassagesPerClaim {
+			break
+		}
+		check.candidates = append(check.candidates, passage)
+	}
+	return check
+}
+
+// modelCitations turns the model's verdict on a claim into citations, or the reason the claim is unsupported
+func modelCitations(check claimCheck, verdict GroundingVerdict) ([]Citation, string) {
+	if !verdict.Supported {
+		return nil, ClaimNotSupported
+	}
+	if verdict.Passage < 1 || verdict.Passage > len(check.candidates) {
+		// Supported without naming a valid passage; locate the support lexically instead
+		return lexicalCitations(check)
+	}
+
+	passage := check.candidates[verdict.Passage-1]
+	quote := strings.TrimSpace(verdict.Quote)
+	start, end := locateQuote(passage, quote)
+	if start < 0 {
+		// Paraphrased quotes are replaced by the closest sentence of the passage
+		quote, start, end = bestQuote(characterBigrams(check.claim.text), passage)
+	}
+	return []Citation{{
+		Marker:   passage.marker,
+		Claim:    check.claim.text,
+		Field:    passage.field,
+		Quote:    quote,
+		Start:    start,
+		End:      end,
+		Verified: GroundingLLM,
+	}}, ""
+}
+
+// lexicalCitations cites the best matching passage of every source whose overlap with the claim is high enough
+func lexicalCitations(check claimCheck) ([]Citation, string) {
+	bigrams := characterBigrams(check.claim.text)
+
+	var citations []Citation
+	for _, marker := range check.markers {
+		for _, passage := range check.candidates {
+			if passage.marker != marker {
+				continue
+			}
+			// Candidates hold the best passage of each source first
+			if passage.overlap >= lexicalSupportThreshold {
+				quote, start, end := bestQuote(bigrams, passage)
+				citations = append(citations, Citation{
+					Marker:   marker,
+					Claim:    check.claim.text,
+					Field:    passage.field,
+					Quote:    quote,
+					Start:    start,
+					End:      end,
+					Verified: GroundingLexical,
+				})
+			}
+			break
+		}
+	}
+
+	if len(citations) == 0 {
+		return nil, ClaimUnverified
+	}
+	return citations, ""
+}
+
+// groundingInstructions is the fixed part of the claim verification prompt
+const groundingInstructions = `Check whether each numbered claim from an answer is supported by the passages listed under it.
+A claim is supported only if its passages state or directly imply it; general knowledge does not count.
+Claims and passages may be in different languages.
+For every claim return its number, whether it is supported, the number of the passage that supports it best (0 if none)
+and the shortest exact span copied from that passage that supports it (empty if none).
+`
+
+// verifyClaims asks the grounding model whether each claim is supported by its candidate passages.
+// Claims that do not fit the context window are left out. Verdicts are keyed by 1-based claim position.
+func (s *Server) verifyClaims(ctx context.Context, checks []claimCheck) (map[int]GroundingVerdict, error) {
+	budget := s.newPromptBudget(llm.TaskGrounding, groundingOutputTokens)
+
+	var sb strings.Builder
+	sb.WriteString(groundingInstructions)
+	var passageOffsets []int // Global number of each claim's first passage, minus one
+	passageCount := 0
+	for i, check := range checks {
+		var claim strings.Builder
+		fmt.Fprintf(&claim, "\nClaim %d: %s\n", i+1, check.claim.text)
+		for j, passage := range check.candidates {
+			fmt.Fprintf(&claim, "  Passage %d (source [%d]): %s\n", passageCount+j+1, passage.marker, strings.Join(strings.Fields(passage.text), " "))
+		}
+		if !budget.fits(sb.String() + claim.String()) {
+			break
+		}
+		sb.WriteString(claim.String())
+		passageOffsets = append(passageOffsets, passageCount)
+		passageCount += len(check.candidates)
+	}
+	if len(passageOffsets) == 0 {
+		return nil, fmt.Errorf("no claim fits the grounding model's context window")
+	}
+
+	var out GroundingOutput
+	schema := groundingSchema(len(passageOffsets), passageCount)
+	if err := s.llmClient.GenerateJSON(ctx, llm.TaskGrounding, sb.String(), "claim_verification", schema, &out); err != nil {
+		return nil, fmt.Errorf("failed to verify claims: %w", err)
+	}
+
+	verdicts := make(map[int]GroundingVerdict, len(out.Verdicts))
+	for _, verdict := range out.Verdicts {
+		if verdict.Claim < 1 || verdict.Claim > len(passageOffsets) {
+			continue
+		}
+		// Passages are numbered across claims in the prompt; make them relative to the claim
+		if verdict.Passage > 0 {
+			verdict.Passage -= passageOffsets[verdict.Claim-1]
+		}
+		verdicts[verdict.Claim] = verdict
+	}
+	return verdicts, nil
+}
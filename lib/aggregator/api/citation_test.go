@@ -0,0 +1,88 @@
+package api
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFindCitationMarkers(t *testing.T) {
+	tests := []struct {
+		text    string
+		numbers [][]int
+	}{
+		{"MongoDB stores documents [3].", [][]int{{3}}},
+		{"Both [1, 2] and [4] agree.", [][]int{{1, 2}, {4}}},
+		{"Spaces inside [ 5 ,6 ] are not a marker.", nil},
+		{"Compact list [7,8,9].", [][]int{{7, 8, 9}}},
+		{"See [1](https://example.com) or [2].", [][]int{{2}}},
+		{"Four digits [1234] are not a source number.", nil},
+		{"No markers here.", nil},
+		{"끝에 붙은 표시[12]", [][]int{{12}}},
+	}
+	for _, tt := range tests {
+		markers := findCitationMarkers(tt.text)
+		if len(markers) != len(tt.numbers) {
+			t.Errorf("findCitationMarkers(%q) found %d markers, want %d", tt.text, len(markers), len(tt.numbers))
+			continue
+		}
+		for i, marker := range markers {
+			if !slices.Equal(marker.numbers, tt.numbers[i]) {
+				t.Errorf("findCitationMarkers(%q)[%d] = %v, want %v", tt.text, i, marker.numbers, tt.numbers[i])
+			}
+			if raw := tt.text[marker.start:marker.end]; raw[0] != '[' || raw[len(raw)-1] != ']' {
+				t.Errorf("findCitationMarkers(%q)[%d] spans %q, want the bracketed marker", tt.text, i, raw)
+			}
+		}
+	}
+}
+
+func TestRewriteCitationMarkers(t *testing.T) {
+	// Three sources were retrieved, so only 1-3 are valid
+	tests := map[string]string{
+		"Go compiles fast [1, 2].":         "Go compiles fast [1][2].",
+		"Unknown source [4].":              "Unknown source.",
+		"Partly valid [2, 2, 9] claim.":    "Partly valid [2] claim.",
+		"Zero is never a source [0][3].":   "Zero is never a source[3].",
+		"A link [1](https://example.com).": "A link [1](https://example.com).",
+		"Nothing cited.":                   "Nothing cited.",
+	}
+	for answer, want := range tests {
+		if got := rewriteCitationMarkers(answer, 3); got != want {
+			t.Errorf("rewriteCitationMarkers(%q) = %q, want %q", answer, got, want)
+		}
+	}
+}
+
+func TestSplitAnswerClaims(t *testing.T) {
+	answer := "## Summary\n" +
+		"Go is a compiled language [1]. It has garbage collection [2][3]!\n" +
+		"\n" +
+		"```go\n" +
+		"fmt.Println(\"not a claim [1].\")\n" +
+		"```\n" +
+		"- Goroutines are cheap [2]\n" +
+		"1. Channels connect them [1, 3].\n" +
+		"|---|:---:|\n" +
+		"東京は日本の首都です。[4]大阪は西日本の中心です。\n" +
+		"Version 1.22 added range over integers [1]."
+
+	want := []answerClaim{
+		{text: "Go is a compiled language.", markers: []int{1}},
+		{text: "It has garbage collection!", markers: []int{2, 3}},
+		{text: "Goroutines are cheap", markers: []int{2}},
+		{text: "Channels connect them.", markers: []int{1, 3}},
+		{text: "東京は日本の首都です。", markers: []int{4}},
+		{text: "大阪は西日本の中心です。"},
+		{text: "Version 1.22 added range over integers.", markers: []int{1}},
+	}
+
+	got := splitAnswerClaims(answer)
+	if len(got) != len(want) {
+		t.Fatalf("splitAnswerClaims returned %d claims, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i].text != want[i].text || !slices.Equal(got[i].markers, want[i].markers) {
+			t.Errorf("claim %d = %q %v, want %q %v", i, got[i].text, got[i].markers, want[i].text, want[i].markers)
+		}
+	}
+}
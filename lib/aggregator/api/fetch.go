@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+
+	"github.com/snowmerak/open-librarian/lib/client/web"
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+	"github.com/snowmerak/open-librarian/lib/util/parser"
+)
+
+// ErrUnsupportedContent is returned when a fetched page is not a type the parsers handle
+var ErrUnsupportedContent = errors.New("unsupported content type")
+
+// AddArticleFromURL fetches a web page or document, extracts its main content and indexes it.
+// The article links to the page's canonical URL, and takes its author and date from the page
+// metadata unless the request sets them.
+func (s *Server) AddArticleFromURL(ctx context.Context, req *ArticleFromURLRequest) (*ArticleResponse, error) {
+	log := logger.NewLogger("add_article_from_url").StartWithMsg("Adding article from URL")
+
+	doc, finalURL, err := s.fetchDocument(ctx, req.URL)
+	if err != nil {
+		log.EndWithError(err)
+		return nil, err
+	}
+	if doc.Content == "" {
+		err := fmt.Errorf("%w: no readable content found", ErrUnsupportedContent)
+		log.EndWithError(err)
+		return nil, err
+	}
+
+	article := articleFromDocument(doc)
+	if article.OriginalURL == "" {
+		article.OriginalURL = finalURL
+	}
+
+	if req.Title != "" {
+		article.Title = req.Title
+	}
+	if req.Author != "" {
+		article.Author = req.Author
+	}
+	if req.CreatedDate != "" {
+		article.CreatedDate = req.CreatedDate
+	}
+	log.Info().Str("title", article.Title).Str("original_url", article.OriginalURL).Msg("Extracted article from page")
+
+	resp, err := s.AddArticle(ctx, article)
+	if err != nil {
+		log.EndWithError(err)
+		return nil, err
+	}
+	resp.Warnings = doc.Warnings
+
+	log.EndWithMsg("Article added from URL")
+	return resp, nil
+}
+
+// fetchDocument fetches rawURL, spools the response, detects its format and parses it.
+// It returns the document and the URL it was fetched from after redirects.
+func (s *Server) fetchDocument(ctx context.Context, rawURL string) (*parser.Document, string, error) {
+	page, err := s.fetcher.Fetch(ctx, rawURL)
+	if err != nil {
+		return nil, "", err
+	}
+	defer page.Body.Close()
+
+	filename := pageFilename(page.URL)
+	upload := &spooledUpload{Filename: filename}
+	defer upload.Close()
+	if err := upload.spool(page.Body, s.uploadConfig, s.uploadConfig.maxUploadSize()); err != nil {
+		return nil, "", err
+	}
+
+	// The content decides the format; the Content-Type header only helps with text formats
+	p, err := upload.detect(s.uploadConfig, page.ContentType)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ctx = parser.WithOCR(ctx, s.ocr)
+	var doc *parser.Document
+	if p.Type() == parser.TypeHTML {
+		doc, err = parser.ParsePage(ctx, upload.File, upload.Size, page.URL, page.ContentType, s.uploadConfig.Limits)
+	} else {
+		doc, err = parser.ParseAs(ctx, p.Type(), upload.File, upload.Size, filename, s.uploadConfig.Limits)
+	}
+	if err != nil {
+		if errors.Is(err, parser.ErrLimitExceeded) || ctx.Err() != nil {
+			return nil, "", err
+		}
+		return nil, "", fmt.Errorf("%w: %v", ErrUnsupportedContent, err)
+	}
+	return doc, page.URL, nil
+}
+
+// pageFilename returns the last path segment of a URL, or its host for the site root
+func pageFilename(pageURL string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return pageURL
+	}
+	if name := path.Base(u.Path); name != "." && name != "/" {
+		return name
+	}
+	return u.Host
+}
+
+// isFetchClientError reports whether a fetch failed because of the requested URL rather than the remote server
+func isFetchClientError(err error) bool {
+	return errors.Is(err, web.ErrInvalidURL) || errors.Is(err, web.ErrBlockedAddress)
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/snowmerak/open-librarian/lib/client/mongo"
+	"github.com/snowmerak/open-librarian/lib/client/opensearch"
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+	"github.com/snowmerak/open-librarian/lib/util/parser"
+)
+
+// File download access modes
+const (
+	FileAccessUsers     = "users"     // Any signed-in user may download original files
+	FileAccessRegistrar = "registrar" // Only the user who added the article may download its file
+)
+
+var (
+	// ErrNoFile is returned when an article has no stored original file
+	ErrNoFile = errors.New("article has no original file")
+	// ErrFileAccessDenied is returned when a user may not download an article's file
+	ErrFileAccessDenied = errors.New("permission denied: only the registrar can download this file")
+)
+
+// storeOriginal keeps the original bytes of an upload in blob storage under a new key and
+// returns the reference to link from the article. It returns nil when no store is configured.
+func (s *Server) storeOriginal(ctx context.Context, upload *spooledUpload, p parser.Parser) (*opensearch.FileRef, error) {
+	if s.blobs == nil {
+		return nil, nil
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(upload.File, 0, upload.Size)); err != nil {
+		return nil, fmt.Errorf("failed to checksum file: %w", err)
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate file key: %w", err)
+	}
+	id := hex.EncodeToString(idBytes)
+
+	contentType := "application/octet-stream"
+	if mediaTypes := p.MediaTypes(); len(mediaTypes) > 0 {
+		contentType = mediaTypes[0]
+	}
+
+	file := &opensearch.FileRef{
+		Key:         "originals/" + id[:2] + "/" + id,
+		Filename:    upload.Filename,
+		ContentType: contentType,
+		Size:        upload.Size,
+		SHA256:      checksum,
+	}
+	if err := s.blobs.Put(ctx, file.Key, io.NewSectionReader(upload.File, 0, upload.Size), file.Size, file.SHA256, file.ContentType); err != nil {
+		return nil, fmt.Errorf("failed to store original file: %w", err)
+	}
+	return file, nil
+}
+
+// discardOriginal removes a stored original that no article links to, such as the file of a
+// duplicate or of an upload that failed to index
+func (s *Server) discardOriginal(ctx context.Context, file *opensearch.FileRef) {
+	if s.blobs == nil || file == nil {
+		return
+	}
+	if err := s.blobs.Delete(context.WithoutCancel(ctx), file.Key); err != nil {
+		logger.NewLogger("original_file").Warn().Err(err).Str("key", file.Key).Msg("Failed to delete unused original file")
+	}
+}
+
+// discardOriginals removes the stored originals of articles that were not indexed
+func (s *Server) discardOriginals(ctx context.Context, articles []ArticleRequest) {
+	s.discardUnusedOriginals(ctx, articles, nil)
+}
+
+// discardUnusedOriginals removes the stored originals of a bulk ingestion that no indexed
+// article links to. Articles split from one file share it, so a file is kept as long as one
+// of them was indexed.
+func (s *Server) discardUnusedOriginals(ctx context.Context, articles []ArticleRequest, results []BulkArticleResult) {
+	used := make(map[string]bool)
+	for _, result := range results {
+		if result.Success && !result.Duplicate && articles[result.Index].File != nil {
+			used[articles[result.Index].File.Key] = true
+		}
+	}
+	for _, article := range articles {
+		if article.File == nil || used[article.File.Key] {
+			continue
+		}
+		used[article.File.Key] = true // Each file is deleted once
+		s.discardOriginal(ctx, article.File)
+	}
+}
+
+// OpenArticleFile returns the original file of an article for the user on ctx. The caller
+// closes the reader.
+func (s *Server) OpenArticleFile(ctx context.Context, id string) (*opensearch.FileRef, io.ReadCloser, error) {
+	user, ok := ctx.Value(UserContextKey).(*mongo.User)
+	if !ok {
+		return nil, nil, fmt.Errorf("authentication required")
+	}
+
+	article, err := s.opensearchClient.GetArticle(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if article.File == nil || s.blobs == nil {
+		return nil, nil, ErrNoFile
+	}
+	if s.fileAccess == FileAccessRegistrar && article.Registrar != user.Username {
+		return nil, nil, ErrFileAccessDenied
+	}
+
+	body, err := s.blobs.Open(ctx, article.File.Key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open original file: %w", err)
+	}
+	return article.File, body, nil
+}
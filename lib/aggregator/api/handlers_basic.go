@@ -2,13 +2,14 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/snowmerak/open-librarian/lib/client/opensearch"
 	"github.com/snowmerak/open-librarian/lib/util/logger"
 )
 
@@ -79,6 +80,13 @@ func (h *HTTPServer) AddArticleHandler(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		addLogger.Error().Err(err).Msg("Error adding article")
 		addLogger.EndWithError(err)
+		if errors.Is(err, ErrEmailNotVerified) {
+			writeErrorResponse(w, http.StatusForbidden, "email_not_verified", "Please verify your email address before adding articles")
+			return
+		}
+		if writeLLMError(w, err) {
+			return
+		}
 		writeErrorResponse(w, http.StatusInternalServerError, "processing_error", "Failed to process article")
 		return
 	}
@@ -117,6 +125,9 @@ func (h *HTTPServer) SearchHandler(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		searchLogger.Error().Err(err).Msg("Error performing search")
 		searchLogger.EndWithError(err)
+		if writeLLMError(w, err) {
+			return
+		}
 		writeErrorResponse(w, http.StatusInternalServerError, "search_error", "Failed to perform search")
 		return
 	}
@@ -126,6 +137,45 @@ func (h *HTTPServer) SearchHandler(w http.ResponseWriter, r *http.Request) {
 	writeJSONResponse(w, http.StatusOK, resp)
 }
 
+// HybridSearchHandler handles deterministic hybrid search requests
+func (h *HTTPServer) HybridSearchHandler(w http.ResponseWriter, r *http.Request) {
+	hybridLogger := logger.NewLogger("hybrid-search-handler")
+	hybridLogger.StartWithMsg("Processing hybrid search request")
+
+	ctx := r.Context()
+
+	var req HybridSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		hybridLogger.Error().Err(err).Msg("Invalid JSON format")
+		hybridLogger.EndWithError(err)
+		writeErrorResponse(w, http.StatusBadRequest, "invalid_json", "Invalid JSON format")
+		return
+	}
+
+	if req.Query == "" {
+		hybridLogger.Error().Msg("Missing query in request")
+		hybridLogger.EndWithError(fmt.Errorf("query is required"))
+		writeErrorResponse(w, http.StatusBadRequest, "missing_query", "Query is required")
+		return
+	}
+
+	resp, err := h.server.HybridSearch(ctx, &req)
+	if err != nil {
+		hybridLogger.Error().Err(err).Msg("Error performing hybrid search")
+		hybridLogger.EndWithError(err)
+		if errors.Is(err, ErrInvalidSearchParams) {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid_params", err.Error())
+			return
+		}
+		writeErrorResponse(w, http.StatusInternalServerError, "search_error", "Failed to perform search")
+		return
+	}
+
+	hybridLogger.Info().Int("result_count", len(resp.Results)).Msg("Hybrid search completed successfully")
+	hybridLogger.EndWithMsg("Hybrid search request completed")
+	writeJSONResponse(w, http.StatusOK, resp)
+}
+
 // GetArticleHandler handles getting a specific article
 func (h *HTTPServer) GetArticleHandler(w http.ResponseWriter, r *http.Request) {
 	getLogger := logger.NewLogger("get-article-handler")
@@ -156,51 +206,76 @@ func (h *HTTPServer) GetArticleHandler(w http.ResponseWriter, r *http.Request) {
 	writeJSONResponse(w, http.StatusOK, article)
 }
 
+// RelatedArticlesHandler handles requests for articles similar to an article
+func (h *HTTPServer) RelatedArticlesHandler(w http.ResponseWriter, r *http.Request) {
+	h.writeRelatedArticles(w, r, logger.NewLogger("related-articles-handler"))
+}
+
 // KeywordSearchHandler handles keyword-only search requests
 func (h *HTTPServer) KeywordSearchHandler(w http.ResponseWriter, r *http.Request) {
 	keywordLogger := logger.NewLogger("keyword-search-handler")
 	keywordLogger.StartWithMsg("Processing keyword search request")
 
-	ctx := r.Context()
+	req, err := keywordSearchRequestFromQuery(r.URL.Query())
+	if err != nil {
+		keywordLogger.EndWithError(err)
+		writeErrorResponse(w, http.StatusBadRequest, "invalid_params", err.Error())
+		return
+	}
 
-	query := r.URL.Query().Get("q")
-	if query == "" {
+	if req.Query == "" {
 		keywordLogger.Error().Msg("Missing query parameter 'q'")
 		keywordLogger.EndWithError(fmt.Errorf("query parameter 'q' is required"))
 		writeErrorResponse(w, http.StatusBadRequest, "missing_query", "Query parameter 'q' is required")
 		return
 	}
 
-	lang := r.URL.Query().Get("lang")
+	h.writeKeywordSearch(w, r, keywordLogger, req, defaultKeywordSize, maxKeywordSize)
+}
 
-	sizeStr := r.URL.Query().Get("size")
-	size := 10
-	if sizeStr != "" {
-		if s, err := strconv.Atoi(sizeStr); err == nil && s > 0 {
-			size = s
-		}
-	}
+// KeywordSearchJSONHandler handles keyword search requests with a JSON body.
+// The query may be empty to browse articles by filters alone.
+func (h *HTTPServer) KeywordSearchJSONHandler(w http.ResponseWriter, r *http.Request) {
+	keywordLogger := logger.NewLogger("keyword-search-handler")
+	keywordLogger.StartWithMsg("Processing structured keyword search request")
 
-	fromStr := r.URL.Query().Get("from")
-	from := 0
-	if fromStr != "" {
-		if f, err := strconv.Atoi(fromStr); err == nil && f >= 0 {
-			from = f
-		}
+	var req KeywordSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		keywordLogger.Error().Err(err).Msg("Invalid JSON format")
+		keywordLogger.EndWithError(err)
+		writeErrorResponse(w, http.StatusBadRequest, "invalid_json", "Invalid JSON format")
+		return
 	}
 
-	keywordLogger.Info().Str("query", query).Str("lang", lang).Int("size", size).Int("from", from).Msg("Keyword search request details")
+	h.writeKeywordSearch(w, r, keywordLogger, &req, defaultKeywordSize, maxKeywordSize)
+}
 
-	resp, err := h.server.opensearchClient.KeywordSearch(ctx, query, lang, size, from)
+// writeKeywordSearch runs a keyword search and writes the response
+func (h *HTTPServer) writeKeywordSearch(w http.ResponseWriter, r *http.Request, handlerLogger *logger.Logger, req *KeywordSearchRequest, defaultSize, maxSize int) {
+	handlerLogger.Info().
+		Str("query", req.Query).
+		Str("lang", req.Lang).
+		Strs("authors", req.Authors).
+		Strs("tags", req.Tags).
+		Str("sort", req.Sort).
+		Int("size", req.Size).
+		Int("from", req.From).
+		Msg("Keyword search request details")
+
+	resp, err := h.server.KeywordSearch(r.Context(), req, defaultSize, maxSize)
 	if err != nil {
-		keywordLogger.Error().Err(err).Msg("Error performing keyword search")
-		keywordLogger.EndWithError(err)
+		handlerLogger.Error().Err(err).Msg("Error performing keyword search")
+		handlerLogger.EndWithError(err)
+		if errors.Is(err, ErrInvalidSearchParams) {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid_params", err.Error())
+			return
+		}
 		writeErrorResponse(w, http.StatusInternalServerError, "search_error", "Failed to perform search")
 		return
 	}
 
-	keywordLogger.Info().Int("result_count", len(resp.Results)).Msg("Keyword search completed successfully")
-	keywordLogger.EndWithMsg("Keyword search request completed")
+	handlerLogger.Info().Int("result_count", len(resp.Results)).Msg("Keyword search completed successfully")
+	handlerLogger.EndWithMsg("Keyword search request completed")
 	writeJSONResponse(w, http.StatusOK, resp)
 }
 
@@ -215,36 +290,29 @@ func (h *HTTPServer) GetSupportedLanguagesHandler(w http.ResponseWriter, r *http
 // ExternalArticleListHandler handles external article listing requests (read-only)
 func (h *HTTPServer) ExternalArticleListHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	listLogger := logger.NewLogger("external-article-list")
 
-	// Parse query parameters
-	lang := r.URL.Query().Get("lang")
-	author := r.URL.Query().Get("author")
-
-	sizeStr := r.URL.Query().Get("size")
-	size := 20 // Default size
-	if sizeStr != "" {
-		if s, err := strconv.Atoi(sizeStr); err == nil && s > 0 && s <= 100 {
-			size = s // Max 100 articles per request
-		}
+	req, err := keywordSearchRequestFromQuery(r.URL.Query())
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "invalid_params", err.Error())
+		return
 	}
 
-	fromStr := r.URL.Query().Get("from")
-	from := 0
-	if fromStr != "" {
-		if f, err := strconv.Atoi(fromStr); err == nil && f >= 0 {
-			from = f
-		}
+	// Listing has no query text, so lang selects the article language
+	req.Query = ""
+	if req.Lang != "" {
+		req.Langs = append(req.Langs, req.Lang)
+		req.Lang = ""
 	}
 
-	// Build search query for listing
-	query := "*" // Match all documents
-	if author != "" {
-		query = fmt.Sprintf("author:\"%s\"", author)
+	opts, err := resolveKeywordSearch(req, defaultListSize, maxListSize)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "invalid_params", err.Error())
+		return
 	}
 
-	resp, err := h.server.opensearchClient.KeywordSearch(ctx, query, lang, size, from)
+	resp, err := h.server.opensearchClient.Search(ctx, opts)
 	if err != nil {
-		listLogger := logger.NewLogger("external-article-list")
 		listLogger.Error().Err(err).Msg("Error listing articles")
 		writeErrorResponse(w, http.StatusInternalServerError, "search_error", "Failed to list articles")
 		return
@@ -255,8 +323,11 @@ func (h *HTTPServer) ExternalArticleListHandler(w http.ResponseWriter, r *http.R
 		"articles": resp.Results,
 		"total":    resp.Total,
 		"took":     resp.Took,
-		"from":     from,
-		"size":     size,
+		"from":     opts.From,
+		"size":     opts.Size,
+	}
+	if resp.Facets != nil {
+		articlesResponse["facets"] = resp.Facets
 	}
 
 	writeJSONResponse(w, http.StatusOK, articlesResponse)
@@ -292,6 +363,45 @@ func (h *HTTPServer) ExternalArticleDetailHandler(w http.ResponseWriter, r *http
 	writeJSONResponse(w, http.StatusOK, article)
 }
 
+// ExternalRelatedArticlesHandler handles related-article requests from external agents
+func (h *HTTPServer) ExternalRelatedArticlesHandler(w http.ResponseWriter, r *http.Request) {
+	h.writeRelatedArticles(w, r, logger.NewLogger("external-related-articles"))
+}
+
+// writeRelatedArticles reads the related-articles parameters and writes the results.
+// more_like_this and tags default to true.
+func (h *HTTPServer) writeRelatedArticles(w http.ResponseWriter, r *http.Request, relatedLogger *logger.Logger) {
+	relatedLogger.StartWithMsg("Processing related articles request")
+
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		relatedLogger.EndWithError(fmt.Errorf("article ID is required"))
+		writeErrorResponse(w, http.StatusBadRequest, "missing_id", "Article ID is required")
+		return
+	}
+
+	req, err := relatedArticlesRequestFromQuery(r.URL.Query())
+	if err != nil {
+		relatedLogger.EndWithError(err)
+		writeErrorResponse(w, http.StatusBadRequest, "invalid_params", err.Error())
+		return
+	}
+
+	resp, err := h.server.RelatedArticles(r.Context(), id, req)
+	if err != nil {
+		relatedLogger.EndWithError(err)
+		if errors.Is(err, opensearch.ErrArticleNotFound) {
+			writeErrorResponse(w, http.StatusNotFound, "article_not_found", "Article not found")
+			return
+		}
+		writeErrorResponse(w, http.StatusInternalServerError, "related_failed", "Failed to find related articles")
+		return
+	}
+
+	relatedLogger.EndWithMsg("Related articles request completed")
+	writeJSONResponse(w, http.StatusOK, resp)
+}
+
 // ExternalSearchHandler handles external search requests (read-only, simplified)
 func (h *HTTPServer) ExternalSearchHandler(w http.ResponseWriter, r *http.Request) {
 	extSearchLogger := logger.NewLogger("external-search-handler")
@@ -331,6 +441,9 @@ func (h *HTTPServer) ExternalSearchHandler(w http.ResponseWriter, r *http.Reques
 	if err != nil {
 		extSearchLogger.Error().Err(err).Msg("Error performing external search")
 		extSearchLogger.EndWithError(err)
+		if writeLLMError(w, err) {
+			return
+		}
 		writeErrorResponse(w, http.StatusInternalServerError, "search_error", "Failed to perform search")
 		return
 	}
@@ -353,47 +466,21 @@ func (h *HTTPServer) ExternalKeywordSearchHandler(w http.ResponseWriter, r *http
 	extKeywordLogger := logger.NewLogger("external-keyword-search")
 	extKeywordLogger.StartWithMsg("Processing external keyword search request")
 
-	ctx := r.Context()
+	req, err := keywordSearchRequestFromQuery(r.URL.Query())
+	if err != nil {
+		extKeywordLogger.EndWithError(err)
+		writeErrorResponse(w, http.StatusBadRequest, "invalid_params", err.Error())
+		return
+	}
 
-	query := r.URL.Query().Get("q")
-	if query == "" {
+	if req.Query == "" {
 		extKeywordLogger.Error().Msg("Missing query parameter 'q'")
 		extKeywordLogger.EndWithError(fmt.Errorf("query parameter 'q' is required"))
 		writeErrorResponse(w, http.StatusBadRequest, "missing_query", "Query parameter 'q' is required")
 		return
 	}
 
-	lang := r.URL.Query().Get("lang")
-
-	sizeStr := r.URL.Query().Get("size")
-	size := 10
-	if sizeStr != "" {
-		if s, err := strconv.Atoi(sizeStr); err == nil && s > 0 && s <= 50 {
-			size = s // Max 50 for external agents
-		}
-	}
-
-	fromStr := r.URL.Query().Get("from")
-	from := 0
-	if fromStr != "" {
-		if f, err := strconv.Atoi(fromStr); err == nil && f >= 0 {
-			from = f
-		}
-	}
-
-	extKeywordLogger.Info().Str("query", query).Str("lang", lang).Int("size", size).Int("from", from).Msg("External keyword search request details")
-
-	resp, err := h.server.opensearchClient.KeywordSearch(ctx, query, lang, size, from)
-	if err != nil {
-		extKeywordLogger.Error().Err(err).Msg("Error performing external keyword search")
-		extKeywordLogger.EndWithError(err)
-		writeErrorResponse(w, http.StatusInternalServerError, "search_error", "Failed to perform search")
-		return
-	}
-
-	extKeywordLogger.Info().Int("result_count", len(resp.Results)).Msg("External keyword search completed successfully")
-	extKeywordLogger.EndWithMsg("External keyword search request completed")
-	writeJSONResponse(w, http.StatusOK, resp)
+	h.writeKeywordSearch(w, r, extKeywordLogger, req, defaultKeywordSize, maxExternalSize)
 }
 
 // DeleteArticleHandler handles article deletion requests
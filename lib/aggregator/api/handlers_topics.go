@@ -0,0 +1,48 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+)
+
+// TopicsHandler lists the topic clusters with their sizes and representative articles
+func (h *HTTPServer) TopicsHandler(w http.ResponseWriter, r *http.Request) {
+	topicsLogger := logger.NewLogger("topics-handler")
+	topicsLogger.StartWithMsg("Processing topics request")
+
+	topics, err := h.server.mongoClient.GetTopics(r.Context())
+	if err != nil {
+		topicsLogger.EndWithError(err)
+		writeErrorResponse(w, http.StatusInternalServerError, "topics_failed", "Failed to get topics")
+		return
+	}
+
+	topicsLogger.Info().Int("topic_count", len(topics)).Msg("Topics loaded")
+	topicsLogger.EndWithMsg("Topics request completed")
+	writeJSONResponse(w, http.StatusOK, TopicsResponse{Topics: topics})
+}
+
+// RefreshTopicsHandler starts a topic clustering run in the background for a topic admin
+func (h *HTTPServer) RefreshTopicsHandler(w http.ResponseWriter, r *http.Request) {
+	refreshLogger := logger.NewLogger("refresh-topics-handler")
+	refreshLogger.StartWithMsg("Processing topic refresh request")
+
+	if err := h.server.RefreshTopics(r.Context()); err != nil {
+		refreshLogger.EndWithError(err)
+		if errors.Is(err, ErrTopicRefreshForbidden) {
+			writeErrorResponse(w, http.StatusForbidden, "forbidden", "Only topic admins can refresh topics")
+			return
+		}
+		if errors.Is(err, ErrTopicClusteringRunning) {
+			writeErrorResponse(w, http.StatusConflict, "clustering_running", "Topic clustering is already running")
+			return
+		}
+		writeErrorResponse(w, http.StatusInternalServerError, "refresh_failed", "Failed to start topic clustering")
+		return
+	}
+
+	refreshLogger.EndWithMsg("Topic clustering started")
+	writeJSONResponse(w, http.StatusAccepted, map[string]string{"status": "started"})
+}
@@ -2,9 +2,21 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/go-chi/chi/v5"
+
+	"github.com/snowmerak/open-librarian/lib/client/blob"
+	"github.com/snowmerak/open-librarian/lib/client/mongo"
+	"github.com/snowmerak/open-librarian/lib/client/opensearch"
+	"github.com/snowmerak/open-librarian/lib/client/web"
 	"github.com/snowmerak/open-librarian/lib/util/logger"
 	"github.com/snowmerak/open-librarian/lib/util/parser"
 )
@@ -15,66 +27,333 @@ func (h *HTTPServer) UploadArticleHandler(w http.ResponseWriter, r *http.Request
 	log := logger.NewLoggerWithContext(ctx, "upload_article").Start()
 	defer log.End()
 
-	// Parse multipart form (max 10MB)
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		log.Error().Err(err).Msg("Failed to parse multipart form")
-		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+	// Reject unverified accounts before reading the upload
+	if _, err := requireVerifiedUser(ctx); err != nil {
+		log.Warn().Err(err).Msg("User is not allowed to upload articles")
+		http.Error(w, "Please verify your email address before adding articles", http.StatusForbidden)
 		return
 	}
 
-	file, header, err := r.FormFile("file")
+	// Spool the file to disk so large documents are never held in memory
+	upload, err := spoolUpload(w, r, h.server.uploadConfig, h.server.uploadConfig.maxUploadSize())
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to get file from form")
-		http.Error(w, "File is required", http.StatusBadRequest)
+		log.Error().Err(err).Msg("Failed to receive upload")
+		if errors.Is(err, ErrUploadTooLarge) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to parse form: %v", err), http.StatusBadRequest)
 		return
 	}
-	defer file.Close()
+	defer upload.Close()
+
+	filename := upload.Filename
+	log.Info().Str("filename", filename).Int64("size", upload.Size).Msg("Processing uploaded file")
 
-	filename := header.Filename
-	log.Info().Str("filename", filename).Msg("Processing uploaded file")
+	sheets, err := upload.sheetOptions(h.server.uploadConfig.Sheets)
+	if err != nil {
+		log.Warn().Err(err).Msg("Invalid spreadsheet options")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	doc, err := parser.Parse(file, filename)
+	// The format is detected from the content, so a wrong or missing extension does not matter
+	p, err := upload.detect(h.server.uploadConfig, upload.MediaType)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to detect file format")
+		switch {
+		case errors.Is(err, ErrUploadTooLarge):
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		case errors.Is(err, parser.ErrUnsupportedFormat):
+			if _, archiveErr := archiveFormat(upload.File); archiveErr == nil {
+				http.Error(w, "Archives are uploaded to /api/v1/articles/upload/archive", http.StatusUnsupportedMediaType)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		default:
+			http.Error(w, fmt.Sprintf("Failed to read file: %v", err), http.StatusBadRequest)
+		}
+		return
+	}
+	log.Info().Str("file_type", p.Type()).Msg("Detected file format")
+
+	parseCtx := parser.WithSheetOptions(parser.WithOCR(ctx, h.server.ocr), sheets)
+	doc, err := parser.ParseAs(parseCtx, p.Type(), upload.File, upload.Size, filename, h.server.uploadConfig.Limits)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to parse file")
-		http.Error(w, fmt.Sprintf("Failed to parse file: %v", err), http.StatusBadRequest)
+		switch {
+		case errors.Is(err, parser.ErrLimitExceeded):
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		case errors.Is(err, parser.ErrUnsupportedFormat):
+			// Images when OCR is not configured
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		default:
+			http.Error(w, fmt.Sprintf("Failed to parse file: %v", err), http.StatusBadRequest)
+		}
 		return
 	}
+	for _, warning := range doc.Warnings {
+		log.Warn().Str("warning", warning).Msg("Part of the file could not be read")
+	}
 
 	if doc.Content == "" {
 		log.Warn().Msg("Parsed content is empty")
+		if len(doc.Warnings) > 0 {
+			// Such as a scanned PDF without OCR configured
+			http.Error(w, "File has no readable text: "+strings.Join(doc.Warnings, "; "), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "File content is empty or could not be valid text", http.StatusBadRequest)
 		return
 	}
 
-	// Create ArticleRequest
-	req := &ArticleRequest{
-		Title:   doc.Title,
-		Content: doc.Content,
-	}
+	// Author, date, source URL and tags come from the document's metadata
+	req := articleFromDocument(doc)
+	log.Info().Str("author", req.Author).Str("created_date", req.CreatedDate).Strs("tags", req.Tags).Msg("Read document metadata")
 
 	// Override/Set metadata from form values
-	if title := r.FormValue("title"); title != "" {
+	if title := upload.Fields["title"]; title != "" {
 		req.Title = title
 	}
-	if author := r.FormValue("author"); author != "" {
+	if author := upload.Fields["author"]; author != "" {
 		req.Author = author
 	}
-	if originalURL := r.FormValue("original_url"); originalURL != "" {
+	if originalURL := upload.Fields["original_url"]; originalURL != "" {
 		req.OriginalURL = originalURL
 	}
-	if createdDate := r.FormValue("created_date"); createdDate != "" {
+	if createdDate := upload.Fields["created_date"]; createdDate != "" {
 		req.CreatedDate = createdDate
 	}
+	if tags := upload.Fields["tags"]; tags != "" {
+		req.Tags = splitTags(tags)
+	}
+
+	// Keep the original file so it can be opened from the article; the article is still
+	// indexed without it if storage fails
+	warnings := doc.Warnings
+	file, err := h.server.storeOriginal(ctx, upload, p)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to store original file")
+		warnings = append(warnings, "the original file could not be kept: "+err.Error())
+	}
+	req.File = file
+
+	// Sheets or row windows of a spreadsheet become separate articles, ingested as a job
+	if articles := sectionArticles(req, doc.Sections); len(articles) > 1 {
+		job, err := h.server.StartDocumentJob(ctx, filename, articles, warnings)
+		if err != nil {
+			h.server.discardOriginal(ctx, file)
+			log.Error().Err(err).Msg("Failed to start document job")
+			if errors.Is(err, ErrTooManyJobs) {
+				http.Error(w, err.Error(), http.StatusTooManyRequests)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Failed to start ingestion: %v", err), http.StatusInternalServerError)
+			return
+		}
+		log.Info().Str("job_id", job.ID).Int("articles", len(articles)).Msg("Document job queued")
+		writeJSONResponse(w, http.StatusAccepted, job)
+		return
+	}
 
 	// Call AddArticle
 	resp, err := h.server.AddArticle(ctx, req)
 	if err != nil {
+		h.server.discardOriginal(ctx, file)
 		log.Error().Err(err).Msg("Failed to add article")
+		if writeLLMError(w, err) {
+			return
+		}
 		http.Error(w, fmt.Sprintf("Failed to index article: %v", err), http.StatusInternalServerError)
 		return
 	}
+	if resp.Duplicate {
+		// The existing article keeps its own file
+		h.server.discardOriginal(ctx, file)
+	}
+	resp.Warnings = warnings
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(resp)
 }
+
+// UploadArchiveHandler accepts a zip, tar or tar.gz archive and ingests every supported file in
+// it as a background job, responding with the job to poll
+func (h *HTTPServer) UploadArchiveHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.NewLoggerWithContext(ctx, "upload_archive_handler").Start()
+	defer log.End()
+
+	if _, err := requireVerifiedUser(ctx); err != nil {
+		log.Warn().Err(err).Msg("User is not allowed to upload articles")
+		writeErrorResponse(w, http.StatusForbidden, "email_not_verified", "Please verify your email address before adding articles")
+		return
+	}
+
+	upload, err := spoolUpload(w, r, h.server.uploadConfig, h.server.uploadConfig.MaxArchiveSize)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to receive archive")
+		if errors.Is(err, ErrUploadTooLarge) {
+			writeErrorResponse(w, http.StatusRequestEntityTooLarge, "archive_too_large", err.Error())
+			return
+		}
+		writeErrorResponse(w, http.StatusBadRequest, "invalid_form", err.Error())
+		return
+	}
+	log.Info().Str("filename", upload.Filename).Int64("size", upload.Size).Msg("Received archive")
+
+	// Applied to every article, alongside the folder each file is in
+	tags := splitTags(upload.Fields["tags"])
+	sheets, err := upload.sheetOptions(h.server.uploadConfig.Sheets)
+	if err != nil {
+		upload.Close()
+		log.Warn().Err(err).Msg("Invalid spreadsheet options")
+		writeErrorResponse(w, http.StatusBadRequest, "invalid_split", err.Error())
+		return
+	}
+
+	job, err := h.server.StartArchiveJob(parser.WithSheetOptions(ctx, sheets), upload, tags)
+	if err != nil {
+		upload.Close()
+		log.Error().Err(err).Msg("Failed to start archive job")
+		switch {
+		case errors.Is(err, ErrUnsupportedArchive):
+			writeErrorResponse(w, http.StatusUnsupportedMediaType, "unsupported_archive", err.Error())
+		case errors.Is(err, ErrArchiveTooLarge):
+			writeErrorResponse(w, http.StatusRequestEntityTooLarge, "archive_too_large", err.Error())
+		case errors.Is(err, ErrTooManyJobs):
+			writeErrorResponse(w, http.StatusTooManyRequests, "too_many_jobs", err.Error())
+		default:
+			writeErrorResponse(w, http.StatusBadRequest, "invalid_archive", err.Error())
+		}
+		return
+	}
+
+	log.Info().Str("job_id", job.ID).Msg("Archive job queued")
+	writeJSONResponse(w, http.StatusAccepted, job)
+}
+
+// GetJobHandler reports the progress of one of the user's bulk jobs
+func (h *HTTPServer) GetJobHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := ctx.Value(UserContextKey).(*mongo.User)
+	if !ok {
+		writeErrorResponse(w, http.StatusUnauthorized, "unauthorized", "Authentication required")
+		return
+	}
+
+	// Other users' jobs are reported as missing
+	job, ok := h.server.jobs.get(chi.URLParam(r, "id"), user.ID.Hex())
+	if !ok {
+		writeErrorResponse(w, http.StatusNotFound, "job_not_found", "Job not found")
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, job)
+}
+
+// AddArticleFromURLHandler handles fetching a web page or document and indexing it
+func (h *HTTPServer) AddArticleFromURLHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.NewLoggerWithContext(ctx, "add_article_from_url").Start()
+	defer log.End()
+
+	if _, err := requireVerifiedUser(ctx); err != nil {
+		log.Warn().Err(err).Msg("User is not allowed to add articles")
+		writeErrorResponse(w, http.StatusForbidden, "email_not_verified", "Please verify your email address before adding articles")
+		return
+	}
+
+	var req ArticleFromURLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error().Err(err).Msg("Invalid JSON format")
+		writeErrorResponse(w, http.StatusBadRequest, "invalid_json", "Invalid JSON format")
+		return
+	}
+	if req.URL == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "missing_url", "URL is required")
+		return
+	}
+	if req.CreatedDate != "" {
+		if _, err := time.Parse(time.RFC3339, req.CreatedDate); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid_date_format", "Created date must be in RFC3339 format (e.g., 2023-12-25T15:30:00Z)")
+			return
+		}
+	}
+	log.Info().Str("url", req.URL).Msg("Adding article from URL")
+
+	resp, err := h.server.AddArticleFromURL(ctx, &req)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to add article from URL")
+		switch {
+		case isFetchClientError(err):
+			writeErrorResponse(w, http.StatusBadRequest, "invalid_url", err.Error())
+		case errors.Is(err, web.ErrFetchFailed):
+			writeErrorResponse(w, http.StatusBadGateway, "fetch_failed", err.Error())
+		case errors.Is(err, ErrUploadTooLarge), errors.Is(err, parser.ErrLimitExceeded):
+			writeErrorResponse(w, http.StatusRequestEntityTooLarge, "content_too_large", err.Error())
+		case errors.Is(err, ErrUnsupportedContent), errors.Is(err, parser.ErrUnsupportedFormat):
+			writeErrorResponse(w, http.StatusUnsupportedMediaType, "unsupported_content", err.Error())
+		case errors.Is(err, ErrEmailNotVerified):
+			writeErrorResponse(w, http.StatusForbidden, "email_not_verified", "Please verify your email address before adding articles")
+		case writeLLMError(w, err):
+		default:
+			writeErrorResponse(w, http.StatusInternalServerError, "processing_error", fmt.Sprintf("Failed to add article: %v", err))
+		}
+		return
+	}
+
+	log.Info().Str("article_id", resp.ID).Msg("Article added from URL")
+	writeJSONResponse(w, http.StatusCreated, resp)
+}
+
+// ArticleFileHandler serves the original file an article was uploaded as. Files open inline
+// unless ?download=1 is given; they are sandboxed so uploaded HTML cannot run scripts.
+func (h *HTTPServer) ArticleFileHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.NewLoggerWithContext(ctx, "article_file_handler").Start()
+	defer log.End()
+
+	id := chi.URLParam(r, "id")
+	file, body, err := h.server.OpenArticleFile(ctx, id)
+	if err != nil {
+		log.Warn().Err(err).Str("article_id", id).Msg("Failed to open original file")
+		switch {
+		case errors.Is(err, opensearch.ErrArticleNotFound):
+			writeErrorResponse(w, http.StatusNotFound, "article_not_found", "Article not found")
+		case errors.Is(err, ErrNoFile), errors.Is(err, blob.ErrNotFound):
+			writeErrorResponse(w, http.StatusNotFound, "file_not_found", "The original file of this article was not kept")
+		case errors.Is(err, ErrFileAccessDenied):
+			writeErrorResponse(w, http.StatusForbidden, "permission_denied", "Only the registrar can download this file")
+		default:
+			writeErrorResponse(w, http.StatusInternalServerError, "file_error", "Failed to read the original file")
+		}
+		return
+	}
+	defer body.Close()
+
+	disposition := "inline"
+	if r.URL.Query().Get("download") == "1" {
+		disposition = "attachment"
+	}
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": file.Filename}))
+	w.Header().Set("ETag", `"`+file.SHA256+`"`)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "sandbox")
+
+	// Seekable stores serve ranges and conditional requests, which PDF viewers use
+	if seeker, ok := body.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", time.Time{}, seeker)
+		return
+	}
+	if match := r.Header.Get("If-None-Match"); match != "" && match == w.Header().Get("ETag") {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(file.Size, 10))
+	if _, err := io.Copy(w, body); err != nil {
+		log.Warn().Err(err).Str("article_id", id).Msg("Failed to send original file")
+	}
+}
@@ -109,10 +109,12 @@ func (h *HTTPServer) WebSocketSearchHandler(w http.ResponseWriter, r *http.Reque
 
 		// Add AI Message & Save Session
 		session.Messages = append(session.Messages, mongoClient.ChatMessage{
-			Role:      "assistant",
-			Content:   resp.Answer,
-			Sources:   resp.Sources,
-			Timestamp: time.Now(),
+			Role:              "assistant",
+			Content:           resp.Answer,
+			Sources:           resp.Sources,
+			Citations:         resp.Citations,
+			UnsupportedClaims: resp.UnsupportedClaims,
+			Timestamp:         time.Now(),
 		})
 
 		if err := h.server.mongoClient.SaveChatSession(ctx, session); err != nil {
@@ -131,6 +133,15 @@ func (h *HTTPServer) WebSocketSearchHandler(w http.ResponseWriter, r *http.Reque
 			Data: resp.Answer,
 		})
 
+		// 인용 및 근거 없는 주장 전송
+		conn.WriteJSON(WSMessage{
+			Type: "citations",
+			Data: map[string]interface{}{
+				"citations":          resp.Citations,
+				"unsupported_claims": resp.UnsupportedClaims,
+			},
+		})
+
 		// 완료 알림
 		conn.WriteJSON(WSMessage{
 			Type: "done",
@@ -183,6 +194,12 @@ func (h *HTTPServer) WebSocketAddArticleHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	if !user.EmailVerified {
+		log.Warn().Str("username", user.Username).Msg("Email not verified")
+		http.Error(w, "Please verify your email address before adding articles", http.StatusForbidden)
+		return
+	}
+
 	// Add user and claims to context
 	ctx = context.WithValue(ctx, UserContextKey, user)
 	ctx = context.WithValue(ctx, ClaimsContextKey, claims)
@@ -328,6 +345,12 @@ func (h *HTTPServer) WebSocketBulkAddArticleHandler(w http.ResponseWriter, r *ht
 		return
 	}
 
+	if !user.EmailVerified {
+		log.Warn().Str("username", user.Username).Msg("Email not verified")
+		http.Error(w, "Please verify your email address before adding articles", http.StatusForbidden)
+		return
+	}
+
 	// Add user and claims to context
 	ctx = context.WithValue(ctx, UserContextKey, user)
 	ctx = context.WithValue(ctx, ClaimsContextKey, claims)
@@ -42,6 +42,8 @@ func (h *HTTPServer) SetupRoutes() *chi.Mux {
 
 	// API routes
 	router.Route("/api/v1", func(r chi.Router) {
+		r.Use(CacheBypassMiddleware)
+
 		// WebSocket routes (handle authentication internally)
 		r.Get("/articles/ws", h.WebSocketAddArticleHandler)
 		r.Get("/articles/bulk/ws", h.WebSocketBulkAddArticleHandler)
@@ -52,16 +54,25 @@ func (h *HTTPServer) SetupRoutes() *chi.Mux {
 			r.Use(h.server.JWTMiddleware(h.server.jwtService))
 			r.Post("/articles", h.AddArticleHandler)
 			r.Post("/articles/upload", h.UploadArticleHandler)
+			r.Post("/articles/upload/archive", h.UploadArchiveHandler)
+			r.Get("/articles/jobs/{id}", h.GetJobHandler)
+			r.Post("/articles/from-url", h.AddArticleFromURLHandler)
 			r.Delete("/articles/{id}", h.DeleteArticleHandler)
+			r.Get("/articles/{id}/file", h.ArticleFileHandler)
 			r.Post("/articles/user", h.GetUserArticlesHandler) // New route for user articles by date range
+			r.Post("/topics/refresh", h.RefreshTopicsHandler)
 		})
 
 		// Articles (public routes)
 		r.Get("/articles/{id}", h.GetArticleHandler)
+		r.Get("/articles/{id}/related", h.RelatedArticlesHandler)
+		r.Get("/topics", h.TopicsHandler)
 
 		// Search
 		r.Post("/search", h.SearchHandler)
+		r.Post("/search/hybrid", h.HybridSearchHandler)
 		r.Get("/search/keyword", h.KeywordSearchHandler)
+		r.Post("/search/keyword", h.KeywordSearchJSONHandler)
 		r.Get("/search/ws", h.WebSocketSearchHandler)
 
 		// Users
@@ -79,6 +90,8 @@ func (h *HTTPServer) SetupRoutes() *chi.Mux {
 		r.Route("/external", func(r chi.Router) {
 			r.Get("/articles", h.ExternalArticleListHandler)
 			r.Get("/articles/{id}", h.ExternalArticleDetailHandler)
+			r.Get("/articles/{id}/related", h.ExternalRelatedArticlesHandler)
+			r.Get("/topics", h.TopicsHandler)
 			r.Post("/search", h.ExternalSearchHandler)
 			r.Get("/search/keyword", h.ExternalKeywordSearchHandler)
 		})
@@ -0,0 +1,417 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/snowmerak/open-librarian/lib/client/opensearch"
+	"github.com/snowmerak/open-librarian/lib/client/qdrant"
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+)
+
+// Fusion methods for hybrid search
+const (
+	FusionRRF      = "rrf"
+	FusionWeighted = "weighted"
+)
+
+const (
+	defaultHybridSize = 10
+	maxHybridSize     = 100
+	defaultRRFK       = 60
+	// hybridCandidateFactor is how many candidates each retriever returns per requested result
+	hybridCandidateFactor = 3
+)
+
+// ErrInvalidSearchParams is returned when search parameters are out of range
+var ErrInvalidSearchParams = errors.New("invalid search parameters")
+
+// hybridOptions are the resolved parameters of a hybrid search
+type hybridOptions struct {
+	query         string
+	lang          string
+	size          int
+	fusion        string
+	vectorWeight  float64
+	keywordWeight float64
+	rrfK          int
+	expand        bool     // Translate the query into the library's other dominant languages
+	topics        []string // Restrict both retrievers to these topic IDs
+}
+
+// hybridResult is the outcome of a hybrid search
+type hybridResult struct {
+	results  []SearchResultWithScore
+	queries  []QueryVariant // The original query first, then its translations
+	degraded bool           // One retriever failed
+}
+
+// resolveHybridOptions applies defaults to a hybrid search request and validates it
+func (s *Server) resolveHybridOptions(req *HybridSearchRequest) (hybridOptions, error) {
+	opts := hybridOptions{
+		query:  req.Query,
+		lang:   req.Lang,
+		size:   req.Size,
+		fusion: req.Fusion,
+		rrfK:   req.RRFK,
+		expand: req.Expand == nil || *req.Expand,
+		topics: req.Topics,
+	}
+
+	if opts.lang == "" {
+		opts.lang = s.languageDetector.DetectLanguage(req.Query)
+	}
+	if opts.size <= 0 {
+		opts.size = defaultHybridSize
+	}
+	if opts.size > maxHybridSize {
+		return opts, fmt.Errorf("%w: size must be at most %d", ErrInvalidSearchParams, maxHybridSize)
+	}
+	if opts.rrfK <= 0 {
+		opts.rrfK = defaultRRFK
+	}
+
+	// RRF weighs both rankings equally by default; weighted fusion keeps the previous 60/40 split
+	switch opts.fusion {
+	case "", FusionRRF:
+		opts.fusion = FusionRRF
+		opts.vectorWeight, opts.keywordWeight = 1, 1
+	case FusionWeighted:
+		opts.vectorWeight, opts.keywordWeight = 0.6, 0.4
+	default:
+		return opts, fmt.Errorf("%w: unknown fusion %q, expected %q or %q", ErrInvalidSearchParams, opts.fusion, FusionRRF, FusionWeighted)
+	}
+
+	if req.VectorWeight != nil {
+		opts.vectorWeight = *req.VectorWeight
+	}
+	if req.KeywordWeight != nil {
+		opts.keywordWeight = *req.KeywordWeight
+	}
+	if opts.vectorWeight < 0 || opts.keywordWeight < 0 || opts.vectorWeight+opts.keywordWeight == 0 {
+		return opts, fmt.Errorf("%w: weights must be non-negative and not both zero", ErrInvalidSearchParams)
+	}
+
+	return opts, nil
+}
+
+// HybridSearch runs keyword and vector search in parallel and fuses the rankings.
+// If one retriever fails, results from the other are returned and the response is marked degraded.
+func (s *Server) HybridSearch(ctx context.Context, req *HybridSearchRequest) (*HybridSearchResponse, error) {
+	hybridLogger := logger.NewLogger("hybrid_search").StartWithMsg("Performing hybrid search")
+	start := time.Now()
+
+	opts, err := s.resolveHybridOptions(req)
+	if err != nil {
+		hybridLogger.EndWithError(err)
+		return nil, err
+	}
+
+	hybridLogger.Info().
+		Str("query", opts.query).
+		Str("lang", opts.lang).
+		Str("fusion", opts.fusion).
+		Float64("vector_weight", opts.vectorWeight).
+		Float64("keyword_weight", opts.keywordWeight).
+		Msg("Hybrid search request")
+
+	hybrid, err := s.hybridSearch(ctx, opts)
+	if err != nil {
+		hybridLogger.EndWithError(err)
+		return nil, err
+	}
+	results := s.rerank(ctx, RerankPathHybrid, opts.query, hybrid.results)
+
+	hybridLogger.Info().Int("result_count", len(results)).Int("queries", len(hybrid.queries)).Bool("degraded", hybrid.degraded).Msg("Hybrid search finished")
+	hybridLogger.EndWithMsg("Hybrid search complete")
+	return &HybridSearchResponse{
+		Results:  results,
+		Fusion:   opts.fusion,
+		Queries:  hybrid.queries,
+		Took:     time.Since(start).Milliseconds(),
+		Degraded: hybrid.degraded,
+	}, nil
+}
+
+// hybridSearch retrieves candidates from both retrievers and fuses them.
+// Keyword search runs once per query variant with that language's analyzer; the multilingual
+// embedding model matches across languages, so vector search uses the original query only.
+func (s *Server) hybridSearch(ctx context.Context, opts hybridOptions) (*hybridResult, error) {
+	candidates := opts.size * hybridCandidateFactor
+	variants := s.understandQuery(ctx, opts.query, opts.lang, opts.expand)
+	queryLang := variants[0].Lang
+
+	var wg sync.WaitGroup
+	keywordResults := make([][]opensearch.SearchResult, len(variants))
+	keywordErrs := make([]error, len(variants))
+	var vectorResults []qdrant.VectorSearchResult
+	var vectorErr error
+
+	// Skip a retriever whose weight is zero
+	if opts.keywordWeight > 0 {
+		for i, variant := range variants {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp, err := s.opensearchClient.Search(ctx, opensearch.KeywordSearchOptions{
+					Query:   variant.Text,
+					Lang:    variant.Lang,
+					Filters: opensearch.SearchFilters{Topics: opts.topics},
+					Size:    candidates,
+				})
+				if err != nil {
+					keywordErrs[i] = err
+					return
+				}
+				keywordResults[i] = resp.Results
+			}()
+		}
+	}
+
+	if opts.vectorWeight > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			embedding, err := s.llmClient.GenerateEmbedding(ctx, "query: "+opts.query)
+			if err != nil {
+				vectorErr = err
+				return
+			}
+			// Each article has a title and a summary point
+			vectorResults, vectorErr = s.qdrantClient.VectorSearch(ctx, embedding, uint64(candidates*2))
+			if vectorErr == nil {
+				vectorResults, vectorErr = s.filterVectorTopics(ctx, vectorResults, opts.topics)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// Keyword retrieval fails only when every variant failed
+	var keywordErr error
+	if opts.keywordWeight > 0 {
+		keywordErr = errors.Join(keywordErrs...)
+		for _, err := range keywordErrs {
+			if err == nil {
+				keywordErr = nil
+				break
+			}
+		}
+	}
+
+	hybridLogger := logger.NewLogger("hybrid_search")
+	if keywordErr != nil {
+		hybridLogger.Warn().Err(keywordErr).Msg("Keyword retrieval failed")
+	}
+	if vectorErr != nil {
+		hybridLogger.Warn().Err(vectorErr).Msg("Vector retrieval failed")
+	}
+	if keywordErr != nil && vectorErr != nil {
+		return nil, fmt.Errorf("hybrid search failed: %w", errors.Join(keywordErr, vectorErr))
+	}
+	degraded := keywordErr != nil || vectorErr != nil
+
+	// Rank articles by their best point, deduplicating title and summary hits.
+	// Points in the query's language get a small boost instead of a hard filter.
+	vectorScores := make(map[string]float64, len(vectorResults))
+	for _, result := range vectorResults {
+		id := s.extractArticleID(result.ID)
+		score := languageBoostedScore(result.Score, result.Lang, queryLang)
+		if best, seen := vectorScores[id]; !seen || score > best {
+			vectorScores[id] = score
+		}
+	}
+	vectorRanking := rankByScore(vectorScores)
+
+	// One keyword ranking per query variant; an article's keyword score is its best across variants
+	lists := []rankedList{{vectorRanking, opts.vectorWeight}}
+	keywordScores := make(map[string]float64)
+	articles := make(map[string]opensearch.Article, len(vectorScores))
+	highlights := make(map[string][]opensearch.Highlight)
+	for i, variant := range variants {
+		weight := s.variantWeight(variant)
+
+		var ranking []string
+		variantScores := make(map[string]float64, len(keywordResults[i]))
+		for _, result := range keywordResults[i] {
+			if _, seen := variantScores[result.Article.ID]; seen {
+				continue
+			}
+			variantScores[result.Article.ID] = result.Score
+			ranking = append(ranking, result.Article.ID)
+			if _, ok := articles[result.Article.ID]; !ok {
+				articles[result.Article.ID] = result.Article
+				highlights[result.Article.ID] = result.Highlights
+			}
+		}
+
+		lists = append(lists, rankedList{ranking, opts.keywordWeight * weight})
+		for id, score := range normalizeMinMax(variantScores) {
+			keywordScores[id] = max(keywordScores[id], score*weight)
+		}
+	}
+
+	var fused map[string]float64
+	switch opts.fusion {
+	case FusionWeighted:
+		fused = weightedFusion(vectorScores, opts.vectorWeight, keywordScores, opts.keywordWeight)
+	default:
+		fused = reciprocalRankFusion(opts.rrfK, lists...)
+	}
+
+	ids := rankByScore(fused)
+	if len(ids) > opts.size {
+		ids = ids[:opts.size]
+	}
+
+	// Vector hits only carry IDs; fetch the articles keyword search did not return
+	var missing []string
+	for _, id := range ids {
+		if _, ok := articles[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		fetched, err := s.opensearchClient.GetArticlesByIDs(ctx, missing)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch articles: %w", err)
+		}
+		for _, article := range fetched {
+			articles[article.ID] = article
+		}
+	}
+
+	results := make([]SearchResultWithScore, 0, len(ids))
+	for _, id := range ids {
+		article, ok := articles[id]
+		if !ok {
+			// Deleted from OpenSearch but still present in Qdrant
+			continue
+		}
+
+		_, inVector := vectorScores[id]
+		_, inKeyword := keywordScores[id]
+		source := "vector"
+		switch {
+		case inVector && inKeyword:
+			source = "hybrid"
+		case inKeyword:
+			source = "keyword"
+		}
+
+		results = append(results, SearchResultWithScore{
+			Article:    article,
+			Score:      fused[id],
+			Source:     source,
+			Highlights: highlights[id],
+		})
+	}
+
+	return &hybridResult{results: results, queries: variants, degraded: degraded}, nil
+}
+
+// filterVectorTopics keeps the vector hits whose article belongs to one of the topics.
+// Qdrant points do not carry topics, which are reassigned by each clustering run, so the
+// articles' topic_id in OpenSearch is checked instead.
+func (s *Server) filterVectorTopics(ctx context.Context, results []qdrant.VectorSearchResult, topics []string) ([]qdrant.VectorSearchResult, error) {
+	if len(topics) == 0 || len(results) == 0 {
+		return results, nil
+	}
+
+	ids := make([]string, 0, len(results))
+	for _, result := range results {
+		ids = append(ids, s.extractArticleID(result.ID))
+	}
+	in, err := s.opensearchClient.ArticlesInTopics(ctx, ids, topics)
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter vector results by topic: %w", err)
+	}
+
+	filtered := results[:0]
+	for _, result := range results {
+		if in[s.extractArticleID(result.ID)] {
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered, nil
+}
+
+// rankByScore returns the IDs ordered by descending score.
+// Ties are broken by ID so the same inputs always produce the same order.
+func rankByScore(scores map[string]float64) []string {
+	ids := make([]string, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// rankedList is one retriever's ranking with its fusion weight
+type rankedList struct {
+	ids    []string // Best first
+	weight float64
+}
+
+// reciprocalRankFusion scores each ID by the weighted sum of 1/(k+rank) over the lists it appears in
+func reciprocalRankFusion(k int, lists ...rankedList) map[string]float64 {
+	scores := make(map[string]float64)
+	for _, list := range lists {
+		for rank, id := range list.ids {
+			scores[id] += list.weight / float64(k+rank+1)
+		}
+	}
+	return scores
+}
+
+// weightedFusion combines two sets of 0-1 scores with a weighted average; a missing score counts as zero
+func weightedFusion(vectorScores map[string]float64, vectorWeight float64, keywordScores map[string]float64, keywordWeight float64) map[string]float64 {
+	total := vectorWeight + keywordWeight
+	fused := make(map[string]float64, len(vectorScores)+len(keywordScores))
+	for id, score := range vectorScores {
+		fused[id] += vectorWeight * score / total
+	}
+	for id, score := range keywordScores {
+		fused[id] += keywordWeight * score / total
+	}
+	return fused
+}
+
+// normalizeMinMax scales scores to 0-1 relative to the best and worst score in the set.
+// BM25 scores are unbounded and depend on the corpus, so a fixed scale does not work across queries.
+func normalizeMinMax(scores map[string]float64) map[string]float64 {
+	normalized := make(map[string]float64, len(scores))
+	if len(scores) == 0 {
+		return normalized
+	}
+
+	lo, hi := 0.0, 0.0
+	first := true
+	for _, score := range scores {
+		if first || score < lo {
+			lo = score
+		}
+		if first || score > hi {
+			hi = score
+		}
+		first = false
+	}
+
+	for id, score := range scores {
+		if hi == lo {
+			normalized[id] = 1
+			continue
+		}
+		normalized[id] = (score - lo) / (hi - lo)
+	}
+	return normalized
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestReciprocalRankFusion(t *testing.T) {
+	tests := []struct {
+		name  string
+		k     int
+		lists []rankedList
+		want  map[string]float64
+	}{
+		{
+			name:  "single list",
+			k:     60,
+			lists: []rankedList{{ids: []string{"a", "b"}, weight: 1}},
+			want:  map[string]float64{"a": 1.0 / 61, "b": 1.0 / 62},
+		},
+		{
+			name: "scores add up across lists",
+			k:    60,
+			lists: []rankedList{
+				{ids: []string{"a", "b", "c"}, weight: 1},
+				{ids: []string{"c", "a"}, weight: 1},
+			},
+			want: map[string]float64{"a": 1.0/61 + 1.0/62, "b": 1.0 / 62, "c": 1.0/63 + 1.0/61},
+		},
+		{
+			name: "weights scale each list",
+			k:    1,
+			lists: []rankedList{
+				{ids: []string{"a"}, weight: 0.5},
+				{ids: []string{"b"}, weight: 2},
+			},
+			want: map[string]float64{"a": 0.25, "b": 1},
+		},
+		{
+			name:  "zero weight list contributes nothing",
+			k:     60,
+			lists: []rankedList{{ids: []string{"a"}, weight: 0}, {ids: []string{"b"}, weight: 1}},
+			want:  map[string]float64{"a": 0, "b": 1.0 / 61},
+		},
+		{
+			name: "no lists",
+			k:    60,
+			want: map[string]float64{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := reciprocalRankFusion(tt.k, tt.lists...)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for id, score := range tt.want {
+				if !approxEqual(got[id], score) {
+					t.Errorf("score of %s = %v, want %v", id, got[id], score)
+				}
+			}
+		})
+	}
+}
+
+func TestReciprocalRankFusionPrefersAgreement(t *testing.T) {
+	// An article ranked second by both retrievers beats one ranked first by only one
+	fused := reciprocalRankFusion(defaultRRFK,
+		rankedList{ids: []string{"vector-only", "both"}, weight: 1},
+		rankedList{ids: []string{"keyword-only", "both"}, weight: 1},
+	)
+	if ranking := rankByScore(fused); ranking[0] != "both" {
+		t.Fatalf("ranking = %v, want both first", ranking)
+	}
+}
+
+func TestWeightedFusion(t *testing.T) {
+	vector := map[string]float64{"a": 1, "b": 0.5}
+	keyword := map[string]float64{"b": 1, "c": 0.2}
+
+	got := weightedFusion(vector, 0.6, keyword, 0.4)
+	want := map[string]float64{"a": 0.6, "b": 0.3 + 0.4, "c": 0.08}
+	for id, score := range want {
+		if !approxEqual(got[id], score) {
+			t.Errorf("score of %s = %v, want %v", id, got[id], score)
+		}
+	}
+
+	// Weights are relative, so scaling both leaves the result unchanged
+	scaled := weightedFusion(vector, 3, keyword, 2)
+	for id := range want {
+		if !approxEqual(scaled[id], got[id]) {
+			t.Errorf("score of %s with weights 3:2 = %v, want %v", id, scaled[id], got[id])
+		}
+	}
+
+	keywordOnly := weightedFusion(vector, 0, keyword, 1)
+	if keywordOnly["a"] != 0 || !approxEqual(keywordOnly["b"], 1) {
+		t.Errorf("keyword-only fusion = %v, want vector scores ignored", keywordOnly)
+	}
+}
+
+func TestNormalizeMinMax(t *testing.T) {
+	got := normalizeMinMax(map[string]float64{"a": 12.5, "b": 5, "c": 2.5})
+	want := map[string]float64{"a": 1, "b": 0.25, "c": 0}
+	for id, score := range want {
+		if !approxEqual(got[id], score) {
+			t.Errorf("normalized %s = %v, want %v", id, got[id], score)
+		}
+	}
+
+	// A single score, or equal scores, cannot be spread and count as the best
+	if got := normalizeMinMax(map[string]float64{"a": 3, "b": 3}); got["a"] != 1 || got["b"] != 1 {
+		t.Errorf("equal scores normalized to %v, want 1", got)
+	}
+	if got := normalizeMinMax(nil); len(got) != 0 {
+		t.Errorf("normalizing no scores = %v, want empty", got)
+	}
+}
+
+func TestRankByScoreBreaksTiesByID(t *testing.T) {
+	scores := map[string]float64{"c": 0.5, "a": 0.5, "d": 0.9, "b": 0.1}
+	want := []string{"d", "a", "c", "b"}
+	for range 5 {
+		if got := rankByScore(scores); !slices.Equal(got, want) {
+			t.Fatalf("rankByScore = %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,406 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/snowmerak/open-librarian/lib/client/opensearch"
+	"github.com/snowmerak/open-librarian/lib/client/qdrant"
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+)
+
+// articleSteps is the number of progress steps reported while ingesting one article
+const articleSteps = 8
+
+// batchLinger is how long a batching stage waits for more articles before flushing a partial batch
+const batchLinger = 500 * time.Millisecond
+
+// IngestConfig tunes the concurrency of the bulk ingestion pipeline.
+// Articles flow through three stages: LLM (summary and tags), embedding and storage.
+type IngestConfig struct {
+	LLMConcurrency       int // Articles summarized and tagged at the same time
+	EmbeddingConcurrency int // Embedding requests in flight
+	EmbeddingBatchSize   int // Articles per embedding request (two texts each)
+	StorageConcurrency   int // OpenSearch/Qdrant bulk writes in flight
+	StorageBatchSize     int // Articles per bulk write
+	ArchiveConcurrency   int // Bulk jobs, such as uploaded archives, processed at the same time; the others wait in the queue
+}
+
+// DefaultIngestConfig returns default ingestion pipeline configuration
+func DefaultIngestConfig() IngestConfig {
+	return IngestConfig{
+		LLMConcurrency:       2,
+		EmbeddingConcurrency: 2,
+		EmbeddingBatchSize:   16,
+		StorageConcurrency:   2,
+		StorageBatchSize:     50,
+		ArchiveConcurrency:   1,
+	}
+}
+
+// articleEmbeddingInputs returns the texts embedded for an article, in the order used by articlePoints
+func articleEmbeddingInputs(title, summary string) []string {
+	return []string{"passage: " + title, "passage: " + summary}
+}
+
+// articlePoints pairs the title and summary embeddings of an article with their Qdrant point IDs
+func articlePoints(id, lang string, embeddings [][]float64) []qdrant.Point {
+	return []qdrant.Point{
+		{ID: id + "_title", Vector: embeddings[0], Lang: lang},
+		{ID: id + "_summary", Vector: embeddings[1], Lang: lang},
+	}
+}
+
+// articleFingerprint hashes the title and content of an article, ignoring case and whitespace,
+// to find the same article repeated within a bulk request
+func articleFingerprint(title, content string) [sha256.Size]byte {
+	hash := sha256.New()
+	for _, word := range strings.Fields(strings.ToLower(title)) {
+		hash.Write([]byte(word + " "))
+	}
+	hash.Write([]byte{0})
+	for _, word := range strings.Fields(strings.ToLower(content)) {
+		hash.Write([]byte(word + " "))
+	}
+	var sum [sha256.Size]byte
+	hash.Sum(sum[:0])
+	return sum
+}
+
+// ingestItem is one article moving through the bulk ingestion pipeline
+type ingestItem struct {
+	index      int
+	article    *opensearch.Article
+	embeddings [][]float64
+	result     BulkArticleResult
+	report     func(step string) error
+}
+
+// collectBatch reads up to size items, waiting at most linger for more after the first one.
+// Returns false once the channel is closed and drained.
+func collectBatch[T any](in <-chan T, size int, linger time.Duration) ([]T, bool) {
+	first, ok := <-in
+	if !ok {
+		return nil, false
+	}
+
+	batch := []T{first}
+	timer := time.NewTimer(linger)
+	defer timer.Stop()
+
+	for len(batch) < size {
+		select {
+		case item, ok := <-in:
+			if !ok {
+				return batch, true
+			}
+			batch = append(batch, item)
+		case <-timer.C:
+			return batch, true
+		}
+	}
+	return batch, true
+}
+
+// AddArticlesBulkWithProgress processes multiple articles with progress callbacks.
+// Articles are summarized and tagged concurrently, then embedded and stored in batches.
+func (s *Server) AddArticlesBulkWithProgress(ctx context.Context, req *BulkArticleRequest, progressCallback BulkProgressCallback) (*BulkArticleResponse, error) {
+	bulkLogger := logger.NewLogger("bulk_article_processing").StartWithMsg("Processing bulk upload")
+	bulkLogger.Info().
+		Int("article_count", len(req.Articles)).
+		Int("llm_concurrency", s.ingestConfig.LLMConcurrency).
+		Int("embedding_concurrency", s.ingestConfig.EmbeddingConcurrency).
+		Int("storage_concurrency", s.ingestConfig.StorageConcurrency).
+		Msg("Starting bulk article processing")
+
+	user, err := requireVerifiedUser(ctx)
+	if err != nil {
+		bulkLogger.EndWithError(err)
+		return nil, err
+	}
+
+	total := len(req.Articles)
+	response := &BulkArticleResponse{
+		Results: make([]BulkArticleResult, total),
+	}
+
+	// Callers such as websocket connections are not safe for concurrent writes
+	var callbackMu sync.Mutex
+	notify := func(index int, step string, progress int, result *BulkArticleResult) error {
+		if progressCallback == nil {
+			return nil
+		}
+		callbackMu.Lock()
+		defer callbackMu.Unlock()
+		return progressCallback(index, total, step, progress, articleSteps, result)
+	}
+
+	// The duplicate check only sees articles that are already indexed, so copies within the batch
+	// would all pass it. Each article is processed once and its copies share its result.
+	firstOf := make(map[[sha256.Size]byte]int, total)
+	copies := make(map[int][]int)
+	unique := make([]int, 0, total)
+	for i, article := range req.Articles {
+		key := articleFingerprint(article.Title, article.Content)
+		if first, ok := firstOf[key]; ok {
+			copies[first] = append(copies[first], i)
+			continue
+		}
+		firstOf[key] = i
+		unique = append(unique, i)
+	}
+	if len(unique) < total {
+		bulkLogger.Info().Int("repeated_count", total-len(unique)).Msg("Skipping articles repeated within the batch")
+	}
+
+	pipelineCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan int)
+	toEmbed := make(chan *ingestItem, s.ingestConfig.EmbeddingBatchSize)
+	toStore := make(chan *ingestItem, s.ingestConfig.StorageBatchSize)
+	done := make(chan *ingestItem, total)
+
+	finish := func(item *ingestItem, err error) {
+		if err != nil {
+			item.result.Success = false
+			item.result.Error = err.Error()
+			logger.NewLogger("bulk_article_processing").Error().Err(err).Int("index", item.index).Str("title", item.result.Title).Msg("Failed to process article")
+		} else {
+			item.result.Success = true
+			logger.NewLogger("bulk_article_processing").Info().Int("index", item.index).Str("title", item.result.Title).Str("article_id", item.result.ID).Msg("Successfully processed article")
+		}
+		done <- item
+	}
+
+	// Stage 1: duplicate check, summary and tags
+	var llmWG sync.WaitGroup
+	for range max(s.ingestConfig.LLMConcurrency, 1) {
+		llmWG.Add(1)
+		go func() {
+			defer llmWG.Done()
+			for index := range jobs {
+				articleReq := req.Articles[index]
+				item := &ingestItem{
+					index:  index,
+					result: BulkArticleResult{Index: index, Title: articleReq.Title},
+				}
+				step := 0
+				item.report = func(stepName string) error {
+					step++
+					return notify(index, stepName, step, nil)
+				}
+
+				// Process individual article with timeout
+				articleCtx, articleCancel := context.WithTimeout(pipelineCtx, 10*time.Minute)
+				article, existingID, err := s.prepareArticle(articleCtx, &articleReq, user.Username, item.report)
+				articleCancel()
+
+				switch {
+				case err != nil:
+					finish(item, err)
+				case existingID != "":
+					item.result.ID = existingID
+					item.result.Duplicate = true
+					finish(item, nil)
+				default:
+					item.article = article
+					toEmbed <- item
+				}
+			}
+		}()
+	}
+
+	// Stage 2: batched embeddings for titles and summaries
+	var embedWG sync.WaitGroup
+	for range max(s.ingestConfig.EmbeddingConcurrency, 1) {
+		embedWG.Add(1)
+		go func() {
+			defer embedWG.Done()
+			for {
+				batch, ok := collectBatch(toEmbed, max(s.ingestConfig.EmbeddingBatchSize, 1), batchLinger)
+				if !ok {
+					return
+				}
+				s.embedBatch(pipelineCtx, batch, toStore, finish)
+			}
+		}()
+	}
+
+	// Stage 3: bulk writes to OpenSearch and Qdrant
+	var storeWG sync.WaitGroup
+	for range max(s.ingestConfig.StorageConcurrency, 1) {
+		storeWG.Add(1)
+		go func() {
+			defer storeWG.Done()
+			for {
+				batch, ok := collectBatch(toStore, max(s.ingestConfig.StorageBatchSize, 1), batchLinger)
+				if !ok {
+					return
+				}
+				s.storeBatch(pipelineCtx, batch, finish)
+			}
+		}()
+	}
+
+	// Close each stage once the previous one has drained
+	go func() {
+		defer close(jobs)
+		for _, i := range unique {
+			select {
+			case jobs <- i:
+			case <-pipelineCtx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		llmWG.Wait()
+		close(toEmbed)
+		embedWG.Wait()
+		close(toStore)
+		storeWG.Wait()
+		close(done)
+	}()
+
+	// Collect all results
+	record := func(result BulkArticleResult) {
+		response.Results[result.Index] = result
+		if result.Success {
+			response.SuccessCount++
+		} else {
+			response.ErrorCount++
+		}
+
+		// Report completion of this article
+		notify(result.Index, "Article completed", articleSteps, &result)
+	}
+	for item := range done {
+		record(item.result)
+		for _, index := range copies[item.index] {
+			result := BulkArticleResult{Index: index, Title: req.Articles[index].Title}
+			if item.result.Success {
+				result.Success = true
+				result.ID = item.result.ID
+				result.Duplicate = true
+			} else {
+				result.Error = fmt.Sprintf("same article as #%d, which failed: %s", item.index+1, item.result.Error)
+			}
+			record(result)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		bulkLogger.EndWithError(err)
+		return nil, err
+	}
+
+	bulkLogger.Info().Int("success_count", response.SuccessCount).Int("error_count", response.ErrorCount).Msg("Bulk upload completed")
+	bulkLogger.EndWithMsg("Bulk processing complete")
+	return response, nil
+}
+
+// embedBatch embeds the titles and summaries of a batch of articles in one request and passes them on
+func (s *Server) embedBatch(ctx context.Context, batch []*ingestItem, next chan<- *ingestItem, finish func(*ingestItem, error)) {
+	var active []*ingestItem
+	var texts []string
+	for _, item := range batch {
+		if err := item.report("Generating embeddings..."); err != nil {
+			finish(item, err)
+			continue
+		}
+		active = append(active, item)
+		texts = append(texts, articleEmbeddingInputs(item.article.Title, item.article.Summary)...)
+	}
+	if len(active) == 0 {
+		return
+	}
+
+	embeddingCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	embeddings, err := s.llmClient.GenerateEmbeddings(embeddingCtx, texts)
+	if err != nil {
+		err = fmt.Errorf("failed to generate embeddings: %w", err)
+		for _, item := range active {
+			finish(item, err)
+		}
+		return
+	}
+
+	for i, item := range active {
+		item.embeddings = embeddings[2*i : 2*i+2]
+		next <- item
+	}
+}
+
+// storeBatch indexes a batch of articles with one OpenSearch bulk request and one Qdrant upsert
+func (s *Server) storeBatch(ctx context.Context, batch []*ingestItem, finish func(*ingestItem, error)) {
+	var active []*ingestItem
+	articles := make([]*opensearch.Article, 0, len(batch))
+	for _, item := range batch {
+		if err := item.report("Indexing in OpenSearch..."); err != nil {
+			finish(item, err)
+			continue
+		}
+		// Until the next clustering run, a new article joins the topic with the closest center
+		item.article.TopicID = s.nearestTopic(ctx, item.embeddings[1])
+		active = append(active, item)
+		articles = append(articles, item.article)
+	}
+	if len(active) == 0 {
+		return
+	}
+
+	results, err := s.opensearchClient.BulkIndexArticles(ctx, articles)
+	if err != nil {
+		err = fmt.Errorf("failed to index article: %w", err)
+		for _, item := range active {
+			finish(item, err)
+		}
+		return
+	}
+
+	var indexed []*ingestItem
+	var points []qdrant.Point
+	for i, item := range active {
+		if results[i].Error != "" {
+			finish(item, fmt.Errorf("failed to index article: %s", results[i].Error))
+			continue
+		}
+		if err := item.report("Indexing embeddings in Qdrant..."); err != nil {
+			s.removeIndexedArticle(ctx, results[i].ID)
+			finish(item, err)
+			continue
+		}
+		item.result.ID = results[i].ID
+		indexed = append(indexed, item)
+		points = append(points, articlePoints(results[i].ID, item.article.Lang, item.embeddings)...)
+	}
+
+	if err := s.qdrantClient.UpsertPoints(ctx, points); err != nil {
+		err = fmt.Errorf("failed to index vectors in Qdrant: %w", err)
+		for _, item := range indexed {
+			// Without vectors the article would only be found by keyword search
+			s.removeIndexedArticle(ctx, item.result.ID)
+			item.result.ID = ""
+			finish(item, err)
+		}
+		return
+	}
+
+	for _, item := range indexed {
+		finish(item, nil)
+	}
+}
+
+// removeIndexedArticle deletes an article from OpenSearch after a later ingestion step failed
+func (s *Server) removeIndexedArticle(ctx context.Context, id string) {
+	if err := s.opensearchClient.DeleteArticle(ctx, id); err != nil {
+		logger.NewLogger("bulk_article_processing").Warn().Err(err).Str("article_id", id).Msg("Failed to clean up partially indexed article")
+	}
+}
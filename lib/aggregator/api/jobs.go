@@ -0,0 +1,308 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+)
+
+// Bulk job statuses
+const (
+	JobQueued    = "queued"    // Waiting for another job to finish
+	JobReading   = "reading"   // Parsing the files of the archive
+	JobIngesting = "ingesting" // Summarizing, embedding and storing the articles
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+)
+
+const (
+	// jobRetention is how long finished jobs can still be looked up
+	jobRetention = 24 * time.Hour
+	// maxActiveJobsPerUser bounds the unfinished jobs one user may have
+	maxActiveJobsPerUser = 3
+)
+
+// ErrTooManyJobs is returned when a user already has maxActiveJobsPerUser unfinished jobs
+var ErrTooManyJobs = errors.New("too many bulk jobs in progress")
+
+// jobStore keeps bulk jobs in memory so their progress can be polled. Jobs do not survive a restart.
+type jobStore struct {
+	mu   sync.Mutex
+	jobs map[string]*BulkJob
+}
+
+func newJobStore() *jobStore {
+	return &jobStore{jobs: make(map[string]*BulkJob)}
+}
+
+// add registers a new job for owner, the ID of the user who started it, after dropping expired ones
+func (s *jobStore) add(owner, filename string) (BulkJob, error) {
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return BulkJob{}, fmt.Errorf("failed to generate job id: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	active := 0
+	for id, job := range s.jobs {
+		if job.finished() && now.Sub(job.UpdatedAt) > jobRetention {
+			delete(s.jobs, id)
+			continue
+		}
+		if job.owner == owner && !job.finished() {
+			active++
+		}
+	}
+	if active >= maxActiveJobsPerUser {
+		return BulkJob{}, fmt.Errorf("%w: wait for one of your %d running jobs to finish", ErrTooManyJobs, active)
+	}
+
+	job := &BulkJob{
+		ID:        hex.EncodeToString(idBytes),
+		Status:    JobQueued,
+		Filename:  filename,
+		CreatedAt: now,
+		UpdatedAt: now,
+		owner:     owner,
+	}
+	s.jobs[job.ID] = job
+	return job.snapshot(), nil
+}
+
+// get returns a copy of a job if it exists and belongs to owner
+func (s *jobStore) get(id, owner string) (BulkJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok || job.owner != owner {
+		return BulkJob{}, false
+	}
+	return job.snapshot(), true
+}
+
+// update changes a job under the store's lock
+func (s *jobStore) update(id string, fn func(job *BulkJob)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job, ok := s.jobs[id]; ok {
+		fn(job)
+		job.UpdatedAt = time.Now()
+	}
+}
+
+func (j *BulkJob) finished() bool {
+	return j.Status == JobCompleted || j.Status == JobFailed
+}
+
+// snapshot copies a job so it can be encoded while the job keeps running
+func (j *BulkJob) snapshot() BulkJob {
+	c := *j
+	c.Results = slices.Clone(j.Results)
+	c.Skipped = slices.Clone(j.Skipped)
+	c.Warnings = slices.Clone(j.Warnings)
+	return c
+}
+
+// StartArchiveJob checks an uploaded archive and ingests its files in the background, returning
+// the job to poll. The job takes ownership of archive and removes it when done. Archives are
+// processed IngestConfig.ArchiveConcurrency at a time; the others wait as queued. Spreadsheets
+// in the archive are split as the parser.SheetOptions on ctx ask.
+func (s *Server) StartArchiveJob(ctx context.Context, archive *spooledUpload, tags []string) (BulkJob, error) {
+	user, err := requireVerifiedUser(ctx)
+	if err != nil {
+		return BulkJob{}, err
+	}
+
+	format, err := archiveFormat(archive.File)
+	if err != nil {
+		return BulkJob{}, err
+	}
+	if err := checkArchive(archive.File, archive.Size, format, s.uploadConfig); err != nil {
+		return BulkJob{}, err
+	}
+
+	job, err := s.jobs.add(user.ID.Hex(), archive.Filename)
+	if err != nil {
+		return BulkJob{}, err
+	}
+
+	// The job outlives the request, but keeps its values such as the user
+	jobCtx := context.WithoutCancel(ctx)
+	go func() {
+		defer archive.Close()
+		s.archiveSlots <- struct{}{}
+		defer func() { <-s.archiveSlots }()
+
+		s.runArchiveJob(jobCtx, job.ID, archive, format, tags)
+	}()
+	return job, nil
+}
+
+// StartDocumentJob ingests the articles split from one uploaded document, such as the sheets
+// of a workbook, in the background and returns the job to poll. warnings are the parts of the
+// document that could not be read. The job waits for a slot like archive jobs do.
+func (s *Server) StartDocumentJob(ctx context.Context, filename string, articles []ArticleRequest, warnings []string) (BulkJob, error) {
+	user, err := requireVerifiedUser(ctx)
+	if err != nil {
+		return BulkJob{}, err
+	}
+
+	job, err := s.jobs.add(user.ID.Hex(), filename)
+	if err != nil {
+		return BulkJob{}, err
+	}
+	s.jobs.update(job.ID, func(job *BulkJob) {
+		job.FilesRead = 1
+		for _, warning := range warnings {
+			job.Warnings = append(job.Warnings, ArchiveEntryIssue{Path: filename, Message: warning})
+		}
+	})
+	job, _ = s.jobs.get(job.ID, user.ID.Hex())
+
+	paths := make([]string, len(articles))
+	for i := range paths {
+		paths[i] = filename
+	}
+
+	jobCtx := context.WithoutCancel(ctx)
+	go func() {
+		s.archiveSlots <- struct{}{}
+		defer func() { <-s.archiveSlots }()
+
+		log := logger.NewLogger("document-job").StartWithMsg("Processing document sections")
+		log.Info().Str("job_id", job.ID).Str("filename", filename).Int("articles", len(articles)).Msg("Document job started")
+		s.ingestJob(jobCtx, job.ID, articles, paths, log)
+	}()
+	return job, nil
+}
+
+// archiveBatch is a batch of the articles read from an archive, with the files they came from
+type archiveBatch struct {
+	articles []ArticleRequest
+	paths    []string
+}
+
+// runArchiveJob reads the files of an archive and ingests them with the bulk pipeline. Batches
+// are ingested while the rest of the archive is read, so the job holds at most a few of them.
+// When reading fails, the articles already ingested stay indexed and listed in the results.
+func (s *Server) runArchiveJob(ctx context.Context, jobID string, archive *spooledUpload, format string, tags []string) {
+	log := logger.NewLogger("archive-job").StartWithMsg("Processing archive upload")
+	log.Info().Str("job_id", jobID).Str("filename", archive.Filename).Str("format", format).Int64("size", archive.Size).Msg("Archive job started")
+
+	s.jobs.update(jobID, func(job *BulkJob) { job.Status = JobReading })
+
+	batches := make(chan archiveBatch, 1)
+	ingested := make(chan error, 1)
+	go func() {
+		var err error
+		offset := 0
+		for batch := range batches {
+			if err == nil {
+				err = s.ingestBatch(ctx, jobID, offset, batch.articles, batch.paths)
+			} else {
+				s.discardOriginals(ctx, batch.articles)
+			}
+			offset += len(batch.articles)
+		}
+		ingested <- err
+	}()
+
+	err := s.readArchive(ctx, jobID, archive, format, tags, func(articles []ArticleRequest, paths []string) {
+		s.jobs.update(jobID, func(job *BulkJob) { job.Total += len(articles) })
+		batches <- archiveBatch{articles: articles, paths: paths}
+	})
+	if err == nil {
+		s.jobs.update(jobID, func(job *BulkJob) { job.Status = JobIngesting })
+	}
+	close(batches)
+	if ingestErr := <-ingested; err == nil {
+		err = ingestErr
+	}
+	if err != nil {
+		s.failJob(jobID, err)
+		log.EndWithError(err)
+		return
+	}
+
+	s.jobs.update(jobID, func(job *BulkJob) { job.Status = JobCompleted })
+	log.EndWithMsg("Bulk job complete")
+}
+
+// failJob marks a job as failed with the reason it stopped
+func (s *Server) failJob(jobID string, err error) {
+	s.jobs.update(jobID, func(job *BulkJob) {
+		job.Status = JobFailed
+		job.Error = err.Error()
+	})
+}
+
+// ingestJob adds the articles of a job with the bulk pipeline, recording progress and results
+// in the job. paths are the files the articles were read from. It ends log.
+func (s *Server) ingestJob(ctx context.Context, jobID string, articles []ArticleRequest, paths []string, log *logger.Logger) {
+	s.jobs.update(jobID, func(job *BulkJob) {
+		job.Status = JobIngesting
+		job.Total = len(articles)
+	})
+	if err := s.ingestBatch(ctx, jobID, 0, articles, paths); err != nil {
+		s.failJob(jobID, err)
+		log.EndWithError(err)
+		return
+	}
+
+	s.jobs.update(jobID, func(job *BulkJob) { job.Status = JobCompleted })
+	log.EndWithMsg("Bulk job complete")
+}
+
+// ingestBatch adds a batch of a job's articles with the bulk pipeline, counting them in the job
+// as they finish and appending their results. offset is the index in the job of the first
+// article and paths are the files the articles were read from. The stored originals of articles
+// that were not indexed are removed.
+func (s *Server) ingestBatch(ctx context.Context, jobID string, offset int, articles []ArticleRequest, paths []string) error {
+	if len(articles) == 0 {
+		return nil
+	}
+
+	progress := func(articleIndex int, totalArticles int, currentStep string, stepProgress int, stepTotal int, result *BulkArticleResult) error {
+		if result == nil {
+			return nil
+		}
+		s.jobs.update(jobID, func(job *BulkJob) {
+			job.Processed++
+			if result.Success {
+				job.SuccessCount++
+			} else {
+				job.ErrorCount++
+			}
+		})
+		return nil
+	}
+	resp, err := s.AddArticlesBulkWithProgress(ctx, &BulkArticleRequest{Articles: articles}, progress)
+	if err != nil {
+		// The pipeline only fails before it indexes anything
+		s.discardOriginals(ctx, articles)
+		return err
+	}
+
+	s.discardUnusedOriginals(ctx, articles, resp.Results)
+	for i := range resp.Results {
+		resp.Results[i].Path = paths[resp.Results[i].Index]
+		resp.Results[i].Index += offset
+	}
+	s.jobs.update(jobID, func(job *BulkJob) {
+		job.Results = append(job.Results, resp.Results...)
+	})
+	logger.NewLogger("bulk-job").Info().Str("job_id", jobID).Int("success_count", resp.SuccessCount).Int("error_count", resp.ErrorCount).Msg("Articles ingested")
+	return nil
+}
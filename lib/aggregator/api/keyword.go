@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/snowmerak/open-librarian/lib/client/opensearch"
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+)
+
+// Result counts for keyword search and article listing
+const (
+	defaultKeywordSize = 10
+	maxKeywordSize     = 100
+	maxExternalSize    = 50 // External agents get smaller pages
+	defaultListSize    = 20
+	maxListSize        = 100
+)
+
+// keywordSearchRequestFromQuery reads a keyword search request from URL query parameters.
+// Filter parameters (author, registrar, tag, article_lang, topic) may be repeated.
+func keywordSearchRequestFromQuery(values url.Values) (*KeywordSearchRequest, error) {
+	req := &KeywordSearchRequest{
+		Query:       values.Get("q"),
+		Lang:        values.Get("lang"),
+		Authors:     values["author"],
+		Registrars:  values["registrar"],
+		Tags:        values["tag"],
+		Langs:       values["article_lang"],
+		Topics:      values["topic"],
+		CreatedFrom: values.Get("created_from"),
+		CreatedTo:   values.Get("created_to"),
+		Sort:        values.Get("sort"),
+		Order:       values.Get("order"),
+	}
+
+	var err error
+	if value := values.Get("facets"); value != "" {
+		if req.Facets, err = strconv.ParseBool(value); err != nil {
+			return nil, fmt.Errorf("%w: facets must be true or false", ErrInvalidSearchParams)
+		}
+	}
+	if value := values.Get("size"); value != "" {
+		if req.Size, err = strconv.Atoi(value); err != nil {
+			return nil, fmt.Errorf("%w: size must be an integer", ErrInvalidSearchParams)
+		}
+	}
+	if value := values.Get("from"); value != "" {
+		if req.From, err = strconv.Atoi(value); err != nil {
+			return nil, fmt.Errorf("%w: from must be an integer", ErrInvalidSearchParams)
+		}
+	}
+
+	return req, nil
+}
+
+// resolveKeywordSearch validates a keyword search request and converts it to OpenSearch options.
+// Sizes above maxSize are capped rather than rejected.
+func resolveKeywordSearch(req *KeywordSearchRequest, defaultSize, maxSize int) (opensearch.KeywordSearchOptions, error) {
+	opts := opensearch.KeywordSearchOptions{
+		Query: req.Query,
+		Lang:  req.Lang,
+		Filters: opensearch.SearchFilters{
+			Authors:    req.Authors,
+			Registrars: req.Registrars,
+			Tags:       req.Tags,
+			Langs:      req.Langs,
+			Topics:     req.Topics,
+		},
+		Sort:   req.Sort,
+		Order:  req.Order,
+		Facets: req.Facets,
+		Size:   req.Size,
+		From:   req.From,
+	}
+
+	switch opts.Sort {
+	case "", opensearch.SortRelevance, opensearch.SortDate, opensearch.SortTitle:
+	default:
+		return opts, fmt.Errorf("%w: unknown sort %q, expected %q, %q or %q", ErrInvalidSearchParams, opts.Sort, opensearch.SortRelevance, opensearch.SortDate, opensearch.SortTitle)
+	}
+	switch opts.Order {
+	case "", opensearch.OrderAsc, opensearch.OrderDesc:
+	default:
+		return opts, fmt.Errorf("%w: unknown order %q, expected %q or %q", ErrInvalidSearchParams, opts.Order, opensearch.OrderAsc, opensearch.OrderDesc)
+	}
+
+	if opts.Size <= 0 {
+		opts.Size = defaultSize
+	}
+	opts.Size = min(opts.Size, maxSize)
+	if opts.From < 0 {
+		return opts, fmt.Errorf("%w: from must not be negative", ErrInvalidSearchParams)
+	}
+
+	var err error
+	if opts.Filters.CreatedFrom, err = parseDateFilter(req.CreatedFrom, false); err != nil {
+		return opts, fmt.Errorf("%w: created_from: %v", ErrInvalidSearchParams, err)
+	}
+	if opts.Filters.CreatedTo, err = parseDateFilter(req.CreatedTo, true); err != nil {
+		return opts, fmt.Errorf("%w: created_to: %v", ErrInvalidSearchParams, err)
+	}
+	if opts.Filters.CreatedFrom != nil && opts.Filters.CreatedTo != nil && opts.Filters.CreatedFrom.After(*opts.Filters.CreatedTo) {
+		return opts, fmt.Errorf("%w: created_from is after created_to", ErrInvalidSearchParams)
+	}
+
+	return opts, nil
+}
+
+// parseDateFilter parses an RFC3339 timestamp or a date. A date used as an upper bound covers the whole day.
+func parseDateFilter(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return &parsed, nil
+	}
+
+	parsed, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q, expected RFC3339 or YYYY-MM-DD", value)
+	}
+	if endOfDay {
+		parsed = parsed.AddDate(0, 0, 1).Add(-time.Millisecond)
+	}
+	return &parsed, nil
+}
+
+// KeywordSearch runs a keyword search with structured filters, sorting and facets
+func (s *Server) KeywordSearch(ctx context.Context, req *KeywordSearchRequest, defaultSize, maxSize int) (*opensearch.SearchResponse, error) {
+	searchLogger := logger.NewLogger("keyword_search").StartWithMsg("Performing keyword search")
+
+	opts, err := resolveKeywordSearch(req, defaultSize, maxSize)
+	if err != nil {
+		searchLogger.EndWithError(err)
+		return nil, err
+	}
+
+	resp, err := s.opensearchClient.Search(ctx, opts)
+	if err != nil {
+		searchLogger.EndWithError(err)
+		return nil, fmt.Errorf("failed to search articles: %w", err)
+	}
+
+	searchLogger.Info().Int("total", resp.Total).Int("result_count", len(resp.Results)).Msg("Keyword search finished")
+	searchLogger.EndWithMsg("Keyword search complete")
+	return resp, nil
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/snowmerak/open-librarian/lib/client/llm"
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+)
+
+// maxShrinkAttempts bounds how many times a prompt input is halved after a context-too-long error
+const maxShrinkAttempts = 3
+
+// generateStructured generates schema-conforming output from a prompt built around content.
+// When the model reports that the prompt does not fit its context, the content is halved and the call is retried.
+func (s *Server) generateStructured(ctx context.Context, task llm.Task, content string, buildPrompt func(string) string, name string, schema *llm.Schema, out any) error {
+	for attempt := 0; ; attempt++ {
+		err := s.llmClient.GenerateJSON(ctx, task, buildPrompt(content), name, schema, out)
+		if err == nil || !errors.Is(err, llm.ErrContextTooLong) || attempt >= maxShrinkAttempts {
+			return err
+		}
+
+		runes := []rune(content)
+		logger.NewLogger("generate_structured").Warn().
+			Str("task", string(task)).
+			Int("content_runes", len(runes)).
+			Int("attempt", attempt+1).
+			Msg("Prompt exceeds model context, retrying with shortened input")
+		content = string(runes[:len(runes)/2])
+	}
+}
+
+// compactChatMessages shrinks a tool-calling conversation after a context-too-long error.
+// Prior chat history is dropped first; after that, long tool results are halved.
+// Returns false when nothing could be removed.
+func compactChatMessages(messages []llm.ChatMessage, historyLen int) ([]llm.ChatMessage, bool) {
+	// messages[0] is the system prompt and history follows it
+	if historyLen > 0 && len(messages) > historyLen {
+		compacted := make([]llm.ChatMessage, 0, len(messages)-historyLen)
+		compacted = append(compacted, messages[0])
+		compacted = append(compacted, messages[1+historyLen:]...)
+		return compacted, true
+	}
+
+	const minToolResultRunes = 500
+	changed := false
+	for i, msg := range messages {
+		if msg.Role != "tool" {
+			continue
+		}
+		runes := []rune(msg.Content)
+		if len(runes) <= minToolResultRunes {
+			continue
+		}
+		messages[i].Content = string(runes[:len(runes)/2]) + "\n...(truncated)"
+		changed = true
+	}
+	return messages, changed
+}
+
+// isLLMOutage reports whether an error means no configured provider can serve the request right now
+func isLLMOutage(err error) bool {
+	return errors.Is(err, llm.ErrUnavailable) ||
+		errors.Is(err, llm.ErrRateLimited) ||
+		errors.Is(err, llm.ErrCircuitOpen) ||
+		errors.Is(err, llm.ErrAuth)
+}
+
+// writeLLMError writes an error response for typed LLM failures.
+// Returns false when the error is not an LLM error so the caller can handle it.
+func writeLLMError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, llm.ErrRateLimited):
+		if retryAfter := llm.RetryAfter(err); retryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		}
+		writeErrorResponse(w, http.StatusTooManyRequests, "llm_rate_limited", "The language model provider is rate limiting requests, please try again later")
+	case errors.Is(err, llm.ErrUnavailable), errors.Is(err, llm.ErrCircuitOpen):
+		writeErrorResponse(w, http.StatusServiceUnavailable, "llm_unavailable", "The language model provider is temporarily unavailable")
+	case errors.Is(err, llm.ErrAuth):
+		writeErrorResponse(w, http.StatusBadGateway, "llm_auth_failed", "The language model provider rejected the configured credentials")
+	case errors.Is(err, llm.ErrContextTooLong):
+		writeErrorResponse(w, http.StatusRequestEntityTooLarge, "llm_context_too_long", "The input is too long for the language model")
+	case errors.Is(err, llm.ErrInvalidResponse), errors.Is(err, llm.ErrMalformedOutput):
+		writeErrorResponse(w, http.StatusBadGateway, "llm_invalid_response", "The language model returned an invalid response")
+	default:
+		return false
+	}
+	return true
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/snowmerak/open-librarian/lib/client/llm"
 	"github.com/snowmerak/open-librarian/lib/client/mongo"
 	"github.com/snowmerak/open-librarian/lib/util/logger"
 )
@@ -174,6 +175,16 @@ func RequireOwnership() func(http.Handler) http.Handler {
 	}
 }
 
+// CacheBypassMiddleware skips LLM cache lookups for requests sent with "Cache-Control: no-cache"
+func CacheBypassMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(strings.ToLower(r.Header.Get("Cache-Control")), "no-cache") {
+			r = r.WithContext(llm.WithCacheBypass(r.Context()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // getUserIDFromURL extracts user ID from URL path
 func getUserIDFromURL(r *http.Request) string {
 	// This is a simple implementation that assumes the user ID is in the URL
@@ -0,0 +1,257 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/snowmerak/open-librarian/lib/client/llm"
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+)
+
+const (
+	// summaryOutputTokens is reserved in the context window for a generated summary
+	summaryOutputTokens = 2048
+	// tagsOutputTokens is reserved in the context window for generated tags
+	tagsOutputTokens = 256
+	// answerOutputTokens is reserved in the context window for a generated answer
+	answerOutputTokens = 1536
+	// maxReduceDepth bounds how many times summaries are summarized again
+	maxReduceDepth = 4
+	// mapConcurrency limits parallel chunk summaries during the map stage
+	mapConcurrency = 3
+)
+
+// promptBudget tracks how many tokens of a task's context window are left for input
+type promptBudget struct {
+	counter llm.TokenCounter
+	limit   int
+}
+
+// newPromptBudget creates a budget for a task, keeping reservedOutput tokens free for the response
+func (s *Server) newPromptBudget(task llm.Task, reservedOutput int) *promptBudget {
+	limit := s.llmClient.ContextLimit(task)
+	// Never let the reservation eat more than half of a small context
+	if reservedOutput > limit/2 {
+		reservedOutput = limit / 2
+	}
+	return &promptBudget{
+		counter: s.llmClient,
+		limit:   limit - reservedOutput,
+	}
+}
+
+// count estimates the token count of text
+func (b *promptBudget) count(text string) int {
+	return b.counter.CountTokens(text)
+}
+
+// fits reports whether a prompt fits the budget
+func (b *promptBudget) fits(prompt string) bool {
+	return b.count(prompt) <= b.limit
+}
+
+// available returns the tokens left for content inserted into a prompt built by buildPrompt
+func (b *promptBudget) available(buildPrompt func(string) string) int {
+	return b.limit - b.count(buildPrompt(""))
+}
+
+// truncateRunes cuts text to at most maxRunes runes, marking the cut with an ellipsis
+func truncateRunes(text string, maxRunes int) string {
+	runes := []rune(text)
+	if len(runes) <= maxRunes {
+		return text
+	}
+	return string(runes[:maxRunes]) + "..."
+}
+
+// truncateToTokens cuts text to at most maxTokens, on a rune boundary
+func truncateToTokens(counter llm.TokenCounter, text string, maxTokens int) string {
+	if maxTokens <= 0 {
+		return ""
+	}
+	if counter.CountTokens(text) <= maxTokens {
+		return text
+	}
+
+	runes := []rune(text)
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if counter.CountTokens(string(runes[:mid])) <= maxTokens {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return string(runes[:lo])
+}
+
+// splitByTokens splits text into chunks of at most maxTokens, preferring paragraph,
+// then line, then sentence boundaries before cutting inside a sentence
+func splitByTokens(counter llm.TokenCounter, text string, maxTokens int) []string {
+	if maxTokens <= 0 {
+		return nil
+	}
+	if counter.CountTokens(text) <= maxTokens {
+		return []string{text}
+	}
+
+	separators := []string{"\n\n", "\n", ". ", "。", " "}
+	return splitRecursive(counter, text, maxTokens, separators)
+}
+
+func splitRecursive(counter llm.TokenCounter, text string, maxTokens int, separators []string) []string {
+	if counter.CountTokens(text) <= maxTokens {
+		return []string{text}
+	}
+
+	if len(separators) == 0 {
+		// No boundary left; cut on runes
+		var chunks []string
+		runes := []rune(text)
+		for len(runes) > 0 {
+			n := len([]rune(truncateToTokens(counter, string(runes), maxTokens)))
+			if n == 0 {
+				n = 1
+			}
+			chunks = append(chunks, string(runes[:n]))
+			runes = runes[n:]
+		}
+		return chunks
+	}
+
+	sep := separators[0]
+	parts := strings.SplitAfter(text, sep)
+	if len(parts) == 1 {
+		return splitRecursive(counter, text, maxTokens, separators[1:])
+	}
+
+	var chunks []string
+	var current strings.Builder
+	for _, part := range parts {
+		if counter.CountTokens(part) > maxTokens {
+			if current.Len() > 0 {
+				chunks = append(chunks, current.String())
+				current.Reset()
+			}
+			chunks = append(chunks, splitRecursive(counter, part, maxTokens, separators[1:])...)
+			continue
+		}
+		if current.Len() > 0 && counter.CountTokens(current.String()+part) > maxTokens {
+			chunks = append(chunks, current.String())
+			current.Reset()
+		}
+		current.WriteString(part)
+	}
+	if current.Len() > 0 {
+		chunks = append(chunks, current.String())
+	}
+	return chunks
+}
+
+// chunkSummaryPrompt builds the map-stage prompt for one part of a long document
+func chunkSummaryPrompt(part, total int) func(string) string {
+	return func(content string) string {
+		return fmt.Sprintf(`The following text is part %d of %d of a longer document. Summarize it in English, keeping every key point, argument, name, number and conclusion. Do not add an introduction or mention that this is a part. Put the summary in the "summary" field.
+
+Text:
+%s`, part, total, content)
+	}
+}
+
+// summarizeContent summarizes content that may not fit the model's context window.
+// Content that fits is summarized in one call. Otherwise it is split into chunks that are summarized
+// independently (map), and the joined chunk summaries are summarized again until they fit the final prompt (reduce).
+func (s *Server) summarizeContent(ctx context.Context, content string, buildPrompt func(string) string) (string, error) {
+	budget := s.newPromptBudget(llm.TaskSummary, summaryOutputTokens)
+	if budget.fits(buildPrompt(content)) {
+		return s.generateSummary(ctx, content, buildPrompt)
+	}
+
+	summaryLogger := logger.NewLogger("map_reduce_summary").StartWithMsg("Summarizing content larger than the context window")
+	summaryLogger.Info().
+		Int("content_tokens", budget.count(content)).
+		Int("budget_tokens", budget.limit).
+		Msg("Content exceeds prompt budget")
+
+	for depth := 0; depth < maxReduceDepth; depth++ {
+		chunkBudget := budget.available(chunkSummaryPrompt(1, 1))
+		chunks := splitByTokens(budget.counter, content, chunkBudget)
+		summaryLogger.Info().Int("depth", depth).Int("chunks", len(chunks)).Int("chunk_tokens", chunkBudget).Msg("Map stage")
+
+		summaries, err := s.summarizeChunks(ctx, chunks)
+		if err != nil {
+			summaryLogger.EndWithError(err)
+			return "", err
+		}
+
+		content = strings.Join(summaries, "\n\n")
+		if budget.fits(buildPrompt(content)) {
+			break
+		}
+	}
+
+	// Reduce: generateStructured still guards against a counter that underestimates
+	summary, err := s.generateSummary(ctx, content, buildPrompt)
+	if err != nil {
+		summaryLogger.EndWithError(err)
+		return "", err
+	}
+
+	summaryLogger.EndWithMsg("Map-reduce summary complete")
+	return summary, nil
+}
+
+// generateSummary runs a single structured summarization call
+func (s *Server) generateSummary(ctx context.Context, content string, buildPrompt func(string) string) (string, error) {
+	var output SummaryOutput
+	if err := s.generateStructured(ctx, llm.TaskSummary, content, buildPrompt, "summary", summarySchema, &output); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output.Summary), nil
+}
+
+// summarizeChunks runs the map stage on a fixed pool of mapConcurrency workers, preserving chunk order
+func (s *Server) summarizeChunks(ctx context.Context, chunks []string) ([]string, error) {
+	summaries := make([]string, len(chunks))
+	errs := make([]error, len(chunks))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(mapConcurrency, len(chunks)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				summaries[i], errs[i] = s.generateSummary(ctx, chunks[i], chunkSummaryPrompt(i+1, len(chunks)))
+			}
+		}()
+	}
+	for i := range chunks {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("failed to summarize chunk %d/%d: %w", i+1, len(chunks), err)
+		}
+	}
+	return summaries, nil
+}
+
+// contentForPrompt returns content when the prompt fits the task's budget, or the fallback otherwise,
+// truncated to whatever room is left
+func (s *Server) contentForPrompt(task llm.Task, reservedOutput int, content, fallback string, buildPrompt func(string) string) string {
+	budget := s.newPromptBudget(task, reservedOutput)
+	if budget.fits(buildPrompt(content)) {
+		return content
+	}
+	if fallback == "" {
+		fallback = content
+	}
+	return truncateToTokens(budget.counter, fallback, budget.available(buildPrompt))
+}
@@ -0,0 +1,316 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/snowmerak/open-librarian/lib/client/llm"
+	"github.com/snowmerak/open-librarian/lib/client/opensearch"
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+)
+
+// Query translator names
+const (
+	QueryTranslatorLLM        = "llm"
+	QueryTranslatorDictionary = "dictionary"
+)
+
+const (
+	// languageStatsTTL is how long the library's language distribution is reused
+	languageStatsTTL = 10 * time.Minute
+	// vectorLanguageBoost raises the similarity of points in the query's language,
+	// so the detected language is preferred without excluding other languages
+	vectorLanguageBoost = 0.05
+)
+
+// QueryVariant is a search query in one language
+type QueryVariant struct {
+	Text       string `json:"text"`
+	Lang       string `json:"lang"`
+	Translated bool   `json:"translated,omitempty"`
+}
+
+// QueryTranslator translates search queries for cross-lingual retrieval
+type QueryTranslator interface {
+	Name() string
+	// Translate returns the query in each target language it could translate to, keyed by language code
+	Translate(ctx context.Context, query, from string, targets []string) (map[string]string, error)
+}
+
+// QueryExpansionConfig configures cross-lingual query expansion.
+// Queries are translated into the languages that make up a large share of the library
+// and keyword search runs once per language with that language's analyzer.
+type QueryExpansionConfig struct {
+	Translator        QueryTranslator // Nil disables expansion
+	MinLanguageShare  float64         // Languages with at least this share of articles are translated into
+	MaxLanguages      int             // Maximum number of translations per query
+	TranslationWeight float64         // Fusion weight of a translated ranking relative to the original query
+}
+
+// DefaultQueryExpansionConfig returns default query expansion configuration, with expansion disabled
+func DefaultQueryExpansionConfig() QueryExpansionConfig {
+	return QueryExpansionConfig{
+		MinLanguageShare:  0.2,
+		MaxLanguages:      2,
+		TranslationWeight: 0.8,
+	}
+}
+
+// languageStats caches the number of articles per language
+type languageStats struct {
+	mu        sync.Mutex
+	buckets   []opensearch.FacetBucket
+	fetchedAt time.Time
+}
+
+// dominantLanguages returns the languages holding at least the configured share of articles, most common first
+func (s *Server) dominantLanguages(ctx context.Context) ([]string, error) {
+	s.languageStats.mu.Lock()
+	defer s.languageStats.mu.Unlock()
+
+	if s.languageStats.buckets == nil || time.Since(s.languageStats.fetchedAt) > languageStatsTTL {
+		buckets, err := s.opensearchClient.LanguageDistribution(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get language distribution: %w", err)
+		}
+		s.languageStats.buckets = buckets
+		s.languageStats.fetchedAt = time.Now()
+	}
+
+	total := 0
+	for _, bucket := range s.languageStats.buckets {
+		total += bucket.Count
+	}
+
+	var langs []string
+	for _, bucket := range s.languageStats.buckets {
+		if total > 0 && float64(bucket.Count)/float64(total) >= s.queryExpansion.MinLanguageShare {
+			langs = append(langs, bucket.Value)
+		}
+	}
+	return langs, nil
+}
+
+// expansionCache keeps the query variants produced while answering one question, so the
+// agent's repeated searches for the same text are translated once
+type expansionCache struct {
+	mu       sync.Mutex
+	variants map[string][]QueryVariant
+}
+
+type expansionCacheKey struct{}
+
+// withExpansionCache returns a context whose query expansions are cached until it is discarded
+func withExpansionCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, expansionCacheKey{}, &expansionCache{variants: make(map[string][]QueryVariant)})
+}
+
+// understandQuery detects the query language and, when expansion is enabled, adds translations
+// into the library's other dominant languages. The original query is always the first variant.
+// Translation failures are logged and the original query is searched alone. Expansions are
+// reused when ctx carries a cache from withExpansionCache.
+func (s *Server) understandQuery(ctx context.Context, query, lang string, expand bool) []QueryVariant {
+	if lang == "" {
+		lang = s.languageDetector.DetectLanguage(query)
+	}
+	if !expand || s.queryExpansion.Translator == nil {
+		return []QueryVariant{{Text: query, Lang: lang}}
+	}
+
+	cache, ok := ctx.Value(expansionCacheKey{}).(*expansionCache)
+	if !ok {
+		return s.expandQuery(ctx, query, lang)
+	}
+	key := lang + "\x00" + query
+	cache.mu.Lock()
+	variants, ok := cache.variants[key]
+	cache.mu.Unlock()
+	if ok {
+		return variants
+	}
+	variants = s.expandQuery(ctx, query, lang)
+	cache.mu.Lock()
+	cache.variants[key] = variants
+	cache.mu.Unlock()
+	return variants
+}
+
+// expandQuery translates a query in lang into the library's other dominant languages
+func (s *Server) expandQuery(ctx context.Context, query, lang string) []QueryVariant {
+	variants := []QueryVariant{{Text: query, Lang: lang}}
+	translator := s.queryExpansion.Translator
+
+	queryLogger := logger.NewLogger("query_understanding")
+
+	dominant, err := s.dominantLanguages(ctx)
+	if err != nil {
+		queryLogger.Warn().Err(err).Msg("Could not determine library languages, skipping query expansion")
+		return variants
+	}
+
+	var targets []string
+	for _, target := range dominant {
+		if target != lang && len(targets) < s.queryExpansion.MaxLanguages {
+			targets = append(targets, target)
+		}
+	}
+	if len(targets) == 0 {
+		return variants
+	}
+
+	translations, err := translator.Translate(ctx, query, lang, targets)
+	if err != nil {
+		queryLogger.Warn().Err(err).Str("translator", translator.Name()).Msg("Query translation failed, searching the original query only")
+		return variants
+	}
+
+	for _, target := range targets {
+		text := strings.TrimSpace(translations[target])
+		if text == "" || text == query {
+			continue
+		}
+		variants = append(variants, QueryVariant{Text: text, Lang: target, Translated: true})
+	}
+
+	queryLogger.Info().
+		Str("query", query).
+		Str("lang", lang).
+		Int("variants", len(variants)).
+		Str("translator", translator.Name()).
+		Msg("Expanded query across languages")
+	return variants
+}
+
+// variantWeight returns the fusion weight of a query variant relative to the original query
+func (s *Server) variantWeight(variant QueryVariant) float64 {
+	if variant.Translated {
+		return s.queryExpansion.TranslationWeight
+	}
+	return 1
+}
+
+// languageBoostedScore prefers vector hits in the query's language
+func languageBoostedScore(score float64, hitLang, queryLang string) float64 {
+	if hitLang != "" && hitLang == queryLang {
+		return score * (1 + vectorLanguageBoost)
+	}
+	return score
+}
+
+// LLMQueryTranslator translates queries with the translate task's model
+type LLMQueryTranslator struct {
+	server *Server
+}
+
+// NewLLMQueryTranslator creates a translator backed by the server's LLM router
+func NewLLMQueryTranslator(server *Server) *LLMQueryTranslator {
+	return &LLMQueryTranslator{server: server}
+}
+
+// Name returns the translator name
+func (t *LLMQueryTranslator) Name() string { return QueryTranslatorLLM }
+
+// Translate asks the model for one search query per target language
+func (t *LLMQueryTranslator) Translate(ctx context.Context, query, from string, targets []string) (map[string]string, error) {
+	prompt := fmt.Sprintf(`Translate the search query below from language "%s" into each of these languages: %s.
+Translate for search: use the words an article written in that language would use for the same topic.
+Keep product names, proper nouns, code identifiers and acronyms unchanged. Do not answer the query.
+
+Query: %s`, from, strings.Join(targets, ", "), query)
+
+	var out TranslationOutput
+	if err := t.server.llmClient.GenerateJSON(ctx, llm.TaskTranslate, prompt, "query_translation", translationSchema(targets), &out); err != nil {
+		return nil, fmt.Errorf("failed to translate query: %w", err)
+	}
+
+	translations := make(map[string]string, len(out.Translations))
+	for _, translation := range out.Translations {
+		translations[translation.Lang] = translation.Text
+	}
+	return translations, nil
+}
+
+// DictionaryQueryTranslator translates queries term by term using a glossary.
+// Queries without any known term are not translated.
+type DictionaryQueryTranslator struct {
+	entries []map[string]string // Each entry maps language codes to the same term
+}
+
+// LoadDictionaryQueryTranslator reads a glossary from a JSON file holding an array of entries,
+// each mapping language codes to one term, e.g. [{"ko": "기계 학습", "en": "machine learning"}]
+func LoadDictionaryQueryTranslator(path string) (*DictionaryQueryTranslator, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read query dictionary: %w", err)
+	}
+
+	var entries []map[string]string
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse query dictionary: %w", err)
+	}
+	return &DictionaryQueryTranslator{entries: entries}, nil
+}
+
+// Name returns the translator name
+func (t *DictionaryQueryTranslator) Name() string { return QueryTranslatorDictionary }
+
+// Translate replaces known terms, longest first, and keeps the rest of the query as is
+func (t *DictionaryQueryTranslator) Translate(ctx context.Context, query, from string, targets []string) (map[string]string, error) {
+	// Longer terms first so "machine learning" wins over "learning"
+	entries := make([]map[string]string, 0, len(t.entries))
+	for _, entry := range t.entries {
+		if entry[from] != "" {
+			entries = append(entries, entry)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return len(entries[i][from]) > len(entries[j][from])
+	})
+
+	translations := make(map[string]string, len(targets))
+	for _, target := range targets {
+		translated := query
+		replaced := false
+		for _, entry := range entries {
+			term, replacement := entry[from], entry[target]
+			if replacement == "" {
+				continue
+			}
+			if next := replaceFold(translated, term, replacement); next != translated {
+				translated = next
+				replaced = true
+			}
+		}
+		if replaced {
+			translations[target] = translated
+		}
+	}
+	return translations, nil
+}
+
+// replaceFold replaces every case-insensitive occurrence of old in text
+func replaceFold(text, old, replacement string) string {
+	lowerText, lowerOld := strings.ToLower(text), strings.ToLower(old)
+	// Case folding that changes byte lengths would misalign offsets; fall back to an exact match
+	if len(lowerText) != len(text) || len(lowerOld) != len(old) {
+		return strings.ReplaceAll(text, old, replacement)
+	}
+
+	var sb strings.Builder
+	for {
+		index := strings.Index(lowerText, lowerOld)
+		if index < 0 {
+			sb.WriteString(text)
+			return sb.String()
+		}
+		sb.WriteString(text[:index])
+		sb.WriteString(replacement)
+		text, lowerText = text[index+len(old):], lowerText[index+len(old):]
+	}
+}
@@ -0,0 +1,292 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/snowmerak/open-librarian/lib/client/opensearch"
+	"github.com/snowmerak/open-librarian/lib/util/cluster"
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+)
+
+const (
+	defaultRelatedSize = 5
+	maxRelatedSize     = 50
+	// relatedCandidateFactor is how many candidates each similarity source returns per requested result
+	relatedCandidateFactor = 3
+)
+
+// Blend weights of the related-article signals; disabled or unavailable signals are left out
+const (
+	relatedVectorWeight       = 0.6
+	relatedMoreLikeThisWeight = 0.25
+	relatedTagWeight          = 0.15
+)
+
+// relatedArticlesRequestFromQuery reads a related-articles request from URL query parameters
+func relatedArticlesRequestFromQuery(values url.Values) (*RelatedArticlesRequest, error) {
+	req := &RelatedArticlesRequest{MoreLikeThis: true, Tags: true}
+
+	var err error
+	if value := values.Get("size"); value != "" {
+		if req.Size, err = strconv.Atoi(value); err != nil {
+			return nil, fmt.Errorf("%w: size must be an integer", ErrInvalidSearchParams)
+		}
+	}
+	if value := values.Get("more_like_this"); value != "" {
+		if req.MoreLikeThis, err = strconv.ParseBool(value); err != nil {
+			return nil, fmt.Errorf("%w: more_like_this must be true or false", ErrInvalidSearchParams)
+		}
+	}
+	if value := values.Get("tags"); value != "" {
+		if req.Tags, err = strconv.ParseBool(value); err != nil {
+			return nil, fmt.Errorf("%w: tags must be true or false", ErrInvalidSearchParams)
+		}
+	}
+
+	return req, nil
+}
+
+// RelatedArticles finds articles similar to an article using its stored summary vector,
+// optionally blended with more_like_this term similarity and tag overlap.
+// If one similarity source fails, results from the other are returned and the response is marked degraded.
+func (s *Server) RelatedArticles(ctx context.Context, id string, req *RelatedArticlesRequest) (*RelatedArticlesResponse, error) {
+	relatedLogger := logger.NewLogger("related_articles").StartWithMsg("Finding related articles")
+
+	size := req.Size
+	if size <= 0 {
+		size = defaultRelatedSize
+	}
+	size = min(size, maxRelatedSize)
+	candidates := size * relatedCandidateFactor
+
+	article, err := s.opensearchClient.GetArticle(ctx, id)
+	if err != nil {
+		relatedLogger.EndWithError(err)
+		return nil, fmt.Errorf("failed to get article: %w", err)
+	}
+
+	articles := make(map[string]opensearch.Article)
+	var degraded bool
+
+	vectorScores, vectorErr := s.recommendArticles(ctx, id, candidates)
+	if vectorErr != nil {
+		relatedLogger.Warn().Err(vectorErr).Str("article_id", id).Msg("Vector recommendation failed")
+		degraded = true
+	}
+
+	var mltScores map[string]float64
+	if req.MoreLikeThis {
+		results, err := s.opensearchClient.MoreLikeThis(ctx, id, candidates)
+		if err != nil {
+			relatedLogger.Warn().Err(err).Str("article_id", id).Msg("More like this search failed")
+			degraded = true
+		} else {
+			raw := make(map[string]float64, len(results))
+			for _, result := range results {
+				raw[result.Article.ID] = result.Score
+				articles[result.Article.ID] = result.Article
+			}
+			mltScores = normalizeMinMax(raw)
+		}
+	}
+
+	if vectorErr != nil && mltScores == nil {
+		relatedLogger.EndWithError(vectorErr)
+		return nil, fmt.Errorf("failed to find related articles: %w", vectorErr)
+	}
+
+	// Recommendations only cover the nearest vectors, so candidates found by more_like_this alone
+	// are compared with the article's vector directly instead of counting as dissimilar
+	if vectorScores != nil {
+		var unscored []string
+		for candidateID := range mltScores {
+			if _, ok := vectorScores[candidateID]; !ok && candidateID != id {
+				unscored = append(unscored, candidateID)
+			}
+		}
+		sort.Strings(unscored)
+		similarities, err := s.vectorSimilarities(ctx, id, unscored)
+		if err != nil {
+			relatedLogger.Warn().Err(err).Str("article_id", id).Msg("Vector lookup of more like this candidates failed")
+			degraded = true
+		}
+		for candidateID, similarity := range similarities {
+			vectorScores[candidateID] = similarity
+		}
+	}
+	// Both signals are blended on the same 0-1 scale relative to this article's candidates
+	vectorBlend := normalizeMinMax(vectorScores)
+
+	var missing []string
+	for candidateID := range vectorScores {
+		if _, ok := articles[candidateID]; !ok {
+			missing = append(missing, candidateID)
+		}
+	}
+	if len(missing) > 0 {
+		fetched, err := s.opensearchClient.GetArticlesByIDs(ctx, missing)
+		if err != nil {
+			relatedLogger.EndWithError(err)
+			return nil, fmt.Errorf("failed to fetch articles: %w", err)
+		}
+		for _, fetchedArticle := range fetched {
+			articles[fetchedArticle.ID] = fetchedArticle
+		}
+	}
+
+	useTags := req.Tags && len(article.Tags) > 0
+	totalWeight := 0.0
+	if vectorScores != nil {
+		totalWeight += relatedVectorWeight
+	}
+	if mltScores != nil {
+		totalWeight += relatedMoreLikeThisWeight
+	}
+	if useTags {
+		totalWeight += relatedTagWeight
+	}
+
+	results := make([]RelatedArticle, 0, len(articles))
+	for candidateID, candidate := range articles {
+		if candidateID == id {
+			continue
+		}
+
+		related := RelatedArticle{
+			Article:           candidate,
+			VectorScore:       vectorScores[candidateID],
+			MoreLikeThisScore: mltScores[candidateID],
+		}
+		score := relatedVectorWeight*vectorBlend[candidateID] + relatedMoreLikeThisWeight*related.MoreLikeThisScore
+		if useTags {
+			var overlap float64
+			related.SharedTags, overlap = tagOverlap(article.Tags, candidate.Tags)
+			score += relatedTagWeight * overlap
+		}
+		related.Score = score / totalWeight
+		results = append(results, related)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Article.ID < results[j].Article.ID
+	})
+	if len(results) > size {
+		results = results[:size]
+	}
+
+	relatedLogger.Info().Str("article_id", id).Int("result_count", len(results)).Bool("degraded", degraded).Msg("Related articles found")
+	relatedLogger.EndWithMsg("Related articles complete")
+	return &RelatedArticlesResponse{
+		ArticleID: id,
+		Results:   results,
+		Degraded:  degraded,
+	}, nil
+}
+
+// recommendArticles returns the articles closest to an article's summary vector, by article ID.
+// Articles stored without a summary vector are matched by their title vector instead.
+func (s *Server) recommendArticles(ctx context.Context, id string, limit int) (map[string]float64, error) {
+	// Every article has a title and a summary point, so twice the limit covers limit articles
+	ownPoints := []string{id + "_summary", id + "_title"}
+
+	var lastErr error
+	for _, pointID := range ownPoints {
+		hits, err := s.qdrantClient.Recommend(ctx, pointID, ownPoints, uint64(limit*2))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		scores := make(map[string]float64, len(hits))
+		for _, hit := range hits {
+			articleID := s.extractArticleID(hit.ID)
+			if articleID != id && hit.Score > scores[articleID] {
+				scores[articleID] = hit.Score
+			}
+		}
+		return scores, nil
+	}
+	return nil, lastErr
+}
+
+// vectorSimilarities returns the cosine similarity of each candidate to an article, scored like
+// recommendArticles: the article's summary vector, or its title vector when it has none, against
+// the closer of each candidate's title and summary vectors. Candidates without vectors are left out.
+func (s *Server) vectorSimilarities(ctx context.Context, id string, candidateIDs []string) (map[string]float64, error) {
+	if len(candidateIDs) == 0 {
+		return nil, nil
+	}
+
+	pointIDs := []string{id + "_summary", id + "_title"}
+	for _, candidateID := range candidateIDs {
+		pointIDs = append(pointIDs, candidateID+"_summary", candidateID+"_title")
+	}
+	vectors := make(map[string][]float32, len(pointIDs))
+	err := s.qdrantClient.GetVectors(ctx, pointIDs, func(pointID string, vector []float32) error {
+		vectors[pointID] = vector
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read article vectors: %w", err)
+	}
+
+	own, ok := vectors[id+"_summary"]
+	if !ok {
+		own = vectors[id+"_title"]
+	}
+	if own == nil {
+		return nil, nil
+	}
+
+	similarities := make(map[string]float64, len(candidateIDs))
+	for _, candidateID := range candidateIDs {
+		for _, pointID := range []string{candidateID + "_summary", candidateID + "_title"} {
+			vector, ok := vectors[pointID]
+			if !ok {
+				continue
+			}
+			similarity := cluster.Cosine(own, vector)
+			if best, seen := similarities[candidateID]; !seen || similarity > best {
+				similarities[candidateID] = similarity
+			}
+		}
+	}
+	return similarities, nil
+}
+
+// tagOverlap returns the tags two articles share and their Jaccard similarity, ignoring case
+func tagOverlap(tags, other []string) ([]string, float64) {
+	set := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		set[strings.ToLower(tag)] = true
+	}
+
+	var shared []string
+	union := len(set)
+	seen := make(map[string]bool, len(other))
+	for _, tag := range other {
+		key := strings.ToLower(tag)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if set[key] {
+			shared = append(shared, tag)
+		} else {
+			union++
+		}
+	}
+
+	if union == 0 {
+		return nil, 0
+	}
+	return shared, float64(len(shared)) / float64(union)
+}
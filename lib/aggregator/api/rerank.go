@@ -0,0 +1,250 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+)
+
+// Search paths that can be configured with their own reranker
+const (
+	RerankPathAgent  = "agent"  // Tool calls made by the chat model in Search
+	RerankPathHybrid = "hybrid" // The hybrid search endpoint
+)
+
+// Reranker names recorded in search result provenance
+const (
+	RerankerNone     = "none"
+	RerankerLLMJudge = "llm"
+	RerankerHTTP     = "http"
+)
+
+// Reranker reorders and filters search candidates by their relevance to a query.
+// Implementations set RerankScore and Reranker on the results they score.
+type Reranker interface {
+	Name() string
+	Rerank(ctx context.Context, query string, results []SearchResultWithScore) ([]SearchResultWithScore, error)
+}
+
+// NoopReranker keeps the retrieval order
+type NoopReranker struct{}
+
+// Name returns the reranker name
+func (NoopReranker) Name() string { return RerankerNone }
+
+// Rerank returns the results unchanged
+func (NoopReranker) Rerank(ctx context.Context, query string, results []SearchResultWithScore) ([]SearchResultWithScore, error) {
+	return results, nil
+}
+
+// LLMJudgeReranker asks the relevance model to score every candidate.
+// It costs one extra generation per call but needs no additional service.
+type LLMJudgeReranker struct {
+	server *Server
+}
+
+// NewLLMJudgeReranker creates a reranker backed by the server's relevance model
+func NewLLMJudgeReranker(server *Server) *LLMJudgeReranker {
+	return &LLMJudgeReranker{server: server}
+}
+
+// Name returns the reranker name
+func (r *LLMJudgeReranker) Name() string { return RerankerLLMJudge }
+
+// Rerank scores candidates with the LLM and drops those below the relevance threshold
+func (r *LLMJudgeReranker) Rerank(ctx context.Context, query string, results []SearchResultWithScore) ([]SearchResultWithScore, error) {
+	return r.server.validateSearchRelevance(ctx, query, results)
+}
+
+// HTTPRerankerConfig configures a cross-encoder served over HTTP
+type HTTPRerankerConfig struct {
+	URL      string        // Base URL; requests go to URL + "/rerank"
+	Model    string        // Model name, sent only in the cohere format
+	APIKey   string        // Optional bearer token
+	Format   string        // "tei" (text-embeddings-inference) or "cohere" (Infinity, Jina, Cohere)
+	MinScore float64       // Results scoring below this are dropped
+	Timeout  time.Duration // Request timeout
+}
+
+// HTTPReranker calls a /rerank endpoint such as text-embeddings-inference or Infinity
+type HTTPReranker struct {
+	config     HTTPRerankerConfig
+	httpClient *http.Client
+}
+
+// NewHTTPReranker creates a new HTTP reranker
+func NewHTTPReranker(config HTTPRerankerConfig) *HTTPReranker {
+	config.URL = strings.TrimRight(config.URL, "/")
+	if config.Format == "" {
+		config.Format = "tei"
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+
+	return &HTTPReranker{
+		config:     config,
+		httpClient: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+// Name returns the reranker name
+func (r *HTTPReranker) Name() string { return RerankerHTTP }
+
+// Rerank scores candidates with the cross-encoder and orders them by that score
+func (r *HTTPReranker) Rerank(ctx context.Context, query string, results []SearchResultWithScore) ([]SearchResultWithScore, error) {
+	rerankLogger := logger.NewLogger("http_reranker")
+
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	documents := make([]string, len(results))
+	for i, result := range results {
+		documents[i] = rerankDocument(result)
+	}
+
+	scores, err := r.score(ctx, query, documents)
+	if err != nil {
+		return nil, err
+	}
+
+	reranked := make([]SearchResultWithScore, 0, len(results))
+	for i, result := range results {
+		score, ok := scores[i]
+		if !ok {
+			rerankLogger.Warn().Str("article_id", result.Article.ID).Msg("Document missing from rerank response, dropping it")
+			continue
+		}
+		if score < r.config.MinScore {
+			continue
+		}
+
+		result.RerankScore = &score
+		result.Reranker = RerankerHTTP
+		result.Score = score
+		reranked = append(reranked, result)
+	}
+
+	sort.SliceStable(reranked, func(i, j int) bool {
+		return reranked[i].Score > reranked[j].Score
+	})
+
+	rerankLogger.Info().Int("candidates", len(results)).Int("kept", len(reranked)).Msg("Reranked search results")
+	return reranked, nil
+}
+
+// score sends the documents to the rerank endpoint and returns scores by document index
+func (r *HTTPReranker) score(ctx context.Context, query string, documents []string) (map[int]float64, error) {
+	var payload any
+	switch r.config.Format {
+	case "cohere":
+		payload = map[string]any{"model": r.config.Model, "query": query, "documents": documents}
+	default:
+		payload = map[string]any{"query": query, "texts": documents, "truncate": true}
+	}
+
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal rerank request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", r.config.URL+"/rerank", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rerank request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if r.config.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+r.config.APIKey)
+	}
+
+	resp, err := r.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send rerank request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rerank response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rerank request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	// TEI answers with a bare array; Cohere-style services wrap the entries in "results"
+	type rerankEntry struct {
+		Index          int      `json:"index"`
+		Score          *float64 `json:"score"`
+		RelevanceScore *float64 `json:"relevance_score"`
+	}
+	var entries []rerankEntry
+	if err := json.Unmarshal(body, &entries); err != nil {
+		var wrapped struct {
+			Results []rerankEntry `json:"results"`
+		}
+		if err := json.Unmarshal(body, &wrapped); err != nil {
+			return nil, fmt.Errorf("failed to decode rerank response: %w", err)
+		}
+		entries = wrapped.Results
+	}
+
+	scores := make(map[int]float64, len(entries))
+	for _, entry := range entries {
+		if entry.Index < 0 || entry.Index >= len(documents) {
+			continue
+		}
+		switch {
+		case entry.RelevanceScore != nil:
+			scores[entry.Index] = *entry.RelevanceScore
+		case entry.Score != nil:
+			scores[entry.Index] = *entry.Score
+		}
+	}
+	return scores, nil
+}
+
+// rerankDocument returns the text a cross-encoder judges for a result
+func rerankDocument(result SearchResultWithScore) string {
+	content := result.Article.Summary
+	if content == "" {
+		content = truncateRunes(result.Article.Content, 2000)
+	}
+	return result.Article.Title + "\n" + content
+}
+
+// rerankerFor returns the reranker configured for a search path, the no-op reranker if none is
+func (s *Server) rerankerFor(path string) Reranker {
+	if reranker, ok := s.rerankers[path]; ok && reranker != nil {
+		return reranker
+	}
+	return NoopReranker{}
+}
+
+// rerank applies the path's reranker, recording the retrieval score of every candidate.
+// If reranking fails the candidates are returned in retrieval order.
+func (s *Server) rerank(ctx context.Context, path, query string, results []SearchResultWithScore) []SearchResultWithScore {
+	for i := range results {
+		results[i].RetrievalScore = results[i].Score
+	}
+
+	reranker := s.rerankerFor(path)
+	reranked, err := reranker.Rerank(ctx, query, results)
+	if err != nil {
+		logger.NewLogger("rerank").Warn().
+			Err(err).
+			Str("path", path).
+			Str("reranker", reranker.Name()).
+			Msg("Reranking failed, returning results in retrieval order")
+		return results
+	}
+	return reranked
+}
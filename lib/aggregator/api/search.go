@@ -3,15 +3,14 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
-	"regexp"
-	"strconv"
+	"sort"
 	"strings"
 
 	"github.com/snowmerak/open-librarian/lib/client/llm"
 	"github.com/snowmerak/open-librarian/lib/client/opensearch"
-	"github.com/snowmerak/open-librarian/lib/client/qdrant"
 	"github.com/snowmerak/open-librarian/lib/util/logger"
 )
 
@@ -23,12 +22,18 @@ func (s *Server) Search(ctx context.Context, req *SearchRequest) (*SearchRespons
 	// Detect query language (still useful for context)
 	queryLang := s.languageDetector.DetectLanguage(req.Query)
 
+	// The agent may search many times per question, so each text is translated only once
+	expand := req.Expand == nil || *req.Expand
+	ctx = withExpansionCache(ctx)
+
 	// Define system prompt
 	systemPrompt := `You are an intelligent librarian. Your goal is to answer the user's question accurately using the provided tools.
-- You MUST use the provided search tools ("vector_search" or "keyword_search") to find relevant information.
+- You MUST use the provided search tools ("hybrid_search", "vector_search" or "keyword_search") to find relevant information.
 - You can use multiple tools or the same tool multiple times if needed.
-- "vector_search" is best for conceptual queries. "keyword_search" is best for specific terms.
+- "hybrid_search" is a good default. "vector_search" is best for conceptual queries. "keyword_search" is best for specific terms.
 - If you find relevant information, use it to answer the user's question comprehensively in Markdown format.
+- Every search result has a source number such as [3]. Cite the sources of each factual statement by putting their numbers right after it, e.g. "... is a document database [3][5]."
+- Only cite numbers that appear in the search results of this conversation turn, and do not state facts you cannot cite.
 - If you cannot find any relevant information after trying, admit that you don't know and suggest what else the user might try.
 - Always answer in the same language as the user's question.`
 
@@ -50,11 +55,35 @@ func (s *Server) Search(ctx context.Context, req *SearchRequest) (*SearchRespons
 	var finalAnswer string
 	var accumulatedSources []SearchResultWithScore
 
+	var degraded bool
+	historyLen := len(req.History)
+
 	// Tool calling loop
 	maxTurns := 5
 	for i := 0; i < maxTurns; i++ {
-		respMsg, err := s.llmClient.Chat(ctx, messages, tools)
+		respMsg, err := s.llmClient.Chat(ctx, llm.TaskChat, messages, tools)
 		if err != nil {
+			if errors.Is(err, llm.ErrContextTooLong) {
+				if compacted, ok := compactChatMessages(messages, historyLen); ok {
+					searchLogger.Warn().Err(err).Int("message_count", len(messages)).Msg("Conversation exceeds model context, compacting")
+					messages = compacted
+					historyLen = 0
+					i-- // Compaction does not consume a turn
+					continue
+				}
+			}
+
+			if isLLMOutage(err) {
+				// Without a chat model, still return whatever documents we can find
+				searchLogger.Warn().Err(err).Int("sources", len(accumulatedSources)).Msg("LLM unavailable, returning search results without an answer")
+				if len(accumulatedSources) == 0 {
+					accumulatedSources = s.searchWithoutAgent(ctx, req.Query, queryLang, expand, req.Topics)
+				}
+				degraded = true
+				break
+			}
+
+			searchLogger.EndWithError(err)
 			return nil, fmt.Errorf("LLM chat error: %w", err)
 		}
 
@@ -78,12 +107,12 @@ func (s *Server) Search(ctx context.Context, req *SearchRequest) (*SearchRespons
 				if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
 					toolResult = fmt.Sprintf("Error parsing arguments: %v", err)
 				} else {
-					results, err := s.executeVectorSearch(ctx, args.Query, queryLang, req.Query)
+					results, err := s.executeVectorSearch(ctx, args.Query, queryLang, req.Query, req.Topics)
 					if err != nil {
 						toolResult = fmt.Sprintf("Error executing vector search: %v", err)
 					} else {
-						toolResult = s.formatSearchResults(results)
 						s.mergeSources(&accumulatedSources, results)
+						toolResult = s.formatSearchResults(results, accumulatedSources)
 					}
 				}
 
@@ -94,12 +123,36 @@ func (s *Server) Search(ctx context.Context, req *SearchRequest) (*SearchRespons
 				if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
 					toolResult = fmt.Sprintf("Error parsing arguments: %v", err)
 				} else {
-					results, err := s.executeKeywordSearch(ctx, args.Keywords, queryLang, req.Query)
+					results, err := s.executeKeywordSearch(ctx, args.Keywords, queryLang, req.Query, expand, req.Topics)
 					if err != nil {
 						toolResult = fmt.Sprintf("Error executing keyword search: %v", err)
 					} else {
-						toolResult = s.formatSearchResults(results)
 						s.mergeSources(&accumulatedSources, results)
+						toolResult = s.formatSearchResults(results, accumulatedSources)
+					}
+				}
+			case ToolHybridSearch:
+				var args struct {
+					Query         string   `json:"query"`
+					VectorWeight  *float64 `json:"vector_weight"`
+					KeywordWeight *float64 `json:"keyword_weight"`
+				}
+				if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
+					toolResult = fmt.Sprintf("Error parsing arguments: %v", err)
+				} else {
+					results, err := s.executeHybridSearch(ctx, &HybridSearchRequest{
+						Query:         args.Query,
+						Lang:          queryLang,
+						VectorWeight:  args.VectorWeight,
+						KeywordWeight: args.KeywordWeight,
+						Expand:        &expand,
+						Topics:        req.Topics,
+					}, req.Query)
+					if err != nil {
+						toolResult = fmt.Sprintf("Error executing hybrid search: %v", err)
+					} else {
+						s.mergeSources(&accumulatedSources, results)
+						toolResult = s.formatSearchResults(results, accumulatedSources)
 					}
 				}
 			default:
@@ -115,32 +168,69 @@ func (s *Server) Search(ctx context.Context, req *SearchRequest) (*SearchRespons
 		}
 	}
 
-	// Convert accumulated sources to Article list for response
-	finalSources := make([]opensearch.Article, len(accumulatedSources))
-	for i, src := range accumulatedSources {
-		finalSources[i] = src.Article
-	}
+	// Link the answer's citation markers to the passages that back them
+	grounding := s.groundAnswer(ctx, finalAnswer, accumulatedSources)
 
+	searchLogger.EndWithMsg("Search complete")
 	return &SearchResponse{
-		Answer:  finalAnswer,
-		Sources: accumulatedSources, // Use the new struct type or convert if needed. The response struct expects []SearchResultWithScore
-		Took:    0,                  // Not tracking singular took time anymore
+		Answer:            grounding.answer,
+		Sources:           accumulatedSources, // Source n of the answer's citation markers is Sources[n-1]
+		Took:              0,                  // Not tracking singular took time anymore
+		Degraded:          degraded,
+		Citations:         grounding.citations,
+		UnsupportedClaims: grounding.unsupported,
 	}, nil
 }
 
-// executeVectorSearch performs vector search and relevance validation
-func (s *Server) executeVectorSearch(ctx context.Context, query string, lang string, originalQuery string) ([]SearchResultWithScore, error) {
+// searchWithoutAgent runs hybrid search directly with the user's query.
+// Used when the chat model is unavailable, so results are not validated by the LLM.
+func (s *Server) searchWithoutAgent(ctx context.Context, query string, lang string, expand bool, topics []string) []SearchResultWithScore {
+	opts, err := s.resolveHybridOptions(&HybridSearchRequest{Query: query, Lang: lang, Expand: &expand, Topics: topics})
+	if err != nil {
+		logger.NewLogger("search_without_agent").Warn().Err(err).Msg("Invalid hybrid search options")
+		return nil
+	}
+
+	hybrid, err := s.hybridSearch(ctx, opts)
+	if err != nil {
+		logger.NewLogger("search_without_agent").Warn().Err(err).Msg("Hybrid search failed")
+		return nil
+	}
+	return hybrid.results
+}
+
+// executeHybridSearch performs hybrid search and relevance validation
+func (s *Server) executeHybridSearch(ctx context.Context, req *HybridSearchRequest, originalQuery string) ([]SearchResultWithScore, error) {
+	opts, err := s.resolveHybridOptions(req)
+	if err != nil {
+		return nil, err
+	}
+
+	hybrid, err := s.hybridSearch(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Relevance Validation
+	return s.rerank(ctx, RerankPathAgent, originalQuery, hybrid.results), nil
+}
+
+// executeVectorSearch performs vector search within topics, when given, and relevance validation
+func (s *Server) executeVectorSearch(ctx context.Context, query string, lang string, originalQuery string, topics []string) ([]SearchResultWithScore, error) {
 	// Generate embedding
 	embedding, err := s.llmClient.GenerateEmbedding(ctx, "query: "+query)
 	if err != nil {
 		return nil, err
 	}
 
-	// Search Qdrant
-	rawResults, err := s.qdrantClient.VectorSearch(ctx, embedding, 10, lang) // Fetch more candidates
+	// Search Qdrant across all languages; the query language is preferred, not required
+	rawResults, err := s.qdrantClient.VectorSearch(ctx, embedding, 10) // Fetch more candidates
 	if err != nil {
 		return nil, err
 	}
+	if rawResults, err = s.filterVectorTopics(ctx, rawResults, topics); err != nil {
+		return nil, err
+	}
 
 	// Process results
 	var candidates []SearchResultWithScore
@@ -170,8 +260,8 @@ func (s *Server) executeVectorSearch(ctx context.Context, query string, lang str
 		maxScore := 0.0
 		for _, raw := range rawResults {
 			if s.extractArticleID(raw.ID) == art.ID {
-				if float64(raw.Score) > maxScore {
-					maxScore = float64(raw.Score)
+				if score := languageBoostedScore(raw.Score, raw.Lang, lang); score > maxScore {
+					maxScore = score
 				}
 			}
 		}
@@ -183,51 +273,104 @@ func (s *Server) executeVectorSearch(ctx context.Context, query string, lang str
 	}
 
 	// Relevance Validation
-	return s.validateSearchRelevance(ctx, originalQuery, candidates)
+	return s.rerank(ctx, RerankPathAgent, originalQuery, candidates), nil
 }
 
-// executeKeywordSearch performs keyword search and relevance validation
-func (s *Server) executeKeywordSearch(ctx context.Context, keywords string, lang string, originalQuery string) ([]SearchResultWithScore, error) {
-	resp, err := s.opensearchClient.KeywordSearch(ctx, keywords, lang, 10, 0)
-	if err != nil {
-		return nil, err
-	}
+// executeKeywordSearch performs keyword search in the query's language and, when expand is set, its translations, then validates relevance.
+// Results are restricted to topics when any are given.
+func (s *Server) executeKeywordSearch(ctx context.Context, keywords string, lang string, originalQuery string, expand bool, topics []string) ([]SearchResultWithScore, error) {
+	variants := s.understandQuery(ctx, keywords, lang, expand)
 
 	var candidates []SearchResultWithScore
-	for _, res := range resp.Results {
-		normalizedScore := s.normalizeKeywordScore(res.Score)
-		candidates = append(candidates, SearchResultWithScore{
-			Article: res.Article,
-			Score:   normalizedScore,
-			Source:  "keyword",
+	seen := make(map[string]int)
+	var errs []error
+	for _, variant := range variants {
+		resp, err := s.opensearchClient.Search(ctx, opensearch.KeywordSearchOptions{
+			Query:   variant.Text,
+			Lang:    variant.Lang,
+			Filters: opensearch.SearchFilters{Topics: topics},
+			Size:    10,
 		})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, res := range resp.Results {
+			normalizedScore := s.normalizeKeywordScore(res.Score) * s.variantWeight(variant)
+			if i, ok := seen[res.Article.ID]; ok {
+				candidates[i].Score = max(candidates[i].Score, normalizedScore)
+				continue
+			}
+			seen[res.Article.ID] = len(candidates)
+			candidates = append(candidates, SearchResultWithScore{
+				Article:    res.Article,
+				Score:      normalizedScore,
+				Source:     "keyword",
+				Highlights: res.Highlights,
+			})
+		}
+	}
+	if len(errs) == len(variants) {
+		return nil, errors.Join(errs...)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	if len(candidates) > 10 {
+		candidates = candidates[:10]
 	}
 
 	// Relevance Validation
-	return s.validateSearchRelevance(ctx, originalQuery, candidates)
+	return s.rerank(ctx, RerankPathAgent, originalQuery, candidates), nil
 }
 
-func (s *Server) formatSearchResults(results []SearchResultWithScore) string {
+// formatSearchResults renders tool results for the chat model, labelling each with its number in sources
+func (s *Server) formatSearchResults(results []SearchResultWithScore, sources []SearchResultWithScore) string {
 	if len(results) == 0 {
 		return "No relevant results found."
 	}
+	// A single tool result may take half of the chat model's prompt budget, shared between documents
+	budget := s.newPromptBudget(llm.TaskChat, answerOutputTokens)
+	perResultTokens := budget.limit / 2 / len(results)
+
+	numbers := make(map[string]int, len(sources))
+	for i, source := range sources {
+		numbers[source.Article.ID] = i + 1
+	}
+
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("Found %d relevant documents:\n", len(results)))
-	for i, res := range results {
-		// Provide summary or truncated content
-		content := res.Article.Summary
+	for _, res := range results {
+		// Prefer the passages that matched the query, then the summary, then the opening of the content
+		label, content := "Matched passages", matchedPassages(res.Highlights)
 		if content == "" {
-			if len(res.Article.Content) > 500 {
-				content = res.Article.Content[:500] + "..."
-			} else {
-				content = res.Article.Content
-			}
+			label, content = "Content", res.Article.Summary
+		}
+		if content == "" {
+			content = res.Article.Content
 		}
-		sb.WriteString(fmt.Sprintf("%d. [ID: %s] Title: %s\nContent: %s\n\n", i+1, res.Article.ID, res.Article.Title, content))
+		if truncated := truncateToTokens(budget.counter, content, perResultTokens); len(truncated) < len(content) {
+			content = truncated + "..."
+		}
+		sb.WriteString(fmt.Sprintf("[%d] Title: %s (ID: %s)\n%s: %s\n\n", numbers[res.Article.ID], res.Article.Title, res.Article.ID, label, content))
 	}
 	return sb.String()
 }
 
+// matchedPassages joins the summary and content passages of a result's highlights
+func matchedPassages(highlights []opensearch.Highlight) string {
+	var passages []string
+	for _, highlight := range highlights {
+		if highlight.Field == "title" {
+			continue
+		}
+		passages = append(passages, highlight.Fragment)
+	}
+	return strings.Join(passages, " ... ")
+}
+
 func (s *Server) mergeSources(target *[]SearchResultWithScore, new []SearchResultWithScore) {
 	existingIDs := make(map[string]bool)
 	for _, t := range *target {
@@ -242,136 +385,6 @@ func (s *Server) mergeSources(target *[]SearchResultWithScore, new []SearchResul
 	}
 }
 
-// combineSearchResults combines vector and keyword search results with scoring
-func (s *Server) combineSearchResults(vectorResults []qdrant.VectorSearchResult, vectorArticles []opensearch.Article, keywordResults []opensearch.SearchResult, limit int) []SearchResultWithScore {
-	combineLogger := logger.NewLogger("combine_search_results").StartWithMsg("Combining search results")
-	defer combineLogger.EndWithMsg("Search results combination complete")
-
-	const minScoreThreshold = 0.35   // Minimum score threshold for quality filtering
-	const singleSourcePenalty = 0.75 // Penalty for non-hybrid results
-
-	// Create maps for easier lookup
-	vectorArticleMap := make(map[string]opensearch.Article)
-	for _, article := range vectorArticles {
-		vectorArticleMap[article.ID] = article
-	}
-
-	vectorScoreMap := make(map[string]float64)
-	for _, result := range vectorResults {
-		articleID := s.extractArticleID(result.ID)
-		vectorScoreMap[articleID] = result.Score
-	}
-
-	// Collect all results
-	resultMap := make(map[string]SearchResultWithScore)
-
-	// Add vector search results
-	for _, result := range vectorResults {
-		articleID := s.extractArticleID(result.ID)
-		if article, exists := vectorArticleMap[articleID]; exists {
-			resultMap[articleID] = SearchResultWithScore{
-				Article: article,
-				Score:   result.Score,
-				Source:  "vector",
-			}
-		}
-	}
-
-	// Add keyword search results (combine scores if duplicate)
-	for _, result := range keywordResults {
-		if existing, exists := resultMap[result.Article.ID]; exists {
-			// Normalize scores to 0-1 range before combining
-			normalizedVectorScore := existing.Score // Vector scores are already 0-1
-			normalizedKeywordScore := s.normalizeKeywordScore(result.Score)
-
-			// Combine normalized scores with weighted average: vector 60%, keyword 40%
-			combinedScore := (0.6 * normalizedVectorScore) + (0.4 * normalizedKeywordScore)
-
-			// Log score combination details
-			combineLogger.Debug().
-				Str("article_id", result.Article.ID).
-				Float64("vector_score", normalizedVectorScore).
-				Float64("keyword_original", result.Score).
-				Float64("keyword_normalized", normalizedKeywordScore).
-				Float64("combined_score", combinedScore).
-				Msg("Score combination calculated")
-
-			resultMap[result.Article.ID] = SearchResultWithScore{
-				Article: result.Article,
-				Score:   combinedScore,
-				Source:  "hybrid",
-			}
-		} else {
-			normalizedScore := s.normalizeKeywordScore(result.Score)
-			// Apply penalty for keyword-only results
-			penalizedScore := normalizedScore * singleSourcePenalty
-			combineLogger.Debug().
-				Str("article_id", result.Article.ID).
-				Float64("original_score", result.Score).
-				Float64("normalized_score", normalizedScore).
-				Float64("penalized_score", penalizedScore).
-				Msg("Keyword-only result scored")
-
-			resultMap[result.Article.ID] = SearchResultWithScore{
-				Article: result.Article,
-				Score:   penalizedScore,
-				Source:  "keyword",
-			}
-		}
-	}
-
-	// Apply penalty to vector-only results
-	for articleID, result := range resultMap {
-		if result.Source == "vector" {
-			penalizedScore := result.Score * singleSourcePenalty
-			combineLogger.Debug().
-				Str("article_id", articleID).
-				Float64("original_score", result.Score).
-				Float64("penalized_score", penalizedScore).
-				Msg("Vector-only result penalized")
-
-			result.Score = penalizedScore
-			resultMap[articleID] = result
-		}
-	}
-
-	// Convert to slice and filter by minimum score threshold
-	var combinedResults []SearchResultWithScore
-	for _, result := range resultMap {
-		if result.Score >= minScoreThreshold {
-			combinedResults = append(combinedResults, result)
-			combineLogger.Debug().
-				Str("article_id", result.Article.ID).
-				Float64("score", result.Score).
-				Str("source", result.Source).
-				Msg("Result included")
-		} else {
-			combineLogger.Debug().
-				Str("article_id", result.Article.ID).
-				Float64("score", result.Score).
-				Str("source", result.Source).
-				Float64("threshold", minScoreThreshold).
-				Msg("Result filtered out (low score)")
-		}
-	}
-
-	// Sort by score descending
-	for i := 0; i < len(combinedResults)-1; i++ {
-		for j := i + 1; j < len(combinedResults); j++ {
-			if combinedResults[i].Score < combinedResults[j].Score {
-				combinedResults[i], combinedResults[j] = combinedResults[j], combinedResults[i]
-			}
-		}
-	}
-
-	// Limit results
-	if len(combinedResults) > limit {
-		combinedResults = combinedResults[:limit]
-	}
-
-	return combinedResults
-}
-
 // normalizeKeywordScore normalizes OpenSearch keyword scores to 0-1 range using sigmoid function
 func (s *Server) normalizeKeywordScore(score float64) float64 {
 	if score <= 0 {
@@ -392,65 +405,6 @@ func (s *Server) normalizeKeywordScore(score float64) float64 {
 	return sigmoid
 }
 
-// combineVectorResults combines title and summary vector search results
-func (s *Server) combineVectorResults(titleResults, summaryResults []qdrant.VectorSearchResult, limit int) []qdrant.VectorSearchResult {
-	resultMap := make(map[string]qdrant.VectorSearchResult)
-
-	// Add title results with boosted scores (titles are more important)
-	for _, result := range titleResults {
-		articleID := s.extractArticleID(result.ID)
-		boostedScore := result.Score * 1.2 // Boost title matches by 20%
-		if boostedScore > 1.0 {
-			boostedScore = 1.0
-		}
-		resultMap[articleID] = qdrant.VectorSearchResult{
-			ID:    articleID, // Use original article ID, not the Qdrant point ID
-			Score: boostedScore,
-		}
-	}
-
-	// Add summary results (combine if duplicate)
-	for _, result := range summaryResults {
-		articleID := s.extractArticleID(result.ID)
-		if existing, exists := resultMap[articleID]; exists {
-			// Take the higher score between title and summary
-			if result.Score > existing.Score {
-				resultMap[articleID] = qdrant.VectorSearchResult{
-					ID:    articleID,
-					Score: result.Score,
-				}
-			}
-		} else {
-			resultMap[articleID] = qdrant.VectorSearchResult{
-				ID:    articleID,
-				Score: result.Score,
-			}
-		}
-	}
-
-	// Convert to slice and sort by score descending
-	var combinedResults []qdrant.VectorSearchResult
-	for _, result := range resultMap {
-		combinedResults = append(combinedResults, result)
-	}
-
-	// Sort by score descending
-	for i := 0; i < len(combinedResults)-1; i++ {
-		for j := i + 1; j < len(combinedResults); j++ {
-			if combinedResults[i].Score < combinedResults[j].Score {
-				combinedResults[i], combinedResults[j] = combinedResults[j], combinedResults[i]
-			}
-		}
-	}
-
-	// Limit results to requested size
-	if len(combinedResults) > limit {
-		combinedResults = combinedResults[:limit]
-	}
-
-	return combinedResults
-}
-
 // extractArticleID extracts the original article ID from Qdrant point ID
 func (s *Server) extractArticleID(pointID string) string {
 	// Remove _title or _summary suffix
@@ -481,9 +435,14 @@ func (s *Server) validateSearchRelevance(ctx context.Context, query string, resu
 	// Detect query language for appropriate prompt
 	queryLang := s.languageDetector.DetectLanguage(query)
 
+	// Document labels follow the prompt language
+	type documentLabels struct{ document, title, content string }
+	var labels documentLabels
+
 	var relevancePrompt string
 	switch queryLang {
 	case "ko":
+		labels = documentLabels{"문서", "제목", "내용"}
 		relevancePrompt = `다음 질문에 대해 제공된 문서들이 얼마나 관련성이 있는지 평가해주세요.
 
 질문: %s
@@ -491,19 +450,16 @@ func (s *Server) validateSearchRelevance(ctx context.Context, query string, resu
 문서들:
 %s
 
-각 문서에 대해 다음 형식으로 0-10 점수를 매겨주세요 (10점이 가장 관련성이 높음):
-문서1: [점수]
-문서2: [점수]
-...
+각 문서에 0-10 점수를 매겨주세요 (10점이 가장 관련성이 높음). "scores" 목록에 문서 번호("document")와 점수("score")를 문서마다 하나씩 넣어주세요.
 
 평가 기준:
 - 질문의 핵심 키워드와 일치하는 정도
 - 문서가 질문에 답변할 수 있는 정보를 포함하는 정도
 - 문맥상 관련성
 - 5점 미만은 관련성이 낮은 것으로 간주됩니다
-
-점수만 제공하고 추가 설명은 하지 마세요.`
+`
 	case "ja":
+		labels = documentLabels{"文書", "タイトル", "内容"}
 		relevancePrompt = `以下の質問に対して、提供された文書がどの程度関連性があるかを評価してください。
 
 質問: %s
@@ -511,19 +467,16 @@ func (s *Server) validateSearchRelevance(ctx context.Context, query string, resu
 文書:
 %s
 
-各文書について以下の形式で0-10のスコアを付けてください（10点が最も関連性が高い）:
-文書1: [スコア]
-文書2: [スコア]
-...
+各文書に0-10のスコアを付けてください（10点が最も関連性が高い）。"scores" リストに文書番号（"document"）とスコア（"score"）を文書ごとに1つずつ入れてください。
 
 評価基準:
 - 質問の核心キーワードとの一致度
 - 文書が質問に答えられる情報を含む度合い
 - 文脈上の関連性
 - 5点未満は関連性が低いと見なされます
-
-スコアのみを提供し、追加説明はしないでください。`
+`
 	case "zh":
+		labels = documentLabels{"文档", "标题", "内容"}
 		relevancePrompt = `请评估以下文档对给定问题的相关性。
 
 问题: %s
@@ -531,19 +484,16 @@ func (s *Server) validateSearchRelevance(ctx context.Context, query string, resu
 文档:
 %s
 
-请为每个文档按以下格式评分0-10分（10分表示最相关）:
-文档1: [分数]
-文档2: [分数]
-...
+请为每个文档评分0-10分（10分表示最相关）。在 "scores" 列表中为每个文档放入一项，包含文档编号（"document"）和分数（"score"）。
 
 评分标准:
 - 与问题核心关键词的匹配程度
 - 文档包含能回答问题的信息程度
 - 上下文相关性
 - 5分以下被认为相关性较低
-
-只提供分数，不要额外说明。`
+`
 	default: // English
+		labels = documentLabels{"Document", "Title", "Content"}
 		relevancePrompt = `Please evaluate how relevant the provided documents are to the given question.
 
 Question: %s
@@ -551,89 +501,127 @@ Question: %s
 Documents:
 %s
 
-Rate each document with a score from 0-10 (10 being most relevant) in the following format:
-Document1: [score]
-Document2: [score]
-...
+Rate each document with a score from 0-10 (10 being most relevant). Put one entry per document in the "scores" list with the document number ("document") and the score ("score").
 
 Evaluation criteria:
 - Match with core keywords in the question
 - Degree to which the document contains information that can answer the question
 - Contextual relevance
 - Scores below 5 are considered low relevance
-
-Provide only scores without additional explanations.`
+`
 	}
 
-	// Build documents string for LLM evaluation
-	var documentsText string
-	for i, result := range results {
+	// excerptFor returns the text judged for a result; excerptLimit > 0 shortens it to that many runes
+	excerptFor := func(result SearchResultWithScore, excerptLimit int) string {
 		// Use summary for relevance check to reduce token usage
 		content := result.Article.Summary
 		if content == "" {
 			// Truncate content if summary is not available
-			content = result.Article.Content
-			if len(content) > 1000 {
-				content = content[:1000] + "..."
-			}
+			content = truncateRunes(result.Article.Content, 1000)
 		}
-
-		documentsText += fmt.Sprintf("문서%d: 제목: %s\n내용: %s\n\n", i+1, result.Article.Title, content)
+		if excerptLimit > 0 {
+			content = truncateRunes(content, excerptLimit)
+		}
+		return content
 	}
 
-	prompt := fmt.Sprintf(relevancePrompt, query, documentsText)
+	// Scores are cached per query and document, so documents judged in earlier searches are not sent again
+	relevanceModel := s.llmClient.PrimaryModel(llm.TaskRelevance)
+	cacheInput := func(result SearchResultWithScore) string {
+		return query + "\x00" + result.Article.ID + "\x00" + result.Article.Title + "\x00" + excerptFor(result, 0)
+	}
 
-	// Get LLM evaluation
-	evaluation, err := s.llmClient.GenerateText(ctx, prompt)
-	if err != nil {
-		relevanceLogger.Error().Err(err).Msg("Failed to get relevance evaluation from LLM")
-		// Return original results if LLM evaluation fails
-		return results, nil
+	relevanceScores := make(map[int]float64, len(results))
+	var pending []int
+	for i, result := range results {
+		var score float64
+		if s.llmClient.Cache().Get(ctx, llm.CacheKindRelevance, relevanceModel, cacheInput(result), &score) {
+			relevanceScores[i] = score
+			continue
+		}
+		pending = append(pending, i)
 	}
 
-	relevanceLogger.Debug().Str("evaluation_response", evaluation).Msg("LLM relevance evaluation received")
+	if len(pending) > 0 {
+		relevanceLogger.Info().
+			Int("cached", len(results)-len(pending)).
+			Int("pending", len(pending)).
+			Msg("Scoring documents without a cached relevance score")
+
+		// Build documents string for LLM evaluation, numbering only the pending documents
+		buildDocuments := func(excerptLimit int) string {
+			var documentsText string
+			for n, i := range pending {
+				result := results[i]
+				documentsText += fmt.Sprintf("%s %d\n%s: %s\n%s: %s\n\n", labels.document, n+1, labels.title, result.Article.Title, labels.content, excerptFor(result, excerptLimit))
+			}
+			return documentsText
+		}
+
+		// Get LLM evaluation
+		schema := relevanceSchema(len(pending))
+		var evaluation RelevanceOutput
+		err := s.llmClient.GenerateJSON(ctx, llm.TaskRelevance, fmt.Sprintf(relevancePrompt, query, buildDocuments(0)), "relevance", schema, &evaluation)
+		if errors.Is(err, llm.ErrContextTooLong) {
+			relevanceLogger.Warn().Err(err).Msg("Relevance prompt exceeds model context, retrying with shorter excerpts")
+			err = s.llmClient.GenerateJSON(ctx, llm.TaskRelevance, fmt.Sprintf(relevancePrompt, query, buildDocuments(300)), "relevance", schema, &evaluation)
+		}
+		if err != nil {
+			relevanceLogger.Error().Err(err).Msg("Failed to get relevance evaluation from LLM, returning unvalidated results")
+			// Return original results if LLM evaluation fails
+			return results, nil
+		}
 
-	// Parse relevance scores from LLM response
-	relevanceScores := s.parseRelevanceScores(evaluation, len(results))
+		// The schema guarantees one in-range entry per document; a repeated number keeps its last score
+		for _, score := range evaluation.Scores {
+			i := pending[score.Document-1]
+			relevanceScores[i] = score.Score
+			s.llmClient.Cache().Set(ctx, llm.CacheKindRelevance, relevanceModel, cacheInput(results[i]), score.Score)
+		}
+	}
 
 	// Filter results based on relevance scores
 	var filteredResults []SearchResultWithScore
 	const relevanceThreshold = 5.0 // Minimum relevance score
 
 	for i, result := range results {
-		if i < len(relevanceScores) {
-			relevanceScore := relevanceScores[i]
-			if relevanceScore >= relevanceThreshold {
-				relevanceLogger.Debug().
-					Str("article_id", result.Article.ID).
-					Float64("search_score", result.Score).
-					Float64("relevance_score", relevanceScore).
-					Msg("Document passed relevance check")
-
-				// Optionally adjust the final score based on relevance
-				// Combine search score (70%) with relevance score normalized to 0-1 (30%)
-				adjustedScore := (result.Score * 0.7) + ((relevanceScore / 10.0) * 0.3)
-				result.Score = adjustedScore
-
-				filteredResults = append(filteredResults, result)
-			} else {
-				relevanceLogger.Debug().
-					Str("article_id", result.Article.ID).
-					Float64("search_score", result.Score).
-					Float64("relevance_score", relevanceScore).
-					Float64("threshold", relevanceThreshold).
-					Msg("Document filtered out due to low relevance")
-			}
-		} else {
-			// If we couldn't parse the score, keep the result
+		relevanceScore, scored := relevanceScores[i]
+		if !scored {
+			relevanceLogger.Warn().
+				Str("article_id", result.Article.ID).
+				Msg("Document missing from relevance evaluation, treating as irrelevant")
+			continue
+		}
+
+		if relevanceScore >= relevanceThreshold {
 			relevanceLogger.Debug().
 				Str("article_id", result.Article.ID).
 				Float64("search_score", result.Score).
-				Msg("Document kept (unparsed relevance)")
+				Float64("relevance_score", relevanceScore).
+				Msg("Document passed relevance check")
+
+			// Optionally adjust the final score based on relevance
+			// Combine search score (70%) with relevance score normalized to 0-1 (30%)
+			adjustedScore := (result.Score * 0.7) + ((relevanceScore / 10.0) * 0.3)
+			result.Score = adjustedScore
+			result.RerankScore = &relevanceScore
+			result.Reranker = RerankerLLMJudge
+
 			filteredResults = append(filteredResults, result)
+		} else {
+			relevanceLogger.Debug().
+				Str("article_id", result.Article.ID).
+				Float64("search_score", result.Score).
+				Float64("relevance_score", relevanceScore).
+				Float64("threshold", relevanceThreshold).
+				Msg("Document filtered out due to low relevance")
 		}
 	}
 
+	sort.SliceStable(filteredResults, func(i, j int) bool {
+		return filteredResults[i].Score > filteredResults[j].Score
+	})
+
 	relevanceLogger.Info().
 		Int("original_count", len(results)).
 		Int("filtered_count", len(filteredResults)).
@@ -641,39 +629,3 @@ Provide only scores without additional explanations.`
 
 	return filteredResults, nil
 }
-
-// parseRelevanceScores parses LLM response to extract relevance scores
-func (s *Server) parseRelevanceScores(evaluation string, expectedCount int) []float64 {
-	scores := make([]float64, 0, expectedCount)
-
-	// Simple parsing - look for patterns like "문서1: 8", "Document1: 7", etc.
-	lines := strings.Split(evaluation, "\n")
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		// Look for patterns like "문서1: 8", "Document1: 7", "文書1: 6"
-		patterns := []string{
-			`문서\d+:\s*(\d+(?:\.\d+)?)`,
-			`Document\d+:\s*(\d+(?:\.\d+)?)`,
-			`文書\d+:\s*(\d+(?:\.\d+)?)`,
-			`文档\d+:\s*(\d+(?:\.\d+)?)`,
-		}
-
-		for _, pattern := range patterns {
-			re := regexp.MustCompile(pattern)
-			matches := re.FindStringSubmatch(line)
-			if len(matches) > 1 {
-				if score, err := strconv.ParseFloat(matches[1], 64); err == nil {
-					scores = append(scores, score)
-					break
-				}
-			}
-		}
-	}
-
-	return scores
-}
@@ -3,27 +3,171 @@ package api
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 
+	"github.com/snowmerak/open-librarian/lib/client/blob"
 	"github.com/snowmerak/open-librarian/lib/client/llm"
+	"github.com/snowmerak/open-librarian/lib/client/mail"
 	"github.com/snowmerak/open-librarian/lib/client/mongo"
 	"github.com/snowmerak/open-librarian/lib/client/opensearch"
 	"github.com/snowmerak/open-librarian/lib/client/qdrant"
+	"github.com/snowmerak/open-librarian/lib/client/web"
 	"github.com/snowmerak/open-librarian/lib/util/language"
 	"github.com/snowmerak/open-librarian/lib/util/logger"
+	"github.com/snowmerak/open-librarian/lib/util/parser"
 )
 
 // Server represents the main API server
 type Server struct {
-	llmClient        *llm.Client
+	llmClient        *llm.Router
 	opensearchClient *opensearch.Client
 	qdrantClient     *qdrant.Client
 	mongoClient      *mongo.Client
 	jwtService       *mongo.JWTService
 	languageDetector *language.Detector
+	mailSender       mail.Sender
+	publicBaseURL    string
+	ingestConfig     IngestConfig
+	uploadConfig     UploadConfig
+	fetcher          web.Fetcher
+	ocr              parser.OCR          // Reads scanned PDF pages and images; nil leaves them unread
+	rerankers        map[string]Reranker // Reranker per search path, see RerankPathAgent and RerankPathHybrid
+	queryExpansion   QueryExpansionConfig
+	languageStats    languageStats
+	groundingMode    string // How answer claims are checked against their sources, GroundingLLM or GroundingLexical
+	topicConfig      TopicConfig
+	topicClustering  sync.Mutex // Held while a topic clustering run is in progress
+	topicCenters     topicCenters
+	jobs             *jobStore
+	archiveSlots     chan struct{} // Held by each bulk job while it runs
+	blobs            blob.Store    // Keeps the original files of uploads; nil discards them
+	fileAccess       string        // Who may download original files, FileAccessUsers or FileAccessRegistrar
+
+	llmCache           *llm.Cache
+	llmCachePersistent bool
+}
+
+// ServerOption configures optional server components
+type ServerOption func(*Server)
+
+// WithMailSender sets the sender used for verification and password reset emails
+func WithMailSender(sender mail.Sender) ServerOption {
+	return func(s *Server) {
+		s.mailSender = sender
+	}
+}
+
+// WithLLMRouter replaces the single-provider LLM client with a per-task router
+func WithLLMRouter(router *llm.Router) ServerOption {
+	return func(s *Server) {
+		s.llmClient = router
+	}
+}
+
+// WithLLMCache caches embeddings and deterministic LLM outputs.
+// With persistent set, entries are also stored in MongoDB and shared between server instances.
+func WithLLMCache(cache *llm.Cache, persistent bool) ServerOption {
+	return func(s *Server) {
+		s.llmCache = cache
+		s.llmCachePersistent = persistent
+	}
+}
+
+// WithIngestConfig sets the concurrency and batch sizes of the bulk ingestion pipeline
+func WithIngestConfig(config IngestConfig) ServerOption {
+	return func(s *Server) {
+		s.ingestConfig = config
+	}
+}
+
+// WithUploadConfig sets the file upload size limits and parsing protections
+func WithUploadConfig(config UploadConfig) ServerOption {
+	return func(s *Server) {
+		s.uploadConfig = config
+	}
+}
+
+// WithFetcher sets the fetcher used to add articles from URLs
+func WithFetcher(fetcher web.Fetcher) ServerOption {
+	return func(s *Server) {
+		s.fetcher = fetcher
+	}
+}
+
+// WithOCR sets the engine used to read scanned PDF pages and image uploads
+func WithOCR(ocr parser.OCR) ServerOption {
+	return func(s *Server) {
+		s.ocr = ocr
+	}
+}
+
+// WithBlobStore sets the store that keeps the original files of uploaded articles
+func WithBlobStore(store blob.Store) ServerOption {
+	return func(s *Server) {
+		s.blobs = store
+	}
+}
+
+// WithFileAccess sets who may download original files: FileAccessUsers or FileAccessRegistrar
+func WithFileAccess(mode string) ServerOption {
+	return func(s *Server) {
+		s.fileAccess = mode
+	}
+}
+
+// WithReranker sets the reranker used on a search path
+func WithReranker(path string, reranker Reranker) ServerOption {
+	return func(s *Server) {
+		s.rerankers[path] = reranker
+	}
+}
+
+// WithLLMJudgeReranker reranks a search path with the relevance model
+func WithLLMJudgeReranker(path string) ServerOption {
+	return func(s *Server) {
+		s.rerankers[path] = NewLLMJudgeReranker(s)
+	}
+}
+
+// WithQueryExpansion translates search queries into the library's dominant languages
+func WithQueryExpansion(config QueryExpansionConfig) ServerOption {
+	return func(s *Server) {
+		s.queryExpansion = config
+	}
+}
+
+// WithLLMQueryTranslator translates search queries with the translate task's model.
+// Apply it after WithQueryExpansion, which would otherwise replace the translator.
+func WithLLMQueryTranslator() ServerOption {
+	return func(s *Server) {
+		s.queryExpansion.Translator = NewLLMQueryTranslator(s)
+	}
+}
+
+// WithGroundingMode sets how answer claims are checked against their cited sources
+func WithGroundingMode(mode string) ServerOption {
+	return func(s *Server) {
+		s.groundingMode = mode
+	}
+}
+
+// WithTopicConfig sets how often and into how many topics articles are clustered
+func WithTopicConfig(config TopicConfig) ServerOption {
+	return func(s *Server) {
+		s.topicConfig = config
+	}
+}
+
+// WithPublicBaseURL sets the externally reachable base URL used in email links
+func WithPublicBaseURL(baseURL string) ServerOption {
+	return func(s *Server) {
+		s.publicBaseURL = strings.TrimRight(baseURL, "/")
+	}
 }
 
 // NewServer creates a new API server instance
-func NewServer(llmBaseURL, llmKey, llmModel, ollamaBaseURL, opensearchBaseURL, qdrantHost, mongoURI, jwtSecret string, qdrantPort int) (*Server, error) {
+func NewServer(llmBaseURL, llmKey, llmModel, ollamaBaseURL, opensearchBaseURL, qdrantHost, mongoURI, jwtSecret string, qdrantPort int, opts ...ServerOption) (*Server, error) {
 	serverLogger := logger.NewLogger("server_init").StartWithMsg("Initializing server components")
 
 	// Initialize Qdrant client
@@ -71,7 +215,7 @@ func NewServer(llmBaseURL, llmKey, llmModel, ollamaBaseURL, opensearchBaseURL, q
 	jwtLogger.EndWithMsg("JWT service initialization complete")
 
 	// Create other clients
-	llmClient := llm.NewClient(llmBaseURL, llmKey, llmModel, ollamaBaseURL)
+	llmClient := llm.NewRouter(llm.NewClient(llmBaseURL, llmKey, llmModel, ollamaBaseURL))
 	opensearchClient := opensearch.NewClient(opensearchBaseURL)
 	languageDetector := language.NewDetector()
 
@@ -85,14 +229,44 @@ func NewServer(llmBaseURL, llmKey, llmModel, ollamaBaseURL, opensearchBaseURL, q
 
 	serverLogger.EndWithMsg("Server initialization complete")
 
-	return &Server{
+	server := &Server{
 		llmClient:        llmClient,
 		opensearchClient: opensearchClient,
 		qdrantClient:     qdrantClient,
 		mongoClient:      mongoClient,
 		jwtService:       jwtService,
 		languageDetector: languageDetector,
-	}, nil
+		mailSender:       mail.NewLogSender(""),
+		publicBaseURL:    "http://localhost:8080",
+		ingestConfig:     DefaultIngestConfig(),
+		uploadConfig:     DefaultUploadConfig(),
+		fetcher:          web.NewHTTPFetcher(web.DefaultHTTPFetcherConfig()),
+		rerankers:        make(map[string]Reranker),
+		queryExpansion:   DefaultQueryExpansionConfig(),
+		groundingMode:    GroundingLLM,
+		topicConfig:      DefaultTopicConfig(),
+		jobs:             newJobStore(),
+		fileAccess:       FileAccessUsers,
+	}
+
+	// The chat agent validates tool results with the LLM unless configured otherwise;
+	// the hybrid endpoint stays deterministic
+	server.rerankers[RerankPathAgent] = NewLLMJudgeReranker(server)
+
+	for _, opt := range opts {
+		opt(server)
+	}
+	server.archiveSlots = make(chan struct{}, max(server.ingestConfig.ArchiveConcurrency, 1))
+
+	// Attached after all options so the cache applies to whichever router was configured
+	if server.llmCache != nil {
+		if server.llmCachePersistent {
+			server.llmCache.SetPersistent(mongoClient)
+		}
+		server.llmClient.SetCache(server.llmCache)
+	}
+
+	return server, nil
 }
 
 // HealthCheck checks the health of all services
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/snowmerak/open-librarian/lib/client/llm"
+)
+
+// SummaryOutput is the structured result of a summarization call
+type SummaryOutput struct {
+	Summary string `json:"summary"`
+}
+
+// TagsOutput is the structured result of a tag extraction call
+type TagsOutput struct {
+	Tags []string `json:"tags"`
+}
+
+// RelevanceOutput is the structured result of a relevance scoring call
+type RelevanceOutput struct {
+	Scores []RelevanceScore `json:"scores"`
+}
+
+// RelevanceScore is the relevance of one numbered document to a query
+type RelevanceScore struct {
+	Document int     `json:"document"` // 1-based position in the prompt
+	Score    float64 `json:"score"`    // 0 (irrelevant) to 10 (answers the question)
+}
+
+// TranslationOutput is the structured result of a query translation call
+type TranslationOutput struct {
+	Translations []Translation `json:"translations"`
+}
+
+// Translation is a query translated into one language
+type Translation struct {
+	Lang string `json:"lang"`
+	Text string `json:"text"`
+}
+
+// GroundingOutput is the structured result of an answer grounding call
+type GroundingOutput struct {
+	Verdicts []GroundingVerdict `json:"verdicts"`
+}
+
+// GroundingVerdict says whether one numbered claim is supported by its passages
+type GroundingVerdict struct {
+	Claim     int    `json:"claim"` // 1-based position in the prompt
+	Supported bool   `json:"supported"`
+	Passage   int    `json:"passage"` // 1-based passage number, 0 if no passage supports the claim
+	Quote     string `json:"quote"`   // Supporting text copied from the passage
+}
+
+// TopicLabelOutput is the structured result of a topic labelling call
+type TopicLabelOutput struct {
+	Label       string `json:"label"`
+	Description string `json:"description"`
+}
+
+func intPtr(v int) *int           { return &v }
+func floatPtr(v float64) *float64 { return &v }
+func boolPtr(v bool) *bool        { return &v }
+
+var summarySchema = &llm.Schema{
+	Type: "object",
+	Properties: map[string]*llm.Schema{
+		"summary": {Type: "string", Description: "The summary text"},
+	},
+	Required:             []string{"summary"},
+	AdditionalProperties: boolPtr(false),
+}
+
+var tagsSchema = &llm.Schema{
+	Type: "object",
+	Properties: map[string]*llm.Schema{
+		"tags": {
+			Type:        "array",
+			Description: "Short keywords describing the text",
+			Items:       &llm.Schema{Type: "string"},
+			MinItems:    intPtr(1),
+			MaxItems:    intPtr(10),
+		},
+	},
+	Required:             []string{"tags"},
+	AdditionalProperties: boolPtr(false),
+}
+
+var topicLabelSchema = &llm.Schema{
+	Type: "object",
+	Properties: map[string]*llm.Schema{
+		"label":       {Type: "string", Description: "A short name for the topic, at most five words"},
+		"description": {Type: "string", Description: "One sentence describing what the articles cover"},
+	},
+	Required:             []string{"label", "description"},
+	AdditionalProperties: boolPtr(false),
+}
+
+// relevanceSchema requires exactly one score per document so a partial answer is repaired instead of ignored
+func relevanceSchema(documentCount int) *llm.Schema {
+	return &llm.Schema{
+		Type: "object",
+		Properties: map[string]*llm.Schema{
+			"scores": {
+				Type: "array",
+				Items: &llm.Schema{
+					Type: "object",
+					Properties: map[string]*llm.Schema{
+						"document": {Type: "integer", Minimum: floatPtr(1), Maximum: floatPtr(float64(documentCount))},
+						"score":    {Type: "number", Minimum: floatPtr(0), Maximum: floatPtr(10)},
+					},
+					Required:             []string{"document", "score"},
+					AdditionalProperties: boolPtr(false),
+				},
+				MinItems: intPtr(documentCount),
+				MaxItems: intPtr(documentCount),
+			},
+		},
+		Required:             []string{"scores"},
+		AdditionalProperties: boolPtr(false),
+	}
+}
+
+// translationSchema requires one translation per target language
+func translationSchema(targets []string) *llm.Schema {
+	return &llm.Schema{
+		Type: "object",
+		Properties: map[string]*llm.Schema{
+			"translations": {
+				Type: "array",
+				Items: &llm.Schema{
+					Type: "object",
+					Properties: map[string]*llm.Schema{
+						"lang": {Type: "string", Enum: targets},
+						"text": {Type: "string", Description: "The query in this language"},
+					},
+					Required:             []string{"lang", "text"},
+					AdditionalProperties: boolPtr(false),
+				},
+				MinItems: intPtr(len(targets)),
+				MaxItems: intPtr(len(targets)),
+			},
+		},
+		Required:             []string{"translations"},
+		AdditionalProperties: boolPtr(false),
+	}
+}
+
+// groundingSchema requires exactly one verdict per claim
+func groundingSchema(claimCount, passageCount int) *llm.Schema {
+	return &llm.Schema{
+		Type: "object",
+		Properties: map[string]*llm.Schema{
+			"verdicts": {
+				Type: "array",
+				Items: &llm.Schema{
+					Type: "object",
+					Properties: map[string]*llm.Schema{
+						"claim":     {Type: "integer", Minimum: floatPtr(1), Maximum: floatPtr(float64(claimCount))},
+						"supported": {Type: "boolean"},
+						"passage":   {Type: "integer", Minimum: floatPtr(0), Maximum: floatPtr(float64(passageCount))},
+						"quote":     {Type: "string", Description: "The shortest exact span of the passage that supports the claim"},
+					},
+					Required:             []string{"claim", "supported", "passage", "quote"},
+					AdditionalProperties: boolPtr(false),
+				},
+				MinItems: intPtr(claimCount),
+				MaxItems: intPtr(claimCount),
+			},
+		},
+		Required:             []string{"verdicts"},
+		AdditionalProperties: boolPtr(false),
+	}
+}
+
+// normalizeTags trims, de-duplicates (case-insensitively) and drops empty tags
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(strings.Trim(tag, "#\"'"))
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
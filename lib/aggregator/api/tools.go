@@ -9,6 +9,7 @@ import (
 const (
 	ToolVectorSearch  = "vector_search"
 	ToolKeywordSearch = "keyword_search"
+	ToolHybridSearch  = "hybrid_search"
 )
 
 func GetSearchTools() []llm.Tool {
@@ -47,5 +48,30 @@ func GetSearchTools() []llm.Tool {
 				}`),
 			},
 		},
+		{
+			Type: "function",
+			Function: llm.FunctionDent{
+				Name:        ToolHybridSearch,
+				Description: "Perform a combined keyword and semantic search whose rankings are fused. A good default when the query has both specific terms and a broader meaning. Returns relevant document summaries.",
+				Parameters: json.RawMessage(`{
+					"type": "object",
+					"properties": {
+						"query": {
+							"type": "string",
+							"description": "The search query. Used both as keywords and as a semantic query."
+						},
+						"vector_weight": {
+							"type": "number",
+							"description": "Optional weight of the semantic ranking (default 1). Raise it for conceptual queries."
+						},
+						"keyword_weight": {
+							"type": "number",
+							"description": "Optional weight of the keyword ranking (default 1). Raise it for exact terms and names."
+						}
+					},
+					"required": ["query"]
+				}`),
+			},
+		},
 	}
 }
@@ -0,0 +1,488 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/snowmerak/open-librarian/lib/client/llm"
+	"github.com/snowmerak/open-librarian/lib/client/mongo"
+	"github.com/snowmerak/open-librarian/lib/util/cluster"
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+)
+
+const (
+	// minTopicArticles is the smallest library that is clustered into topics
+	minTopicArticles = 4
+	// topicIterations bounds the k-means refinement steps of a clustering run
+	topicIterations = 50
+	// topicSeed makes repeated runs over the same library produce the same topics
+	topicSeed = 1
+	// topicRepresentatives is the number of representative articles stored per topic
+	topicRepresentatives = 5
+	// topicLabelTitles is the number of member titles shown to the model when labelling a topic
+	topicLabelTitles = 10
+	// topicLabelOutputTokens is reserved for the label and description
+	topicLabelOutputTokens = 256
+	// topicMatchSimilarity is how similar a topic's center must stay to the previous run's to keep its ID
+	topicMatchSimilarity = 0.9
+	// topicCentersTTL is how long the stored topic centers are reused to place new articles
+	topicCentersTTL = 10 * time.Minute
+)
+
+var (
+	// ErrTopicClusteringRunning is returned when a clustering run is requested while one is in progress
+	ErrTopicClusteringRunning = errors.New("topic clustering is already running")
+	// ErrTopicRefreshForbidden is returned when a user who is not a topic admin requests a clustering run
+	ErrTopicRefreshForbidden = errors.New("only topic admins can refresh topics")
+)
+
+// TopicConfig controls periodic topic clustering
+type TopicConfig struct {
+	Interval    time.Duration // Time between clustering runs; 0 disables periodic clustering
+	Clusters    int           // Fixed number of topics; 0 derives it from the library size
+	MaxClusters int           // Upper bound on the derived number of topics
+	Admins      []string      // IDs of the users who may start a run on demand; none disables on-demand runs
+}
+
+// DefaultTopicConfig returns default topic clustering configuration
+func DefaultTopicConfig() TopicConfig {
+	return TopicConfig{
+		Interval:    24 * time.Hour,
+		MaxClusters: 30,
+	}
+}
+
+// topicMember is a clustered article and its similarity to the topic center
+type topicMember struct {
+	id         string
+	similarity float64
+}
+
+// topicGroup is a cluster of a run with its members, closest to the center first
+type topicGroup struct {
+	centroid []float32
+	members  []topicMember
+}
+
+// topicCenters caches the stored topic centers used to place new articles between runs
+type topicCenters struct {
+	mu        sync.Mutex
+	ids       []string
+	centroids [][]float32
+	fetchedAt time.Time
+}
+
+// StartTopicClustering clusters the library on the configured interval until ctx is done.
+// The first run happens immediately when no topics have been stored yet.
+func (s *Server) StartTopicClustering(ctx context.Context) {
+	if s.topicConfig.Interval <= 0 {
+		return
+	}
+
+	go func() {
+		topicLogger := logger.NewLogger("topic_scheduler")
+
+		topics, err := s.mongoClient.GetTopics(ctx)
+		if err != nil {
+			topicLogger.Warn().Err(err).Msg("Failed to load stored topics")
+		}
+		if err != nil || len(topics) == 0 {
+			if _, err := s.ClusterTopics(ctx); err != nil {
+				topicLogger.Error().Err(err).Msg("Topic clustering failed")
+			}
+		}
+
+		ticker := time.NewTicker(s.topicConfig.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.ClusterTopics(ctx); err != nil {
+					topicLogger.Error().Err(err).Msg("Topic clustering failed")
+				}
+			}
+		}
+	}()
+}
+
+// RefreshTopics starts a clustering run in the background for a topic admin.
+// A run reclusters the whole library, so other users cannot start one.
+func (s *Server) RefreshTopics(ctx context.Context) error {
+	user, ok := ctx.Value(UserContextKey).(*mongo.User)
+	if !ok || !slices.Contains(s.topicConfig.Admins, user.ID.Hex()) {
+		return ErrTopicRefreshForbidden
+	}
+	if !s.topicClustering.TryLock() {
+		return ErrTopicClusteringRunning
+	}
+
+	go func() {
+		defer s.topicClustering.Unlock()
+		if _, err := s.clusterTopics(context.Background()); err != nil {
+			logger.NewLogger("topic_refresh").Error().Err(err).Msg("Topic clustering failed")
+		}
+	}()
+	return nil
+}
+
+// ClusterTopics groups articles by their summary vectors, labels each group and stores
+// the topics and each article's assignment. Only one run happens at a time.
+func (s *Server) ClusterTopics(ctx context.Context) ([]mongo.Topic, error) {
+	if !s.topicClustering.TryLock() {
+		return nil, ErrTopicClusteringRunning
+	}
+	defer s.topicClustering.Unlock()
+
+	return s.clusterTopics(ctx)
+}
+
+func (s *Server) clusterTopics(ctx context.Context) ([]mongo.Topic, error) {
+	topicLogger := logger.NewLogger("topic_clustering").StartWithMsg("Clustering articles into topics")
+
+	ids, vectors, err := s.articleVectors(ctx)
+	if err != nil {
+		topicLogger.EndWithError(err)
+		return nil, err
+	}
+	if len(ids) < minTopicArticles {
+		topicLogger.Info().Int("article_count", len(ids)).Msg("Too few articles to cluster")
+		topicLogger.EndWithMsg("Topic clustering skipped")
+		return nil, nil
+	}
+
+	k := s.topicConfig.Clusters
+	if k <= 0 {
+		k = cluster.SuggestK(len(ids), s.topicConfig.MaxClusters)
+	}
+	result, err := cluster.KMeans(vectors, k, topicIterations, topicSeed)
+	if err != nil {
+		topicLogger.EndWithError(err)
+		return nil, fmt.Errorf("failed to cluster articles: %w", err)
+	}
+	topicLogger.Info().Int("article_count", len(ids)).Int("clusters", k).Int("iterations", result.Iterations).Msg("Articles clustered")
+
+	groups := make([]topicGroup, len(result.Centroids))
+	for c, centroid := range result.Centroids {
+		groups[c].centroid = centroid
+	}
+	for i, id := range ids {
+		c := result.Assignments[i]
+		groups[c].members = append(groups[c].members, topicMember{id: id, similarity: result.Similarities[i]})
+	}
+	groups = slices.DeleteFunc(groups, func(group topicGroup) bool { return len(group.members) == 0 })
+	for _, group := range groups {
+		members := group.members
+		sort.Slice(members, func(i, j int) bool {
+			if members[i].similarity != members[j].similarity {
+				return members[i].similarity > members[j].similarity
+			}
+			return members[i].id < members[j].id
+		})
+	}
+	// Largest topics first
+	sort.SliceStable(groups, func(i, j int) bool { return len(groups[i].members) > len(groups[j].members) })
+
+	// Topics whose center barely moved keep the ID they had, so filters and links stay valid
+	previous, err := s.mongoClient.GetTopics(ctx)
+	if err != nil {
+		topicLogger.Warn().Err(err).Msg("Failed to load previous topics, all topics get new IDs")
+	}
+	topicIDs := s.topicIDs(previous, groups)
+
+	now := time.Now()
+	topics := make([]mongo.Topic, len(groups))
+	assignments := make(map[string]string, len(ids))
+	kept := 0
+	for i, group := range groups {
+		topic, err := s.describeTopic(ctx, topicIDs[i], i+1, group.members)
+		if err != nil {
+			topicLogger.EndWithError(err)
+			return nil, err
+		}
+		topic.Centroid = group.centroid
+		topic.UpdatedAt = now
+		topics[i] = *topic
+
+		for _, member := range group.members {
+			assignments[member.id] = topic.ID
+		}
+		if slices.ContainsFunc(previous, func(old mongo.Topic) bool { return old.ID == topic.ID }) {
+			kept++
+		}
+	}
+	topicLogger.Info().Int("kept_ids", kept).Int("new_ids", len(topics)-kept).Msg("Topics matched to the previous run")
+
+	if _, err := s.opensearchClient.SetArticleTopics(ctx, assignments); err != nil {
+		topicLogger.EndWithError(err)
+		return nil, fmt.Errorf("failed to store article topics: %w", err)
+	}
+	if err := s.mongoClient.ReplaceTopics(ctx, topics); err != nil {
+		topicLogger.EndWithError(err)
+		return nil, fmt.Errorf("failed to store topics: %w", err)
+	}
+
+	s.setTopicCenters(topics)
+
+	topicLogger.Info().Int("topic_count", len(topics)).Msg("Topics stored")
+	topicLogger.EndWithMsg("Topic clustering complete")
+	return topics, nil
+}
+
+// articleVectors returns the summary vector of every article, sorted by article ID.
+// Articles stored without a summary vector are represented by their title vector. Point IDs are
+// listed before any vector is read, so only one vector per article is loaded, and vectors are
+// kept as the float32 values Qdrant stores.
+func (s *Server) articleVectors(ctx context.Context) ([]string, [][]float32, error) {
+	summaries := make(map[string]bool)
+	titles := make(map[string]bool)
+	err := s.qdrantClient.ScrollPointIDs(ctx, func(pointID string) error {
+		switch articleID := s.extractArticleID(pointID); {
+		case strings.HasSuffix(pointID, "_summary"):
+			summaries[articleID] = true
+		case strings.HasSuffix(pointID, "_title"):
+			titles[articleID] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list article vectors: %w", err)
+	}
+
+	pointIDs := make([]string, 0, len(summaries)+len(titles))
+	for id := range summaries {
+		pointIDs = append(pointIDs, id+"_summary")
+	}
+	for id := range titles {
+		if !summaries[id] {
+			pointIDs = append(pointIDs, id+"_title")
+		}
+	}
+	sort.Strings(pointIDs)
+
+	byArticle := make(map[string][]float32, len(pointIDs))
+	err = s.qdrantClient.GetVectors(ctx, pointIDs, func(pointID string, vector []float32) error {
+		byArticle[s.extractArticleID(pointID)] = vector
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read article vectors: %w", err)
+	}
+
+	ids := make([]string, 0, len(byArticle))
+	for id := range byArticle {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	vectors := make([][]float32, len(ids))
+	for i, id := range ids {
+		vectors[i] = byArticle[id]
+	}
+	return ids, vectors, nil
+}
+
+// topicIDs names the groups of a run. A group whose center matches a previous topic's keeps its
+// ID; the others are named after their member closest to the center, which does not depend on
+// the group's position or on how many topics the run produced.
+func (s *Server) topicIDs(previous []mongo.Topic, groups []topicGroup) []string {
+	previousCentroids := make([][]float32, len(previous))
+	for i, topic := range previous {
+		previousCentroids[i] = topic.Centroid
+	}
+	centroids := make([][]float32, len(groups))
+	for i, group := range groups {
+		centroids[i] = group.centroid
+	}
+	matches := cluster.Match(previousCentroids, centroids, topicMatchSimilarity)
+
+	ids := make([]string, len(groups))
+	taken := make(map[string]bool, len(groups))
+	for i, match := range matches {
+		if match >= 0 {
+			ids[i] = previous[match].ID
+			taken[ids[i]] = true
+		}
+	}
+	for i, group := range groups {
+		if ids[i] != "" {
+			continue
+		}
+		for _, member := range group.members {
+			sum := sha256.Sum256([]byte(member.id))
+			if id := "topic-" + hex.EncodeToString(sum[:6]); !taken[id] {
+				ids[i] = id
+				break
+			}
+		}
+		taken[ids[i]] = true
+	}
+	return ids
+}
+
+// nearestTopic returns the ID of the stored topic whose center is closest to an article's
+// summary embedding, or "" before the first clustering run. Articles added between runs are
+// placed this way, and the next run may move them.
+func (s *Server) nearestTopic(ctx context.Context, embedding []float64) string {
+	s.topicCenters.mu.Lock()
+	defer s.topicCenters.mu.Unlock()
+
+	if s.topicCenters.fetchedAt.IsZero() || time.Since(s.topicCenters.fetchedAt) > topicCentersTTL {
+		topics, err := s.mongoClient.GetTopics(ctx)
+		if err != nil {
+			logger.NewLogger("topic_assignment").Warn().Err(err).Msg("Failed to load topic centers, article left without a topic")
+			return ""
+		}
+		s.topicCenters.ids, s.topicCenters.centroids = topicCentroids(topics)
+		s.topicCenters.fetchedAt = time.Now()
+	}
+	if len(s.topicCenters.centroids) == 0 {
+		return ""
+	}
+
+	vector := make([]float32, len(embedding))
+	for i, value := range embedding {
+		vector[i] = float32(value)
+	}
+	cluster.Normalize(vector)
+	best, _ := cluster.Nearest(vector, s.topicCenters.centroids)
+	return s.topicCenters.ids[best]
+}
+
+// setTopicCenters replaces the cached topic centers with those of a clustering run
+func (s *Server) setTopicCenters(topics []mongo.Topic) {
+	s.topicCenters.mu.Lock()
+	defer s.topicCenters.mu.Unlock()
+	s.topicCenters.ids, s.topicCenters.centroids = topicCentroids(topics)
+	s.topicCenters.fetchedAt = time.Now()
+}
+
+// topicCentroids returns the IDs and centers of the topics that have a stored center
+func topicCentroids(topics []mongo.Topic) ([]string, [][]float32) {
+	var ids []string
+	var centroids [][]float32
+	for _, topic := range topics {
+		if len(topic.Centroid) == 0 {
+			continue
+		}
+		ids = append(ids, topic.ID)
+		centroids = append(centroids, topic.Centroid)
+	}
+	return ids, centroids
+}
+
+// describeTopic builds a topic from its members, ordered closest to the center first,
+// and labels it with the model from the titles of its closest members
+func (s *Server) describeTopic(ctx context.Context, id string, number int, members []topicMember) (*mongo.Topic, error) {
+	topic := &mongo.Topic{
+		ID:              id,
+		Size:            len(members),
+		Representatives: []mongo.TopicArticle{},
+	}
+
+	total := 0.0
+	for _, member := range members {
+		total += member.similarity
+	}
+	topic.Cohesion = total / float64(len(members))
+
+	closest := make([]string, 0, topicLabelTitles)
+	for _, member := range members[:min(topicLabelTitles, len(members))] {
+		closest = append(closest, member.id)
+	}
+	articles, err := s.opensearchClient.GetArticlesByIDs(ctx, closest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch topic articles: %w", err)
+	}
+	byID := make(map[string]int, len(articles))
+	for i, article := range articles {
+		byID[article.ID] = i
+	}
+
+	var titles []string
+	tagCounts := make(map[string]int)
+	for _, id := range closest {
+		i, ok := byID[id]
+		if !ok {
+			continue
+		}
+		article := articles[i]
+		titles = append(titles, article.Title)
+		for _, tag := range article.Tags {
+			tagCounts[tag]++
+		}
+		if len(topic.Representatives) < topicRepresentatives {
+			topic.Representatives = append(topic.Representatives, mongo.TopicArticle{ID: article.ID, Title: article.Title})
+		}
+	}
+
+	label, err := s.labelTopic(ctx, titles)
+	if err != nil {
+		logger.NewLogger("topic_label").Warn().Err(err).Str("topic_id", topic.ID).Msg("Topic labelling failed, using tags")
+	}
+	if label != nil && strings.TrimSpace(label.Label) != "" {
+		topic.Label = strings.TrimSpace(label.Label)
+		topic.Description = strings.TrimSpace(label.Description)
+	} else {
+		topic.Label = fallbackTopicLabel(number, tagCounts)
+	}
+	return topic, nil
+}
+
+// labelTopic asks the model for a short name and description of a group of article titles
+func (s *Server) labelTopic(ctx context.Context, titles []string) (*TopicLabelOutput, error) {
+	if len(titles) == 0 {
+		return nil, nil
+	}
+
+	budget := s.newPromptBudget(llm.TaskTopics, topicLabelOutputTokens)
+	buildPrompt := func(list string) string {
+		return fmt.Sprintf(`The following article titles belong to one topic of a document library.
+Name the topic they share in at most five words and describe it in one sentence.
+Use the language most of the titles are written in.
+
+Titles:
+%s`, list)
+	}
+
+	var list strings.Builder
+	for _, title := range titles {
+		line := "- " + title + "\n"
+		if !budget.fits(buildPrompt(list.String() + line)) {
+			break
+		}
+		list.WriteString(line)
+	}
+	if list.Len() == 0 {
+		return nil, nil
+	}
+
+	var out TopicLabelOutput
+	if err := s.llmClient.GenerateJSON(ctx, llm.TaskTopics, buildPrompt(list.String()), "topic_label", topicLabelSchema, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// fallbackTopicLabel names a topic after its members' most common tag
+func fallbackTopicLabel(number int, tagCounts map[string]int) string {
+	best, bestCount := "", 0
+	for tag, count := range tagCounts {
+		if count > bestCount || (count == bestCount && tag < best) {
+			best, bestCount = tag, count
+		}
+	}
+	if best == "" {
+		return fmt.Sprintf("Topic %d", number)
+	}
+	return best
+}
@@ -1,17 +1,33 @@
 package api
 
 import (
+	"time"
+
 	"github.com/snowmerak/open-librarian/lib/client/llm"
+	"github.com/snowmerak/open-librarian/lib/client/mongo"
 	"github.com/snowmerak/open-librarian/lib/client/opensearch"
 )
 
 // ArticleRequest represents the request to add an article
 type ArticleRequest struct {
-	Title       string `json:"title" validate:"required"`
-	Content     string `json:"content" validate:"required"`
-	OriginalURL string `json:"original_url,omitempty"`
+	Title       string   `json:"title" validate:"required"`
+	Content     string   `json:"content" validate:"required"`
+	OriginalURL string   `json:"original_url,omitempty"`
+	Author      string   `json:"author,omitempty"`
+	CreatedDate string   `json:"created_date,omitempty"` // RFC3339 format (e.g., "2023-12-25T15:30:00Z")
+	Tags        []string `json:"tags,omitempty"`         // Kept ahead of the generated tags
+
+	// Internal field linking the article to its stored original file
+	File *opensearch.FileRef `json:"-"`
+}
+
+// ArticleFromURLRequest represents a request to add an article from a web page.
+// Title, author and created date override the values extracted from the page.
+type ArticleFromURLRequest struct {
+	URL         string `json:"url" validate:"required"`
+	Title       string `json:"title,omitempty"`
 	Author      string `json:"author,omitempty"`
-	CreatedDate string `json:"created_date,omitempty"` // RFC3339 format (e.g., "2023-12-25T15:30:00Z")
+	CreatedDate string `json:"created_date,omitempty"` // RFC3339 format
 }
 
 // SearchRequest represents the search request
@@ -22,6 +38,9 @@ type SearchRequest struct {
 	DateFrom  string `json:"date_from,omitempty"`  // RFC3339 format for filtering articles created after this date
 	DateTo    string `json:"date_to,omitempty"`    // RFC3339 format for filtering articles created before this date
 	SessionID string `json:"session_id,omitempty"` // For chat history
+	Expand    *bool  `json:"expand,omitempty"`     // Cross-lingual query expansion of the agent's searches, on by default when configured
+	// Topics restricts every search of the agent to articles in any of these topic IDs
+	Topics []string `json:"topics,omitempty"`
 
 	// Internal field for history
 	History []llm.ChatMessage `json:"-"`
@@ -29,22 +48,128 @@ type SearchRequest struct {
 
 // ArticleResponse represents the response after adding an article
 type ArticleResponse struct {
-	ID      string `json:"id"`
-	Message string `json:"message"`
+	ID        string `json:"id"`
+	Message   string `json:"message"`
+	Duplicate bool   `json:"duplicate,omitempty"` // True when ID is an existing article with the same content
+	// Warnings list the parts of an uploaded document that could not be read
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 // SearchResultWithScore represents a search result with score
 type SearchResultWithScore struct {
 	Article opensearch.Article `json:"article"`
 	Score   float64            `json:"score"`
-	Source  string             `json:"source"` // "keyword" or "vector"
+	Source  string             `json:"source"` // "keyword", "vector" or "hybrid"
+
+	// Passages that matched the query, with character offsets into the article fields.
+	// Only keyword retrieval produces highlights, so vector-only results have none.
+	Highlights []opensearch.Highlight `json:"highlights,omitempty"`
+
+	// Score provenance: Score is the final ranking score, derived from these
+	RetrievalScore float64  `json:"retrieval_score,omitempty"` // Score from keyword, vector or fused retrieval
+	RerankScore    *float64 `json:"rerank_score,omitempty"`    // Score assigned by the reranker, if one ran
+	Reranker       string   `json:"reranker,omitempty"`        // "llm" or "http" when a reranker scored the result
 }
 
 // SearchResponse represents the search response
 type SearchResponse struct {
-	Answer  string                  `json:"answer"`
-	Sources []SearchResultWithScore `json:"sources"`
-	Took    int                     `json:"took"`
+	Answer   string                  `json:"answer"`
+	Sources  []SearchResultWithScore `json:"sources"`
+	Took     int                     `json:"took"`
+	Degraded bool                    `json:"degraded,omitempty"` // True when the LLM was unavailable and no answer was generated
+	// Citations link the [n] markers in the answer to passages of Sources[n-1]
+	Citations []Citation `json:"citations,omitempty"`
+	// UnsupportedClaims are answer sentences that are uncited or not backed by the sources they cite
+	UnsupportedClaims []Claim `json:"unsupported_claims,omitempty"`
+}
+
+// Citation is the passage of a source that backs a cited claim in the answer
+type Citation struct {
+	Marker   int    `json:"marker"`    // Source number used in the answer, e.g. 2 for [2]
+	SourceID string `json:"source_id"` // Article ID
+	Title    string `json:"title"`
+	Claim    string `json:"claim"` // Answer sentence citing the source, without markers
+	Field    string `json:"field"` // "summary" or "content"
+	Quote    string `json:"quote"`
+	Start    int    `json:"start"`          // Offset of the quote in the field in code points, -1 if it could not be located
+	End      int    `json:"end"`            // Exclusive end of the quote in the field, -1 if it could not be located
+	Verified string `json:"verified"`       // How support was checked: "llm" or "lexical"
+	Page     int    `json:"page,omitempty"` // Page of the quote in paged documents such as PDFs
+}
+
+// Claim is a sentence of the answer that could not be grounded in the sources
+type Claim struct {
+	Text    string `json:"text"`
+	Markers []int  `json:"markers,omitempty"`
+	Reason  string `json:"reason"` // One of the Claim* reasons
+}
+
+// KeywordSearchRequest represents a keyword search with filters, sorting and facets
+type KeywordSearchRequest struct {
+	Query       string   `json:"query,omitempty"`        // Free text; empty lists every matching article
+	Lang        string   `json:"lang,omitempty"`         // Query language, boosts its analyzed fields
+	Authors     []string `json:"authors,omitempty"`      // Any of these authors
+	Registrars  []string `json:"registrars,omitempty"`   // Any of these registrars
+	Tags        []string `json:"tags,omitempty"`         // All of these tags
+	Langs       []string `json:"langs,omitempty"`        // Any of these article languages
+	Topics      []string `json:"topics,omitempty"`       // Any of these topic IDs
+	CreatedFrom string   `json:"created_from,omitempty"` // RFC3339 or YYYY-MM-DD, inclusive
+	CreatedTo   string   `json:"created_to,omitempty"`   // RFC3339 or YYYY-MM-DD, inclusive
+	Sort        string   `json:"sort,omitempty"`         // "relevance", "date" or "title"
+	Order       string   `json:"order,omitempty"`        // "asc" or "desc"
+	Facets      bool     `json:"facets,omitempty"`       // Include facet counts in the response
+	Size        int      `json:"size,omitempty"`
+	From        int      `json:"from,omitempty"`
+}
+
+// RelatedArticlesRequest holds the options of a related-articles lookup
+type RelatedArticlesRequest struct {
+	Size         int  // Number of results, 5 by default
+	MoreLikeThis bool // Blend in OpenSearch more_like_this term similarity
+	Tags         bool // Blend in tag overlap
+}
+
+// RelatedArticle is an article similar to another one, with the signals that ranked it
+type RelatedArticle struct {
+	Article           opensearch.Article `json:"article"`
+	Score             float64            `json:"score"`                          // Weighted blend of the signals, each scaled to 0-1 across the candidates
+	VectorScore       float64            `json:"vector_score,omitempty"`         // Cosine similarity to the article's summary vector
+	MoreLikeThisScore float64            `json:"more_like_this_score,omitempty"` // more_like_this score normalized to 0-1
+	SharedTags        []string           `json:"shared_tags,omitempty"`
+}
+
+// RelatedArticlesResponse represents the related articles of an article
+type RelatedArticlesResponse struct {
+	ArticleID string           `json:"article_id"`
+	Results   []RelatedArticle `json:"results"`
+	Degraded  bool             `json:"degraded,omitempty"` // True when one similarity source failed
+}
+
+// TopicsResponse represents the topic clusters of the library
+type TopicsResponse struct {
+	Topics []mongo.Topic `json:"topics"` // Largest first
+}
+
+// HybridSearchRequest represents a deterministic keyword and vector search request
+type HybridSearchRequest struct {
+	Query         string   `json:"query" validate:"required"`
+	Lang          string   `json:"lang,omitempty"`           // Defaults to the detected query language
+	Size          int      `json:"size,omitempty"`           // Number of results, 10 by default
+	Fusion        string   `json:"fusion,omitempty"`         // "rrf" (default) or "weighted"
+	VectorWeight  *float64 `json:"vector_weight,omitempty"`  // Defaults to 1 for rrf and 0.6 for weighted
+	KeywordWeight *float64 `json:"keyword_weight,omitempty"` // Defaults to 1 for rrf and 0.4 for weighted
+	RRFK          int      `json:"rrf_k,omitempty"`          // RRF rank constant, 60 by default
+	Expand        *bool    `json:"expand,omitempty"`         // Cross-lingual query expansion, on by default when configured
+	Topics        []string `json:"topics,omitempty"`         // Any of these topic IDs
+}
+
+// HybridSearchResponse represents the hybrid search response
+type HybridSearchResponse struct {
+	Results  []SearchResultWithScore `json:"results"`
+	Fusion   string                  `json:"fusion"`
+	Queries  []QueryVariant          `json:"queries"`            // The original query first, then its translations
+	Took     int64                   `json:"took"`               // Milliseconds
+	Degraded bool                    `json:"degraded,omitempty"` // True when one of the retrievers failed
 }
 
 // BulkArticleRequest represents a bulk upload request
@@ -66,6 +191,36 @@ type BulkArticleResult struct {
 	Success bool   `json:"success"`
 	ID      string `json:"id,omitempty"`
 	Error   string `json:"error,omitempty"`
+	Path    string `json:"path,omitempty"` // Archive file or uploaded document the article was read from
+
+	Duplicate bool `json:"duplicate,omitempty"` // True when ID is an existing article with the same content
+}
+
+// BulkJob is a bulk ingestion running in the background, such as an uploaded archive or the
+// sheets of an uploaded workbook
+type BulkJob struct {
+	ID           string              `json:"id"`
+	Status       string              `json:"status"` // queued, reading, ingesting, completed or failed
+	Filename     string              `json:"filename"`
+	FilesRead    int                 `json:"files_read"` // Archive files parsed so far, including skipped ones
+	Total        int                 `json:"total"`      // Articles being ingested
+	Processed    int                 `json:"processed"`  // Articles finished, successfully or not
+	SuccessCount int                 `json:"success_count"`
+	ErrorCount   int                 `json:"error_count"`
+	Results      []BulkArticleResult `json:"results,omitempty"`
+	Skipped      []ArchiveEntryIssue `json:"skipped,omitempty"`  // Files that were not ingested and why
+	Warnings     []ArchiveEntryIssue `json:"warnings,omitempty"` // Parts of ingested files that could not be read
+	Error        string              `json:"error,omitempty"`    // Why a failed job stopped
+	CreatedAt    time.Time           `json:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+
+	owner string // ID of the user who started the job; usernames can change
+}
+
+// ArchiveEntryIssue describes a problem with one file of an archive
+type ArchiveEntryIssue struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
 }
 
 // ErrorResponse represents an error response
@@ -0,0 +1,278 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/snowmerak/open-librarian/lib/util/parser"
+)
+
+const (
+	// maxUploadFieldSize bounds each non-file multipart field
+	maxUploadFieldSize = 64 << 10
+	// uploadOverhead allows for multipart boundaries and form fields on top of the file itself
+	uploadOverhead = 1 << 20
+)
+
+// ErrUploadTooLarge is returned when an uploaded file is larger than the limit for its type
+var ErrUploadTooLarge = errors.New("uploaded file is too large")
+
+// UploadConfig holds file upload size limits and parsing protections
+type UploadConfig struct {
+	MaxSizes map[string]int64 // Maximum file size in bytes per parser file type
+	TempDir  string           // Directory uploads are spooled to; empty uses the system default
+	Limits   parser.Limits
+	Sheets   parser.SheetOptions // How spreadsheets and CSV files are split when the upload does not say
+
+	MaxArchiveSize         int64 // Size of an uploaded archive
+	MaxArchiveFiles        int   // Files in an archive; 0 disables the limit
+	MaxArchiveExpandedSize int64 // Bytes all files of an archive expand to; 0 disables the limit
+}
+
+// DefaultUploadConfig returns default upload configuration
+func DefaultUploadConfig() UploadConfig {
+	return UploadConfig{
+		MaxSizes: map[string]int64{
+			parser.TypePDF:   200 << 20,
+			parser.TypeDocx:  50 << 20,
+			parser.TypeExcel: 50 << 20,
+			parser.TypeCSV:   50 << 20,
+			parser.TypeText:  20 << 20,
+			parser.TypeHTML:  20 << 20,
+			parser.TypePptx:  100 << 20,
+			parser.TypeOdt:   50 << 20,
+			parser.TypeOds:   50 << 20,
+			parser.TypeEPUB:  50 << 20,
+			parser.TypeRTF:   50 << 20,
+			parser.TypeImage: 20 << 20,
+		},
+		Limits:                 parser.DefaultLimits(),
+		Sheets:                 parser.DefaultSheetOptions(),
+		MaxArchiveSize:         500 << 20,
+		MaxArchiveFiles:        1000,
+		MaxArchiveExpandedSize: 2 << 30,
+	}
+}
+
+// maxUploadSize returns the largest file size accepted for any type
+func (c UploadConfig) maxUploadSize() int64 {
+	var largest int64
+	for _, size := range c.MaxSizes {
+		largest = max(largest, size)
+	}
+	return largest
+}
+
+// maxSize returns the size limit of a file type; types without their own limit get the largest one
+func (c UploadConfig) maxSize(fileType string) int64 {
+	if size, ok := c.MaxSizes[fileType]; ok {
+		return size
+	}
+	return c.maxUploadSize()
+}
+
+// spooledUpload is an uploaded file written to a temporary file, with the form fields sent alongside it
+type spooledUpload struct {
+	File     *os.File
+	Size     int64
+	Filename string
+	// MediaType is the Content-Type sent with the file part, if any
+	MediaType string
+	Fields    map[string]string
+}
+
+// Close removes the temporary file
+func (u *spooledUpload) Close() error {
+	if u.File == nil {
+		return nil
+	}
+	name := u.File.Name()
+	u.File.Close()
+	return os.Remove(name)
+}
+
+// spoolUpload streams a multipart upload to a temporary file without buffering it in memory.
+// The file part must be named "file" and may be at most limit bytes; for documents the limit is
+// the largest one, and the limit of the file's type is enforced once its content has been detected.
+func spoolUpload(w http.ResponseWriter, r *http.Request, config UploadConfig, limit int64) (*spooledUpload, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, limit+uploadOverhead)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read multipart form: %w", err)
+	}
+
+	upload := &spooledUpload{Fields: make(map[string]string)}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			upload.Close()
+			return nil, uploadReadError(err)
+		}
+
+		if part.FormName() == "file" && part.FileName() != "" {
+			if upload.File != nil {
+				part.Close()
+				upload.Close()
+				return nil, fmt.Errorf("only one file can be uploaded at a time")
+			}
+			if err := upload.spoolFile(part, config, limit); err != nil {
+				part.Close()
+				upload.Close()
+				return nil, err
+			}
+		} else if err := upload.readField(part); err != nil {
+			part.Close()
+			upload.Close()
+			return nil, err
+		}
+		part.Close()
+	}
+
+	if upload.File == nil {
+		return nil, fmt.Errorf("file is required")
+	}
+	return upload, nil
+}
+
+// spoolFile copies the file part to a temporary file
+func (u *spooledUpload) spoolFile(part *multipart.Part, config UploadConfig, limit int64) error {
+	u.Filename = part.FileName()
+	u.MediaType = part.Header.Get("Content-Type")
+	return u.spool(part, config, limit)
+}
+
+// spool copies r to a temporary file, failing once it exceeds limit bytes. The type of a
+// document is not known until its content is detected, so callers pass the largest limit.
+func (u *spooledUpload) spool(r io.Reader, config UploadConfig, limit int64) error {
+	file, err := os.CreateTemp(config.TempDir, "upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	u.File = file
+
+	u.Size, err = io.Copy(file, io.LimitReader(r, limit+1))
+	if err != nil {
+		return uploadReadError(err)
+	}
+	if u.Size > limit {
+		return fmt.Errorf("%w: files are limited to %d MB", ErrUploadTooLarge, limit>>20)
+	}
+	return nil
+}
+
+// detect finds the parser for the spooled file from its content and enforces the size limit
+// of its type. mediaType is the declared MIME type, if any.
+func (u *spooledUpload) detect(config UploadConfig, mediaType string) (parser.Parser, error) {
+	p, err := parser.Detect(u.File, u.Size, u.Filename, mediaType)
+	if err != nil {
+		return nil, err
+	}
+	if limit := config.maxSize(p.Type()); u.Size > limit {
+		return nil, fmt.Errorf("%w: %s files are limited to %d MB", ErrUploadTooLarge, p.Type(), limit>>20)
+	}
+	return p, nil
+}
+
+// articleFromDocument builds an article request from a parsed document, taking the author, date,
+// source URL and tags from the document's metadata. Aliases are kept as tags so searches for them
+// find the article. Dates in the future are dropped since they would fail created_date validation.
+func articleFromDocument(doc *parser.Document) *ArticleRequest {
+	req := &ArticleRequest{
+		Title:       doc.Title,
+		Content:     doc.Content,
+		Author:      doc.Metadata[parser.MetaAuthor],
+		OriginalURL: doc.Metadata[parser.MetaCanonicalURL],
+	}
+	if req.OriginalURL == "" {
+		req.OriginalURL = doc.Metadata[parser.MetaURL]
+	}
+	if published, err := time.Parse(time.RFC3339, doc.Metadata[parser.MetaPublishedDate]); err == nil && !published.After(time.Now()) {
+		req.CreatedDate = published.Format(time.RFC3339)
+	}
+	req.Tags = append(splitTags(doc.Metadata[parser.MetaTags]), splitTags(doc.Metadata[parser.MetaAliases])...)
+	return req
+}
+
+// sectionArticles splits an article into one per section of its document, titled after the
+// article and the section. Without sections, the article is returned as it is.
+func sectionArticles(req *ArticleRequest, sections []parser.Section) []ArticleRequest {
+	if len(sections) == 0 {
+		return []ArticleRequest{*req}
+	}
+	articles := make([]ArticleRequest, 0, len(sections))
+	for _, section := range sections {
+		article := *req
+		article.Content = section.Content
+		article.Tags = slices.Clone(req.Tags)
+		if section.Title != "" {
+			article.Title = req.Title + " - " + section.Title
+		}
+		articles = append(articles, article)
+	}
+	return articles
+}
+
+// sheetOptions returns how to split a spreadsheet: the "split" and "chunk_rows" form fields
+// sent with the upload override the configured defaults
+func (u *spooledUpload) sheetOptions(defaults parser.SheetOptions) (parser.SheetOptions, error) {
+	options := defaults
+	if split := strings.TrimSpace(u.Fields["split"]); split != "" {
+		options.Split = strings.ToLower(split)
+	}
+	if chunkRows := strings.TrimSpace(u.Fields["chunk_rows"]); chunkRows != "" {
+		n, err := strconv.Atoi(chunkRows)
+		if err != nil {
+			return options, fmt.Errorf("invalid chunk_rows %q", chunkRows)
+		}
+		options.ChunkRows = n
+	}
+	if err := options.Validate(); err != nil {
+		return options, err
+	}
+	return options, nil
+}
+
+// splitTags splits a comma-separated tag list
+func splitTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// readField stores a small form field sent with the upload
+func (u *spooledUpload) readField(part *multipart.Part) error {
+	value, err := io.ReadAll(io.LimitReader(part, maxUploadFieldSize+1))
+	if err != nil {
+		return uploadReadError(err)
+	}
+	if len(value) > maxUploadFieldSize {
+		return fmt.Errorf("%w: form field %q is too long", ErrUploadTooLarge, part.FormName())
+	}
+	u.Fields[part.FormName()] = string(value)
+	return nil
+}
+
+// uploadReadError reports a request body cut off by the overall size limit as ErrUploadTooLarge
+func uploadReadError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return fmt.Errorf("%w: request exceeds %d MB", ErrUploadTooLarge, maxBytesErr.Limit>>20)
+	}
+	return fmt.Errorf("failed to read upload: %w", err)
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -17,6 +18,11 @@ func (s *Server) RegisterUserRoutes(r chi.Router) {
 		r.Post("/", s.createUserHandler)
 		r.Post("/auth", s.authenticateUserHandler)
 		r.Post("/refresh", s.refreshTokenHandler)
+		r.Post("/verify-email/request", s.requestEmailVerificationHandler)
+		r.Get("/verify-email/confirm", s.confirmEmailVerificationHandler)
+		r.Post("/verify-email/confirm", s.confirmEmailVerificationHandler)
+		r.Post("/password-reset/request", s.requestPasswordResetHandler)
+		r.Post("/password-reset/confirm", s.confirmPasswordResetHandler)
 
 		// Protected routes (authentication required)
 		r.Group(func(r chi.Router) {
@@ -88,6 +94,12 @@ func (s *Server) createUserHandler(w http.ResponseWriter, r *http.Request) {
 		"email":    user.Email,
 		"username": user.Username,
 	})
+
+	// Account creation succeeds even if the email cannot be sent; the user can request it again
+	if err := s.SendEmailVerification(r.Context(), user); err != nil {
+		userLogger.Warn().Err(err).Str("user_id", user.ID.Hex()).Msg("Failed to send verification email")
+	}
+
 	userLogger.EndWithMsg("User created successfully")
 
 	w.Header().Set("Content-Type", "application/json")
@@ -192,7 +204,7 @@ func (s *Server) getUserByIDHandler(w http.ResponseWriter, r *http.Request) {
 
 	user, err := s.mongoClient.GetUserByID(r.Context(), id)
 	if err != nil {
-		if err.Error() == "user not found" {
+		if errors.Is(err, mongo.ErrUserNotFound) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
@@ -214,7 +226,7 @@ func (s *Server) getUserByUsernameHandler(w http.ResponseWriter, r *http.Request
 
 	user, err := s.mongoClient.GetUserByUsername(r.Context(), username)
 	if err != nil {
-		if err.Error() == "user not found" {
+		if errors.Is(err, mongo.ErrUserNotFound) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
@@ -247,7 +259,7 @@ func (s *Server) updateUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.mongoClient.UpdateUser(r.Context(), id, updates); err != nil {
-		if err.Error() == "user not found" {
+		if errors.Is(err, mongo.ErrUserNotFound) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
@@ -291,7 +303,7 @@ func (s *Server) changePasswordHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.mongoClient.ChangePassword(r.Context(), id, req.OldPassword, req.NewPassword); err != nil {
-		if err.Error() == "user not found" {
+		if errors.Is(err, mongo.ErrUserNotFound) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
@@ -321,7 +333,7 @@ func (s *Server) deleteUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.mongoClient.DeleteUser(r.Context(), id); err != nil {
-		if err.Error() == "user not found" {
+		if errors.Is(err, mongo.ErrUserNotFound) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
@@ -0,0 +1,39 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+var (
+	// ErrNotFound is returned when no object is stored under a key
+	ErrNotFound = errors.New("blob not found")
+	// ErrChecksumMismatch is returned when the bytes written do not match the declared checksum
+	ErrChecksumMismatch = errors.New("blob checksum mismatch")
+)
+
+// Store keeps binary objects, such as the original files of uploaded articles, by key.
+// Keys are slash-separated paths like "originals/3f/3fa9...".
+type Store interface {
+	// Put stores size bytes read from r under key. checksum is the hex SHA-256 of the bytes;
+	// the object is not stored when the bytes read do not match it.
+	Put(ctx context.Context, key string, r io.Reader, size int64, checksum string, contentType string) error
+	// Open returns the content of an object. The reader also implements io.Seeker when the
+	// store can serve ranges of it.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes an object; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// validKey rejects keys that are empty, absolute or not clean, so a key cannot leave the
+// directory or bucket prefix it is stored under
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || key == ".." || strings.HasPrefix(key, "../") || strings.ContainsAny(key, "\\\x00") {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package blob
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func checksumOf(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestValidKey(t *testing.T) {
+	tests := []struct {
+		key   string
+		valid bool
+	}{
+		{"originals/3f/3fa9", true},
+		{"file.pdf", true},
+		{"a/..b/c", true},
+		{"", false},
+		{"..", false},
+		{"../x", false},
+		{"a/../../x", false},
+		{"a/../x", false},
+		{"/abs", false},
+		{"/etc/passwd", false},
+		{"a\\b", false},
+		{"..\\x", false},
+		{"a/./b", false},
+		{"a//b", false},
+		{"a/", false},
+		{"a\x00b", false},
+	}
+	for _, tt := range tests {
+		err := validKey(tt.key)
+		if tt.valid && err != nil {
+			t.Errorf("validKey(%q) = %v, want valid", tt.key, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("validKey(%q) accepted an invalid key", tt.key)
+		}
+	}
+}
+
+func TestLocalRejectsTraversalKeys(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewLocal(filepath.Join(root, "blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for _, key := range []string{"../x", "/abs", "a\\b"} {
+		if err := store.Put(ctx, key, strings.NewReader("data"), 4, checksumOf("data"), ""); err == nil {
+			t.Errorf("Put(%q) accepted an invalid key", key)
+		}
+		if _, err := store.Open(ctx, key); err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("Open(%q) = %v, want an invalid key error", key, err)
+		}
+		if err := store.Delete(ctx, key); err == nil {
+			t.Errorf("Delete(%q) accepted an invalid key", key)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "x")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("a file was written outside the blob directory: %v", err)
+	}
+}
+
+func TestLocalPutOpenDelete(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	const key, data = "originals/ab/abc", "original file"
+	if err := store.Put(ctx, key, strings.NewReader(data), int64(len(data)), checksumOf(data), "text/plain"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	rc, err := store.Open(ctx, key)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	got, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil || string(got) != data {
+		t.Fatalf("Open returned %q, %v; want %q", got, err, data)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Open(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Open after Delete = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete of a missing object = %v, want nil", err)
+	}
+}
+
+func TestLocalPutChecksumMismatch(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	const key = "originals/cd/cde"
+	tests := []struct {
+		name     string
+		data     string
+		size     int64
+		checksum string
+	}{
+		{"wrong checksum", "tampered", 8, checksumOf("original")},
+		{"short body", "short", 10, checksumOf("short")},
+		{"long body", "longer than declared", 4, checksumOf("long")},
+	}
+	for _, tt := range tests {
+		err := store.Put(ctx, key, strings.NewReader(tt.data), tt.size, tt.checksum, "")
+		if !errors.Is(err, ErrChecksumMismatch) {
+			t.Errorf("%s: Put = %v, want ErrChecksumMismatch", tt.name, err)
+		}
+		if _, err := store.Open(ctx, key); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: object was stored despite the mismatch: %v", tt.name, err)
+		}
+	}
+}
+
+func TestLocalPutReplacesAtomically(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewLocal(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	const key, first = "originals/ef/efg", "first version"
+	if err := store.Put(ctx, key, strings.NewReader(first), int64(len(first)), checksumOf(first), ""); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	// A failed replacement leaves the stored object untouched
+	const second = "second version"
+	err = store.Put(ctx, key, strings.NewReader(second), int64(len(second)), checksumOf("something else"), "")
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Put = %v, want ErrChecksumMismatch", err)
+	}
+	got, err := os.ReadFile(filepath.Join(root, "originals", "ef", "efg"))
+	if err != nil || string(got) != first {
+		t.Fatalf("stored object is %q, %v; want %q", got, err, first)
+	}
+
+	if err := store.Put(ctx, key, strings.NewReader(second), int64(len(second)), checksumOf(second), ""); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	got, err = os.ReadFile(filepath.Join(root, "originals", "ef", "efg"))
+	if err != nil || string(got) != second {
+		t.Fatalf("stored object is %q, %v; want %q", got, err, second)
+	}
+
+	// No temporary files are left next to the object
+	entries, err := os.ReadDir(filepath.Join(root, "originals", "ef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "efg" {
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		t.Fatalf("expected only the object in its directory, found %v", names)
+	}
+}
@@ -0,0 +1,143 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+)
+
+const (
+	breakerClosed = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// BreakerConfig holds configuration for per-provider circuit breakers
+type BreakerConfig struct {
+	FailureThreshold int           // Consecutive failures that open the circuit
+	Cooldown         time.Duration // Time the circuit stays open before a probe request is allowed
+}
+
+// DefaultBreakerConfig returns default circuit breaker configuration
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{
+		FailureThreshold: 5,
+		Cooldown:         30 * time.Second,
+	}
+}
+
+// circuitBreaker stops sending requests to a provider that keeps failing
+type circuitBreaker struct {
+	mu       sync.Mutex
+	name     string
+	config   BreakerConfig
+	state    int
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = make(map[string]*circuitBreaker)
+)
+
+// embeddingTask keys the breakers of embedding requests, which are not routed by task
+const embeddingTask Task = "embedding"
+
+// breakerFor returns the shared breaker for an endpoint and task. All clients of the same
+// provider trip together regardless of the model they use, but a model that keeps failing
+// for one task (a vision model for OCR, say) does not cut off the others.
+func breakerFor(endpoint string, task Task, config BreakerConfig) *circuitBreaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	key := endpoint + " " + string(task)
+	if b, ok := breakers[key]; ok {
+		return b
+	}
+	b := &circuitBreaker{name: key, config: config}
+	breakers[key] = b
+	return b
+}
+
+type taskKey struct{}
+
+// withTask records the routed task in the context so requests pick the task's breaker
+func withTask(ctx context.Context, task Task) context.Context {
+	return context.WithValue(ctx, taskKey{}, task)
+}
+
+// taskFrom returns the task recorded in the context, TaskDefault when there is none
+func taskFrom(ctx context.Context) Task {
+	if task, ok := ctx.Value(taskKey{}).(Task); ok {
+		return task
+	}
+	return TaskDefault
+}
+
+// allow reports whether a request may be sent. In the half-open state only one probe is let through.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.config.Cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+		return nil
+	case breakerHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	}
+	return nil
+}
+
+// record updates the breaker with the outcome of a request
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// A cancelled probe says nothing about the provider; let the next request probe instead
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		b.probing = false
+		return
+	}
+
+	// Only provider-side failures count; bad prompts and bad keys do not
+	countsAsFailure := err != nil && (errors.Is(err, ErrUnavailable) || errors.Is(err, ErrRateLimited) || errors.Is(err, ErrInvalidResponse))
+
+	if !countsAsFailure {
+		if b.state != breakerClosed {
+			logger.NewLogger("llm-breaker").Info().Str("endpoint", b.name).Msg("Circuit closed")
+		}
+		b.state = breakerClosed
+		b.failures = 0
+		b.probing = false
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.config.FailureThreshold {
+		if b.state != breakerOpen {
+			logger.NewLogger("llm-breaker").Warn().
+				Err(err).
+				Str("endpoint", b.name).
+				Int("failures", b.failures).
+				Dur("cooldown", b.config.Cooldown).
+				Msg("Circuit opened")
+		}
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+		b.probing = false
+	}
+}
@@ -0,0 +1,219 @@
+package llm
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+)
+
+// Cache kinds group entries so each can have its own TTL
+const (
+	CacheKindEmbedding = "embedding"
+	CacheKindText      = "text"
+	CacheKindJSON      = "json"
+	CacheKindRelevance = "relevance"
+)
+
+// CacheStore is a persistent cache tier shared between server instances
+type CacheStore interface {
+	GetCacheEntry(ctx context.Context, key string) ([]byte, bool, error)
+	SetCacheEntry(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// CacheConfig holds configuration for the LLM response cache
+type CacheConfig struct {
+	MaxBytes   int64                    // Memory budget of the in-memory LRU tier
+	TTLs       map[string]time.Duration // TTL per cache kind; kinds without an entry are not cached
+	Persistent CacheStore               // Optional second tier, consulted on in-memory misses
+}
+
+// DefaultCacheConfig returns default cache configuration
+func DefaultCacheConfig() CacheConfig {
+	return CacheConfig{
+		MaxBytes: 64 << 20,
+		TTLs: map[string]time.Duration{
+			CacheKindEmbedding: 7 * 24 * time.Hour,
+			CacheKindText:      24 * time.Hour,
+			CacheKindJSON:      24 * time.Hour,
+			CacheKindRelevance: 24 * time.Hour,
+		},
+	}
+}
+
+// Cache is a two-tier cache for embeddings and deterministic LLM outputs.
+// Entries are keyed by kind, model and a hash of the whitespace-normalized input.
+type Cache struct {
+	config CacheConfig
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // Front is most recently used
+	size    int64
+}
+
+type cacheEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewCache creates a new cache
+func NewCache(config CacheConfig) *Cache {
+	return &Cache{
+		config:  config,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// SetPersistent sets the persistent tier of the cache
+func (c *Cache) SetPersistent(store CacheStore) {
+	c.config.Persistent = store
+}
+
+// CacheKey returns the cache key for an input, ignoring differences in whitespace
+func CacheKey(kind, model, input string) string {
+	normalized := strings.Join(strings.Fields(input), " ")
+	sum := sha256.Sum256([]byte(model + "\x00" + normalized))
+	return kind + ":" + hex.EncodeToString(sum[:])
+}
+
+type cacheBypassKey struct{}
+
+// WithCacheBypass returns a context whose LLM calls skip cache lookups.
+// Fresh results are still written so later requests benefit from them.
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+// cacheBypassed reports whether cache lookups are disabled for the context
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+type deterministicKey struct{}
+
+// withDeterministic marks generation calls in the context as deterministic, so they run at
+// temperature zero and their output can be reused for the same input
+func withDeterministic(ctx context.Context) context.Context {
+	return context.WithValue(ctx, deterministicKey{}, true)
+}
+
+// temperatureFor returns the sampling temperature to request, nil for the provider default
+func temperatureFor(ctx context.Context) *float64 {
+	if deterministic, _ := ctx.Value(deterministicKey{}).(bool); deterministic {
+		zero := 0.0
+		return &zero
+	}
+	return nil
+}
+
+// Get looks up a cached value and decodes it into out
+func (c *Cache) Get(ctx context.Context, kind, model, input string, out any) bool {
+	if c == nil || cacheBypassed(ctx) {
+		return false
+	}
+	if _, ok := c.config.TTLs[kind]; !ok {
+		return false
+	}
+
+	key := CacheKey(kind, model, input)
+	value, ok := c.getMemory(key)
+	if !ok && c.config.Persistent != nil {
+		stored, found, err := c.config.Persistent.GetCacheEntry(ctx, key)
+		if err != nil {
+			logger.NewLogger("llm-cache").Warn().Err(err).Str("kind", kind).Msg("Persistent cache lookup failed")
+		} else if found {
+			value, ok = stored, true
+			c.setMemory(key, stored, c.config.TTLs[kind])
+		}
+	}
+	if !ok {
+		return false
+	}
+
+	if err := json.Unmarshal(value, out); err != nil {
+		return false
+	}
+	return true
+}
+
+// Set stores a value under the kind's TTL
+func (c *Cache) Set(ctx context.Context, kind, model, input string, value any) {
+	if c == nil {
+		return
+	}
+	ttl, ok := c.config.TTLs[kind]
+	if !ok || ttl <= 0 {
+		return
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+
+	key := CacheKey(kind, model, input)
+	c.setMemory(key, encoded, ttl)
+
+	if c.config.Persistent != nil {
+		if err := c.config.Persistent.SetCacheEntry(ctx, key, encoded, ttl); err != nil {
+			logger.NewLogger("llm-cache").Warn().Err(err).Str("kind", kind).Msg("Persistent cache write failed")
+		}
+	}
+}
+
+func (c *Cache) getMemory(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.removeElement(elem)
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+func (c *Cache) setMemory(key string, value []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if int64(len(value)) > c.config.MaxBytes {
+		return
+	}
+
+	if elem, ok := c.entries[key]; ok {
+		c.removeElement(elem)
+	}
+
+	elem := c.order.PushFront(&cacheEntry{key: key, value: value, expiresAt: time.Now().Add(ttl)})
+	c.entries[key] = elem
+	c.size += int64(len(value))
+
+	for c.size > c.config.MaxBytes {
+		c.removeElement(c.order.Back())
+	}
+}
+
+func (c *Cache) removeElement(elem *list.Element) {
+	entry := elem.Value.(*cacheEntry)
+	c.order.Remove(elem)
+	delete(c.entries, entry.key)
+	c.size -= int64(len(entry.value))
+}
@@ -4,11 +4,13 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/snowmerak/open-librarian/lib/util/logger"
@@ -21,6 +23,10 @@ type Client struct {
 	genModel      string
 	ollamaBaseURL string
 	httpClient    *http.Client
+	retryConfig   RetryConfig
+	contextTokens int // Context window override, zero to use the model default
+
+	noStructuredOutputs atomic.Bool // Set once the provider rejects response_format
 }
 
 type ChatMessage struct {
@@ -28,6 +34,47 @@ type ChatMessage struct {
 	Content    string     `json:"content"`
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
 	ToolCallID string     `json:"tool_call_id,omitempty"` // For tool response messages
+	Images     []Image    `json:"-"`                      // Sent as image parts after the text; needs a vision model
+}
+
+// Image is an image attached to a chat message
+type Image struct {
+	MediaType string // image/png, image/jpeg, image/gif or image/webp
+	Data      []byte
+}
+
+// contentPart is an element of the array form of a message's content
+type contentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *imageURL `json:"image_url,omitempty"`
+}
+
+type imageURL struct {
+	URL string `json:"url"`
+}
+
+// MarshalJSON writes messages with images in the content-parts form of the chat completions API,
+// with each image inlined as a data URL
+func (m ChatMessage) MarshalJSON() ([]byte, error) {
+	type plain ChatMessage
+	if len(m.Images) == 0 {
+		return json.Marshal(plain(m))
+	}
+
+	parts := make([]contentPart, 0, len(m.Images)+1)
+	if m.Content != "" {
+		parts = append(parts, contentPart{Type: "text", Text: m.Content})
+	}
+	for _, image := range m.Images {
+		url := "data:" + image.MediaType + ";base64," + base64.StdEncoding.EncodeToString(image.Data)
+		parts = append(parts, contentPart{Type: "image_url", ImageURL: &imageURL{URL: url}})
+	}
+
+	return json.Marshal(struct {
+		plain
+		Content []contentPart `json:"content"`
+	}{plain(m), parts})
 }
 
 type ToolCall struct {
@@ -53,11 +100,13 @@ type FunctionDent struct {
 }
 
 type ChatRequest struct {
-	Model      string        `json:"model"`
-	Messages   []ChatMessage `json:"messages"`
-	Stream     bool          `json:"stream"`
-	Tools      []Tool        `json:"tools,omitempty"`
-	ToolChoice interface{}   `json:"tool_choice,omitempty"` // "auto", "none" or specific tool
+	Model          string          `json:"model"`
+	Messages       []ChatMessage   `json:"messages"`
+	Stream         bool            `json:"stream"`
+	Tools          []Tool          `json:"tools,omitempty"`
+	ToolChoice     interface{}     `json:"tool_choice,omitempty"` // "auto", "none" or specific tool
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	Temperature    *float64        `json:"temperature,omitempty"`
 }
 
 type ChatResponse struct {
@@ -77,8 +126,8 @@ type ChatStreamResponse struct {
 }
 
 type EmbedRequest struct {
-	Model string `json:"model"`
-	Input string `json:"input"`
+	Model string   `json:"model"`
+	Input []string `json:"input"`
 }
 
 type EmbedResponse struct {
@@ -89,13 +138,22 @@ type EmbedResponse struct {
 const (
 	DefaultEmbeddingModel = "embeddinggemma:300m"
 
+	// MaxEmbeddingBatch is the largest number of texts sent in one embedding request
+	MaxEmbeddingBatch = 32
+
 	ProviderOllama     = "ollama"
 	ProviderOpenAPI    = "openapi"
 	ProviderOpenRouter = "openrouter"
 )
 
-// NewClient creates a new LLM client
+// NewClient creates a new LLM client, detecting the provider from the URL
 func NewClient(genBaseURL, genKey, genModel, ollamaBaseURL string) *Client {
+	return NewClientWithProvider("", genBaseURL, genKey, genModel, ollamaBaseURL)
+}
+
+// NewClientWithProvider creates a new LLM client for an explicit provider.
+// An empty provider falls back to detection from the URL.
+func NewClientWithProvider(provider, genBaseURL, genKey, genModel, ollamaBaseURL string) *Client {
 	log := logger.NewLogger("llm-client")
 	log.StartWithMsg("Creating new LLM client")
 
@@ -103,13 +161,8 @@ func NewClient(genBaseURL, genKey, genModel, ollamaBaseURL string) *Client {
 	genBaseURL = strings.TrimRight(genBaseURL, "/")
 	ollamaBaseURL = strings.TrimRight(ollamaBaseURL, "/")
 
-	var provider string
-	if strings.Contains(genBaseURL, "openrouter.ai") {
-		provider = ProviderOpenRouter
-	} else if genBaseURL == ollamaBaseURL {
-		provider = ProviderOllama
-	} else {
-		provider = ProviderOpenAPI
+	if provider == "" {
+		provider = detectProvider(genBaseURL, ollamaBaseURL)
 	}
 
 	log.Info().
@@ -128,12 +181,33 @@ func NewClient(genBaseURL, genKey, genModel, ollamaBaseURL string) *Client {
 		httpClient: &http.Client{
 			Timeout: 15 * time.Minute,
 		},
+		retryConfig: DefaultRetryConfig(),
 	}
 
 	log.EndWithMsg("LLM client created successfully")
 	return client
 }
 
+// detectProvider guesses the provider type from the generation URL
+func detectProvider(genBaseURL, ollamaBaseURL string) string {
+	if strings.Contains(genBaseURL, "openrouter.ai") {
+		return ProviderOpenRouter
+	} else if genBaseURL == ollamaBaseURL {
+		return ProviderOllama
+	}
+	return ProviderOpenAPI
+}
+
+// Provider returns the provider type of the client
+func (c *Client) Provider() string {
+	return c.provider
+}
+
+// Model returns the generation model used by the client
+func (c *Client) Model() string {
+	return c.genModel
+}
+
 // Chat generates a response for a chat conversation, potentially calling tools
 func (c *Client) Chat(ctx context.Context, messages []ChatMessage, tools []Tool) (*ChatMessage, error) {
 	log := logger.NewLogger("llm-chat")
@@ -148,49 +222,8 @@ func (c *Client) Chat(ctx context.Context, messages []ChatMessage, tools []Tool)
 		reqPayload.ToolChoice = "auto"
 	}
 
-	reqBody, err := json.Marshal(reqPayload)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	url := fmt.Sprintf("%s/v1/chat/completions", c.genBaseURL)
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-	if c.genKey != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+c.genKey)
-	}
-	if c.provider == ProviderOpenRouter {
-		httpReq.Header.Set("HTTP-Referer", "https://github.com/snowmerak/open-librarian")
-		httpReq.Header.Set("X-Title", "Open Librarian")
-	}
-
-	log.Info().Str("url", url).Str("model", c.genModel).Int("msg_count", len(messages)).Msg("Sending chat request")
-
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
-	}
-
-	var chatResp ChatResponse
-	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	if len(chatResp.Choices) == 0 {
-		return nil, fmt.Errorf("no choices in response")
-	}
-
-	return &chatResp.Choices[0].Message, nil
+	log.Info().Str("model", c.genModel).Int("msg_count", len(messages)).Msg("Sending chat request")
+	return c.complete(ctx, reqPayload)
 }
 
 // GenerateText generates text using the configured provider via OpenAI-compatible API
@@ -210,58 +243,51 @@ Remember: Output ONLY the requested content, nothing else.`, prompt)
 		Messages: []ChatMessage{
 			{Role: "user", Content: strictPrompt},
 		},
-		Stream: false,
+		Stream:      false,
+		Temperature: temperatureFor(ctx),
 	}
 
-	reqBody, err := json.Marshal(reqPayload)
+	log.Info().Str("model", c.genModel).Msg("Sending request")
+	message, err := c.complete(ctx, reqPayload)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		log.EndWithError(err)
+		return "", err
 	}
 
-	url := fmt.Sprintf("%s/v1/chat/completions", c.genBaseURL)
-	// OpenRouter specific path adjustment if needed, but usually v1/chat/completions works.
-	// However, user said "api/v1/chat/completions" for OpenRouter sometimes?
-	// Standard OpenRouter: https://openrouter.ai/api/v1/chat/completions
-	// If genBaseURL is https://openrouter.ai/api, then + /v1/chat/completions is correct.
+	log.EndWithMsg("Text generated")
+	return message.Content, nil
+}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
+// complete sends a non-streaming chat completion request and returns the first choice
+func (c *Client) complete(ctx context.Context, reqPayload ChatRequest) (*ChatMessage, error) {
+	reqBody, err := json.Marshal(reqPayload)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-	if c.genKey != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+c.genKey)
-	}
-	// OpenRouter specific headers
-	if c.provider == ProviderOpenRouter {
-		httpReq.Header.Set("HTTP-Referer", "https://github.com/snowmerak/open-librarian")
-		httpReq.Header.Set("X-Title", "Open Librarian")
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	log.Info().Str("url", url).Str("model", c.genModel).Msg("Sending request")
-
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
+	// Standard OpenRouter: https://openrouter.ai/api/v1/chat/completions
+	// If genBaseURL is https://openrouter.ai/api, then + /v1/chat/completions is correct.
+	url := fmt.Sprintf("%s/v1/chat/completions", c.genBaseURL)
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
-	}
+	var message *ChatMessage
+	err = c.post(ctx, breakerFor(c.genBaseURL, taskFrom(ctx), DefaultBreakerConfig()), url, reqBody, true, func(resp *http.Response) error {
+		var chatResp ChatResponse
+		if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+			return fmt.Errorf("failed to decode response: %w: %w", ErrInvalidResponse, err)
+		}
 
-	var chatResp ChatResponse
-	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
-	}
+		if len(chatResp.Choices) == 0 {
+			return fmt.Errorf("no choices in response: %w", ErrInvalidResponse)
+		}
 
-	if len(chatResp.Choices) == 0 {
-		return "", fmt.Errorf("no choices in response")
+		message = &chatResp.Choices[0].Message
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return chatResp.Choices[0].Message.Content, nil
+	return message, nil
 }
 
 // GenerateTextStream generates text using streaming mode via OpenAI-compatible API
@@ -286,39 +312,30 @@ Remember: Output ONLY the requested content, nothing else.`, prompt)
 	}
 
 	url := fmt.Sprintf("%s/v1/chat/completions", c.genBaseURL)
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-	if c.genKey != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+c.genKey)
-	}
-	if c.provider == ProviderOpenRouter {
-		httpReq.Header.Set("HTTP-Referer", "https://github.com/snowmerak/open-librarian")
-		httpReq.Header.Set("X-Title", "Open Librarian")
-	}
-
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
-	}
+	return c.post(ctx, breakerFor(c.genBaseURL, taskFrom(ctx), DefaultBreakerConfig()), url, reqBody, true, func(resp *http.Response) error {
+		started := false
+		err := readStream(resp.Body, func(content string) error {
+			started = true
+			return callback(content)
+		})
+		// Chunks already delivered cannot be taken back, so a broken stream is not retried
+		if err != nil && started {
+			return &nonRetryableError{err: err}
+		}
+		return err
+	})
+}
 
-	reader := bufio.NewReader(resp.Body)
+// readStream reads an SSE chat completion stream and passes content deltas to the callback
+func readStream(body io.Reader, callback func(string) error) error {
+	reader := bufio.NewReader(body)
 	for {
 		line, err := reader.ReadString('\n')
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
-			return fmt.Errorf("error reading stream: %w", err)
+			return fmt.Errorf("error reading stream: %w: %w", ErrUnavailable, err)
 		}
 
 		line = strings.TrimSpace(line)
@@ -345,7 +362,7 @@ Remember: Output ONLY the requested content, nothing else.`, prompt)
 			content := streamResp.Choices[0].Delta.Content
 			if content != "" {
 				if err := callback(content); err != nil {
-					return fmt.Errorf("callback error: %w", err)
+					return &nonRetryableError{err: fmt.Errorf("callback error: %w", err)}
 				}
 			}
 		}
@@ -356,13 +373,35 @@ Remember: Output ONLY the requested content, nothing else.`, prompt)
 
 // GenerateEmbedding generates embeddings using Ollama (Native API) with the specified embeddinggemma model
 func (c *Client) GenerateEmbedding(ctx context.Context, text string) ([]float64, error) {
-	model := DefaultEmbeddingModel
+	embeddings, err := c.GenerateEmbeddings(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// GenerateEmbeddings generates embeddings for several texts, sending up to MaxEmbeddingBatch texts per request.
+// The returned embeddings are in the same order as texts.
+func (c *Client) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float64, error) {
+	embeddings := make([][]float64, 0, len(texts))
+	for start := 0; start < len(texts); start += MaxEmbeddingBatch {
+		end := min(start+MaxEmbeddingBatch, len(texts))
+		batch, err := c.embedBatch(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		embeddings = append(embeddings, batch...)
+	}
+	return embeddings, nil
+}
 
+// embedBatch sends a single embedding request for all texts
+func (c *Client) embedBatch(ctx context.Context, texts []string) ([][]float64, error) {
 	// We use Ollama native API for embeddings as requested/implied by "embedding is done by ollama"
-	// and specific usage of an Ollama model tag.
+	// and specific usage of an Ollama model tag. /api/embed accepts an array input.
 	req := EmbedRequest{
-		Model: model,
-		Input: text,
+		Model: DefaultEmbeddingModel,
+		Input: texts,
 	}
 
 	reqBody, err := json.Marshal(req)
@@ -373,34 +412,74 @@ func (c *Client) GenerateEmbedding(ctx context.Context, text string) ([]float64,
 	// Use c.ollamaBaseURL specifically for embedding
 	url := fmt.Sprintf("%s/api/embed", c.ollamaBaseURL)
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
+	var embeddings [][]float64
+	err = c.post(ctx, breakerFor(c.ollamaBaseURL, embeddingTask, DefaultBreakerConfig()), url, reqBody, false, func(resp *http.Response) error {
+		var embedResp EmbedResponse
+		if err := json.NewDecoder(resp.Body).Decode(&embedResp); err != nil {
+			return fmt.Errorf("failed to decode response: %w: %w", ErrInvalidResponse, err)
+		}
+
+		if len(embedResp.Embeddings) != len(texts) {
+			return fmt.Errorf("expected %d embeddings, got %d: %w", len(texts), len(embedResp.Embeddings), ErrInvalidResponse)
+		}
+
+		embeddings = embedResp.Embeddings
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return embeddings, nil
+}
+
+// post sends a JSON request with retries and circuit breaking, and passes a successful response to handle
+func (c *Client) post(ctx context.Context, breaker *circuitBreaker, url string, reqBody []byte, generation bool, handle func(*http.Response) error) error {
+	return retryWithBackoff(ctx, c.retryConfig, url, func() error {
+		if err := breaker.allow(); err != nil {
+			return fmt.Errorf("%s: %w", breaker.name, err)
+		}
+
+		err := c.send(ctx, url, reqBody, generation, handle)
+		breaker.record(err)
+		return err
+	})
+}
+
+// send performs a single HTTP attempt
+func (c *Client) send(ctx context.Context, url string, reqBody []byte, generation bool, handle func(*http.Response) error) error {
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBody))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
+	if generation {
+		if c.genKey != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+c.genKey)
+		}
+		// OpenRouter specific headers
+		if c.provider == ProviderOpenRouter {
+			httpReq.Header.Set("HTTP-Referer", "https://github.com/snowmerak/open-librarian")
+			httpReq.Header.Set("X-Title", "Open Librarian")
+		}
+	}
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		if ctx.Err() != nil {
+			return fmt.Errorf("failed to send request: %w", err)
+		}
+		return fmt.Errorf("failed to send request: %w: %w", ErrUnavailable, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
-	}
-
-	var embedResp EmbedResponse
-	if err := json.NewDecoder(resp.Body).Decode(&embedResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	if len(embedResp.Embeddings) == 0 {
-		return nil, fmt.Errorf("no embeddings returned from API")
+		return newStatusError(resp, body)
 	}
 
-	return embedResp.Embeddings[0], nil
+	return handle(resp)
 }
 
 // HealthCheck checks if both configured LLM and Ollama are reachable
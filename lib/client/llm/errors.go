@@ -0,0 +1,160 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Error kinds returned by the client. Use errors.Is to check for them.
+var (
+	ErrRateLimited     = errors.New("llm provider rate limited the request")
+	ErrContextTooLong  = errors.New("llm prompt exceeds the model context length")
+	ErrAuth            = errors.New("llm provider rejected the credentials")
+	ErrUnavailable     = errors.New("llm provider is unavailable")
+	ErrCircuitOpen     = errors.New("llm provider circuit breaker is open")
+	ErrInvalidResponse = errors.New("llm provider returned an invalid response")
+)
+
+// StatusError is returned when a provider responds with a non-200 status code
+type StatusError struct {
+	StatusCode int
+	Body       string
+	Kind       error         // One of the Err* kinds, nil when the status is not classified
+	RetryAfter time.Duration // Parsed from the Retry-After header, zero when absent
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("API request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Kind
+}
+
+// newStatusError builds a classified StatusError from a failed HTTP response
+func newStatusError(resp *http.Response, body []byte) *StatusError {
+	return &StatusError{
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+		Kind:       classifyStatus(resp.StatusCode, string(body)),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// contextTooLongMarkers are substrings providers use when the prompt does not fit
+var contextTooLongMarkers = []string{
+	"context length",
+	"context_length",
+	"context window",
+	"maximum context",
+	"too many tokens",
+	"prompt is too long",
+	"input is too long",
+}
+
+// classifyStatus maps an HTTP status and error body to an error kind
+func classifyStatus(statusCode int, body string) error {
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case statusCode == http.StatusUnauthorized, statusCode == http.StatusForbidden, statusCode == http.StatusPaymentRequired:
+		return ErrAuth
+	case statusCode == http.StatusRequestEntityTooLarge:
+		return ErrContextTooLong
+	case statusCode == http.StatusRequestTimeout, statusCode >= 500:
+		return ErrUnavailable
+	}
+
+	if statusCode == http.StatusBadRequest {
+		lower := strings.ToLower(body)
+		for _, marker := range contextTooLongMarkers {
+			if strings.Contains(lower, marker) {
+				return ErrContextTooLong
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	var seconds int
+	if _, err := fmt.Sscanf(value, "%d", &seconds); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		if d := at.Sub(now); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
+
+// nonRetryableError marks an error that must not trigger a retry or fallback
+type nonRetryableError struct {
+	err error
+}
+
+func (e *nonRetryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *nonRetryableError) Unwrap() error {
+	return e.err
+}
+
+// IsRetryable reports whether the error is transient and the same request may succeed
+// on another attempt against the same provider
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	// The caller gave up; trying again would not help
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var nonRetryable *nonRetryableError
+	if errors.As(err, &nonRetryable) {
+		return false
+	}
+
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrUnavailable) || errors.Is(err, ErrInvalidResponse)
+}
+
+// shouldFallback reports whether another provider may succeed where this one failed
+func shouldFallback(err error) bool {
+	if IsRetryable(err) {
+		return true
+	}
+
+	var nonRetryable *nonRetryableError
+	if errors.As(err, &nonRetryable) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	// A broken, disabled or weaker model says nothing about the next one
+	return errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrAuth) || errors.Is(err, ErrMalformedOutput)
+}
+
+// RetryAfter returns how long the provider asked the caller to wait, if it did
+func RetryAfter(err error) time.Duration {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.RetryAfter
+	}
+	return 0
+}
@@ -0,0 +1,84 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+)
+
+// RetryConfig holds configuration for retry logic
+type RetryConfig struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration // Upper bound for a single wait, including Retry-After
+}
+
+// DefaultRetryConfig returns default retry configuration
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxRetries: 3,
+		BaseDelay:  1 * time.Second,
+		MaxDelay:   30 * time.Second,
+	}
+}
+
+// backoffDelay returns the wait before the given retry attempt (1-based).
+// Uses full jitter so concurrent ingests do not retry in lockstep.
+func (rc RetryConfig) backoffDelay(attempt int) time.Duration {
+	ceiling := rc.BaseDelay << (attempt - 1)
+	if ceiling <= 0 || ceiling > rc.MaxDelay {
+		ceiling = rc.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(ceiling)) + 1)
+}
+
+// retryWithBackoff executes fn, retrying transient failures with jittered exponential backoff.
+// A Retry-After hint from the provider takes precedence over the computed delay; when it is
+// longer than MaxDelay the error is returned immediately so the router can fall back instead.
+func retryWithBackoff(ctx context.Context, config RetryConfig, op string, fn func() error) error {
+	var lastErr error
+
+	for attempt := 0; attempt <= config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			delay := config.backoffDelay(attempt)
+			if retryAfter := RetryAfter(lastErr); retryAfter > 0 {
+				if retryAfter > config.MaxDelay {
+					return lastErr
+				}
+				delay = retryAfter
+			}
+
+			logger.NewLogger("llm-retry").Warn().
+				Err(lastErr).
+				Str("operation", op).
+				Int("attempt", attempt).
+				Dur("delay", delay).
+				Msg("Retrying LLM request")
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return fmt.Errorf("%w (last error: %v)", ctx.Err(), lastErr)
+			}
+		}
+
+		lastErr = fn()
+		if lastErr == nil {
+			return nil
+		}
+
+		if !IsRetryable(lastErr) || ctx.Err() != nil {
+			return lastErr
+		}
+	}
+
+	return lastErr
+}
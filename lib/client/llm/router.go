@@ -0,0 +1,392 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+)
+
+// Task identifies the kind of work an LLM call performs so it can be routed
+type Task string
+
+const (
+	TaskDefault   Task = "default"
+	TaskSummary   Task = "summary"
+	TaskTags      Task = "tags"
+	TaskRelevance Task = "relevance"
+	TaskChat      Task = "chat"
+	TaskAnswer    Task = "answer"
+	TaskTranslate Task = "translate"
+	TaskGrounding Task = "grounding"
+	TaskTopics    Task = "topics"
+	TaskOCR       Task = "ocr" // Reads text from images; route it to a vision model
+)
+
+// ProviderConfig describes a single LLM endpoint in the router configuration
+type ProviderConfig struct {
+	Type      string `json:"type,omitempty"` // ollama, openapi or openrouter; detected from the URL when empty
+	URL       string `json:"url"`
+	APIKey    string `json:"api_key,omitempty"`
+	APIKeyEnv string `json:"api_key_env,omitempty"` // Read the key from this environment variable instead
+}
+
+// RouteTarget selects a provider and model for a task
+type RouteTarget struct {
+	Provider      string `json:"provider"`
+	Model         string `json:"model"`
+	ContextTokens int    `json:"context_tokens,omitempty"` // Overrides the model's known context window
+}
+
+// RouterConfig maps tasks to an ordered list of targets.
+// The first target is the primary; the rest are tried in order when it is unavailable, rate limited,
+// circuit-broken or rejects its credentials.
+type RouterConfig struct {
+	Providers map[string]ProviderConfig `json:"providers"`
+	Routes    map[Task][]RouteTarget    `json:"routes"`
+}
+
+// Router dispatches LLM calls to per-task clients with ordered fallback
+type Router struct {
+	routes   map[Task][]*Client
+	embedder *Client
+	counter  TokenCounter
+	cache    *Cache
+}
+
+// deterministicTasks produce the same output for the same input, so their results are cached
+var deterministicTasks = map[Task]bool{
+	TaskSummary:   true,
+	TaskTags:      true,
+	TaskRelevance: true,
+	TaskTranslate: true,
+	TaskGrounding: true,
+	TaskTopics:    true,
+	TaskOCR:       true,
+}
+
+// NewRouter creates a router that sends every task to the given client
+func NewRouter(defaultClient *Client) *Router {
+	return &Router{
+		routes: map[Task][]*Client{
+			TaskDefault: {defaultClient},
+		},
+		embedder: defaultClient,
+		counter:  HeuristicCounter{},
+	}
+}
+
+// LoadRouterConfig reads a router configuration from a JSON file
+func LoadRouterConfig(path string) (*RouterConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read router config: %w", err)
+	}
+
+	var cfg RouterConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse router config: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// NewRouterFromConfig builds a router from configuration.
+// defaultClient is used for embeddings and for tasks without a route, unless the config defines a default route.
+func NewRouterFromConfig(cfg *RouterConfig, defaultClient *Client, ollamaBaseURL string) (*Router, error) {
+	routerLogger := logger.NewLogger("llm-router").StartWithMsg("Building LLM router")
+
+	router := NewRouter(defaultClient)
+	for task, targets := range cfg.Routes {
+		if len(targets) == 0 {
+			continue
+		}
+
+		clients := make([]*Client, 0, len(targets))
+		for _, target := range targets {
+			providerCfg, ok := cfg.Providers[target.Provider]
+			if !ok {
+				err := fmt.Errorf("route %q references unknown provider %q", task, target.Provider)
+				routerLogger.EndWithError(err)
+				return nil, err
+			}
+			if target.Model == "" {
+				err := fmt.Errorf("route %q has no model for provider %q", task, target.Provider)
+				routerLogger.EndWithError(err)
+				return nil, err
+			}
+
+			apiKey := providerCfg.APIKey
+			if providerCfg.APIKeyEnv != "" {
+				apiKey = os.Getenv(providerCfg.APIKeyEnv)
+			}
+
+			client := NewClientWithProvider(providerCfg.Type, providerCfg.URL, apiKey, target.Model, ollamaBaseURL)
+			if target.ContextTokens > 0 {
+				client.SetContextLimit(target.ContextTokens)
+			}
+			clients = append(clients, client)
+		}
+
+		router.SetRoute(task, clients...)
+		routerLogger.Info().Str("task", string(task)).Int("targets", len(clients)).Msg("Route configured")
+	}
+
+	routerLogger.EndWithMsg("LLM router built")
+	return router, nil
+}
+
+// SetRoute sets the ordered list of clients used for a task
+func (r *Router) SetRoute(task Task, clients ...*Client) {
+	r.routes[task] = clients
+}
+
+// SetTokenCounter replaces the token counter used for prompt budgeting
+func (r *Router) SetTokenCounter(counter TokenCounter) {
+	r.counter = counter
+}
+
+// SetCache sets the cache used for embeddings and deterministic tasks
+func (r *Router) SetCache(cache *Cache) {
+	r.cache = cache
+}
+
+// Cache returns the router's cache, nil when caching is disabled.
+// A nil cache is safe to use and never hits.
+func (r *Router) Cache() *Cache {
+	return r.cache
+}
+
+// PrimaryModel returns the model of the first client routed for a task, for use in cache keys
+func (r *Router) PrimaryModel(task Task) string {
+	clients := r.clientsFor(task)
+	if len(clients) == 0 {
+		return ""
+	}
+	return clients[0].Provider() + ":" + clients[0].Model()
+}
+
+// CountTokens estimates the token count of text with the router's counter
+func (r *Router) CountTokens(text string) int {
+	return r.counter.CountTokens(text)
+}
+
+// ContextLimit returns the smallest context window among the clients of a task,
+// so a prompt that fits the primary also fits every fallback
+func (r *Router) ContextLimit(task Task) int {
+	limit := 0
+	for _, client := range r.clientsFor(task) {
+		if l := client.ContextLimit(); limit == 0 || l < limit {
+			limit = l
+		}
+	}
+	if limit == 0 {
+		return DefaultContextTokens
+	}
+	return limit
+}
+
+// clientsFor returns the clients for a task, falling back to the default route
+func (r *Router) clientsFor(task Task) []*Client {
+	if clients, ok := r.routes[task]; ok && len(clients) > 0 {
+		return clients
+	}
+	return r.routes[TaskDefault]
+}
+
+// callWithFallback runs fn against each client for the task until one succeeds
+// or fails with an error that another provider could not fix
+func callWithFallback[T any](ctx context.Context, r *Router, task Task, fn func(context.Context, *Client) (T, error)) (T, error) {
+	var zero T
+	var lastErr error
+
+	ctx = withTask(ctx, task)
+	clients := r.clientsFor(task)
+	for i, client := range clients {
+		result, err := fn(ctx, client)
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+
+		if !shouldFallback(err) || ctx.Err() != nil {
+			break
+		}
+
+		if i < len(clients)-1 {
+			logger.NewLogger("llm-router").Warn().
+				Err(err).
+				Str("task", string(task)).
+				Str("provider", client.Provider()).
+				Str("model", client.Model()).
+				Msg("LLM call failed, falling back to next provider")
+		}
+	}
+
+	if lastErr == nil {
+		return zero, fmt.Errorf("no LLM provider configured for task %q", task)
+	}
+	return zero, lastErr
+}
+
+// Chat runs a chat completion for the task
+func (r *Router) Chat(ctx context.Context, task Task, messages []ChatMessage, tools []Tool) (*ChatMessage, error) {
+	return callWithFallback(ctx, r, task, func(ctx context.Context, c *Client) (*ChatMessage, error) {
+		return c.Chat(ctx, messages, tools)
+	})
+}
+
+// GenerateText generates text for the task.
+// Deterministic tasks run at temperature zero and are served from the cache when possible.
+func (r *Router) GenerateText(ctx context.Context, task Task, prompt string) (string, error) {
+	cacheable := deterministicTasks[task]
+	if cacheable {
+		ctx = withDeterministic(ctx)
+	}
+
+	return callWithFallback(ctx, r, task, func(ctx context.Context, c *Client) (string, error) {
+		model := c.Provider() + ":" + c.Model()
+
+		var text string
+		if cacheable && r.cache.Get(ctx, CacheKindText, model, prompt, &text) {
+			return text, nil
+		}
+
+		text, err := c.GenerateText(ctx, prompt)
+		if err == nil && cacheable {
+			r.cache.Set(ctx, CacheKindText, model, prompt, text)
+		}
+		return text, err
+	})
+}
+
+// GenerateJSON generates schema-conforming output for the task and decodes it into out.
+// Deterministic tasks run at temperature zero and are served from the cache when possible.
+func (r *Router) GenerateJSON(ctx context.Context, task Task, prompt string, name string, schema *Schema, out any) error {
+	return r.GenerateJSONWithImages(ctx, task, prompt, nil, name, schema, out)
+}
+
+// GenerateJSONWithImages is GenerateJSON with images attached to the prompt. Cached results are
+// keyed by the images' content as well as the prompt.
+func (r *Router) GenerateJSONWithImages(ctx context.Context, task Task, prompt string, images []Image, name string, schema *Schema, out any) error {
+	cacheable := deterministicTasks[task]
+	var cacheInput string
+	if cacheable {
+		ctx = withDeterministic(ctx)
+		schemaJSON, err := json.Marshal(schema)
+		if err != nil {
+			return fmt.Errorf("failed to marshal schema: %w", err)
+		}
+		cacheInput = name + "\x00" + string(schemaJSON) + "\x00" + prompt
+		for _, image := range images {
+			sum := sha256.Sum256(image.Data)
+			cacheInput += "\x00" + image.MediaType + ":" + hex.EncodeToString(sum[:])
+		}
+	}
+
+	_, err := callWithFallback(ctx, r, task, func(ctx context.Context, c *Client) (struct{}, error) {
+		model := c.Provider() + ":" + c.Model()
+		if cacheable && r.cache.Get(ctx, CacheKindJSON, model, cacheInput, out) {
+			return struct{}{}, nil
+		}
+
+		err := c.GenerateJSONWithImages(ctx, prompt, images, name, schema, out)
+		if err == nil && cacheable {
+			r.cache.Set(ctx, CacheKindJSON, model, cacheInput, out)
+		}
+		return struct{}{}, err
+	})
+	return err
+}
+
+// GenerateTextStream streams text for the task.
+// Fallback only happens before the first chunk is delivered, so callers never see mixed output.
+func (r *Router) GenerateTextStream(ctx context.Context, task Task, prompt string, callback func(string) error) error {
+	_, err := callWithFallback(ctx, r, task, func(ctx context.Context, c *Client) (struct{}, error) {
+		started := false
+		err := c.GenerateTextStream(ctx, prompt, func(chunk string) error {
+			started = true
+			return callback(chunk)
+		})
+		if err != nil && started {
+			return struct{}{}, &nonRetryableError{err: err}
+		}
+		return struct{}{}, err
+	})
+	return err
+}
+
+// GenerateEmbedding generates an embedding with the embedding client, reusing cached vectors
+func (r *Router) GenerateEmbedding(ctx context.Context, text string) ([]float64, error) {
+	embeddings, err := r.GenerateEmbeddings(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// GenerateEmbeddings generates embeddings for several texts in batches, reusing cached vectors.
+// The returned embeddings are in the same order as texts.
+func (r *Router) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float64, error) {
+	embeddings := make([][]float64, len(texts))
+
+	var missing []string
+	var missingIndexes []int
+	for i, text := range texts {
+		if r.cache.Get(ctx, CacheKindEmbedding, DefaultEmbeddingModel, text, &embeddings[i]) {
+			continue
+		}
+		missing = append(missing, text)
+		missingIndexes = append(missingIndexes, i)
+	}
+
+	if len(missing) == 0 {
+		return embeddings, nil
+	}
+
+	generated, err := r.embedder.GenerateEmbeddings(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	for j, i := range missingIndexes {
+		embeddings[i] = generated[j]
+		r.cache.Set(ctx, CacheKindEmbedding, DefaultEmbeddingModel, missing[j], generated[j])
+	}
+	return embeddings, nil
+}
+
+// HealthCheck checks every distinct client used by the router
+func (r *Router) HealthCheck(ctx context.Context) error {
+	seen := make(map[*Client]bool)
+	for task, clients := range r.routes {
+		for _, client := range clients {
+			if seen[client] {
+				continue
+			}
+			seen[client] = true
+			if err := client.HealthCheck(ctx); err != nil {
+				return fmt.Errorf("%s route (%s/%s): %w", task, client.Provider(), client.Model(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// Describe returns a short human-readable summary of the routes for logging
+func (r *Router) Describe() string {
+	parts := make([]string, 0, len(r.routes))
+	for task, clients := range r.routes {
+		models := make([]string, 0, len(clients))
+		for _, client := range clients {
+			models = append(models, client.Provider()+":"+client.Model())
+		}
+		parts = append(parts, string(task)+"="+strings.Join(models, ">"))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,243 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+)
+
+// ErrMalformedOutput is returned when the model keeps answering with output that does not match the schema
+var ErrMalformedOutput = errors.New("llm output does not match the requested schema")
+
+// maxRepairAttempts is how many times the model is asked to fix invalid JSON before giving up
+const maxRepairAttempts = 2
+
+// ResponseFormat requests structured output from OpenAI-compatible providers
+type ResponseFormat struct {
+	Type       string          `json:"type"` // "json_schema" or "json_object"
+	JSONSchema *JSONSchemaSpec `json:"json_schema,omitempty"`
+}
+
+// JSONSchemaSpec names a schema for the json_schema response format
+type JSONSchemaSpec struct {
+	Name   string  `json:"name"`
+	Strict bool    `json:"strict"`
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is the subset of JSON Schema used for structured outputs
+type Schema struct {
+	Type                 string             `json:"type"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+}
+
+// Validate checks a decoded JSON value against the schema
+func (s *Schema) Validate(value any) error {
+	return s.validate("$", value)
+}
+
+func (s *Schema) validate(path string, value any) error {
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected object", path)
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%s: missing required field %q", path, name)
+			}
+		}
+		for name, prop := range s.Properties {
+			if v, ok := obj[name]; ok {
+				if err := prop.validate(path+"."+name, v); err != nil {
+					return err
+				}
+			}
+		}
+	case "array":
+		arr, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected array", path)
+		}
+		if s.MinItems != nil && len(arr) < *s.MinItems {
+			return fmt.Errorf("%s: expected at least %d items, got %d", path, *s.MinItems, len(arr))
+		}
+		if s.MaxItems != nil && len(arr) > *s.MaxItems {
+			return fmt.Errorf("%s: expected at most %d items, got %d", path, *s.MaxItems, len(arr))
+		}
+		if s.Items != nil {
+			for i, item := range arr {
+				if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected string", path)
+		}
+		if len(s.Enum) > 0 {
+			found := false
+			for _, e := range s.Enum {
+				if e == str {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("%s: %q is not one of %v", path, str, s.Enum)
+			}
+		}
+	case "number", "integer":
+		num, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("%s: expected number", path)
+		}
+		if s.Type == "integer" && num != float64(int64(num)) {
+			return fmt.Errorf("%s: expected integer, got %v", path, num)
+		}
+		if s.Minimum != nil && num < *s.Minimum {
+			return fmt.Errorf("%s: %v is below the minimum %v", path, num, *s.Minimum)
+		}
+		if s.Maximum != nil && num > *s.Maximum {
+			return fmt.Errorf("%s: %v is above the maximum %v", path, num, *s.Maximum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected boolean", path)
+		}
+	}
+	return nil
+}
+
+// extractJSON returns the JSON object or array in a model reply, ignoring code fences and surrounding prose
+func extractJSON(text string) string {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+		text = strings.TrimSpace(text)
+	}
+
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return text
+	}
+	closer := byte('}')
+	if text[start] == '[' {
+		closer = ']'
+	}
+	end := strings.LastIndexByte(text, closer)
+	if end < start {
+		return text[start:]
+	}
+	return text[start : end+1]
+}
+
+// parseStructured decodes a model reply, validates it against the schema and stores it in out
+func parseStructured(reply string, schema *Schema, out any) error {
+	raw := extractJSON(reply)
+
+	var generic any
+	if err := json.Unmarshal([]byte(raw), &generic); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	if err := schema.Validate(generic); err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(raw), out); err != nil {
+		return fmt.Errorf("JSON does not match the expected structure: %w", err)
+	}
+	return nil
+}
+
+// GenerateJSON generates a response that conforms to schema and decodes it into out.
+// Providers that support response_format get the schema natively; otherwise the schema is described
+// in the prompt. Replies that fail validation are sent back to the model with the error for repair.
+func (c *Client) GenerateJSON(ctx context.Context, prompt string, name string, schema *Schema, out any) error {
+	return c.GenerateJSONWithImages(ctx, prompt, nil, name, schema, out)
+}
+
+// GenerateJSONWithImages is GenerateJSON with images attached to the prompt, for vision models
+func (c *Client) GenerateJSONWithImages(ctx context.Context, prompt string, images []Image, name string, schema *Schema, out any) error {
+	log := logger.NewLogger("llm-generate-json")
+
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return fmt.Errorf("failed to marshal schema: %w", err)
+	}
+
+	messages := []ChatMessage{
+		{Role: "system", Content: "You respond only with a single JSON value that matches this JSON Schema, with no code fences or commentary:\n" + string(schemaJSON)},
+		{Role: "user", Content: prompt, Images: images},
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= maxRepairAttempts; attempt++ {
+		reqPayload := ChatRequest{
+			Model:       c.genModel,
+			Messages:    messages,
+			Stream:      false,
+			Temperature: temperatureFor(ctx),
+		}
+		native := c.supportsStructuredOutputs()
+		if native {
+			reqPayload.ResponseFormat = &ResponseFormat{
+				Type:       "json_schema",
+				JSONSchema: &JSONSchemaSpec{Name: name, Strict: false, Schema: schema},
+			}
+		}
+
+		message, err := c.complete(ctx, reqPayload)
+		if err != nil {
+			var statusErr *StatusError
+			if native && errors.As(err, &statusErr) && statusErr.StatusCode == 400 && strings.Contains(strings.ToLower(statusErr.Body), "response_format") {
+				// The provider or model does not support structured outputs; continue with the prompt-only contract
+				log.Warn().Str("model", c.genModel).Msg("Provider rejected response_format, falling back to prompt-only JSON")
+				c.disableStructuredOutputs()
+				attempt--
+				continue
+			}
+			return err
+		}
+
+		lastErr = parseStructured(message.Content, schema, out)
+		if lastErr == nil {
+			return nil
+		}
+
+		log.Warn().Err(lastErr).Str("schema", name).Int("attempt", attempt+1).Msg("Model returned invalid structured output")
+		messages = append(messages,
+			ChatMessage{Role: "assistant", Content: message.Content},
+			ChatMessage{Role: "user", Content: fmt.Sprintf("Your reply was invalid: %v. Reply again with only the corrected JSON.", lastErr)},
+		)
+	}
+
+	return fmt.Errorf("%w: %s: %v", ErrMalformedOutput, name, lastErr)
+}
+
+// supportsStructuredOutputs reports whether response_format should be sent to the provider
+func (c *Client) supportsStructuredOutputs() bool {
+	return !c.noStructuredOutputs.Load()
+}
+
+// disableStructuredOutputs stops sending response_format after the provider rejected it
+func (c *Client) disableStructuredOutputs() {
+	c.noStructuredOutputs.Store(true)
+}
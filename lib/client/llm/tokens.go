@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// TokenCounter estimates how many tokens a text occupies in a model's context
+type TokenCounter interface {
+	CountTokens(text string) int
+}
+
+// HeuristicCounter estimates tokens without a tokenizer.
+// Latin text averages about four characters per token while CJK characters are usually one token each,
+// so the estimate errs on the high side for mixed Korean/Japanese/Chinese documents.
+type HeuristicCounter struct{}
+
+// CountTokens returns the estimated token count of text
+func (HeuristicCounter) CountTokens(text string) int {
+	var ascii, wide, other int
+	for _, r := range text {
+		switch {
+		case r < unicode.MaxASCII:
+			ascii++
+		case unicode.In(r, unicode.Hangul, unicode.Han, unicode.Hiragana, unicode.Katakana):
+			wide++
+		default:
+			other++
+		}
+	}
+	return int(math.Ceil(float64(ascii)/4 + float64(wide) + float64(other)/2))
+}
+
+const (
+	// DefaultContextTokens is used for models without a known context window
+	DefaultContextTokens = 8192
+
+	// DefaultOllamaContextTokens matches Ollama's default num_ctx, which the OpenAI-compatible
+	// endpoint uses unless the server is started with a larger OLLAMA_CONTEXT_LENGTH
+	DefaultOllamaContextTokens = 4096
+)
+
+// knownContextTokens maps model name prefixes to their context windows
+var knownContextTokens = []struct {
+	prefix string
+	tokens int
+}{
+	{"openai/gpt-oss", 131072},
+	{"openai/gpt-4o", 128000},
+	{"openai/gpt-4.1", 1047576},
+	{"gpt-4o", 128000},
+	{"gpt-4.1", 1047576},
+	{"anthropic/claude", 200000},
+	{"google/gemini", 1048576},
+	{"google/gemma-3", 131072},
+	{"meta-llama/llama-3", 131072},
+	{"mistralai/", 32768},
+	{"qwen/", 32768},
+	{"deepseek/", 65536},
+}
+
+// ContextLimit returns the context window of the client's model in tokens.
+// An explicit limit wins; Ollama defaults to its server context size; other providers use known model windows.
+func (c *Client) ContextLimit() int {
+	if c.contextTokens > 0 {
+		return c.contextTokens
+	}
+
+	if c.provider == ProviderOllama {
+		return DefaultOllamaContextTokens
+	}
+
+	model := strings.ToLower(c.genModel)
+	for _, known := range knownContextTokens {
+		if strings.HasPrefix(model, known.prefix) {
+			return known.tokens
+		}
+	}
+
+	return DefaultContextTokens
+}
+
+// SetContextLimit overrides the context window used for prompt budgeting
+func (c *Client) SetContextLimit(tokens int) {
+	c.contextTokens = tokens
+}
@@ -0,0 +1,53 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+)
+
+// LogSender writes messages to the log and optionally appends them to a file.
+// It is meant for local development and testing where no SMTP relay is available.
+type LogSender struct {
+	filePath string
+	mu       sync.Mutex
+}
+
+// NewLogSender creates a new log sender. If filePath is empty, messages are only logged.
+func NewLogSender(filePath string) *LogSender {
+	return &LogSender{filePath: filePath}
+}
+
+// Send logs the message and appends it to the configured file
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	log := logger.NewLogger("mail-log-send")
+	log.Info().
+		Str("to", msg.To).
+		Str("subject", msg.Subject).
+		Str("body", msg.Body).
+		Msg("Email (log sender)")
+
+	if s.filePath == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open mail log file: %w", err)
+	}
+	defer f.Close()
+
+	entry := fmt.Sprintf("=== %s ===\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().Format(time.RFC3339), msg.To, msg.Subject, msg.Body)
+	if _, err := f.WriteString(entry); err != nil {
+		return fmt.Errorf("failed to write mail log file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,18 @@
+package mail
+
+import (
+	"context"
+)
+
+// Message represents a single outgoing email
+type Message struct {
+	To      string
+	Subject string
+	Body    string // Plain text body
+}
+
+// Sender delivers email messages.
+// Implementations must be safe for concurrent use.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,91 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+)
+
+// SMTPConfig holds the connection settings for an SMTP relay
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPSender sends email through an SMTP relay using PLAIN auth
+type SMTPSender struct {
+	config SMTPConfig
+}
+
+// NewSMTPSender creates a new SMTP sender
+func NewSMTPSender(config SMTPConfig) (*SMTPSender, error) {
+	if config.Host == "" {
+		return nil, fmt.Errorf("smtp host is required")
+	}
+	if config.From == "" {
+		return nil, fmt.Errorf("smtp from address is required")
+	}
+	if config.Port == 0 {
+		config.Port = 587
+	}
+
+	return &SMTPSender{config: config}, nil
+}
+
+// Send sends a message through the configured SMTP relay
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	log := logger.NewLogger("mail-smtp-send")
+	log.StartWithMsg("Sending email via SMTP")
+	log.Info().Str("to", msg.To).Str("subject", msg.Subject).Str("host", s.config.Host).Msg("SMTP send request")
+
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+
+	var auth smtp.Auth
+	if s.config.Username != "" {
+		auth = smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+	}
+
+	// net/smtp has no context support, so run it in a goroutine and respect cancellation
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- smtp.SendMail(addr, auth, s.config.From, []string{msg.To}, buildMIMEMessage(s.config.From, msg))
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			log.EndWithError(err)
+			return fmt.Errorf("failed to send email: %w", err)
+		}
+	case <-ctx.Done():
+		log.EndWithError(ctx.Err())
+		return ctx.Err()
+	}
+
+	log.EndWithMsg("Email sent successfully")
+	return nil
+}
+
+// buildMIMEMessage builds a minimal RFC 5322 plain text message
+func buildMIMEMessage(from string, msg Message) []byte {
+	var sb strings.Builder
+	sb.WriteString("From: " + from + "\r\n")
+	sb.WriteString("To: " + msg.To + "\r\n")
+	sb.WriteString("Subject: " + msg.Subject + "\r\n")
+	sb.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	sb.WriteString("MIME-Version: 1.0\r\n")
+	sb.WriteString("Content-Type: text/plain; charset=\"UTF-8\"\r\n")
+	sb.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	sb.WriteString("\r\n")
+	sb.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(sb.String())
+}
@@ -12,10 +12,12 @@ import (
 
 // ChatMessage represents a single message in a chat session
 type ChatMessage struct {
-	Role      string      `bson:"role" json:"role"` // "user" or "assistant"
-	Content   string      `bson:"content" json:"content"`
-	Sources   interface{} `bson:"sources,omitempty" json:"sources,omitempty"` // For assistant messages
-	Timestamp time.Time   `bson:"timestamp" json:"timestamp"`
+	Role              string      `bson:"role" json:"role"` // "user" or "assistant"
+	Content           string      `bson:"content" json:"content"`
+	Sources           interface{} `bson:"sources,omitempty" json:"sources,omitempty"` // For assistant messages
+	Citations         interface{} `bson:"citations,omitempty" json:"citations,omitempty"`
+	UnsupportedClaims interface{} `bson:"unsupported_claims,omitempty" json:"unsupported_claims,omitempty"` // Answer sentences not backed by the sources
+	Timestamp         time.Time   `bson:"timestamp" json:"timestamp"`
 }
 
 // ChatSession represents a chat conversation
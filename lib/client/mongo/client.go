@@ -74,6 +74,20 @@ func (c *Client) InitializeDatabase(ctx context.Context) error {
 		return err
 	}
 
+	// Create user token indexes
+	err = c.CreateTokenIndexes(ctx)
+	if err != nil {
+		initLogger.EndWithError(err)
+		return err
+	}
+
+	// Create LLM cache indexes
+	err = c.CreateLLMCacheIndexes(ctx)
+	if err != nil {
+		initLogger.EndWithError(err)
+		return err
+	}
+
 	initLogger.EndWithMsg("MongoDB database initialization complete")
 	return nil
 }
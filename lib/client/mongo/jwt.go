@@ -2,6 +2,10 @@ package mongo
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
@@ -96,6 +100,80 @@ func (j *JWTService) RefreshToken(tokenString string) (string, error) {
 	return token.SignedString(j.secretKey)
 }
 
+// ActionClaims represents the claims of a single-purpose action token
+// (email verification, password reset). Each token carries a unique ID
+// that is stored in MongoDB so it can only be used once.
+type ActionClaims struct {
+	UserID  string `json:"user_id"`
+	Email   string `json:"email"`
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// actionKey derives a purpose-specific signing key so action tokens can never
+// be accepted as session tokens (and vice versa)
+func (j *JWTService) actionKey(purpose string) []byte {
+	mac := hmac.New(sha256.New, j.secretKey)
+	mac.Write([]byte("action:" + purpose))
+	return mac.Sum(nil)
+}
+
+// GenerateActionToken generates a signed, expiring token for the given purpose.
+// It returns the token string together with its unique ID and expiration time.
+func (j *JWTService) GenerateActionToken(user *User, purpose string, ttl time.Duration) (string, string, time.Time, error) {
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", "", time.Time{}, err
+	}
+	tokenID := hex.EncodeToString(idBytes)
+
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	claims := ActionClaims{
+		UserID:  user.ID.Hex(),
+		Email:   user.Email,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			Issuer:    j.issuer,
+			Subject:   user.ID.Hex(),
+			Audience:  jwt.ClaimStrings{purpose},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString(j.actionKey(purpose))
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	return signed, tokenID, expiresAt, nil
+}
+
+// ValidateActionToken validates an action token for the given purpose and returns its claims
+func (j *JWTService) ValidateActionToken(tokenString, purpose string) (*ActionClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &ActionClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return j.actionKey(purpose), nil
+	}, jwt.WithAudience(purpose), jwt.WithIssuer(j.issuer))
+
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*ActionClaims)
+	if !ok || !token.Valid || claims.Purpose != purpose || claims.ID == "" {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
+
 // AuthResponse represents the authentication response
 type AuthResponse struct {
 	User  *User  `json:"user"`
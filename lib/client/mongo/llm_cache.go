@@ -0,0 +1,66 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const LLMCacheCollection = "llm_cache"
+
+// LLMCacheEntry is a cached embedding or LLM output
+type LLMCacheEntry struct {
+	Key       string    `bson:"_id"`
+	Value     []byte    `bson:"value"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// CreateLLMCacheIndexes creates indexes for the LLM cache collection.
+// Expired entries are removed automatically by a TTL index.
+func (c *Client) CreateLLMCacheIndexes(ctx context.Context) error {
+	collection := c.client.Database(DatabaseName).Collection(LLMCacheCollection)
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// GetCacheEntry returns a cached value if it exists and has not expired
+func (c *Client) GetCacheEntry(ctx context.Context, key string) ([]byte, bool, error) {
+	collection := c.client.Database(DatabaseName).Collection(LLMCacheCollection)
+
+	// The TTL monitor runs periodically, so expired entries may still be present
+	filter := bson.M{
+		"_id":        key,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+
+	var entry LLMCacheEntry
+	if err := collection.FindOne(ctx, filter).Decode(&entry); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	return entry.Value, true, nil
+}
+
+// SetCacheEntry stores a value that expires after ttl
+func (c *Client) SetCacheEntry(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	collection := c.client.Database(DatabaseName).Collection(LLMCacheCollection)
+
+	entry := LLMCacheEntry{
+		Key:       key,
+		Value:     value,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	_, err := collection.ReplaceOne(ctx, bson.M{"_id": key}, entry, options.Replace().SetUpsert(true))
+	return err
+}
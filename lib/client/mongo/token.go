@@ -0,0 +1,111 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Token purposes
+const (
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposePasswordReset     = "password_reset"
+)
+
+const TokenCollection = "user_tokens"
+
+// ErrTokenInvalid is returned when a token is unknown, expired or already used
+var ErrTokenInvalid = errors.New("invalid or expired token")
+
+// UserToken records an issued action token so that it can be used only once
+type UserToken struct {
+	ID        string        `bson:"_id" json:"id"` // Token ID (jti claim)
+	UserID    bson.ObjectID `bson:"user_id" json:"user_id"`
+	Purpose   string        `bson:"purpose" json:"purpose"`
+	ExpiresAt time.Time     `bson:"expires_at" json:"expires_at"`
+	UsedAt    *time.Time    `bson:"used_at,omitempty" json:"used_at,omitempty"`
+	CreatedAt time.Time     `bson:"created_at" json:"created_at"`
+}
+
+// CreateTokenIndexes creates indexes for the user tokens collection.
+// Expired tokens are removed automatically by a TTL index.
+func (c *Client) CreateTokenIndexes(ctx context.Context) error {
+	collection := c.client.Database(DatabaseName).Collection(TokenCollection)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "purpose", Value: 1},
+			},
+		},
+	})
+	return err
+}
+
+// SaveUserToken stores a newly issued token and revokes any older unused tokens
+// for the same user and purpose
+func (c *Client) SaveUserToken(ctx context.Context, token *UserToken) error {
+	tokenLogger := logger.NewLogger("mongo_save_user_token").StartWithMsg("Saving user token")
+	tokenLogger.Info().Str("user_id", token.UserID.Hex()).Str("purpose", token.Purpose).Msg("Saving token")
+
+	collection := c.client.Database(DatabaseName).Collection(TokenCollection)
+
+	// Only the most recently issued token stays valid
+	if _, err := collection.DeleteMany(ctx, bson.M{
+		"user_id": token.UserID,
+		"purpose": token.Purpose,
+		"used_at": bson.M{"$exists": false},
+	}); err != nil {
+		tokenLogger.EndWithError(err)
+		return err
+	}
+
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+
+	if _, err := collection.InsertOne(ctx, token); err != nil {
+		tokenLogger.EndWithError(err)
+		return err
+	}
+
+	tokenLogger.EndWithMsg("User token saved")
+	return nil
+}
+
+// ConsumeUserToken atomically marks a token as used and returns it.
+// Returns ErrTokenInvalid if the token does not exist, has expired or was already used.
+func (c *Client) ConsumeUserToken(ctx context.Context, tokenID, purpose string) (*UserToken, error) {
+	collection := c.client.Database(DatabaseName).Collection(TokenCollection)
+
+	now := time.Now()
+	filter := bson.M{
+		"_id":        tokenID,
+		"purpose":    purpose,
+		"used_at":    bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": now},
+	}
+	update := bson.M{"$set": bson.M{"used_at": now}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var token UserToken
+	err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&token)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrTokenInvalid
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
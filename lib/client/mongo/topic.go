@@ -0,0 +1,63 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const TopicCollection = "topics"
+
+// TopicArticle is a representative article of a topic
+type TopicArticle struct {
+	ID    string `bson:"id" json:"id"`
+	Title string `bson:"title" json:"title"`
+}
+
+// Topic is a cluster of articles with similar summaries
+type Topic struct {
+	ID              string         `bson:"_id" json:"id"`
+	Label           string         `bson:"label" json:"label"`
+	Description     string         `bson:"description,omitempty" json:"description,omitempty"`
+	Size            int            `bson:"size" json:"size"`
+	Cohesion        float64        `bson:"cohesion" json:"cohesion"`               // Mean cosine similarity of members to the topic center
+	Representatives []TopicArticle `bson:"representatives" json:"representatives"` // Members closest to the topic center
+	Centroid        []float32      `bson:"centroid,omitempty" json:"-"`            // Unit-length center, used to keep IDs across runs and to place new articles
+	UpdatedAt       time.Time      `bson:"updated_at" json:"updated_at"`
+}
+
+// ReplaceTopics stores the result of a clustering run and removes topics it no longer contains
+func (c *Client) ReplaceTopics(ctx context.Context, topics []Topic) error {
+	collection := c.client.Database(DatabaseName).Collection(TopicCollection)
+
+	ids := make([]string, 0, len(topics))
+	for _, topic := range topics {
+		if _, err := collection.ReplaceOne(ctx, bson.M{"_id": topic.ID}, topic, options.Replace().SetUpsert(true)); err != nil {
+			return err
+		}
+		ids = append(ids, topic.ID)
+	}
+
+	_, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$nin": ids}})
+	return err
+}
+
+// GetTopics returns all topics, largest first
+func (c *Client) GetTopics(ctx context.Context) ([]Topic, error) {
+	collection := c.client.Database(DatabaseName).Collection(TopicCollection)
+
+	opts := options.Find().SetSort(bson.D{{Key: "size", Value: -1}, {Key: "_id", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	topics := []Topic{}
+	if err = cursor.All(ctx, &topics); err != nil {
+		return nil, err
+	}
+	return topics, nil
+}
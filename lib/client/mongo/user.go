@@ -14,15 +14,20 @@ import (
 	"golang.org/x/crypto/argon2"
 )
 
+// ErrUserNotFound is returned when no user matches the given ID, username or email
+var ErrUserNotFound = errors.New("user not found")
+
 // User represents a user in the system
 type User struct {
-	ID           bson.ObjectID `bson:"_id,omitempty" json:"id"`
-	Email        string        `bson:"email" json:"email"`
-	Username     string        `bson:"username" json:"username"`
-	PasswordHash string        `bson:"password_hash" json:"-"`
-	Salt         string        `bson:"salt" json:"-"`
-	CreatedAt    time.Time     `bson:"created_at" json:"created_at"`
-	UpdatedAt    time.Time     `bson:"updated_at" json:"updated_at"`
+	ID              bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	Email           string        `bson:"email" json:"email"`
+	Username        string        `bson:"username" json:"username"`
+	PasswordHash    string        `bson:"password_hash" json:"-"`
+	Salt            string        `bson:"salt" json:"-"`
+	EmailVerified   bool          `bson:"email_verified" json:"email_verified"`
+	EmailVerifiedAt *time.Time    `bson:"email_verified_at,omitempty" json:"email_verified_at,omitempty"`
+	CreatedAt       time.Time     `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time     `bson:"updated_at" json:"updated_at"`
 }
 
 // UserCredentials represents login credentials
@@ -188,7 +193,7 @@ func (c *Client) GetUserByID(ctx context.Context, id bson.ObjectID) (*User, erro
 	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&user)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -204,7 +209,7 @@ func (c *Client) GetUserByUsername(ctx context.Context, username string) (*User,
 	err := collection.FindOne(ctx, bson.M{"username": username}).Decode(&user)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -220,7 +225,7 @@ func (c *Client) GetUserByEmail(ctx context.Context, email string) (*User, error
 	err := collection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -242,6 +247,13 @@ func (c *Client) UpdateUser(ctx context.Context, id bson.ObjectID, updates bson.
 	delete(updates, "password_hash")
 	delete(updates, "salt")
 	delete(updates, "_id")
+	delete(updates, "email_verified")
+	delete(updates, "email_verified_at")
+
+	// A changed email address has to be verified again
+	if _, ok := updates["email"]; ok {
+		updates["email_verified"] = false
+	}
 
 	result, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": updates})
 	if err != nil {
@@ -252,8 +264,8 @@ func (c *Client) UpdateUser(ctx context.Context, id bson.ObjectID, updates bson.
 
 	if result.MatchedCount == 0 {
 		updateLogger.Warn().Str("user_id", id.Hex()).Msg("User not found for update")
-		updateLogger.EndWithError(errors.New("user not found"))
-		return errors.New("user not found")
+		updateLogger.EndWithError(ErrUserNotFound)
+		return ErrUserNotFound
 	}
 
 	updateLogger.DataUpdated("user", id.Hex(), updates)
@@ -263,8 +275,6 @@ func (c *Client) UpdateUser(ctx context.Context, id bson.ObjectID, updates bson.
 
 // ChangePassword changes a user's password
 func (c *Client) ChangePassword(ctx context.Context, id bson.ObjectID, oldPassword, newPassword string) error {
-	collection := c.client.Database("open_librarian").Collection("users")
-
 	// Get user to verify old password
 	user, err := c.GetUserByID(ctx, id)
 	if err != nil {
@@ -281,28 +291,79 @@ func (c *Client) ChangePassword(ctx context.Context, id bson.ObjectID, oldPasswo
 		return errors.New("invalid old password")
 	}
 
+	return c.setPassword(ctx, id, newPassword, nil)
+}
+
+// ResetPassword sets a new password without checking the old one.
+// Callers must have proven ownership of the account (e.g. via a password reset token).
+// Since the reset link was delivered by email, the address is marked as verified as well.
+func (c *Client) ResetPassword(ctx context.Context, id bson.ObjectID, newPassword string) error {
+	now := time.Now()
+	return c.setPassword(ctx, id, newPassword, bson.M{
+		"email_verified":    true,
+		"email_verified_at": now,
+	})
+}
+
+// setPassword hashes and stores a new password, applying any extra fields in the same update
+func (c *Client) setPassword(ctx context.Context, id bson.ObjectID, newPassword string, extra bson.M) error {
+	collection := c.client.Database("open_librarian").Collection("users")
+
 	// Hash new password
 	newPasswordHash, newSalt, err := HashPassword(newPassword)
 	if err != nil {
 		return err
 	}
 
+	set := bson.M{
+		"password_hash": newPasswordHash,
+		"salt":          newSalt,
+		"updated_at":    time.Now(),
+	}
+	for k, v := range extra {
+		set[k] = v
+	}
+
 	// Update password
-	result, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// MarkEmailVerified marks the user's email address as verified if it is still email, so a
+// verification sent to an address the user has since changed cannot verify the new one
+func (c *Client) MarkEmailVerified(ctx context.Context, id bson.ObjectID, email string) error {
+	verifyLogger := logger.NewLogger("mongo_mark_email_verified").StartWithMsg("Marking email as verified")
+
+	collection := c.client.Database("open_librarian").Collection("users")
+
+	now := time.Now()
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id, "email": email}, bson.M{
 		"$set": bson.M{
-			"password_hash": newPasswordHash,
-			"salt":          newSalt,
-			"updated_at":    time.Now(),
+			"email_verified":    true,
+			"email_verified_at": now,
+			"updated_at":        now,
 		},
 	})
 	if err != nil {
+		verifyLogger.EndWithError(err)
 		return err
 	}
 
 	if result.MatchedCount == 0 {
-		return errors.New("user not found")
+		verifyLogger.EndWithError(ErrUserNotFound)
+		return ErrUserNotFound
 	}
 
+	verifyLogger.DataUpdated("user", id.Hex(), map[string]interface{}{"email_verified": true})
+	verifyLogger.EndWithMsg("Email marked as verified")
 	return nil
 }
 
@@ -322,8 +383,8 @@ func (c *Client) DeleteUser(ctx context.Context, id bson.ObjectID) error {
 
 	if result.DeletedCount == 0 {
 		delLogger.Warn().Str("user_id", id.Hex()).Msg("User not found for deletion")
-		delLogger.EndWithError(errors.New("user not found"))
-		return errors.New("user not found")
+		delLogger.EndWithError(ErrUserNotFound)
+		return ErrUserNotFound
 	}
 
 	delLogger.DataDeleted("user", id.Hex())
@@ -348,5 +409,15 @@ func (c *Client) CreateUserIndexes(ctx context.Context) error {
 	}
 
 	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{emailIndex, usernameIndex})
+	if err != nil {
+		return err
+	}
+
+	// Accounts created before email verification existed have no email_verified field.
+	// Treat them as verified so existing users are not locked out of adding articles.
+	_, err = collection.UpdateMany(ctx,
+		bson.M{"email_verified": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"email_verified": true}},
+	)
 	return err
 }
@@ -0,0 +1,151 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+)
+
+// TesseractConfig holds the settings of the tesseract command
+type TesseractConfig struct {
+	Path      string        // Executable, looked up in PATH when it has no directory
+	Languages string        // Installed language models joined with "+", such as "eng+kor"
+	Timeout   time.Duration // Time allowed for one image; 0 disables the limit
+}
+
+// DefaultTesseractConfig returns the settings for a tesseract installed in PATH with English
+func DefaultTesseractConfig() TesseractConfig {
+	return TesseractConfig{
+		Path:      "tesseract",
+		Languages: "eng",
+		Timeout:   2 * time.Minute,
+	}
+}
+
+// Tesseract recognizes text by running the tesseract command line tool
+type Tesseract struct {
+	config TesseractConfig
+}
+
+// NewTesseract creates a Tesseract engine, failing when the executable cannot be found
+func NewTesseract(config TesseractConfig) (*Tesseract, error) {
+	if config.Languages == "" {
+		config.Languages = "eng"
+	}
+	path, err := exec.LookPath(config.Path)
+	if err != nil {
+		return nil, fmt.Errorf("tesseract executable not found: %w", err)
+	}
+	config.Path = path
+
+	return &Tesseract{config: config}, nil
+}
+
+// Recognize runs tesseract on an image and returns its text with the mean word confidence.
+// The image is passed on stdin, so any format tesseract's image library reads is accepted.
+func (t *Tesseract) Recognize(ctx context.Context, image []byte, mediaType string) (string, float64, error) {
+	log := logger.NewLoggerWithContext(ctx, "ocr-tesseract")
+	log.Start()
+	defer log.End()
+
+	if t.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.config.Timeout)
+		defer cancel()
+	}
+
+	// The tsv config writes one row per recognized block, paragraph, line and word
+	cmd := exec.CommandContext(ctx, t.config.Path, "stdin", "stdout", "-l", t.config.Languages, "tsv")
+	cmd.Stdin = bytes.NewReader(image)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	started := time.Now()
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", 0, fmt.Errorf("tesseract stopped: %w", context.Cause(ctx))
+		}
+		return "", 0, fmt.Errorf("tesseract failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	text, confidence := readTesseractTSV(stdout.String())
+	log.Info().
+		Str("media_type", mediaType).
+		Int("bytes", len(image)).
+		Int("text_length", len(text)).
+		Float64("confidence", confidence).
+		Dur("duration", time.Since(started)).
+		Msg("Image recognized")
+	return text, confidence, nil
+}
+
+// readTesseractTSV rebuilds the text from tesseract's TSV output, with a line per recognized
+// line and a blank line between paragraphs. The confidence is the mean of the words'
+// confidences, weighted by their length so stray marks count for little.
+func readTesseractTSV(tsv string) (string, float64) {
+	const (
+		colLevel = iota
+		colPage
+		colBlock
+		colParagraph
+		colLine
+		colWord
+		colLeft
+		colTop
+		colWidth
+		colHeight
+		colConfidence
+		colText
+		columnCount
+	)
+	const wordLevel = "5"
+
+	var (
+		sb             strings.Builder
+		lastParagraph  string
+		lastLine       string
+		weightedTotal  float64
+		weightedLength int
+	)
+	for i, row := range strings.Split(tsv, "\n") {
+		fields := strings.SplitN(strings.TrimRight(row, "\r"), "\t", columnCount)
+		if i == 0 || len(fields) < columnCount || fields[colLevel] != wordLevel {
+			continue
+		}
+		word := strings.TrimSpace(fields[colText])
+		confidence, err := strconv.ParseFloat(fields[colConfidence], 64)
+		if word == "" || err != nil || confidence < 0 {
+			continue
+		}
+
+		paragraph := fields[colPage] + "." + fields[colBlock] + "." + fields[colParagraph]
+		line := paragraph + "." + fields[colLine]
+		switch {
+		case sb.Len() == 0:
+		case paragraph != lastParagraph:
+			sb.WriteString("\n\n")
+		case line != lastLine:
+			sb.WriteString("\n")
+		default:
+			sb.WriteString(" ")
+		}
+		sb.WriteString(word)
+		lastParagraph, lastLine = paragraph, line
+
+		length := len([]rune(word))
+		weightedTotal += confidence * float64(length)
+		weightedLength += length
+	}
+
+	if weightedLength == 0 {
+		return "", 0
+	}
+	return sb.String(), weightedTotal / float64(weightedLength)
+}
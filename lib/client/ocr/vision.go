@@ -0,0 +1,75 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/png"
+
+	"golang.org/x/image/tiff"
+
+	"github.com/snowmerak/open-librarian/lib/client/llm"
+)
+
+const visionPrompt = `Transcribe all of the text in this image exactly as written, in reading order.
+Put each paragraph, heading and list item on its own line, with a blank line between paragraphs.
+Do not translate, correct, summarize or describe the image. If the image has no text, return an empty text.
+Rate from 0 to 100 how confident you are that the transcription is accurate.`
+
+var visionSchema = &llm.Schema{
+	Type: "object",
+	Properties: map[string]*llm.Schema{
+		"text":       {Type: "string", Description: "The transcribed text"},
+		"confidence": {Type: "number", Minimum: floatPtr(0), Maximum: floatPtr(100)},
+	},
+	Required: []string{"text", "confidence"},
+}
+
+func floatPtr(v float64) *float64 { return &v }
+
+// Vision recognizes text with a vision-capable model through the router's ocr task. Its
+// confidence is the model's own estimate, which is coarser than a dedicated engine's.
+type Vision struct {
+	router *llm.Router
+}
+
+// NewVision creates a Vision engine; route llm.TaskOCR to a model that accepts images
+func NewVision(router *llm.Router) *Vision {
+	return &Vision{router: router}
+}
+
+// Recognize sends an image to the model and returns its transcription
+func (v *Vision) Recognize(ctx context.Context, image []byte, mediaType string) (string, float64, error) {
+	image, mediaType, err := visionImage(image, mediaType)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var out struct {
+		Text       string  `json:"text"`
+		Confidence float64 `json:"confidence"`
+	}
+	images := []llm.Image{{MediaType: mediaType, Data: image}}
+	if err := v.router.GenerateJSONWithImages(ctx, llm.TaskOCR, visionPrompt, images, "ocr", visionSchema, &out); err != nil {
+		return "", 0, fmt.Errorf("failed to recognize text: %w", err)
+	}
+	return out.Text, out.Confidence, nil
+}
+
+// visionImage converts images that chat APIs do not accept to PNG. They take PNG, JPEG, GIF
+// and WebP.
+func visionImage(image []byte, mediaType string) ([]byte, string, error) {
+	if mediaType != "image/tiff" {
+		return image, mediaType, nil
+	}
+
+	img, err := tiff.Decode(bytes.NewReader(image))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode tiff: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", fmt.Errorf("failed to encode png: %w", err)
+	}
+	return buf.Bytes(), "image/png", nil
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -31,8 +32,22 @@ type Article struct {
 	Author      string    `json:"author,omitempty"`
 	CreatedDate time.Time `json:"created_date"`
 	Registrar   string    `json:"registrar,omitempty"`
+	TopicID     string    `json:"topic_id,omitempty"` // Topic cluster the article was last assigned to
+	File        *FileRef  `json:"file,omitempty"`     // Original uploaded file, if it was kept
 }
 
+// FileRef links an article to the original file it was parsed from, kept in blob storage
+type FileRef struct {
+	Key         string `json:"key"`          // Blob storage key
+	Filename    string `json:"filename"`     // Name the file was uploaded with
+	ContentType string `json:"content_type"` // MIME type of the detected format
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"` // Hex checksum of the file
+}
+
+// articleSourceFields are the stored fields returned for articles in search responses
+var articleSourceFields = []string{"title", "summary", "content", "original_url", "author", "lang", "tags", "created_date", "registrar", "topic_id", "file"}
+
 // SearchRequest represents a search query
 type SearchRequest struct {
 	Query string `json:"query"`
@@ -43,15 +58,17 @@ type SearchRequest struct {
 
 // SearchResult represents a single search result with score
 type SearchResult struct {
-	Article Article `json:"article"`
-	Score   float64 `json:"score"`
+	Article    Article     `json:"article"`
+	Score      float64     `json:"score"`
+	Highlights []Highlight `json:"highlights,omitempty"` // Passages that matched the query
 }
 
 // SearchResponse represents the search results
 type SearchResponse struct {
-	Total   int            `json:"total"`
-	Results []SearchResult `json:"results"`
-	Took    int            `json:"took"`
+	Total   int                      `json:"total"`
+	Results []SearchResult           `json:"results"`
+	Took    int                      `json:"took"`
+	Facets  map[string][]FacetBucket `json:"facets,omitempty"` // Counts per facet value, when requested
 }
 
 // IndexResponse represents the response from indexing an article
@@ -67,6 +84,9 @@ const (
 	DefaultBaseURL   = "http://localhost:9200"
 )
 
+// ErrArticleNotFound is returned when no article has the requested ID
+var ErrArticleNotFound = errors.New("article not found")
+
 // NewClient creates a new OpenSearch client
 func NewClient(baseURL string) *Client {
 	logger := logger.NewLogger("opensearch-client")
@@ -97,31 +117,7 @@ func (c *Client) IndexArticle(ctx context.Context, article *Article) (*IndexResp
 	logger.StartWithMsg("Indexing article in OpenSearch")
 	logger.Info().Str("article_id", article.ID).Str("title", article.Title).Msg("Article indexing request")
 
-	// Auto-detect language if not provided
-	if article.Lang == "" {
-		article.Lang = c.languageDetector.DetectLanguage(article.Title + " " + article.Summary)
-		logger.Info().Str("detected_language", article.Lang).Msg("Language auto-detected")
-	}
-
-	// Validate language code
-	if !c.languageDetector.ValidateLanguageCode(article.Lang) {
-		originalLang := article.Lang
-		article.Lang = "en" // Default to English for unsupported languages
-		logger.Warn().Str("original_lang", originalLang).Str("default_lang", article.Lang).Msg("Invalid language code, using default")
-	}
-
-	// Prepare the document for indexing
-	doc := map[string]interface{}{
-		"lang":         article.Lang,
-		"title":        article.Title,
-		"summary":      article.Summary,
-		"content":      article.Content,
-		"tags":         article.Tags,
-		"original_url": article.OriginalURL,
-		"author":       article.Author,
-		"created_date": article.CreatedDate,
-		"registrar":    article.Registrar,
-	}
+	doc := c.articleDocument(article, logger)
 
 	reqBody, err := json.Marshal(doc)
 	if err != nil {
@@ -174,23 +170,169 @@ func (c *Client) IndexArticle(ctx context.Context, article *Article) (*IndexResp
 	return &indexResp, nil
 }
 
+// articleDocument validates the article language and builds the document to index
+func (c *Client) articleDocument(article *Article, indexLogger *logger.Logger) map[string]interface{} {
+	// Auto-detect language if not provided
+	if article.Lang == "" {
+		article.Lang = c.languageDetector.DetectLanguage(article.Title + " " + article.Summary)
+		indexLogger.Info().Str("detected_language", article.Lang).Msg("Language auto-detected")
+	}
+
+	// Validate language code
+	if !c.languageDetector.ValidateLanguageCode(article.Lang) {
+		originalLang := article.Lang
+		article.Lang = "en" // Default to English for unsupported languages
+		indexLogger.Warn().Str("original_lang", originalLang).Str("default_lang", article.Lang).Msg("Invalid language code, using default")
+	}
+
+	// Prepare the document for indexing
+	document := map[string]interface{}{
+		"lang":         article.Lang,
+		"title":        article.Title,
+		"summary":      article.Summary,
+		"content":      article.Content,
+		"tags":         article.Tags,
+		"original_url": article.OriginalURL,
+		"author":       article.Author,
+		"created_date": article.CreatedDate,
+		"registrar":    article.Registrar,
+	}
+	if article.TopicID != "" {
+		document["topic_id"] = article.TopicID
+	}
+	if article.File != nil {
+		document["file"] = article.File
+	}
+	return document
+}
+
+// BulkIndexResult is the outcome of indexing one article in a bulk request
+type BulkIndexResult struct {
+	ID    string // Document ID, empty when indexing failed
+	Error string // Failure reason, empty on success
+}
+
+// BulkIndexArticles indexes several articles with a single _bulk request.
+// Results are in the same order as articles; a failed item does not fail the others.
+func (c *Client) BulkIndexArticles(ctx context.Context, articles []*Article) ([]BulkIndexResult, error) {
+	logger := logger.NewLogger("opensearch-bulk-index")
+	logger.StartWithMsg("Bulk indexing articles in OpenSearch")
+	logger.Info().Int("article_count", len(articles)).Msg("Bulk indexing request")
+
+	if len(articles) == 0 {
+		logger.EndWithMsg("No articles to index")
+		return nil, nil
+	}
+
+	// The bulk body is newline-delimited JSON: an action line followed by the document
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, article := range articles {
+		action := map[string]interface{}{"_index": DefaultIndexName}
+		if article.ID != "" {
+			action["_id"] = article.ID
+		}
+		if err := encoder.Encode(map[string]interface{}{"index": action}); err != nil {
+			logger.EndWithError(fmt.Errorf("failed to marshal bulk action: %w", err))
+			return nil, fmt.Errorf("failed to marshal bulk action: %w", err)
+		}
+		if err := encoder.Encode(c.articleDocument(article, logger)); err != nil {
+			logger.EndWithError(fmt.Errorf("failed to marshal document: %w", err))
+			return nil, fmt.Errorf("failed to marshal document: %w", err)
+		}
+	}
+
+	url := fmt.Sprintf("%s/_bulk", c.baseURL)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, &body)
+	if err != nil {
+		logger.EndWithError(fmt.Errorf("failed to create request: %w", err))
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		logger.EndWithError(fmt.Errorf("failed to send request: %w", err))
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		err := fmt.Errorf("bulk indexing failed with status %d: %s", resp.StatusCode, string(respBody))
+		logger.Error().Int("status_code", resp.StatusCode).Msg("Bulk indexing failed")
+		logger.EndWithError(err)
+		return nil, err
+	}
+
+	var bulkResp struct {
+		Errors bool `json:"errors"`
+		Items  []struct {
+			Index struct {
+				ID     string          `json:"_id"`
+				Status int             `json:"status"`
+				Error  json.RawMessage `json:"error,omitempty"`
+			} `json:"index"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&bulkResp); err != nil {
+		logger.EndWithError(fmt.Errorf("failed to decode response: %w", err))
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(bulkResp.Items) != len(articles) {
+		err := fmt.Errorf("bulk response has %d items for %d articles", len(bulkResp.Items), len(articles))
+		logger.EndWithError(err)
+		return nil, err
+	}
+
+	results := make([]BulkIndexResult, len(articles))
+	failed := 0
+	for i, item := range bulkResp.Items {
+		if item.Index.Status != http.StatusOK && item.Index.Status != http.StatusCreated {
+			results[i].Error = fmt.Sprintf("indexing failed with status %d: %s", item.Index.Status, string(item.Index.Error))
+			failed++
+			continue
+		}
+
+		results[i].ID = item.Index.ID
+		logger.DataCreated("article", item.Index.ID, map[string]interface{}{
+			"index":  DefaultIndexName,
+			"status": item.Index.Status,
+			"lang":   articles[i].Lang,
+		})
+	}
+
+	logger.Info().Int("indexed", len(articles)-failed).Int("failed", failed).Msg("Bulk indexing finished")
+	logger.EndWithMsg("Bulk indexing complete")
+	return results, nil
+}
+
 // KeywordSearch performs traditional keyword-based search
 func (c *Client) KeywordSearch(ctx context.Context, query, lang string, size, from int) (*SearchResponse, error) {
+	return c.Search(ctx, KeywordSearchOptions{Query: query, Lang: lang, Size: size, From: from})
+}
+
+// Search performs a keyword search with filters, sorting and optional facet counts
+func (c *Client) Search(ctx context.Context, opts KeywordSearchOptions) (*SearchResponse, error) {
 	searchLogger := logger.NewLogger("opensearch-keyword-search")
 	searchLogger.StartWithMsg("Starting OpenSearch keyword search")
 
-	if size == 0 {
-		size = 10
+	if opts.Size == 0 {
+		opts.Size = 10
 	}
 
 	searchLogger.Info().
-		Str("query", query).
-		Str("language", lang).
-		Int("size", size).
-		Int("from", from).
+		Str("query", opts.Query).
+		Str("language", opts.Lang).
+		Str("sort", opts.Sort).
+		Bool("facets", opts.Facets).
+		Int("size", opts.Size).
+		Int("from", opts.From).
 		Msg("Start keyword search")
 
-	searchQuery := c.buildKeywordQuery(query, lang, size, from)
+	searchQuery := c.buildKeywordQuery(opts)
 
 	// Log the search query
 	queryJSON, _ := json.MarshalIndent(searchQuery, "", "  ")
@@ -246,11 +388,13 @@ func (c *Client) KeywordSearch(ctx context.Context, query, lang string, size, fr
 				Value int `json:"value"`
 			} `json:"total"`
 			Hits []struct {
-				ID     string  `json:"_id"`
-				Score  float64 `json:"_score"`
-				Source Article `json:"_source"`
+				ID        string              `json:"_id"`
+				Score     float64             `json:"_score"`
+				Source    Article             `json:"_source"`
+				Highlight map[string][]string `json:"highlight"`
 			} `json:"hits"`
 		} `json:"hits"`
+		Aggregations map[string]aggregationResult `json:"aggregations"`
 	}
 
 	if err := json.Unmarshal(responseBody, &esResp); err != nil {
@@ -271,6 +415,7 @@ func (c *Client) KeywordSearch(ctx context.Context, query, lang string, size, fr
 			Score:   hit.Score,
 		}
 		results[i].Article.ID = hit.ID
+		results[i].Highlights = parseHighlights(&hit.Source, hit.Highlight)
 		searchLogger.Debug().
 			Int("result_index", i+1).
 			Str("article_id", hit.ID).
@@ -283,6 +428,7 @@ func (c *Client) KeywordSearch(ctx context.Context, query, lang string, size, fr
 		Total:   esResp.Hits.Total.Value,
 		Results: results,
 		Took:    esResp.Took,
+		Facets:  parseFacets(esResp.Aggregations),
 	}
 
 	searchLogger.EndWithMsg("OpenSearch keyword search completed successfully")
@@ -306,6 +452,10 @@ func (c *Client) CreateIndexWithMapping(ctx context.Context) error {
 				"title": map[string]interface{}{
 					"type": "text",
 					"fields": map[string]interface{}{
+						"keyword": map[string]interface{}{
+							"type":         "keyword",
+							"ignore_above": 256,
+						},
 						"ko": map[string]interface{}{
 							"type":     "text",
 							"analyzer": "korean",
@@ -370,6 +520,18 @@ func (c *Client) CreateIndexWithMapping(ctx context.Context) error {
 				"registrar": map[string]interface{}{
 					"type": "keyword",
 				},
+				"topic_id": map[string]interface{}{
+					"type": "keyword",
+				},
+				"file": map[string]interface{}{
+					"properties": map[string]interface{}{
+						"key":          map[string]interface{}{"type": "keyword", "index": false},
+						"filename":     map[string]interface{}{"type": "keyword", "index": false},
+						"content_type": map[string]interface{}{"type": "keyword"},
+						"size":         map[string]interface{}{"type": "long", "index": false},
+						"sha256":       map[string]interface{}{"type": "keyword"},
+					},
+				},
 			},
 		},
 	}
@@ -401,67 +563,6 @@ func (c *Client) CreateIndexWithMapping(ctx context.Context) error {
 	return nil
 }
 
-// buildKeywordQuery builds a keyword-only search query using simple_query_string
-func (c *Client) buildKeywordQuery(queryText, lang string, size, from int) map[string]interface{} {
-	query := map[string]interface{}{
-		"size":    size,
-		"from":    from,
-		"_source": []string{"title", "summary", "content", "original_url", "author", "lang", "tags", "created_date"},
-	}
-
-	// Use simple_query_string for natural language search across all fields
-	var fields []string
-	if lang != "" {
-		// Language-specific fields with boost, plus fallback to default fields
-		fields = []string{
-			"title^4", // Always include default fields first
-			"summary^2",
-			"content",
-			"tags^2",
-			"author",
-			fmt.Sprintf("title.%s^5", lang), // Higher boost for language-specific
-			fmt.Sprintf("summary.%s^3", lang),
-			fmt.Sprintf("content.%s^1.5", lang),
-		}
-	} else {
-		// Default fields for all languages
-		fields = []string{
-			"title^4",
-			"summary^2",
-			"content",
-			"tags^2",
-			"author",
-		}
-	}
-
-	query["query"] = map[string]interface{}{
-		"simple_query_string": map[string]interface{}{
-			"query":            queryText,
-			"fields":           fields,
-			"default_operator": "or", // Changed from "and" to "or" for better recall
-			"flags":            "ALL",
-			"analyze_wildcard": true,
-			"lenient":          true,
-		},
-	}
-
-	// Add highlighting
-	query["highlight"] = map[string]interface{}{
-		"fields": map[string]interface{}{
-			"title":   map[string]interface{}{},
-			"summary": map[string]interface{}{},
-			"content": map[string]interface{}{
-				"fragment_size":       150,
-				"number_of_fragments": 3,
-			},
-		},
-		"pre_tags":  []string{"<mark>"},
-		"post_tags": []string{"</mark>"},
-	}
-
-	return query
-}
-
 // GetArticle retrieves an article by ID
 func (c *Client) GetArticle(ctx context.Context, id string) (*Article, error) {
 	url := fmt.Sprintf("%s/%s/_doc/%s", c.baseURL, DefaultIndexName, id)
@@ -477,7 +578,7 @@ func (c *Client) GetArticle(ctx context.Context, id string) (*Article, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("article not found")
+		return nil, ErrArticleNotFound
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -599,6 +700,78 @@ func (c *Client) GetArticlesByIDs(ctx context.Context, articleIDs []string) ([]A
 	return articles, nil
 }
 
+// maxFileReferences is the number of articles checked for links to one stored file
+const maxFileReferences = 10000
+
+// FileInUse reports whether an article other than excludeID links to the stored file. The file
+// key is not indexed, so articles are found by the file's checksum and then compared by key;
+// when more articles share the checksum than can be checked, the file is reported in use.
+func (c *Client) FileInUse(ctx context.Context, file *FileRef, excludeID string) (bool, error) {
+	query := map[string]interface{}{
+		"size":             maxFileReferences,
+		"track_total_hits": true,
+		"_source":          []string{"file.key"},
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []map[string]interface{}{
+					{"term": map[string]interface{}{"file.sha256": file.SHA256}},
+				},
+				// A deleted article can still be found until the index refreshes
+				"must_not": []map[string]interface{}{
+					{"ids": map[string]interface{}{"values": []string{excludeID}}},
+				},
+			},
+		},
+	}
+
+	reqBody, err := json.Marshal(query)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/%s/_search", c.baseURL, DefaultIndexName)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return false, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return false, fmt.Errorf("search failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var esResp struct {
+		Hits struct {
+			Total struct {
+				Value int `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				Source struct {
+					File *FileRef `json:"file"`
+				} `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&esResp); err != nil {
+		return false, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	for _, hit := range esResp.Hits.Hits {
+		if hit.Source.File != nil && hit.Source.File.Key == file.Key {
+			return true, nil
+		}
+	}
+	return esResp.Hits.Total.Value > len(esResp.Hits.Hits), nil
+}
+
 // HealthCheck checks if OpenSearch is accessible
 func (c *Client) HealthCheck(ctx context.Context) error {
 	httpReq, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/_cluster/health", nil)
@@ -677,7 +850,7 @@ func (c *Client) GetUserArticlesByDateRange(ctx context.Context, username, dateF
 	query := map[string]interface{}{
 		"size":    size,
 		"from":    from,
-		"_source": []string{"title", "summary", "content", "original_url", "author", "lang", "tags", "created_date", "registrar"},
+		"_source": articleSourceFields,
 		"query": map[string]interface{}{
 			"bool": map[string]interface{}{
 				"must": []map[string]interface{}{
@@ -0,0 +1,147 @@
+package opensearch
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Highlight markers are private-use characters so they cannot collide with markup in article text
+const (
+	highlightPreTag  = '\ue000'
+	highlightPostTag = '\ue001'
+)
+
+// highlightedFields are the article fields highlighted in keyword search, in display order
+var highlightedFields = []string{"title", "summary", "content"}
+
+// Span is a range of characters, counted in Unicode code points
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"` // Exclusive
+}
+
+// Highlight is a passage of an article field that matched the query
+type Highlight struct {
+	Field    string `json:"field"`             // "title", "summary" or "content"
+	Fragment string `json:"fragment"`          // Passage text without markup
+	Start    int    `json:"start"`             // Offset of the passage in the field, -1 if it could not be located
+	End      int    `json:"end"`               // Exclusive end of the passage in the field, -1 if it could not be located
+	Matches  []Span `json:"matches,omitempty"` // Matched terms, relative to Fragment
+}
+
+// buildHighlight returns the highlight clause for the article fields and their language subfields
+func buildHighlight(lang string) map[string]interface{} {
+	fields := make(map[string]interface{})
+	for _, field := range highlightedFields {
+		options := map[string]interface{}{
+			// Titles and summaries are short, so they are returned whole
+			"number_of_fragments": 0,
+		}
+		if field == "content" {
+			options = map[string]interface{}{
+				"fragment_size":       150,
+				"number_of_fragments": 3,
+			}
+		}
+
+		fields[field] = options
+		if lang != "" {
+			fields[field+"."+lang] = options
+		}
+	}
+
+	return map[string]interface{}{
+		"fields":    fields,
+		"order":     "score",
+		"pre_tags":  []string{string(highlightPreTag)},
+		"post_tags": []string{string(highlightPostTag)},
+	}
+}
+
+// parseHighlights converts the highlight section of a hit into passages with offsets into the article.
+// Fragments of a field and its language subfield are merged, keeping the one with more matched terms.
+func parseHighlights(article *Article, raw map[string][]string) []Highlight {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var highlights []Highlight
+	for _, field := range highlightedFields {
+		source := articleField(article, field)
+
+		var fieldHighlights []Highlight
+		seen := make(map[string]int)
+		for _, name := range highlightNames(raw, field) {
+			for _, fragment := range raw[name] {
+				highlight := parseFragment(field, fragment, source)
+				if i, ok := seen[highlight.Fragment]; ok {
+					if len(highlight.Matches) > len(fieldHighlights[i].Matches) {
+						fieldHighlights[i] = highlight
+					}
+					continue
+				}
+				seen[highlight.Fragment] = len(fieldHighlights)
+				fieldHighlights = append(fieldHighlights, highlight)
+			}
+		}
+		highlights = append(highlights, fieldHighlights...)
+	}
+
+	return highlights
+}
+
+// highlightNames returns the highlighted names of a field and its subfields, the field itself first
+func highlightNames(raw map[string][]string, field string) []string {
+	var names []string
+	for name := range raw {
+		if name == field || strings.HasPrefix(name, field+".") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseFragment strips the highlight markers from a fragment and locates it in the field text
+func parseFragment(field, fragment, source string) Highlight {
+	highlight := Highlight{Field: field, Start: -1, End: -1}
+
+	var plain strings.Builder
+	offset := 0
+	matchStart := -1
+	for _, r := range fragment {
+		switch r {
+		case highlightPreTag:
+			matchStart = offset
+		case highlightPostTag:
+			if matchStart >= 0 {
+				highlight.Matches = append(highlight.Matches, Span{Start: matchStart, End: offset})
+				matchStart = -1
+			}
+		default:
+			plain.WriteRune(r)
+			offset++
+		}
+	}
+	highlight.Fragment = plain.String()
+
+	if index := strings.Index(source, highlight.Fragment); index >= 0 {
+		highlight.Start = utf8.RuneCountInString(source[:index])
+		highlight.End = highlight.Start + offset
+	}
+
+	return highlight
+}
+
+// articleField returns the text of a highlighted article field
+func articleField(article *Article, field string) string {
+	switch field {
+	case "title":
+		return article.Title
+	case "summary":
+		return article.Summary
+	default:
+		return article.Content
+	}
+}
@@ -0,0 +1,74 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// moreLikeThisFields are the article fields compared by MoreLikeThis
+var moreLikeThisFields = []string{"title", "summary", "content", "tags"}
+
+// MoreLikeThis returns the articles whose text shares the most distinctive terms with an article.
+// The article itself is not included.
+func (c *Client) MoreLikeThis(ctx context.Context, id string, size int) ([]SearchResult, error) {
+	query := map[string]interface{}{
+		"size":    size,
+		"_source": articleSourceFields,
+		"query": map[string]interface{}{
+			"more_like_this": map[string]interface{}{
+				"fields":          moreLikeThisFields,
+				"like":            []interface{}{map[string]interface{}{"_index": DefaultIndexName, "_id": id}},
+				"min_term_freq":   1,
+				"min_doc_freq":    2,
+				"max_query_terms": 25,
+			},
+		},
+	}
+
+	reqBody, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/%s/_search", c.baseURL, DefaultIndexName)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("more like this search failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var esResp struct {
+		Hits struct {
+			Hits []struct {
+				ID     string  `json:"_id"`
+				Score  float64 `json:"_score"`
+				Source Article `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&esResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	results := make([]SearchResult, len(esResp.Hits.Hits))
+	for i, hit := range esResp.Hits.Hits {
+		results[i] = SearchResult{Article: hit.Source, Score: hit.Score}
+		results[i].Article.ID = hit.ID
+	}
+	return results, nil
+}
@@ -0,0 +1,295 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Sort orders for keyword search
+const (
+	SortRelevance = "relevance"
+	SortDate      = "date"
+	SortTitle     = "title"
+)
+
+// Sort directions
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// Facet names returned in SearchResponse.Facets
+const (
+	FacetAuthor      = "author"
+	FacetRegistrar   = "registrar"
+	FacetTags        = "tags"
+	FacetLang        = "lang"
+	FacetCreatedDate = "created_date" // Monthly buckets keyed "yyyy-MM"
+	FacetTopic       = "topic"        // Topic IDs
+)
+
+// facetSize is the number of buckets returned per terms facet
+const facetSize = 20
+
+// SearchFilters restricts keyword search to matching articles.
+// Empty fields do not filter; values within a field are alternatives except for tags.
+type SearchFilters struct {
+	Authors     []string
+	Registrars  []string
+	Tags        []string // Articles must have every tag
+	Langs       []string // Article languages
+	Topics      []string // Topic IDs
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time // Inclusive
+}
+
+// KeywordSearchOptions holds the parameters of a keyword search
+type KeywordSearchOptions struct {
+	Query   string // Free text; empty matches every article
+	Lang    string // Query language, whose analyzed subfields are boosted
+	Filters SearchFilters
+	Sort    string // SortRelevance (default with a query), SortDate (default without one) or SortTitle
+	Order   string // OrderAsc or OrderDesc; defaults to descending for relevance and date, ascending for title
+	Facets  bool   // Return facet counts over all matching articles
+	Size    int
+	From    int
+}
+
+// FacetBucket is the number of matching articles with a facet value
+type FacetBucket struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// aggregationResult is the bucket list of a terms or date_histogram aggregation
+type aggregationResult struct {
+	Buckets []struct {
+		Key         interface{} `json:"key"`
+		KeyAsString string      `json:"key_as_string"`
+		DocCount    int         `json:"doc_count"`
+	} `json:"buckets"`
+}
+
+// buildKeywordQuery builds a keyword search query using simple_query_string with filters in bool.filter
+func (c *Client) buildKeywordQuery(opts KeywordSearchOptions) map[string]interface{} {
+	query := map[string]interface{}{
+		"size":    opts.Size,
+		"from":    opts.From,
+		"_source": articleSourceFields,
+	}
+
+	var must interface{} = map[string]interface{}{"match_all": map[string]interface{}{}}
+	if opts.Query != "" {
+		must = map[string]interface{}{
+			"simple_query_string": map[string]interface{}{
+				"query":            opts.Query,
+				"fields":           keywordFields(opts.Lang),
+				"default_operator": "or", // Changed from "and" to "or" for better recall
+				"flags":            "ALL",
+				"analyze_wildcard": true,
+				"lenient":          true,
+			},
+		}
+
+		// Add highlighting
+		query["highlight"] = buildHighlight(opts.Lang)
+	}
+
+	query["query"] = map[string]interface{}{
+		"bool": map[string]interface{}{
+			"must":   must,
+			"filter": buildFilters(opts.Filters),
+		},
+	}
+
+	query["sort"] = buildSort(opts)
+
+	if opts.Facets {
+		query["aggs"] = map[string]interface{}{
+			FacetAuthor:    termsAggregation("author"),
+			FacetRegistrar: termsAggregation("registrar"),
+			FacetTags:      termsAggregation("tags"),
+			FacetLang:      termsAggregation("lang"),
+			FacetTopic:     termsAggregation("topic_id"),
+			FacetCreatedDate: map[string]interface{}{
+				"date_histogram": map[string]interface{}{
+					"field":             "created_date",
+					"calendar_interval": "month",
+					"format":            "yyyy-MM",
+					"min_doc_count":     1,
+				},
+			},
+		}
+	}
+
+	return query
+}
+
+// keywordFields returns the searched fields with their boosts
+func keywordFields(lang string) []string {
+	// Default fields for all languages
+	fields := []string{
+		"title^4",
+		"summary^2",
+		"content",
+		"tags^2",
+		"author",
+	}
+	if lang != "" {
+		// Higher boost for language-specific fields
+		fields = append(fields,
+			fmt.Sprintf("title.%s^5", lang),
+			fmt.Sprintf("summary.%s^3", lang),
+			fmt.Sprintf("content.%s^1.5", lang),
+		)
+	}
+	return fields
+}
+
+// buildFilters converts search filters into bool.filter clauses.
+// Filter clauses do not affect scoring and are cached by OpenSearch.
+func buildFilters(filters SearchFilters) []interface{} {
+	clauses := []interface{}{}
+
+	if len(filters.Authors) > 0 {
+		clauses = append(clauses, map[string]interface{}{"terms": map[string]interface{}{"author": filters.Authors}})
+	}
+	if len(filters.Registrars) > 0 {
+		clauses = append(clauses, map[string]interface{}{"terms": map[string]interface{}{"registrar": filters.Registrars}})
+	}
+	if len(filters.Langs) > 0 {
+		clauses = append(clauses, map[string]interface{}{"terms": map[string]interface{}{"lang": filters.Langs}})
+	}
+	if len(filters.Topics) > 0 {
+		clauses = append(clauses, map[string]interface{}{"terms": map[string]interface{}{"topic_id": filters.Topics}})
+	}
+	for _, tag := range filters.Tags {
+		clauses = append(clauses, map[string]interface{}{"term": map[string]interface{}{"tags": tag}})
+	}
+
+	if filters.CreatedFrom != nil || filters.CreatedTo != nil {
+		dateRange := map[string]interface{}{}
+		if filters.CreatedFrom != nil {
+			dateRange["gte"] = filters.CreatedFrom.Format(time.RFC3339Nano)
+		}
+		if filters.CreatedTo != nil {
+			dateRange["lte"] = filters.CreatedTo.Format(time.RFC3339Nano)
+		}
+		clauses = append(clauses, map[string]interface{}{"range": map[string]interface{}{"created_date": dateRange}})
+	}
+
+	return clauses
+}
+
+// buildSort returns the sort clause for the requested order, breaking ties by newest first
+func buildSort(opts KeywordSearchOptions) []interface{} {
+	sortBy := opts.Sort
+	if sortBy == "" {
+		sortBy = SortRelevance
+		if opts.Query == "" {
+			sortBy = SortDate
+		}
+	}
+
+	order := opts.Order
+	if order == "" {
+		order = OrderDesc
+		if sortBy == SortTitle {
+			order = OrderAsc
+		}
+	}
+
+	var clauses []interface{}
+	switch sortBy {
+	case SortDate:
+		clauses = append(clauses, map[string]interface{}{"created_date": map[string]interface{}{"order": order}})
+	case SortTitle:
+		// Indices created before the keyword subfield existed sort these documents last instead of failing
+		clauses = append(clauses, map[string]interface{}{"title.keyword": map[string]interface{}{"order": order, "unmapped_type": "keyword"}})
+	default:
+		clauses = append(clauses, map[string]interface{}{"_score": map[string]interface{}{"order": order}})
+	}
+
+	if sortBy != SortDate {
+		clauses = append(clauses, map[string]interface{}{"created_date": map[string]interface{}{"order": OrderDesc}})
+	}
+	return clauses
+}
+
+// termsAggregation counts the most common values of a keyword field
+func termsAggregation(field string) map[string]interface{} {
+	return map[string]interface{}{
+		"terms": map[string]interface{}{
+			"field": field,
+			"size":  facetSize,
+		},
+	}
+}
+
+// parseFacets converts aggregation buckets into facet counts
+func parseFacets(aggregations map[string]aggregationResult) map[string][]FacetBucket {
+	if len(aggregations) == 0 {
+		return nil
+	}
+
+	facets := make(map[string][]FacetBucket, len(aggregations))
+	for name, aggregation := range aggregations {
+		buckets := make([]FacetBucket, 0, len(aggregation.Buckets))
+		for _, bucket := range aggregation.Buckets {
+			value := bucket.KeyAsString
+			if value == "" {
+				value = fmt.Sprint(bucket.Key)
+			}
+			buckets = append(buckets, FacetBucket{Value: value, Count: bucket.DocCount})
+		}
+		facets[name] = buckets
+	}
+	return facets
+}
+
+// LanguageDistribution returns the number of articles per language, most common first
+func (c *Client) LanguageDistribution(ctx context.Context) ([]FacetBucket, error) {
+	query := map[string]interface{}{
+		"size": 0,
+		"aggs": map[string]interface{}{
+			FacetLang: termsAggregation("lang"),
+		},
+	}
+
+	reqBody, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/%s/_search", c.baseURL, DefaultIndexName)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("language aggregation failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var esResp struct {
+		Aggregations map[string]aggregationResult `json:"aggregations"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&esResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return parseFacets(esResp.Aggregations)[FacetLang], nil
+}
@@ -0,0 +1,197 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+)
+
+// topicBulkSize is the number of article updates sent per _bulk request
+const topicBulkSize = 500
+
+// SetArticleTopics stores the topic of each article, keyed by article ID.
+// Articles that no longer exist are skipped; it returns the number of articles updated.
+func (c *Client) SetArticleTopics(ctx context.Context, topics map[string]string) (int, error) {
+	topicLogger := logger.NewLogger("opensearch-article-topics")
+	topicLogger.StartWithMsg("Storing article topics")
+	topicLogger.Info().Int("article_count", len(topics)).Msg("Article topic update request")
+
+	// Indices created before topics existed would otherwise map topic_id as analyzed text
+	if err := c.ensureTopicMapping(ctx); err != nil {
+		topicLogger.EndWithError(err)
+		return 0, err
+	}
+
+	ids := make([]string, 0, len(topics))
+	for id := range topics {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	updated := 0
+	for start := 0; start < len(ids); start += topicBulkSize {
+		chunk := ids[start:min(start+topicBulkSize, len(ids))]
+		count, err := c.bulkUpdateTopics(ctx, chunk, topics)
+		if err != nil {
+			topicLogger.EndWithError(err)
+			return updated, err
+		}
+		updated += count
+	}
+
+	topicLogger.Info().Int("updated", updated).Int("skipped", len(ids)-updated).Msg("Article topics stored")
+	topicLogger.EndWithMsg("Article topics complete")
+	return updated, nil
+}
+
+// ensureTopicMapping adds the topic_id keyword field to the index mapping
+func (c *Client) ensureTopicMapping(ctx context.Context) error {
+	mapping := map[string]interface{}{
+		"properties": map[string]interface{}{
+			"topic_id": map[string]interface{}{"type": "keyword"},
+		},
+	}
+
+	reqBody, err := json.Marshal(mapping)
+	if err != nil {
+		return fmt.Errorf("failed to marshal mapping: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/%s/_mapping", c.baseURL, DefaultIndexName)
+	httpReq, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(reqBody))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("topic mapping update failed with status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// bulkUpdateTopics sets topic_id on a batch of articles with a single _bulk request
+func (c *Client) bulkUpdateTopics(ctx context.Context, ids []string, topics map[string]string) (int, error) {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, id := range ids {
+		action := map[string]interface{}{"_index": DefaultIndexName, "_id": id, "retry_on_conflict": 3}
+		if err := encoder.Encode(map[string]interface{}{"update": action}); err != nil {
+			return 0, fmt.Errorf("failed to marshal bulk action: %w", err)
+		}
+		if err := encoder.Encode(map[string]interface{}{"doc": map[string]interface{}{"topic_id": topics[id]}}); err != nil {
+			return 0, fmt.Errorf("failed to marshal document: %w", err)
+		}
+	}
+
+	url := fmt.Sprintf("%s/_bulk", c.baseURL)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, &body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf("bulk topic update failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var bulkResp struct {
+		Items []struct {
+			Update struct {
+				Status int `json:"status"`
+			} `json:"update"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&bulkResp); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	updated := 0
+	for _, item := range bulkResp.Items {
+		if item.Update.Status == http.StatusOK {
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+// ArticlesInTopics returns the subset of ids whose articles belong to one of the topics.
+// Vector hits carry only article IDs, so this filters them the way SearchFilters.Topics filters keyword search.
+func (c *Client) ArticlesInTopics(ctx context.Context, ids []string, topics []string) (map[string]bool, error) {
+	in := make(map[string]bool)
+	if len(ids) == 0 || len(topics) == 0 {
+		return in, nil
+	}
+
+	query := map[string]interface{}{
+		"size":    len(ids),
+		"_source": false,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					map[string]interface{}{"ids": map[string]interface{}{"values": ids}},
+					map[string]interface{}{"terms": map[string]interface{}{"topic_id": topics}},
+				},
+			},
+		},
+	}
+
+	reqBody, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/%s/_search", c.baseURL, DefaultIndexName)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("topic filter failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var esResp struct {
+		Hits struct {
+			Hits []struct {
+				ID string `json:"_id"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&esResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	for _, hit := range esResp.Hits.Hits {
+		in[hit.ID] = true
+	}
+	return in, nil
+}
@@ -19,6 +19,7 @@ type Client struct {
 type VectorSearchResult struct {
 	ID    string  `json:"id"`
 	Score float64 `json:"score"`
+	Lang  string  `json:"lang,omitempty"` // Language of the article the point belongs to
 }
 
 const (
@@ -88,30 +89,79 @@ func (c *Client) CreateCollection(ctx context.Context) error {
 	return nil
 }
 
+// Point is a vector to store, identified by the OpenSearch document it belongs to
+type Point struct {
+	ID     string
+	Vector []float64
+	Lang   string
+}
+
 // UpsertPoint inserts or updates a point in the collection
 func (c *Client) UpsertPoint(ctx context.Context, pointID string, vector []float64, lang string) error {
-	upsertLogger := logger.NewLogger("qdrant-upsert-point")
-	upsertLogger.StartWithMsg("Upserting point to Qdrant")
-	upsertLogger.Info().Str("point_id", pointID).Str("lang", lang).Int("vector_dim", len(vector)).Msg("Upsert point request")
+	return c.UpsertPoints(ctx, []Point{{ID: pointID, Vector: vector, Lang: lang}})
+}
+
+// UpsertPoints inserts or updates several points in a single request
+func (c *Client) UpsertPoints(ctx context.Context, points []Point) error {
+	upsertLogger := logger.NewLogger("qdrant-upsert-points")
+	upsertLogger.StartWithMsg("Upserting points to Qdrant")
+	upsertLogger.Info().Int("point_count", len(points)).Msg("Upsert points request")
+
+	if len(points) == 0 {
+		upsertLogger.EndWithMsg("No points to upsert")
+		return nil
+	}
+
+	structs := make([]*qdrant.PointStruct, 0, len(points))
+	for _, p := range points {
+		point, err := c.newPointStruct(p)
+		if err != nil {
+			upsertLogger.EndWithError(err)
+			return err
+		}
+		structs = append(structs, point)
+	}
+
+	_, err := c.client.Upsert(ctx, &qdrant.UpsertPoints{
+		CollectionName: c.collectionName,
+		Points:         structs,
+	})
 
+	if err != nil {
+		upsertLogger.EndWithError(fmt.Errorf("failed to upsert points: %w", err))
+		return fmt.Errorf("failed to upsert points: %w", err)
+	}
+
+	for _, p := range points {
+		upsertLogger.DataCreated("vector_point", p.ID, map[string]interface{}{
+			"numeric_id": c.stringToNumericID(p.ID),
+			"language":   p.Lang,
+			"vector_dim": len(p.Vector),
+			"collection": c.collectionName,
+		})
+	}
+	upsertLogger.EndWithMsg("Points upserted successfully")
+	return nil
+}
+
+// newPointStruct converts a point to its Qdrant representation
+func (c *Client) newPointStruct(p Point) (*qdrant.PointStruct, error) {
 	// Convert float64 to float32 for Qdrant
-	vector32 := make([]float32, len(vector))
-	for i, v := range vector {
+	vector32 := make([]float32, len(p.Vector))
+	for i, v := range p.Vector {
 		vector32[i] = float32(v)
 	}
 
 	// Create minimal payload with only language for filtering
-	langValue, err := qdrant.NewValue(lang)
+	langValue, err := qdrant.NewValue(p.Lang)
 	if err != nil {
-		upsertLogger.EndWithError(fmt.Errorf("failed to create language value: %w", err))
-		return fmt.Errorf("failed to create language value: %w", err)
+		return nil, fmt.Errorf("failed to create language value: %w", err)
 	}
 
 	// Also store the original OpenSearch ID in payload for mapping
-	idValue, err := qdrant.NewValue(pointID)
+	idValue, err := qdrant.NewValue(p.ID)
 	if err != nil {
-		upsertLogger.EndWithError(fmt.Errorf("failed to create id value: %w", err))
-		return fmt.Errorf("failed to create id value: %w", err)
+		return nil, fmt.Errorf("failed to create id value: %w", err)
 	}
 
 	payload := map[string]*qdrant.Value{
@@ -120,37 +170,15 @@ func (c *Client) UpsertPoint(ctx context.Context, pointID string, vector []float
 	}
 
 	// Convert string ID to numeric ID using hash
-	numericID := c.stringToNumericID(pointID)
-	upsertLogger.Info().Uint64("numeric_id", numericID).Msg("Generated numeric ID")
-
-	point := &qdrant.PointStruct{
-		Id:      qdrant.NewIDNum(numericID),
+	return &qdrant.PointStruct{
+		Id:      qdrant.NewIDNum(c.stringToNumericID(p.ID)),
 		Vectors: qdrant.NewVectorsDense(vector32),
 		Payload: payload,
-	}
-
-	_, err = c.client.Upsert(ctx, &qdrant.UpsertPoints{
-		CollectionName: c.collectionName,
-		Points:         []*qdrant.PointStruct{point},
-	})
-
-	if err != nil {
-		upsertLogger.EndWithError(fmt.Errorf("failed to upsert point: %w", err))
-		return fmt.Errorf("failed to upsert point: %w", err)
-	}
-
-	upsertLogger.DataCreated("vector_point", pointID, map[string]interface{}{
-		"numeric_id": numericID,
-		"language":   lang,
-		"vector_dim": len(vector),
-		"collection": c.collectionName,
-	})
-	upsertLogger.EndWithMsg("Point upserted successfully")
-	return nil
+	}, nil
 }
 
 // VectorSearch performs vector similarity search and returns IDs with scores
-func (c *Client) VectorSearch(ctx context.Context, queryVector []float64, limit uint64, lang string) ([]VectorSearchResult, error) {
+func (c *Client) VectorSearch(ctx context.Context, queryVector []float64, limit uint64) ([]VectorSearchResult, error) {
 	searchLogger := logger.NewLogger("qdrant-vector-search")
 	searchLogger.StartWithMsg("Performing vector similarity search")
 
@@ -163,7 +191,6 @@ func (c *Client) VectorSearch(ctx context.Context, queryVector []float64, limit
 	searchLogger.Info().
 		Int("query_vector_dim", len(queryVector)).
 		Uint64("limit", limit).
-		Str("lang", lang).
 		Msg("Starting vector search")
 
 	// Convert float64 to float32 for Qdrant
@@ -179,19 +206,6 @@ func (c *Client) VectorSearch(ctx context.Context, queryVector []float64, limit
 		WithPayload:    qdrant.NewWithPayload(true), // Include payload to get original ID
 	}
 
-	// Add language filter if specified
-	// Temporarily disable language filtering for debugging
-	if false && lang != "" {
-		queryRequest.Filter = &qdrant.Filter{
-			Must: []*qdrant.Condition{
-				qdrant.NewMatch("lang", lang),
-			},
-		}
-		searchLogger.Debug().Str("language_filter", lang).Msg("Added language filter")
-	} else {
-		searchLogger.Debug().Msg("No language filter applied (debugging: language filtering disabled)")
-	}
-
 	searchResult, err := c.client.Query(ctx, queryRequest)
 	if err != nil {
 		searchLogger.EndWithError(fmt.Errorf("failed to search vectors: %w", err))
@@ -202,39 +216,167 @@ func (c *Client) VectorSearch(ctx context.Context, queryVector []float64, limit
 
 	results := make([]VectorSearchResult, 0, len(searchResult))
 	for i, hit := range searchResult {
-		// Try to get original OpenSearch ID from payload first
-		var id string
-		if hit.Payload != nil && hit.Payload["opensearch_id"] != nil {
-			if stringVal := hit.Payload["opensearch_id"].GetStringValue(); stringVal != "" {
-				id = stringVal
+		result := vectorSearchResult(hit)
+		results = append(results, result)
+
+		searchLogger.Debug().
+			Int("result_index", i+1).
+			Str("id", result.ID).
+			Float64("score", result.Score).
+			Msg("Vector search result")
+	}
+
+	searchLogger.EndWithMsg("Vector search completed")
+	return results, nil
+}
+
+// Recommend returns the points most similar to the stored vector of pointID.
+// The point itself and the points in excludeIDs are left out of the results.
+func (c *Client) Recommend(ctx context.Context, pointID string, excludeIDs []string, limit uint64) ([]VectorSearchResult, error) {
+	recommendLogger := logger.NewLogger("qdrant-recommend")
+	recommendLogger.StartWithMsg("Recommending similar points")
+	recommendLogger.Info().Str("point_id", pointID).Uint64("limit", limit).Msg("Starting recommendation")
+
+	excluded := make([]*qdrant.PointId, 0, len(excludeIDs))
+	for _, id := range excludeIDs {
+		excluded = append(excluded, qdrant.NewIDNum(c.stringToNumericID(id)))
+	}
+
+	queryRequest := &qdrant.QueryPoints{
+		CollectionName: c.collectionName,
+		Query: qdrant.NewQueryRecommend(&qdrant.RecommendInput{
+			Positive: []*qdrant.VectorInput{qdrant.NewVectorInputID(qdrant.NewIDNum(c.stringToNumericID(pointID)))},
+		}),
+		Limit:       &limit,
+		WithPayload: qdrant.NewWithPayload(true),
+	}
+	if len(excluded) > 0 {
+		queryRequest.Filter = &qdrant.Filter{MustNot: []*qdrant.Condition{qdrant.NewHasID(excluded...)}}
+	}
+
+	hits, err := c.client.Query(ctx, queryRequest)
+	if err != nil {
+		recommendLogger.EndWithError(fmt.Errorf("failed to recommend points: %w", err))
+		return nil, fmt.Errorf("failed to recommend points: %w", err)
+	}
+
+	results := make([]VectorSearchResult, 0, len(hits))
+	for _, hit := range hits {
+		results = append(results, vectorSearchResult(hit))
+	}
+
+	recommendLogger.Info().Int("result_count", len(results)).Msg("Recommendation completed")
+	recommendLogger.EndWithMsg("Recommendation completed")
+	return results, nil
+}
+
+// scrollPageSize is the number of points fetched per ScrollPointIDs page and GetVectors request
+const scrollPageSize = 256
+
+// ScrollPointIDs calls fn with the ID of every stored point, page by page, without loading
+// vectors. Iteration stops at the first error returned by fn.
+func (c *Client) ScrollPointIDs(ctx context.Context, fn func(id string) error) error {
+	limit := uint32(scrollPageSize)
+	request := &qdrant.ScrollPoints{
+		CollectionName: c.collectionName,
+		Limit:          &limit,
+		WithPayload:    qdrant.NewWithPayloadInclude("opensearch_id"),
+		WithVectors:    qdrant.NewWithVectors(false),
+	}
+
+	for {
+		resp, err := c.client.GetPointsClient().Scroll(ctx, request)
+		if err != nil {
+			return fmt.Errorf("failed to scroll points: %w", err)
+		}
+
+		for _, retrieved := range resp.GetResult() {
+			id := retrieved.GetPayload()["opensearch_id"].GetStringValue()
+			if id == "" {
+				continue
+			}
+			if err := fn(id); err != nil {
+				return err
 			}
 		}
 
-		// Fallback to numeric ID if original ID not found
-		if id == "" {
-			switch idType := hit.Id.PointIdOptions.(type) {
-			case *qdrant.PointId_Uuid:
-				id = idType.Uuid
-			case *qdrant.PointId_Num:
-				id = fmt.Sprintf("%d", idType.Num)
+		if resp.GetNextPageOffset() == nil {
+			return nil
+		}
+		request.Offset = resp.GetNextPageOffset()
+	}
+}
+
+// GetVectors calls fn with the stored vector of each of the points, as Qdrant stores it.
+// Points that do not exist are skipped. Iteration stops at the first error returned by fn.
+func (c *Client) GetVectors(ctx context.Context, pointIDs []string, fn func(id string, vector []float32) error) error {
+	for start := 0; start < len(pointIDs); start += scrollPageSize {
+		chunk := pointIDs[start:min(start+scrollPageSize, len(pointIDs))]
+		ids := make([]*qdrant.PointId, len(chunk))
+		for i, id := range chunk {
+			ids[i] = qdrant.NewIDNum(c.stringToNumericID(id))
+		}
+
+		points, err := c.client.Get(ctx, &qdrant.GetPoints{
+			CollectionName: c.collectionName,
+			Ids:            ids,
+			WithPayload:    qdrant.NewWithPayloadInclude("opensearch_id"),
+			WithVectors:    qdrant.NewWithVectors(true),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get points: %w", err)
+		}
+
+		for _, point := range points {
+			id := point.GetPayload()["opensearch_id"].GetStringValue()
+			vector := denseVector(point.GetVectors().GetVector())
+			if id == "" || len(vector) == 0 {
+				continue
+			}
+			if err := fn(id, vector); err != nil {
+				return err
 			}
 		}
+	}
+	return nil
+}
+
+// denseVector returns the data of a stored dense vector
+func denseVector(vector *qdrant.VectorOutput) []float32 {
+	if data := vector.GetDense().GetData(); len(data) > 0 {
+		return data
+	}
+	return vector.GetData()
+}
 
-		result := VectorSearchResult{
-			ID:    id,
-			Score: float64(hit.Score),
+// vectorSearchResult converts a scored point, mapping it back to its OpenSearch ID
+func vectorSearchResult(hit *qdrant.ScoredPoint) VectorSearchResult {
+	// Try to get original OpenSearch ID from payload first
+	var id string
+	if hit.Payload != nil && hit.Payload["opensearch_id"] != nil {
+		if stringVal := hit.Payload["opensearch_id"].GetStringValue(); stringVal != "" {
+			id = stringVal
 		}
-		results = append(results, result)
+	}
 
-		searchLogger.Debug().
-			Int("result_index", i+1).
-			Str("id", id).
-			Float64("score", float64(hit.Score)).
-			Msg("Vector search result")
+	// Fallback to numeric ID if original ID not found
+	if id == "" {
+		switch idType := hit.Id.PointIdOptions.(type) {
+		case *qdrant.PointId_Uuid:
+			id = idType.Uuid
+		case *qdrant.PointId_Num:
+			id = fmt.Sprintf("%d", idType.Num)
+		}
 	}
 
-	searchLogger.EndWithMsg("Vector search completed")
-	return results, nil
+	result := VectorSearchResult{
+		ID:    id,
+		Score: float64(hit.Score),
+	}
+	if hit.Payload != nil && hit.Payload["lang"] != nil {
+		result.Lang = hit.Payload["lang"].GetStringValue()
+	}
+	return result
 }
 
 // DeletePoint deletes a point from the collection
@@ -0,0 +1,236 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/snowmerak/open-librarian/lib/util/logger"
+)
+
+// Error kinds returned by fetchers. Use errors.Is to check for them.
+var (
+	ErrInvalidURL     = errors.New("invalid url")
+	ErrBlockedAddress = errors.New("address is not allowed")
+	ErrFetchFailed    = errors.New("failed to fetch page")
+)
+
+// Page is a fetched web resource. The caller must close Body.
+type Page struct {
+	URL         string // Final URL after redirects
+	ContentType string
+	Body        io.ReadCloser
+}
+
+// Fetcher retrieves web pages for ingestion
+type Fetcher interface {
+	Fetch(ctx context.Context, rawURL string) (*Page, error)
+}
+
+// HTTPFetcherConfig configures HTTPFetcher
+type HTTPFetcherConfig struct {
+	Timeout      time.Duration // Time limit for the whole request including reading the body
+	UserAgent    string
+	MaxRedirects int
+	// AllowedHosts are host names, such as an internal wiki, that may resolve to private
+	// network addresses. A leading dot allows every subdomain: ".corp.example.com".
+	AllowedHosts []string
+	// AllowedNetworks are non-public network ranges that may be fetched from. Loopback and
+	// link-local addresses are only reachable when listed here, such as 127.0.0.0/8 for a
+	// server on the same host.
+	AllowedNetworks []netip.Prefix
+}
+
+// DefaultHTTPFetcherConfig returns default fetcher configuration, which only fetches from
+// public addresses. Private addresses are refused unless allowed by host or network, and
+// loopback and link-local addresses, where the server's own databases and cloud metadata
+// services listen, are refused unless allowed by network.
+func DefaultHTTPFetcherConfig() HTTPFetcherConfig {
+	return HTTPFetcherConfig{
+		Timeout:      30 * time.Second,
+		UserAgent:    "open-librarian/1.0 (+https://github.com/snowmerak/open-librarian)",
+		MaxRedirects: 5,
+	}
+}
+
+// HTTPFetcher fetches pages over HTTP and HTTPS
+type HTTPFetcher struct {
+	config HTTPFetcherConfig
+	client *http.Client
+}
+
+// NewHTTPFetcher creates a fetcher that checks every address it connects to, including
+// those reached through redirects
+func NewHTTPFetcher(config HTTPFetcherConfig) *HTTPFetcher {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		// The host name is only known before it is resolved; the resolved address is checked
+		// when the connection is made, so DNS cannot point an allowed name elsewhere later
+		host, _, _ := net.SplitHostPort(address)
+		allowHost := hostAllowed(host, config.AllowedHosts)
+		dialer := &net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				return checkAddress(address, allowHost, config.AllowedNetworks)
+			},
+		}
+		return dialer.DialContext(ctx, network, address)
+	}
+	transport.Proxy = nil // A proxy would hide the target address from the dialer check
+
+	return &HTTPFetcher{
+		config: config,
+		client: &http.Client{
+			Timeout:   config.Timeout,
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) > config.MaxRedirects {
+					return fmt.Errorf("%w: stopped after %d redirects", ErrFetchFailed, config.MaxRedirects)
+				}
+				return checkScheme(req.URL)
+			},
+		},
+	}
+}
+
+// Fetch requests rawURL and returns the response body when the server answers 200
+func (f *HTTPFetcher) Fetch(ctx context.Context, rawURL string) (*Page, error) {
+	logger := logger.NewLogger("web-fetch")
+	logger.StartWithMsg("Fetching web page")
+
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		logger.EndWithError(err)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	if err := checkScheme(u); err != nil {
+		logger.EndWithError(err)
+		return nil, err
+	}
+	if u.Host == "" {
+		err := fmt.Errorf("%w: missing host", ErrInvalidURL)
+		logger.EndWithError(err)
+		return nil, err
+	}
+	logger.Info().Str("url", u.String()).Msg("Requesting page")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		logger.EndWithError(err)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	req.Header.Set("User-Agent", f.config.UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain;q=0.9,*/*;q=0.8")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		logger.EndWithError(err)
+		if errors.Is(err, ErrBlockedAddress) || errors.Is(err, ErrInvalidURL) {
+			return nil, unwrapURLError(err)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrFetchFailed, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		err := fmt.Errorf("%w: server responded with status %d", ErrFetchFailed, resp.StatusCode)
+		logger.EndWithError(err)
+		return nil, err
+	}
+
+	page := &Page{
+		URL:         resp.Request.URL.String(),
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        resp.Body,
+	}
+	logger.Info().Str("final_url", page.URL).Str("content_type", page.ContentType).Msg("Page fetched")
+	logger.EndWithMsg("Web page fetched successfully")
+	return page, nil
+}
+
+// checkScheme allows only http and https URLs
+func checkScheme(u *url.URL) error {
+	switch u.Scheme {
+	case "http", "https":
+		return nil
+	default:
+		return fmt.Errorf("%w: scheme must be http or https", ErrInvalidURL)
+	}
+}
+
+// hostAllowed reports whether host is one of the allowed host names or a subdomain of an
+// allowed ".domain"
+func hostAllowed(host string, allowed []string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, name := range allowed {
+		name = strings.ToLower(name)
+		if host == name || (strings.HasPrefix(name, ".") && strings.HasSuffix(host, name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// thisNetwork is 0.0.0.0/8, which some systems route to the local host
+var thisNetwork = netip.MustParsePrefix("0.0.0.0/8")
+
+// reservedNetworks are ranges that are not public but not covered by netip.Addr.IsPrivate
+var reservedNetworks = []netip.Prefix{
+	netip.MustParsePrefix("100.64.0.0/10"), // Carrier-grade NAT
+	netip.MustParsePrefix("198.18.0.0/15"), // Benchmarking, used for internal networks
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, which reaches any IPv4 address behind it
+}
+
+// checkAddress refuses loopback, link-local and unspecified addresses unless they are in an
+// allowed network, and private or reserved addresses unless the host was allowed by name or the
+// address is in an allowed network
+func checkAddress(address string, allowHost bool, allowedNetworks []netip.Prefix) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, address)
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, address)
+	}
+	ip = ip.Unmap()
+
+	for _, network := range allowedNetworks {
+		if network.Contains(ip) {
+			return nil
+		}
+	}
+	if ip.IsUnspecified() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() || thisNetwork.Contains(ip) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, ip)
+	}
+	if allowHost || (!ip.IsPrivate() && !reserved(ip)) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s is a private address", ErrBlockedAddress, ip)
+}
+
+// reserved reports whether ip is in one of the reservedNetworks
+func reserved(ip netip.Addr) bool {
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// unwrapURLError drops the *url.Error wrapper so the message names the cause only once
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
@@ -0,0 +1,218 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// loopback allows fetching from httptest servers, which listen on 127.0.0.1
+var loopback = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+
+func testFetcher(mutate func(*HTTPFetcherConfig)) *HTTPFetcher {
+	config := DefaultHTTPFetcherConfig()
+	config.AllowedNetworks = loopback
+	if mutate != nil {
+		mutate(&config)
+	}
+	return NewHTTPFetcher(config)
+}
+
+func fetchBody(t *testing.T, f *HTTPFetcher, rawURL string) (string, error) {
+	t.Helper()
+	page, err := f.Fetch(context.Background(), rawURL)
+	if err != nil {
+		return "", err
+	}
+	defer page.Body.Close()
+	body, err := io.ReadAll(page.Body)
+	if err != nil {
+		t.Fatalf("failed to read body: %v", err)
+	}
+	return string(body), nil
+}
+
+func TestFetchRefusesLoopbackByDefault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "internal")
+	}))
+	defer server.Close()
+
+	f := NewHTTPFetcher(DefaultHTTPFetcherConfig())
+	if _, err := fetchBody(t, f, server.URL); !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("expected ErrBlockedAddress, got %v", err)
+	}
+
+	body, err := fetchBody(t, testFetcher(nil), server.URL)
+	if err != nil {
+		t.Fatalf("expected loopback to be fetched once allowed by network, got %v", err)
+	}
+	if body != "internal" {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+func TestFetchRefusesRedirectToBlockedAddress(t *testing.T) {
+	targets := []string{
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.1/",
+		"http://100.64.0.1/",
+		"http://[::1]/",
+	}
+	for _, target := range targets {
+		t.Run(target, func(t *testing.T) {
+			server := httptest.NewServer(http.RedirectHandler(target, http.StatusFound))
+			defer server.Close()
+
+			if _, err := fetchBody(t, testFetcher(nil), server.URL); !errors.Is(err, ErrBlockedAddress) {
+				t.Fatalf("expected ErrBlockedAddress, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFetchRefusesNonHTTPScheme(t *testing.T) {
+	server := httptest.NewServer(http.RedirectHandler("file:///etc/passwd", http.StatusFound))
+	defer server.Close()
+
+	for _, rawURL := range []string{"ftp://example.com/file", "file:///etc/passwd", "gopher://example.com", server.URL} {
+		if _, err := fetchBody(t, testFetcher(nil), rawURL); !errors.Is(err, ErrInvalidURL) {
+			t.Errorf("%s: expected ErrInvalidURL, got %v", rawURL, err)
+		}
+	}
+}
+
+func TestFetchMaxRedirects(t *testing.T) {
+	// /n redirects to /n-1 until /0, which answers with the page
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if n > 0 {
+			http.Redirect(w, r, "/"+strconv.Itoa(n-1), http.StatusFound)
+			return
+		}
+		fmt.Fprint(w, "done")
+	}))
+	defer server.Close()
+
+	f := testFetcher(func(config *HTTPFetcherConfig) { config.MaxRedirects = 2 })
+
+	page, err := f.Fetch(context.Background(), server.URL+"/2")
+	if err != nil {
+		t.Fatalf("expected 2 redirects to be followed, got %v", err)
+	}
+	page.Body.Close()
+	if page.URL != server.URL+"/0" {
+		t.Fatalf("expected the final URL after redirects, got %s", page.URL)
+	}
+
+	if _, err := fetchBody(t, f, server.URL+"/3"); !errors.Is(err, ErrFetchFailed) {
+		t.Fatalf("expected ErrFetchFailed after too many redirects, got %v", err)
+	}
+}
+
+func TestFetchAllowedHost(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "wiki")
+	}))
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	localURL := "http://localhost:" + u.Port()
+
+	// Allowing a host name opens private addresses, but not loopback
+	byHost := NewHTTPFetcher(HTTPFetcherConfig{MaxRedirects: 5, AllowedHosts: []string{"localhost"}})
+	if _, err := fetchBody(t, byHost, localURL); !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("expected loopback to stay blocked for an allowed host, got %v", err)
+	}
+
+	byNetwork := NewHTTPFetcher(HTTPFetcherConfig{MaxRedirects: 5, AllowedHosts: []string{"localhost"}, AllowedNetworks: loopback})
+	body, err := fetchBody(t, byNetwork, localURL)
+	if err != nil {
+		t.Fatalf("expected allowed host on an allowed network to be fetched, got %v", err)
+	}
+	if body != "wiki" {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+func TestHostAllowed(t *testing.T) {
+	allowed := []string{"wiki.corp.local", ".corp.example.com"}
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"wiki.corp.local", true},
+		{"WIKI.corp.local.", true},
+		{"other.corp.local", false},
+		{"docs.corp.example.com", true},
+		{"a.b.corp.example.com", true},
+		{"corp.example.com", false},
+		{"evilcorp.example.com", false},
+		{"corp.example.com.evil.net", false},
+	}
+	for _, tt := range tests {
+		if got := hostAllowed(tt.host, allowed); got != tt.want {
+			t.Errorf("hostAllowed(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestCheckAddress(t *testing.T) {
+	private := []netip.Prefix{netip.MustParsePrefix("10.20.0.0/16")}
+	tests := []struct {
+		address  string
+		host     bool
+		networks []netip.Prefix
+		allowed  bool
+	}{
+		{"93.184.216.34:80", false, nil, true},
+		{"[2606:2800:220:1::]:443", false, nil, true},
+		{"127.0.0.1:80", false, nil, false},
+		{"127.0.0.1:80", true, nil, false},
+		{"127.0.0.1:80", false, loopback, true},
+		{"[::ffff:127.0.0.1]:80", false, nil, false},
+		{"[::1]:80", false, nil, false},
+		{"0.0.0.0:80", false, nil, false},
+		{"0.1.2.3:80", true, nil, false},
+		{"169.254.169.254:80", true, nil, false},
+		{"224.0.0.1:80", false, nil, false},
+		{"10.20.1.1:80", false, nil, false},
+		{"10.20.1.1:80", true, nil, true},
+		{"10.20.1.1:80", false, private, true},
+		{"10.30.1.1:80", false, private, false},
+		{"192.168.1.1:80", false, nil, false},
+		{"[fd00::1]:80", false, nil, false},
+		{"100.64.0.1:80", false, nil, false},
+		{"100.127.255.254:80", false, nil, false},
+		{"100.128.0.1:80", false, nil, true},
+		{"198.18.0.1:80", false, nil, false},
+		{"198.19.255.254:80", false, nil, false},
+		{"[64:ff9b::a00:1]:80", false, nil, false},
+		{"[64:ff9b::a00:1]:80", true, nil, true},
+		{"not-an-address:80", false, nil, false},
+	}
+	for _, tt := range tests {
+		err := checkAddress(tt.address, tt.host, tt.networks)
+		if tt.allowed && err != nil {
+			t.Errorf("checkAddress(%s, host=%v, %v) = %v, want allowed", tt.address, tt.host, tt.networks, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrBlockedAddress) {
+			t.Errorf("checkAddress(%s, host=%v, %v) = %v, want ErrBlockedAddress", tt.address, tt.host, tt.networks, err)
+		}
+	}
+}
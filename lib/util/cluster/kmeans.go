@@ -0,0 +1,228 @@
+package cluster
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+)
+
+// ErrNoVectors is returned when there is nothing to cluster
+var ErrNoVectors = errors.New("no vectors to cluster")
+
+// Result is the outcome of a clustering run
+type Result struct {
+	Assignments  []int       // Cluster of each vector, in input order
+	Centroids    [][]float32 // Unit-length cluster centers
+	Similarities []float64   // Cosine similarity of each vector to its cluster's centroid
+	Iterations   int
+}
+
+// Sizes returns the number of vectors in each cluster
+func (r *Result) Sizes() []int {
+	sizes := make([]int, len(r.Centroids))
+	for _, cluster := range r.Assignments {
+		sizes[cluster]++
+	}
+	return sizes
+}
+
+// SuggestK returns a cluster count for n vectors using the sqrt(n/2) rule of thumb, between 2 and maxK
+func SuggestK(n, maxK int) int {
+	k := int(math.Round(math.Sqrt(float64(n) / 2)))
+	return max(2, min(k, maxK))
+}
+
+// KMeans groups vectors into k clusters by cosine similarity (spherical k-means) with k-means++ seeding.
+// Vectors are normalized in place, so a library's embeddings are held once. The same seed gives the same clustering.
+func KMeans(vectors [][]float32, k, maxIterations int, seed uint64) (*Result, error) {
+	if len(vectors) == 0 {
+		return nil, ErrNoVectors
+	}
+	k = max(1, min(k, len(vectors)))
+
+	points := vectors
+	for _, point := range points {
+		Normalize(point)
+	}
+
+	rng := rand.New(rand.NewPCG(seed, seed))
+	centroids := seedCentroids(points, k, rng)
+
+	result := &Result{
+		Assignments:  make([]int, len(points)),
+		Similarities: make([]float64, len(points)),
+	}
+	for i := range result.Assignments {
+		result.Assignments[i] = -1
+	}
+
+	for result.Iterations < maxIterations {
+		result.Iterations++
+
+		changed := false
+		for i, point := range points {
+			best, bestSimilarity := Nearest(point, centroids)
+			if result.Assignments[i] != best {
+				result.Assignments[i] = best
+				changed = true
+			}
+			result.Similarities[i] = bestSimilarity
+		}
+		if !changed {
+			break
+		}
+
+		centroids = recomputeCentroids(points, result.Assignments, result.Similarities, k)
+	}
+
+	// Similarities refer to the final centroids even when the iteration limit was hit
+	for i, point := range points {
+		result.Similarities[i] = dot(point, centroids[result.Assignments[i]])
+	}
+	result.Centroids = centroids
+	return result, nil
+}
+
+// seedCentroids picks initial centers with k-means++: each next center is drawn with
+// probability proportional to its squared cosine distance from the nearest chosen center
+func seedCentroids(points [][]float32, k int, rng *rand.Rand) [][]float32 {
+	centroids := [][]float32{clone(points[rng.IntN(len(points))])}
+
+	distances := make([]float64, len(points))
+	for len(centroids) < k {
+		total := 0.0
+		for i, point := range points {
+			nearest := math.Inf(1)
+			for _, centroid := range centroids {
+				nearest = min(nearest, cosineDistance(point, centroid))
+			}
+			distances[i] = nearest * nearest
+			total += distances[i]
+		}
+
+		// Every point coincides with a center; any point will do
+		if total == 0 {
+			centroids = append(centroids, clone(points[rng.IntN(len(points))]))
+			continue
+		}
+
+		target := rng.Float64() * total
+		chosen := len(points) - 1
+		for i, distance := range distances {
+			target -= distance
+			if target <= 0 {
+				chosen = i
+				break
+			}
+		}
+		centroids = append(centroids, clone(points[chosen]))
+	}
+	return centroids
+}
+
+// recomputeCentroids averages the members of each cluster. A cluster left empty is restarted
+// at the point that is currently worst served by its own cluster.
+func recomputeCentroids(points [][]float32, assignments []int, similarities []float64, k int) [][]float32 {
+	// Members are summed in float64 so large clusters do not lose precision
+	dimension := len(points[0])
+	sums := make([][]float64, k)
+	counts := make([]int, k)
+	for c := range sums {
+		sums[c] = make([]float64, dimension)
+	}
+	for i, point := range points {
+		c := assignments[i]
+		counts[c]++
+		for d, value := range point {
+			sums[c][d] += float64(value)
+		}
+	}
+
+	centroids := make([][]float32, k)
+	taken := make(map[int]bool)
+	for c, sum := range sums {
+		if counts[c] > 0 {
+			norm := math.Sqrt(dot64(sum, sum))
+			centroid := make([]float32, dimension)
+			for d, value := range sum {
+				if norm > 0 {
+					centroid[d] = float32(value / norm)
+				}
+			}
+			centroids[c] = centroid
+			continue
+		}
+
+		worst, worstSimilarity := -1, math.Inf(1)
+		for i, similarity := range similarities {
+			if !taken[i] && similarity < worstSimilarity {
+				worst, worstSimilarity = i, similarity
+			}
+		}
+		if worst >= 0 {
+			taken[worst] = true
+			centroids[c] = clone(points[worst])
+		} else {
+			centroids[c] = make([]float32, dimension)
+		}
+	}
+	return centroids
+}
+
+// Nearest returns the index of the centroid most similar to a unit vector and their cosine
+// similarity, or -1 when there are no centroids
+func Nearest(vector []float32, centroids [][]float32) (int, float64) {
+	best, bestSimilarity := -1, math.Inf(-1)
+	for c, centroid := range centroids {
+		if similarity := dot(vector, centroid); similarity > bestSimilarity {
+			best, bestSimilarity = c, similarity
+		}
+	}
+	return best, bestSimilarity
+}
+
+// Cosine returns the cosine similarity of two vectors of any length, 0 when either is zero
+func Cosine(a, b []float32) float64 {
+	norms := math.Sqrt(dot(a, a) * dot(b, b))
+	if norms == 0 {
+		return 0
+	}
+	return dot(a, b) / norms
+}
+
+// cosineDistance returns 1 - cosine similarity of two unit vectors, never negative
+func cosineDistance(a, b []float32) float64 {
+	return max(0, 1-dot(a, b))
+}
+
+// dot returns the dot product of two vectors, accumulated in float64
+func dot(a, b []float32) float64 {
+	sum := 0.0
+	for i := range min(len(a), len(b)) {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
+
+func dot64(a, b []float64) float64 {
+	sum := 0.0
+	for i := range min(len(a), len(b)) {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// Normalize scales a vector to unit length in place; a zero vector is left as is
+func Normalize(vector []float32) {
+	norm := math.Sqrt(dot(vector, vector))
+	if norm == 0 {
+		return
+	}
+	for i, value := range vector {
+		vector[i] = float32(float64(value) / norm)
+	}
+}
+
+func clone(vector []float32) []float32 {
+	return append([]float32(nil), vector...)
+}
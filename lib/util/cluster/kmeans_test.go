@@ -0,0 +1,186 @@
+package cluster
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+// blobs returns n vectors scattered around each center, in center order
+func blobs(centers [][]float32, n int, spread float64, seed uint64) [][]float32 {
+	rng := rand.New(rand.NewPCG(seed, seed))
+	var vectors [][]float32
+	for _, center := range centers {
+		for range n {
+			vector := make([]float32, len(center))
+			for d, value := range center {
+				vector[d] = value + float32(rng.NormFloat64()*spread)
+			}
+			vectors = append(vectors, vector)
+		}
+	}
+	return vectors
+}
+
+func cloneAll(vectors [][]float32) [][]float32 {
+	out := make([][]float32, len(vectors))
+	for i, vector := range vectors {
+		out[i] = clone(vector)
+	}
+	return out
+}
+
+var axes = [][]float32{
+	{1, 0, 0, 0},
+	{0, 1, 0, 0},
+	{0, 0, 1, 0},
+}
+
+func TestKMeansRecoversSeparatedClusters(t *testing.T) {
+	const perCluster = 20
+	vectors := blobs(axes, perCluster, 0.05, 7)
+
+	result, err := KMeans(vectors, len(axes), 50, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every blob lands in one cluster of its own, whatever the cluster numbers are
+	seen := make(map[int]bool)
+	for b := range axes {
+		members := result.Assignments[b*perCluster : (b+1)*perCluster]
+		for _, c := range members {
+			if c != members[0] {
+				t.Fatalf("blob %d split across clusters: %v", b, members)
+			}
+		}
+		if seen[members[0]] {
+			t.Fatalf("blob %d shares cluster %d with another blob", b, members[0])
+		}
+		seen[members[0]] = true
+	}
+
+	if sizes := result.Sizes(); !slices.Equal(sizes, []int{perCluster, perCluster, perCluster}) {
+		t.Errorf("Sizes = %v", sizes)
+	}
+	for c, centroid := range result.Centroids {
+		if norm := math.Sqrt(dot(centroid, centroid)); math.Abs(norm-1) > 1e-5 {
+			t.Errorf("centroid %d has length %v, want 1", c, norm)
+		}
+	}
+	for i, similarity := range result.Similarities {
+		if similarity < 0.9 {
+			t.Errorf("vector %d is %v similar to its centroid, want close to 1", i, similarity)
+		}
+	}
+}
+
+func TestKMeansIsDeterministic(t *testing.T) {
+	vectors := blobs(axes, 10, 0.3, 3)
+
+	first, err := KMeans(cloneAll(vectors), 3, 50, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := KMeans(cloneAll(vectors), 3, 50, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(first.Assignments, second.Assignments) || first.Iterations != second.Iterations {
+		t.Fatalf("same seed gave different clusterings: %v and %v", first.Assignments, second.Assignments)
+	}
+}
+
+func TestKMeansEdgeCases(t *testing.T) {
+	if _, err := KMeans(nil, 3, 10, 1); !errors.Is(err, ErrNoVectors) {
+		t.Errorf("KMeans(nil) = %v, want ErrNoVectors", err)
+	}
+
+	// k is clamped to the number of vectors
+	result, err := KMeans([][]float32{{1, 0}, {0, 1}}, 5, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Centroids) != 2 {
+		t.Errorf("got %d centroids for 2 vectors, want 2", len(result.Centroids))
+	}
+
+	// Identical vectors cannot fill more than one cluster, but every vector is still assigned
+	same := [][]float32{{2, 2}, {2, 2}, {2, 2}, {2, 2}}
+	result, err = KMeans(same, 2, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range result.Assignments {
+		if c < 0 || c >= len(result.Centroids) {
+			t.Errorf("vector %d assigned to cluster %d of %d", i, c, len(result.Centroids))
+		}
+	}
+
+	// Vectors are normalized in place
+	if norm := math.Sqrt(dot(same[0], same[0])); math.Abs(norm-1) > 1e-6 {
+		t.Errorf("input vector has length %v after clustering, want 1", norm)
+	}
+}
+
+func TestSuggestK(t *testing.T) {
+	tests := []struct{ n, maxK, want int }{
+		{0, 30, 2},
+		{4, 30, 2},
+		{50, 30, 5},
+		{200, 30, 10},
+		{5000, 30, 30},
+		{5000, 1, 2},
+	}
+	for _, tt := range tests {
+		if got := SuggestK(tt.n, tt.maxK); got != tt.want {
+			t.Errorf("SuggestK(%d, %d) = %d, want %d", tt.n, tt.maxK, got, tt.want)
+		}
+	}
+}
+
+func TestNearestAndCosine(t *testing.T) {
+	if best, _ := Nearest([]float32{1, 0}, nil); best != -1 {
+		t.Errorf("Nearest without centroids = %d, want -1", best)
+	}
+
+	centroids := [][]float32{{1, 0}, {0, 1}, {-1, 0}}
+	vector := []float32{0.6, 0.8}
+	best, similarity := Nearest(vector, centroids)
+	if best != 1 || math.Abs(similarity-0.8) > 1e-6 {
+		t.Errorf("Nearest = %d (%v), want 1 (0.8)", best, similarity)
+	}
+
+	if got := Cosine([]float32{3, 4}, []float32{6, 8}); math.Abs(got-1) > 1e-9 {
+		t.Errorf("Cosine of parallel vectors = %v, want 1", got)
+	}
+	if got := Cosine([]float32{1, 0}, []float32{0, 0}); got != 0 {
+		t.Errorf("Cosine with a zero vector = %v, want 0", got)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	previous := [][]float32{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	next := [][]float32{
+		{0, 0.995, 0.0998}, // The second topic, barely moved
+		{0.8, 0.6, 0},      // Between the first two, too far from both
+		{0.0998, 0, 0.995}, // The third topic
+	}
+
+	if got := Match(previous, next, 0.9); !slices.Equal(got, []int{1, -1, 2}) {
+		t.Errorf("Match = %v, want [1 -1 2]", got)
+	}
+
+	// A previous centroid is given to the closest next one only
+	twins := [][]float32{{0.98, 0.199, 0}, {0.995, 0.0998, 0}}
+	if got := Match(previous, twins, 0.9); !slices.Equal(got, []int{-1, 0}) {
+		t.Errorf("Match of two centroids near one previous = %v, want [-1 0]", got)
+	}
+
+	// Topics stored without a centroid never match
+	if got := Match([][]float32{nil}, next, 0.9); !slices.Equal(got, []int{-1, -1, -1}) {
+		t.Errorf("Match against an empty centroid = %v, want no matches", got)
+	}
+}
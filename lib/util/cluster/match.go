@@ -0,0 +1,45 @@
+package cluster
+
+import "sort"
+
+// Match pairs each of the next centroids with one of the previous centroids, so clusters that
+// survive a re-run can keep their identity. Pairs are taken most similar first, each previous
+// centroid is used at most once and pairs less similar than minSimilarity are not made.
+// It returns the index of the previous centroid for each next one, or -1 when it has none.
+func Match(previous, next [][]float32, minSimilarity float64) []int {
+	type pair struct {
+		next, previous int
+		similarity     float64
+	}
+	var pairs []pair
+	for n, centroid := range next {
+		for p, old := range previous {
+			if similarity := dot(centroid, old); similarity >= minSimilarity {
+				pairs = append(pairs, pair{n, p, similarity})
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].similarity != pairs[j].similarity {
+			return pairs[i].similarity > pairs[j].similarity
+		}
+		if pairs[i].next != pairs[j].next {
+			return pairs[i].next < pairs[j].next
+		}
+		return pairs[i].previous < pairs[j].previous
+	})
+
+	matches := make([]int, len(next))
+	for i := range matches {
+		matches[i] = -1
+	}
+	used := make([]bool, len(previous))
+	for _, pair := range pairs {
+		if matches[pair.next] >= 0 || used[pair.previous] {
+			continue
+		}
+		matches[pair.next] = pair.previous
+		used[pair.previous] = true
+	}
+	return matches
+}
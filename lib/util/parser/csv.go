@@ -0,0 +1,135 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/transform"
+)
+
+// csvDelimiters are the field separators recognized in CSV files, in order of preference
+var csvDelimiters = []rune{',', ';', '\t'}
+
+// csvSniffSize is the prefix of a CSV file from which its encoding and delimiter are detected
+const csvSniffSize = 64 << 10
+
+func init() {
+	Register(formatParser{TypeCSV, []string{".csv", ".tsv"}, []string{"text/csv", "text/tab-separated-values"}, ParseCSV})
+}
+
+// ParseCSV parses a CSV or TSV file into a Markdown table the way a one-sheet workbook is read.
+// Files that are not UTF-8 are read as EUC-KR, or as Windows-1252 when that fails, which covers
+// the exports of Korean and Western versions of Excel. The encoding and delimiter are detected
+// from the first csvSniffSize bytes and the rest is decoded as it is read.
+func ParseCSV(ctx context.Context, r io.ReaderAt, size int64, filename string, limits Limits) (*Document, error) {
+	prefix := make([]byte, min(size, csvSniffSize))
+	n, err := r.ReadAt(prefix, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	prefix = prefix[:n]
+
+	var offset int64
+	if bom := []byte("\ufeff"); bytes.HasPrefix(prefix, bom) {
+		offset = int64(len(bom))
+		prefix = prefix[len(bom):]
+	}
+	// A cut prefix may end inside a character, so it is cut back to a line break
+	if int64(n) < size {
+		if i := bytes.LastIndexByte(prefix, '\n'); i >= 0 {
+			prefix = prefix[:i+1]
+		} else {
+			prefix = prefix[:max(len(prefix)-utf8.UTFMax+1, 0)]
+		}
+	}
+
+	metadata := map[string]string{"type": "csv"}
+	var body io.Reader = io.NewSectionReader(r, offset, size-offset)
+	if !utf8.Valid(prefix) {
+		var enc encoding.Encoding = korean.EUCKR
+		metadata["encoding"] = "euc-kr"
+		if decoded, err := korean.EUCKR.NewDecoder().Bytes(prefix); err != nil || bytes.ContainsRune(decoded, utf8.RuneError) {
+			enc = charmap.Windows1252
+			metadata["encoding"] = "windows-1252"
+		}
+		if prefix, err = enc.NewDecoder().Bytes(prefix); err != nil {
+			return nil, fmt.Errorf("failed to decode csv: %w", err)
+		}
+		body = transform.NewReader(body, enc.NewDecoder())
+	}
+
+	reader := csv.NewReader(body)
+	reader.Comma = csvDelimiter(prefix, filename)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	s := newSheet("", limits)
+	for num := 1; ; num++ {
+		if num%1000 == 0 {
+			if err := context.Cause(ctx); err != nil {
+				return nil, err
+			}
+		}
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read csv: %w", err)
+		}
+		if !s.add(num, record) {
+			break
+		}
+	}
+
+	book := newWorkbook(sheetOptionsFrom(ctx))
+	book.addSheet(s)
+	return book.document(documentTitle(metadata, filename), metadata), nil
+}
+
+// csvDelimiter returns tab for .tsv files and otherwise the separator that occurs most often
+// outside quotes in the first lines, defaulting to a comma
+func csvDelimiter(data []byte, filename string) rune {
+	if strings.EqualFold(filepath.Ext(filename), ".tsv") {
+		return '\t'
+	}
+
+	counts := make(map[rune]int, len(csvDelimiters))
+	lines, quoted := 0, false
+	for _, c := range string(data[:min(len(data), 64<<10)]) {
+		if c == '"' {
+			quoted = !quoted
+			continue
+		}
+		if quoted {
+			continue
+		}
+		if c == '\n' {
+			if lines++; lines == 20 {
+				break
+			}
+			continue
+		}
+		if slices.Contains(csvDelimiters, c) {
+			counts[c]++
+		}
+	}
+
+	best := ','
+	for _, delimiter := range csvDelimiters {
+		if counts[delimiter] > counts[best] {
+			best = delimiter
+		}
+	}
+	return best
+}
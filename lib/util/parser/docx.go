@@ -3,23 +3,25 @@ package parser
 import (
 	"archive/zip"
 	"bytes"
+	"context"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"strings"
 )
 
-// ParseDocx extracts text from a .docx file (which is a zip archive)
-func ParseDocx(r io.Reader, filename string) (*Document, error) {
-	// Read full content to support random access needed by zip reader
-	content, err := io.ReadAll(r)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read docx content: %w", err)
-	}
+func init() {
+	Register(zipFormatParser{
+		formatParser: formatParser{TypeDocx, []string{".docx"}, []string{"application/vnd.openxmlformats-officedocument.wordprocessingml.document"}, ParseDocx},
+		marker:       "word/document.xml",
+	})
+}
 
-	zipReader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+// ParseDocx extracts text from a .docx file (which is a zip archive)
+func ParseDocx(ctx context.Context, r io.ReaderAt, size int64, filename string, limits Limits) (*Document, error) {
+	zipReader, budget, err := openZip(r, size, limits)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open docx as zip: %w", err)
+		return nil, fmt.Errorf("failed to open docx: %w", err)
 	}
 
 	// Find word/document.xml
@@ -36,7 +38,7 @@ func ParseDocx(r io.Reader, filename string) (*Document, error) {
 	}
 
 	// Open and parse the XML
-	rc, err := documentXML.Open()
+	rc, err := budget.open(documentXML)
 	if err != nil {
 		return nil, err
 	}
@@ -47,10 +49,15 @@ func ParseDocx(r io.Reader, filename string) (*Document, error) {
 		return nil, err
 	}
 
+	metadata := map[string]string{"type": "docx"}
+	if err := readCoreProperties(zipReader, budget, metadata); err != nil {
+		return nil, err
+	}
+
 	return &Document{
-		Title:    strings.TrimSuffix(filename, ".docx"),
+		Title:    documentTitle(metadata, filename),
 		Content:  text,
-		Metadata: map[string]string{"type": "docx"},
+		Metadata: metadata,
 	}, nil
 }
 
@@ -129,37 +136,7 @@ func extractMarkdownFromDocxXML(r io.Reader) (string, error) {
 			case "tbl":
 				inTable = false
 				if len(tableRows) > 0 {
-					sb.WriteString("\n")
-					maxCols := 0
-					for _, row := range tableRows {
-						if len(row) > maxCols {
-							maxCols = len(row)
-						}
-					}
-
-					sanitize := func(s string) string {
-						return strings.TrimSpace(strings.ReplaceAll(strings.ReplaceAll(s, "|", "\\|"), "\n", " "))
-					}
-
-					for i, row := range tableRows {
-						sb.WriteString("|")
-						for j := 0; j < maxCols; j++ {
-							val := ""
-							if j < len(row) {
-								val = sanitize(row[j])
-							}
-							sb.WriteString(" " + val + " |")
-						}
-						sb.WriteString("\n")
-						if i == 0 {
-							sb.WriteString("|")
-							for j := 0; j < maxCols; j++ {
-								sb.WriteString(" --- |")
-							}
-							sb.WriteString("\n")
-						}
-					}
-					sb.WriteString("\n")
+					sb.WriteString("\n" + markdownTable(tableRows) + "\n\n")
 				}
 			case "tr":
 				if inTable {
@@ -0,0 +1,200 @@
+package parser
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// epubContainerPart points to the package document of an EPUB
+const epubContainerPart = "META-INF/container.xml"
+
+// epubPackage is the part of the OPF package document needed to read a book in order
+type epubPackage struct {
+	Metadata struct {
+		Titles   []string `xml:"title"`
+		Creators []string `xml:"creator"`
+		Language string   `xml:"language"`
+		Date     string   `xml:"date"`
+	} `xml:"metadata"`
+	Manifest []struct {
+		ID         string `xml:"id,attr"`
+		Href       string `xml:"href,attr"`
+		MediaType  string `xml:"media-type,attr"`
+		Properties string `xml:"properties,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+func init() {
+	Register(zipFormatParser{
+		formatParser: formatParser{TypeEPUB, []string{".epub"}, []string{"application/epub+zip"}, ParseEpub},
+	})
+}
+
+// ParseEpub extracts the chapters of an EPUB book in reading order as Markdown.
+// Chapters that do not start with a heading get one from their document title.
+func ParseEpub(ctx context.Context, r io.ReaderAt, size int64, filename string, limits Limits) (*Document, error) {
+	zipReader, budget, err := openZip(r, size, limits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open epub: %w", err)
+	}
+
+	opfPart, err := epubPackagePart(zipReader, budget)
+	if err != nil {
+		return nil, err
+	}
+	opf := findZipFile(zipReader, opfPart)
+	if opf == nil {
+		return nil, fmt.Errorf("invalid epub: package document %s not found", opfPart)
+	}
+	var pkg epubPackage
+	if err := decodeZipXML(opf, budget, &pkg); err != nil {
+		return nil, err
+	}
+
+	bookTitle := ""
+	if len(pkg.Metadata.Titles) > 0 {
+		bookTitle = collapseSpace(pkg.Metadata.Titles[0])
+	}
+
+	hrefs := make(map[string]string, len(pkg.Manifest))
+	for _, item := range pkg.Manifest {
+		isDocument := item.MediaType == "application/xhtml+xml" || item.MediaType == "text/html"
+		isNav := strings.Contains(" "+item.Properties+" ", " nav ")
+		if isDocument && !isNav {
+			hrefs[item.ID] = item.Href
+		}
+	}
+
+	var chapters []string
+	for _, itemRef := range pkg.Spine {
+		if err := context.Cause(ctx); err != nil {
+			return nil, err
+		}
+		href, ok := hrefs[itemRef.IDRef]
+		if !ok {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(href); err == nil {
+			href = unescaped
+		}
+		f := findZipFile(zipReader, resolvePartName(opfPart, href))
+		if f == nil {
+			continue
+		}
+
+		chapter, err := readEpubChapter(f, budget, bookTitle)
+		if err != nil {
+			return nil, err
+		}
+		if chapter != "" {
+			chapters = append(chapters, chapter)
+		}
+	}
+
+	title := bookTitle
+	if title == "" {
+		title = strings.TrimSuffix(filename, ".epub")
+	}
+	metadata := map[string]string{"type": "epub", "chapters": strconv.Itoa(len(chapters))}
+	if bookTitle != "" {
+		metadata[MetaTitle] = bookTitle
+	}
+	if len(pkg.Metadata.Creators) > 0 {
+		var authors []string
+		for _, creator := range pkg.Metadata.Creators {
+			if creator = collapseSpace(creator); creator != "" {
+				authors = append(authors, creator)
+			}
+		}
+		metadata[MetaAuthor] = strings.Join(authors, ", ")
+	}
+	if lang := strings.TrimSpace(pkg.Metadata.Language); lang != "" {
+		metadata["lang"] = lang
+	}
+	if published, ok := normalizeDate(pkg.Metadata.Date); ok {
+		metadata[MetaPublishedDate] = published
+	}
+
+	return &Document{
+		Title:    title,
+		Content:  strings.Join(chapters, "\n\n"),
+		Metadata: metadata,
+	}, nil
+}
+
+// epubPackagePart reads the location of the package document from the container
+func epubPackagePart(zr *zip.Reader, budget *zipBudget) (string, error) {
+	f := findZipFile(zr, epubContainerPart)
+	if f == nil {
+		return "", fmt.Errorf("invalid epub: %s not found", epubContainerPart)
+	}
+	var container struct {
+		Rootfiles []struct {
+			FullPath string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	if err := decodeZipXML(f, budget, &container); err != nil {
+		return "", err
+	}
+	if len(container.Rootfiles) == 0 || container.Rootfiles[0].FullPath == "" {
+		return "", fmt.Errorf("invalid epub: no package document in %s", epubContainerPart)
+	}
+	return container.Rootfiles[0].FullPath, nil
+}
+
+// readEpubChapter renders one content document as Markdown. EPUB content documents are
+// UTF-8, or UTF-16 with a byte order mark.
+func readEpubChapter(f *zip.File, budget *zipBudget, bookTitle string) (string, error) {
+	rc, err := budget.open(f)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	decoded, err := charset.NewReader(rc, "application/xhtml+xml; charset=utf-8")
+	if err != nil {
+		return "", fmt.Errorf("failed to decode %s: %w", f.Name, err)
+	}
+	root, err := html.Parse(decoded)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", f.Name, err)
+	}
+
+	body := findFirst(root, atom.Body)
+	if body == nil {
+		return "", nil
+	}
+	var remove []*html.Node
+	for n := range body.Descendants() {
+		if n.Type == html.ElementNode && (n.DataAtom == atom.Script || n.DataAtom == atom.Style || n.DataAtom == atom.Template) {
+			remove = append(remove, n)
+		}
+	}
+	for _, n := range remove {
+		n.Parent.RemoveChild(n)
+	}
+
+	content := markdownRenderer{dropLinks: true}.render([]*html.Node{body})
+	if content == "" {
+		return "", nil
+	}
+
+	if !strings.HasPrefix(content, "#") {
+		if title := collapseSpace(textContent(findFirst(root, atom.Title))); title != "" && title != bookTitle {
+			content = "## " + title + "\n\n" + content
+		}
+	}
+	return content, nil
+}
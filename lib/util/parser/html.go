@@ -0,0 +1,347 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+func init() {
+	Register(formatParser{TypeHTML, []string{".html", ".htm", ".xhtml"}, []string{"text/html", "application/xhtml+xml"}, ParseHTML})
+}
+
+// ParseHTML extracts the main content of an HTML file as Markdown, without navigation,
+// footers, scripts and other page boilerplate
+func ParseHTML(ctx context.Context, r io.ReaderAt, size int64, filename string, limits Limits) (*Document, error) {
+	title := strings.TrimSuffix(filename, filepath.Ext(filename))
+	return parseHTML(ctx, io.NewSectionReader(r, 0, size), "", nil, title)
+}
+
+// ParsePage extracts the main content of a fetched web page as Markdown.
+// contentType is the response Content-Type, used to detect the character set; relative
+// links are resolved against pageURL.
+func ParsePage(ctx context.Context, r io.ReaderAt, size int64, pageURL, contentType string, limits Limits) (*Document, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page url: %w", err)
+	}
+	return parseWithLimits(ctx, pageURL, limits, func(ctx context.Context) (*Document, error) {
+		return parseHTML(ctx, io.NewSectionReader(r, 0, size), contentType, base, pageURL)
+	})
+}
+
+func parseHTML(ctx context.Context, r io.Reader, contentType string, base *url.URL, fallbackTitle string) (*Document, error) {
+	decoded, err := charset.NewReader(r, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect html charset: %w", err)
+	}
+
+	root, err := html.Parse(decoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse html: %w", err)
+	}
+
+	// <base href> changes how the page's relative links resolve
+	if href := attr(findFirst(root, atom.Base), "href"); href != "" {
+		if resolved := resolveURL(base, href); resolved != "" {
+			base, _ = url.Parse(resolved)
+		}
+	}
+
+	// Metadata comes first: JSON-LD lives in script elements that boilerplate removal drops
+	metadata := extractHTMLMetadata(root, base)
+	if err := context.Cause(ctx); err != nil {
+		return nil, err
+	}
+
+	body := findFirst(root, atom.Body)
+	if body == nil {
+		body = root
+	}
+	removeBoilerplate(body)
+	content := readableContent(body)
+	if err := context.Cause(ctx); err != nil {
+		return nil, err
+	}
+
+	title := metadata[MetaTitle]
+	if title == "" {
+		title = fallbackTitle
+	}
+	metadata["type"] = "html"
+
+	return &Document{
+		Title:    title,
+		Content:  htmlToMarkdown(content, base),
+		Metadata: metadata,
+	}, nil
+}
+
+// extractHTMLMetadata reads the title, author, canonical URL, published date, description
+// and site name from meta tags, link elements and JSON-LD
+func extractHTMLMetadata(root *html.Node, base *url.URL) map[string]string {
+	meta := make(map[string]string)
+	for n := range root.Descendants() {
+		if n.Type != html.ElementNode || n.DataAtom != atom.Meta {
+			continue
+		}
+		key := strings.ToLower(attr(n, "property"))
+		if key == "" {
+			key = strings.ToLower(attr(n, "name"))
+		}
+		if key == "" {
+			key = strings.ToLower(attr(n, "itemprop"))
+		}
+		if value := strings.TrimSpace(attr(n, "content")); key != "" && value != "" {
+			if _, ok := meta[key]; !ok {
+				meta[key] = value
+			}
+		}
+	}
+	ld := jsonLDMetadata(root)
+
+	metadata := make(map[string]string)
+	set := func(key string, candidates ...string) {
+		for _, candidate := range candidates {
+			if candidate = strings.TrimSpace(candidate); candidate != "" {
+				metadata[key] = candidate
+				return
+			}
+		}
+	}
+
+	set(MetaTitle, meta["og:title"], meta["twitter:title"], ld["headline"], pageTitle(root))
+	set(MetaAuthor,
+		meta["author"], meta["dc.creator"], meta["byl"], meta["parsely-author"], meta["sailthru.author"],
+		nonURL(meta["article:author"]), ld["author"])
+	set(MetaDescription, meta["og:description"], meta["description"], meta["twitter:description"], ld["description"])
+	set("site_name", meta["og:site_name"], meta["application-name"])
+	set("lang", attr(findFirst(root, atom.Html), "lang"))
+
+	var canonical string
+	for n := range root.Descendants() {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Link && strings.EqualFold(attr(n, "rel"), "canonical") {
+			canonical = resolveURL(base, attr(n, "href"))
+			break
+		}
+	}
+	set(MetaCanonicalURL, canonical, resolveURL(base, meta["og:url"]), resolveURL(base, ld["url"]))
+
+	for _, candidate := range []string{
+		meta["article:published_time"], meta["og:published_time"], meta["datepublished"], ld["datePublished"],
+		meta["date"], meta["pubdate"], meta["publishdate"], meta["dc.date"], meta["dc.date.issued"],
+		meta["dcterms.created"], meta["citation_publication_date"], meta["parsely-pub-date"], meta["sailthru.date"],
+		firstTimeElement(root),
+	} {
+		if published, ok := normalizeDate(candidate); ok {
+			metadata[MetaPublishedDate] = published
+			break
+		}
+	}
+
+	return metadata
+}
+
+// pageTitle returns the <title> text, or the page's only <h1> when the title looks like
+// "Post title | Site name" and contains it
+func pageTitle(root *html.Node) string {
+	title := collapseSpace(textContent(findFirst(root, atom.Title)))
+
+	var headings []string
+	for n := range root.Descendants() {
+		if n.Type == html.ElementNode && n.DataAtom == atom.H1 {
+			headings = append(headings, collapseSpace(textContent(n)))
+		}
+	}
+	if len(headings) == 1 && headings[0] != "" && (title == "" || strings.Contains(title, headings[0])) {
+		return headings[0]
+	}
+	return title
+}
+
+// firstTimeElement returns the datetime attribute of the first <time> element
+func firstTimeElement(root *html.Node) string {
+	for n := range root.Descendants() {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Time {
+			if value := attr(n, "datetime"); value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// jsonLDMetadata reads headline, author, datePublished, description and url from the first
+// JSON-LD object that has any of them, following arrays and @graph
+func jsonLDMetadata(root *html.Node) map[string]string {
+	values := make(map[string]string)
+	for n := range root.Descendants() {
+		if n.Type != html.ElementNode || n.DataAtom != atom.Script || !strings.EqualFold(attr(n, "type"), "application/ld+json") {
+			continue
+		}
+		var data interface{}
+		if err := json.Unmarshal([]byte(textContent(n)), &data); err != nil {
+			continue
+		}
+		collectJSONLD(data, values)
+		if len(values) > 0 {
+			break
+		}
+	}
+	return values
+}
+
+func collectJSONLD(data interface{}, values map[string]string) {
+	switch v := data.(type) {
+	case []interface{}:
+		for _, item := range v {
+			collectJSONLD(item, values)
+		}
+	case map[string]interface{}:
+		if graph, ok := v["@graph"]; ok {
+			collectJSONLD(graph, values)
+		}
+		for _, key := range []string{"headline", "datePublished", "description", "url"} {
+			if s, ok := v[key].(string); ok && values[key] == "" {
+				values[key] = s
+			}
+		}
+		if values["author"] == "" {
+			values["author"] = jsonLDName(v["author"])
+		}
+	}
+}
+
+// jsonLDName returns the name of a JSON-LD person, or the names of several joined with commas
+func jsonLDName(data interface{}) string {
+	switch v := data.(type) {
+	case string:
+		return nonURL(v)
+	case map[string]interface{}:
+		name, _ := v["name"].(string)
+		return name
+	case []interface{}:
+		var names []string
+		for _, item := range v {
+			if name := jsonLDName(item); name != "" {
+				names = append(names, name)
+			}
+		}
+		return strings.Join(names, ", ")
+	}
+	return ""
+}
+
+// dateLayouts are the published date formats found in page metadata
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"2006/01/02",
+	"20060102",
+	time.RFC1123Z,
+	time.RFC1123,
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+}
+
+// normalizeDate converts a date in one of dateLayouts to RFC3339
+func normalizeDate(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", false
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Format(time.RFC3339), true
+		}
+	}
+	return "", false
+}
+
+// nonURL drops values that are profile links rather than names
+func nonURL(value string) string {
+	if strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://") {
+		return ""
+	}
+	return value
+}
+
+// resolveURL resolves href against base; it returns "" for empty, fragment-only and script links
+func resolveURL(base *url.URL, href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") {
+		return ""
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		ref = base.ResolveReference(ref)
+	}
+	switch ref.Scheme {
+	case "http", "https", "mailto", "":
+		return ref.String()
+	default:
+		return ""
+	}
+}
+
+// attr returns an attribute of an element, or "" for a missing attribute or nil node
+func attr(n *html.Node, key string) string {
+	if n == nil {
+		return ""
+	}
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// findFirst returns the first element with the given tag
+func findFirst(root *html.Node, tag atom.Atom) *html.Node {
+	for n := range root.Descendants() {
+		if n.Type == html.ElementNode && n.DataAtom == tag {
+			return n
+		}
+	}
+	return nil
+}
+
+// textContent returns the text of a node and its descendants
+func textContent(n *html.Node) string {
+	if n == nil {
+		return ""
+	}
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for d := range n.Descendants() {
+		if d.Type == html.TextNode {
+			sb.WriteString(d.Data)
+		}
+	}
+	return sb.String()
+}
+
+// collapseSpace replaces runs of whitespace with single spaces and trims the result
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
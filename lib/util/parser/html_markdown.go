@@ -0,0 +1,284 @@
+package parser
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	spaceRun    = regexp.MustCompile(`[ \t\r\n\f]+`)
+	repeatSpace = regexp.MustCompile(` {2,}`)
+)
+
+// markdownRenderer converts HTML elements to Markdown, resolving links against base
+type markdownRenderer struct {
+	base      *url.URL
+	dropLinks bool // Render links as their text, for documents whose links point into the file itself
+}
+
+// htmlToMarkdown renders content elements as Markdown blocks separated by blank lines
+func htmlToMarkdown(nodes []*html.Node, base *url.URL) string {
+	return markdownRenderer{base: base}.render(nodes)
+}
+
+// render renders elements as Markdown blocks separated by blank lines
+func (m markdownRenderer) render(nodes []*html.Node) string {
+	var blocks []string
+	for _, n := range nodes {
+		blocks = append(blocks, m.block(n)...)
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+// block renders an element as one or more Markdown blocks
+func (m markdownRenderer) block(n *html.Node) []string {
+	if n.Type != html.ElementNode {
+		return m.blocks(n)
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		text := m.inlineText(n)
+		if text == "" {
+			return nil
+		}
+		level := int(n.Data[1] - '0')
+		return []string{strings.Repeat("#", level) + " " + text}
+	case atom.Pre:
+		code := strings.TrimRight(textContent(n), "\n")
+		if strings.TrimSpace(code) == "" {
+			return nil
+		}
+		fence := "```"
+		for strings.Contains(code, fence) {
+			fence += "`"
+		}
+		return []string{fence + codeLanguage(n) + "\n" + code + "\n" + fence}
+	case atom.Blockquote:
+		inner := m.blocks(n)
+		if len(inner) == 0 {
+			return nil
+		}
+		lines := strings.Split(strings.Join(inner, "\n\n"), "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight("> "+line, " ")
+		}
+		return []string{strings.Join(lines, "\n")}
+	case atom.Ul, atom.Ol:
+		if list := m.list(n); list != "" {
+			return []string{list}
+		}
+		return nil
+	case atom.Table:
+		if table := m.table(n); table != "" {
+			return []string{table}
+		}
+		return nil
+	case atom.Hr:
+		return []string{"---"}
+	case atom.Dt:
+		if text := m.inlineText(n); text != "" {
+			return []string{"**" + text + "**"}
+		}
+		return nil
+	}
+	return m.blocks(n)
+}
+
+// blocks renders the children of a node, grouping runs of inline content into paragraphs
+func (m markdownRenderer) blocks(n *html.Node) []string {
+	var out []string
+	var inline strings.Builder
+	flush := func() {
+		if text := cleanInline(inline.String()); text != "" {
+			out = append(out, text)
+		}
+		inline.Reset()
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch {
+		case c.Type == html.TextNode:
+			inline.WriteString(spaceRun.ReplaceAllString(c.Data, " "))
+		case c.Type != html.ElementNode:
+			continue
+		case blockTags[c.DataAtom] || c.DataAtom == atom.Table:
+			flush()
+			out = append(out, m.block(c)...)
+		default:
+			inline.WriteString(m.inline(c))
+		}
+	}
+	flush()
+	return out
+}
+
+// list renders a list with one item per line, indenting continuation lines and nested lists
+func (m markdownRenderer) list(n *html.Node) string {
+	var items []string
+	number := 1
+	if start, err := strconv.Atoi(attr(n, "start")); err == nil {
+		number = start
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || c.DataAtom != atom.Li {
+			continue
+		}
+		content := strings.Join(m.blocks(c), "\n")
+		if content == "" {
+			continue
+		}
+
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = strconv.Itoa(number) + ". "
+			number++
+		}
+		indent := strings.Repeat(" ", len(marker))
+		lines := strings.Split(content, "\n")
+		for i := 1; i < len(lines); i++ {
+			if lines[i] != "" {
+				lines[i] = indent + lines[i]
+			}
+		}
+		items = append(items, marker+strings.Join(lines, "\n"))
+	}
+	return strings.Join(items, "\n")
+}
+
+// table renders a table in the same Markdown form as tables extracted from Word documents
+func (m markdownRenderer) table(n *html.Node) string {
+	var rows [][]string
+	for d := range n.Descendants() {
+		if d.Type != html.ElementNode || d.DataAtom != atom.Tr || nearestTable(d) != n {
+			continue
+		}
+		var row []string
+		for cell := d.FirstChild; cell != nil; cell = cell.NextSibling {
+			if cell.Type == html.ElementNode && (cell.DataAtom == atom.Td || cell.DataAtom == atom.Th) {
+				row = append(row, m.inlineText(cell))
+			}
+		}
+		if len(row) > 0 {
+			rows = append(rows, row)
+		}
+	}
+	return markdownTable(rows)
+}
+
+// inline renders an inline element: links, code, emphasis and line breaks
+func (m markdownRenderer) inline(n *html.Node) string {
+	switch n.DataAtom {
+	case atom.Br:
+		return "\n"
+	case atom.Img:
+		return ""
+	case atom.Code, atom.Kbd, atom.Samp:
+		code := collapseSpace(textContent(n))
+		if code == "" {
+			return ""
+		}
+		fence := "`"
+		for strings.Contains(code, fence) {
+			fence += "`"
+		}
+		return fence + code + fence
+	case atom.Strong, atom.B:
+		return wrapInline(m.inlineChildren(n), "**")
+	case atom.Em, atom.I:
+		return wrapInline(m.inlineChildren(n), "*")
+	case atom.A:
+		text := m.inlineChildren(n)
+		if m.dropLinks {
+			return text
+		}
+		href := resolveURL(m.base, attr(n, "href"))
+		if href == "" || strings.TrimSpace(text) == "" {
+			return text
+		}
+		return wrapInline(text, "[", "]("+href+")")
+	}
+	return m.inlineChildren(n)
+}
+
+// inlineChildren renders the children of an element as inline content
+func (m markdownRenderer) inlineChildren(n *html.Node) string {
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.TextNode:
+			sb.WriteString(spaceRun.ReplaceAllString(c.Data, " "))
+		case html.ElementNode:
+			if blockTags[c.DataAtom] {
+				sb.WriteString(" " + m.inline(c) + " ")
+			} else {
+				sb.WriteString(m.inline(c))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// inlineText renders an element's content on a single line
+func (m markdownRenderer) inlineText(n *html.Node) string {
+	return collapseSpace(m.inlineChildren(n))
+}
+
+// wrapInline surrounds text with Markdown markers, keeping its outer spaces outside them
+func wrapInline(text, open string, closing ...string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	end := open
+	if len(closing) > 0 {
+		end = closing[0]
+	}
+	lead := text[:strings.Index(text, trimmed)]
+	trail := text[len(lead)+len(trimmed):]
+	return lead + open + trimmed + end + trail
+}
+
+// cleanInline collapses spaces in a paragraph and trims each of its lines
+func cleanInline(s string) string {
+	lines := strings.Split(s, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if line = strings.TrimSpace(repeatSpace.ReplaceAllString(line, " ")); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// codeLanguage returns the language of a code block from a "language-" or "lang-" class
+func codeLanguage(pre *html.Node) string {
+	classes := attr(pre, "class")
+	if code := findFirst(pre, atom.Code); code != nil {
+		classes += " " + attr(code, "class")
+	}
+	for _, class := range strings.Fields(classes) {
+		for _, prefix := range []string{"language-", "lang-"} {
+			if lang, ok := strings.CutPrefix(class, prefix); ok && lang != "" {
+				return lang
+			}
+		}
+	}
+	return ""
+}
+
+// nearestTable returns the table element a row belongs to
+func nearestTable(n *html.Node) *html.Node {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.DataAtom == atom.Table {
+			return p
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+)
+
+// TypeImage is the type of image files, which are read with OCR
+const TypeImage = "image"
+
+func init() {
+	Register(imageParser{formatParser{
+		TypeImage,
+		[]string{".png", ".jpg", ".jpeg", ".gif", ".tif", ".tiff", ".webp"},
+		[]string{"image/png", "image/jpeg", "image/gif", "image/tiff", "image/webp"},
+		ParseImage,
+	}})
+}
+
+// imageSignatures maps the bytes image files start with to their MIME type
+var imageSignatures = []struct {
+	signature []byte
+	mediaType string
+}{
+	{[]byte("\x89PNG\r\n\x1a\n"), "image/png"},
+	{[]byte("\xff\xd8\xff"), "image/jpeg"},
+	{[]byte("GIF87a"), "image/gif"},
+	{[]byte("GIF89a"), "image/gif"},
+	{[]byte("II*\x00"), "image/tiff"},
+	{[]byte("MM\x00*"), "image/tiff"},
+}
+
+// imageMediaType returns the MIME type of an image from its first bytes, or "" when it is not
+// an image format that can be read
+func imageMediaType(header []byte) string {
+	for _, s := range imageSignatures {
+		if bytes.HasPrefix(header, s.signature) {
+			return s.mediaType
+		}
+	}
+	// RIFF, the chunk size, then the form type
+	if len(header) >= 12 && bytes.Equal(header[:4], []byte("RIFF")) && bytes.Equal(header[8:12], []byte("WEBP")) {
+		return "image/webp"
+	}
+	return ""
+}
+
+// imageParser recognizes images by signature; WebP's signature is split around the file size,
+// so the prefixes of signatureParser do not describe it
+type imageParser struct {
+	formatParser
+}
+
+func (p imageParser) Sniff(header []byte) bool {
+	return imageMediaType(header) != ""
+}
+
+// ParseImage reads the text of an image, such as a scanned page or a photo of a document, with
+// the OCR engine set on ctx with WithOCR, within limits.OCRTimeout. It fails with
+// ErrUnsupportedFormat when there is no engine.
+func ParseImage(ctx context.Context, r io.ReaderAt, size int64, filename string, limits Limits) (*Document, error) {
+	ocr := ocrFrom(ctx)
+	if ocr == nil {
+		return nil, fmt.Errorf("%w: images are read with OCR, which is not configured", ErrUnsupportedFormat)
+	}
+
+	data, err := io.ReadAll(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, err
+	}
+	mediaType := imageMediaType(data)
+	if mediaType == "" {
+		return nil, fmt.Errorf("%w: not a PNG, JPEG, GIF, TIFF or WebP image", ErrUnsupportedFormat)
+	}
+
+	ocrCtx, cancel := withOCRTimeout(ctx, limits)
+	defer cancel()
+	text, confidence, err := ocr.Recognize(ocrCtx, data, mediaType)
+	if err != nil {
+		if cause := context.Cause(ocrCtx); cause != nil {
+			return nil, cause
+		}
+		return nil, fmt.Errorf("failed to recognize text: %w", err)
+	}
+
+	metadata := map[string]string{
+		"type":           TypeImage,
+		"media_type":     mediaType,
+		"ocr_confidence": formatConfidence(confidence),
+	}
+	content := ocrMarkdown(text)
+
+	var warnings []string
+	if content == "" {
+		warnings = append(warnings, "no text was recognized in the image")
+	} else if warning := lowConfidenceWarning("image", confidence); warning != "" {
+		warnings = append(warnings, warning)
+	}
+
+	return &Document{
+		Title:    documentTitle(metadata, filename),
+		Content:  content,
+		Metadata: metadata,
+		Warnings: warnings,
+	}, nil
+}
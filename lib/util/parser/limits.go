@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrLimitExceeded is returned when a file exceeds one of the parsing limits
+var ErrLimitExceeded = errors.New("file exceeds parsing limits")
+
+// Limits protects parsing against oversized or malicious files
+type Limits struct {
+	MaxUncompressedSize int64         // Total bytes decompressed from a zip-based document (DOCX, XLSX, PPTX, ODT, ODS, EPUB)
+	MaxZipEntries       int           // Files in a zip-based document
+	MaxPDFPages         int           // Pages of a PDF
+	MaxOCRPages         int           // Pages of a PDF read with OCR; 0 disables the limit
+	MaxSheetRows        int           // Rows read from each sheet of a spreadsheet or CSV file; 0 disables the limit
+	MaxSheetColumns     int           // Columns read from each sheet; 0 disables the limit
+	Timeout             time.Duration // Time spent extracting text from one file, not counting OCR; 0 disables the limit
+	OCRTimeout          time.Duration // Time spent reading the scanned pages or image of one file with OCR; 0 disables the limit
+}
+
+// DefaultLimits returns limits sized for large specification documents
+func DefaultLimits() Limits {
+	return Limits{
+		MaxUncompressedSize: 1 << 30,
+		MaxZipEntries:       10000,
+		MaxPDFPages:         5000,
+		MaxOCRPages:         100,
+		MaxSheetRows:        100000,
+		MaxSheetColumns:     200,
+		Timeout:             5 * time.Minute,
+		OCRTimeout:          15 * time.Minute,
+	}
+}
+
+// openZip opens a zip-based document after checking its declared entry count and sizes.
+// Declared sizes can be forged, so entries must still be read through the returned budget.
+func openZip(r io.ReaderAt, size int64, limits Limits) (*zip.Reader, *zipBudget, error) {
+	zipReader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open zip archive: %w", err)
+	}
+
+	if limits.MaxZipEntries > 0 && len(zipReader.File) > limits.MaxZipEntries {
+		return nil, nil, fmt.Errorf("%w: archive has %d entries, limit is %d", ErrLimitExceeded, len(zipReader.File), limits.MaxZipEntries)
+	}
+
+	if limits.MaxUncompressedSize > 0 {
+		var total uint64
+		for _, f := range zipReader.File {
+			total += f.UncompressedSize64
+			if total > uint64(limits.MaxUncompressedSize) {
+				return nil, nil, fmt.Errorf("%w: archive expands to more than %d bytes", ErrLimitExceeded, limits.MaxUncompressedSize)
+			}
+		}
+	}
+
+	return zipReader, &zipBudget{limit: limits.MaxUncompressedSize}, nil
+}
+
+// zipBudget counts the bytes decompressed from the entries of one zip-based document and fails
+// reads once they pass limits.MaxUncompressedSize. It is shared by all entries, so a document
+// cannot exceed the limit by spreading its data over entries, or by reading one twice.
+type zipBudget struct {
+	limit int64
+	read  int64
+}
+
+// zipEntryReader reads a decompressed archive entry, counting the bytes produced toward its budget
+type zipEntryReader struct {
+	rc     io.ReadCloser
+	budget *zipBudget
+}
+
+// open opens an archive entry whose decompressed bytes count toward the budget
+func (b *zipBudget) open(f *zip.File) (io.ReadCloser, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	if b.limit <= 0 {
+		return rc, nil
+	}
+	return &zipEntryReader{rc: rc, budget: b}, nil
+}
+
+func (z *zipEntryReader) Read(p []byte) (int, error) {
+	n, err := z.rc.Read(p)
+	z.budget.read += int64(n)
+	if z.budget.read > z.budget.limit {
+		return n, fmt.Errorf("%w: archive expands to more than %d bytes", ErrLimitExceeded, z.budget.limit)
+	}
+	return n, err
+}
+
+func (z *zipEntryReader) Close() error {
+	return z.rc.Close()
+}
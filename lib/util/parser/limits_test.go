@@ -0,0 +1,244 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"context"
+	"errors"
+	"hash/crc32"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type zipEntry struct {
+	name string
+	data []byte
+	// declared replaces the uncompressed size written to the headers when it is not zero
+	declared uint64
+}
+
+func buildZip(t *testing.T, entries []zipEntry) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		if entry.declared == 0 {
+			w, err := zw.Create(entry.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := w.Write(entry.data); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		var compressed bytes.Buffer
+		fw, err := flate.NewWriter(&compressed, flate.BestCompression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(entry.data)
+		fw.Close()
+		w, err := zw.CreateRaw(&zip.FileHeader{
+			Name:               entry.name,
+			Method:             zip.Deflate,
+			CRC32:              crc32.ChecksumIEEE(entry.data),
+			CompressedSize64:   uint64(compressed.Len()),
+			UncompressedSize64: entry.declared,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(compressed.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func zipLimits(size int64, entries int) Limits {
+	limits := DefaultLimits()
+	limits.MaxUncompressedSize = size
+	limits.MaxZipEntries = entries
+	return limits
+}
+
+// readEntry reads a whole entry through the budget and returns the bytes produced
+func readEntry(budget *zipBudget, f *zip.File) (int64, error) {
+	rc, err := budget.open(f)
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	return io.Copy(io.Discard, rc)
+}
+
+func TestOpenZipChecksDeclaredLimits(t *testing.T) {
+	entries := []zipEntry{
+		{name: "a.xml", data: make([]byte, 600<<10)},
+		{name: "b.xml", data: make([]byte, 600<<10)},
+		{name: "c.xml", data: []byte("<c/>")},
+	}
+	r := buildZip(t, entries)
+
+	tests := []struct {
+		name    string
+		limits  Limits
+		wantErr bool
+	}{
+		{"within limits", zipLimits(2<<20, 10), false},
+		{"declared total over the size limit", zipLimits(1<<20, 10), true},
+		{"too many entries", zipLimits(2<<20, 2), true},
+		{"limits disabled", zipLimits(0, 0), false},
+	}
+	for _, tt := range tests {
+		_, _, err := openZip(r, r.Size(), tt.limits)
+		if tt.wantErr && !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("%s: openZip = %v, want ErrLimitExceeded", tt.name, err)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("%s: openZip = %v, want nil", tt.name, err)
+		}
+	}
+}
+
+func TestZipEntryWithForgedSize(t *testing.T) {
+	// The headers claim 1 KB, so the declared total passes, but the entry expands to 4 MB
+	r := buildZip(t, []zipEntry{{name: "word/document.xml", data: make([]byte, 4<<20), declared: 1 << 10}})
+	limits := zipLimits(1<<20, 10)
+
+	zr, budget, err := openZip(r, r.Size(), limits)
+	if err != nil {
+		t.Fatalf("openZip = %v, expected the forged size to pass the declared check", err)
+	}
+	read, err := readEntry(budget, zr.File[0])
+	if !errors.Is(err, ErrLimitExceeded) && !errors.Is(err, zip.ErrFormat) {
+		t.Fatalf("reading a forged entry = %v, want ErrLimitExceeded or zip.ErrFormat", err)
+	}
+	if read > limits.MaxUncompressedSize {
+		t.Fatalf("read %d bytes past a limit of %d", read, limits.MaxUncompressedSize)
+	}
+
+	if _, err := ParseDocx(context.Background(), r, r.Size(), "forged.docx", limits); err == nil {
+		t.Fatal("ParseDocx accepted a document with a forged entry size")
+	}
+}
+
+func TestZipBudgetIsSharedByEntries(t *testing.T) {
+	entries := []zipEntry{
+		{name: "a.xml", data: make([]byte, 400<<10)},
+		{name: "b.xml", data: make([]byte, 400<<10)},
+		{name: "c.xml", data: make([]byte, 400<<10)},
+	}
+	r := buildZip(t, entries)
+	zr, err := zip.NewReader(r, r.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each entry fits the budget on its own; the third one takes the total past it
+	budget := &zipBudget{limit: 1 << 20}
+	for i, f := range zr.File {
+		_, err := readEntry(budget, f)
+		if i < 2 && err != nil {
+			t.Fatalf("entry %d: %v, want nil", i, err)
+		}
+		if i == 2 && !errors.Is(err, ErrLimitExceeded) {
+			t.Fatalf("entry %d: %v, want ErrLimitExceeded", i, err)
+		}
+	}
+}
+
+func TestZipBudgetCountsRepeatedReads(t *testing.T) {
+	entries := []zipEntry{
+		{name: "a.xml", data: make([]byte, 600<<10)},
+		{name: "b.xml", data: make([]byte, 600<<10)},
+	}
+	r := buildZip(t, entries)
+	zr, budget, err := openZip(r, r.Size(), zipLimits(1536<<10, 10))
+	if err != nil {
+		t.Fatalf("openZip = %v, want nil", err)
+	}
+
+	for _, f := range zr.File {
+		if _, err := readEntry(budget, f); err != nil {
+			t.Fatalf("%s: %v, want nil", f.Name, err)
+		}
+	}
+	// Reading an entry again counts its bytes again
+	if _, err := readEntry(budget, zr.File[0]); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("second read of %s = %v, want ErrLimitExceeded", zr.File[0].Name, err)
+	}
+}
+
+func TestZipBudgetDisabled(t *testing.T) {
+	r := buildZip(t, []zipEntry{{name: "a.xml", data: []byte(strings.Repeat("x", 4096))}})
+	zr, budget, err := openZip(r, r.Size(), zipLimits(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if read, err := readEntry(budget, zr.File[0]); err != nil || read != 4096 {
+			t.Fatalf("read %d bytes, %v; want 4096 without a limit", read, err)
+		}
+	}
+}
+
+func TestParseWithLimitsKeepsSlotOfAbandonedParse(t *testing.T) {
+	limits := DefaultLimits()
+	limits.Timeout = 20 * time.Millisecond
+
+	// The parse ignores ctx, like a decoder stuck inside one page
+	release := make(chan struct{})
+	returned := make(chan struct{})
+	_, err := parseWithLimits(context.Background(), "stuck.pdf", limits, func(context.Context) (*Document, error) {
+		defer close(returned)
+		<-release
+		return &Document{}, nil
+	})
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("parseWithLimits = %v, want ErrLimitExceeded", err)
+	}
+	if len(parseSlots) != 1 {
+		t.Fatalf("%d slots held after the timeout, want the abandoned parse to keep its slot", len(parseSlots))
+	}
+
+	close(release)
+	<-returned
+	deadline := time.Now().Add(time.Second)
+	for len(parseSlots) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the slot was not released after the parse returned")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestParseWithLimitsWaitsForSlot(t *testing.T) {
+	for i := 0; i < cap(parseSlots); i++ {
+		parseSlots <- struct{}{}
+	}
+	defer func() {
+		for i := 0; i < cap(parseSlots); i++ {
+			<-parseSlots
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	called := false
+	_, err := parseWithLimits(ctx, "queued.pdf", DefaultLimits(), func(context.Context) (*Document, error) {
+		called = true
+		return &Document{}, nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || called {
+		t.Fatalf("parseWithLimits = %v (parse called: %v), want to give up waiting for a slot", err, called)
+	}
+}
@@ -0,0 +1,40 @@
+package parser
+
+import "strings"
+
+// markdownTable renders rows as a Markdown table with the first row as its header.
+// Rows shorter than the widest row are padded with empty cells.
+func markdownTable(rows [][]string) string {
+	maxCols := 0
+	for _, row := range rows {
+		maxCols = max(maxCols, len(row))
+	}
+	if maxCols == 0 {
+		return ""
+	}
+
+	sanitize := func(s string) string {
+		return strings.TrimSpace(strings.ReplaceAll(strings.ReplaceAll(s, "|", "\\|"), "\n", " "))
+	}
+
+	var sb strings.Builder
+	for i, row := range rows {
+		sb.WriteString("|")
+		for j := range maxCols {
+			val := ""
+			if j < len(row) {
+				val = sanitize(row[j])
+			}
+			sb.WriteString(" " + val + " |")
+		}
+		sb.WriteString("\n")
+		if i == 0 {
+			sb.WriteString("|")
+			for range maxCols {
+				sb.WriteString(" --- |")
+			}
+			sb.WriteString("\n")
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
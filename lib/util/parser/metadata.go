@@ -0,0 +1,220 @@
+package parser
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// Metadata keys shared by the parsers. Parsers only set the keys they find.
+const (
+	MetaTitle         = "title"
+	MetaAuthor        = "author"
+	MetaDescription   = "description"
+	MetaPublishedDate = "published_date" // RFC3339
+	MetaURL           = "url"            // Where the document was originally published
+	MetaCanonicalURL  = "canonical_url"  // The canonical URL a web page declares
+	MetaTags          = "tags"           // Comma-separated
+	MetaAliases       = "aliases"        // Comma-separated alternative titles
+)
+
+// setMeta stores a metadata value with its whitespace collapsed, unless it is empty or the key
+// is already set by a more reliable source
+func setMeta(metadata map[string]string, key, value string) {
+	if value = collapseSpace(value); value == "" || metadata[key] != "" {
+		return
+	}
+	metadata[key] = value
+}
+
+// setMetaDate stores a date in RFC3339 when it can be parsed
+func setMetaDate(metadata map[string]string, key, value string) {
+	if date, ok := normalizeDate(value); ok {
+		setMeta(metadata, key, date)
+	}
+}
+
+// setMetaList stores a list as a comma-separated value, dropping empty and repeated items
+func setMetaList(metadata map[string]string, key string, values []string) {
+	seen := make(map[string]bool, len(values))
+	var items []string
+	for _, value := range values {
+		value = strings.TrimSpace(strings.ReplaceAll(collapseSpace(value), ",", " "))
+		if value == "" || seen[strings.ToLower(value)] {
+			continue
+		}
+		seen[strings.ToLower(value)] = true
+		items = append(items, value)
+	}
+	setMeta(metadata, key, strings.Join(items, ", "))
+}
+
+// splitKeywords splits a keyword field, which tools separate with commas or semicolons
+func splitKeywords(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' })
+}
+
+// documentTitle returns the title from the document's metadata, or the file name without its
+// extension when the document has none
+func documentTitle(metadata map[string]string, filename string) string {
+	if title := metadata[MetaTitle]; title != "" {
+		return title
+	}
+	return strings.TrimSuffix(filename, path.Ext(filename))
+}
+
+// usableTitle reports whether a title from document properties names the document rather than
+// being a placeholder left by the authoring tool, such as "Untitled" or "Microsoft Word - a.docx"
+func usableTitle(title string) bool {
+	lower := strings.ToLower(strings.TrimSpace(title))
+	switch {
+	case lower == "", lower == "untitled", strings.HasPrefix(lower, "untitled "):
+		return false
+	case strings.HasPrefix(lower, "microsoft "):
+		return false
+	}
+	switch path.Ext(lower) {
+	case ".doc", ".docx", ".pdf", ".ppt", ".pptx", ".xls", ".xlsx", ".odt", ".txt", ".rtf", ".tex", ".dvi", ".indd":
+		return false
+	}
+	return true
+}
+
+// corePropertiesPart holds the Dublin Core properties of Office Open XML documents
+const corePropertiesPart = "docProps/core.xml"
+
+// readCoreProperties adds the title, creator, creation date, keywords and description of an
+// Office Open XML document to metadata. Documents without core properties add nothing.
+func readCoreProperties(zr *zip.Reader, budget *zipBudget, metadata map[string]string) error {
+	f := findZipFile(zr, corePropertiesPart)
+	if f == nil {
+		return nil
+	}
+
+	var props struct {
+		Title       string `xml:"title"`
+		Subject     string `xml:"subject"`
+		Creator     string `xml:"creator"`
+		Keywords    string `xml:"keywords"`
+		Description string `xml:"description"`
+		Created     string `xml:"created"`
+	}
+	if err := decodeZipXML(f, budget, &props); err != nil {
+		return err
+	}
+
+	if usableTitle(props.Title) {
+		setMeta(metadata, MetaTitle, props.Title)
+	}
+	setMeta(metadata, MetaAuthor, props.Creator)
+	setMetaDate(metadata, MetaPublishedDate, props.Created)
+	setMetaList(metadata, MetaTags, splitKeywords(props.Keywords))
+	setMeta(metadata, MetaDescription, props.Description)
+	setMeta(metadata, MetaDescription, props.Subject)
+	return nil
+}
+
+// xmpNamespaces are the XMP schemas the document properties are read from
+var xmpNamespaces = map[string]bool{
+	"http://purl.org/dc/elements/1.1/": true,
+	"http://ns.adobe.com/xap/1.0/":     true,
+	"http://ns.adobe.com/pdf/1.3/":     true,
+}
+
+// readXMP collects the values of XMP properties by local name. Simple properties and the items
+// of rdf:Alt, rdf:Bag and rdf:Seq containers are both read, whether written as elements or as
+// attributes of rdf:Description.
+func readXMP(r io.Reader) (map[string][]string, error) {
+	values := make(map[string][]string)
+	decoder := xml.NewDecoder(r)
+
+	var (
+		property string
+		depth    int
+		text     strings.Builder
+	)
+	for {
+		t, err := decoder.Token()
+		if err == io.EOF {
+			return values, nil
+		}
+		if err != nil {
+			return values, fmt.Errorf("failed to read xmp: %w", err)
+		}
+
+		switch se := t.(type) {
+		case xml.StartElement:
+			if property != "" {
+				depth++
+				text.Reset()
+				continue
+			}
+			if se.Name.Local == "Description" {
+				for _, a := range se.Attr {
+					if xmpNamespaces[a.Name.Space] {
+						values[a.Name.Local] = append(values[a.Name.Local], a.Value)
+					}
+				}
+				continue
+			}
+			if xmpNamespaces[se.Name.Space] {
+				property, depth = se.Name.Local, 0
+				text.Reset()
+			}
+		case xml.CharData:
+			if property != "" {
+				text.Write(se)
+			}
+		case xml.EndElement:
+			if property == "" {
+				continue
+			}
+			// Values are the text of the property itself or of its container items
+			if value := strings.TrimSpace(text.String()); value != "" {
+				values[property] = append(values[property], value)
+			}
+			text.Reset()
+			if depth == 0 {
+				property = ""
+			} else {
+				depth--
+			}
+		}
+	}
+}
+
+// parsePDFDate converts a PDF date string (D:YYYYMMDDHHmmSSOHH'mm') to RFC3339. Every part after
+// the year is optional; dates without a time zone are taken as UTC.
+func parsePDFDate(value string) (string, bool) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "D:")
+	digits := value
+	for i, r := range value {
+		if r < '0' || r > '9' {
+			digits = value[:i]
+			break
+		}
+	}
+	if len(digits) < 4 || len(digits) > 14 || len(digits)%2 != 0 {
+		return "", false
+	}
+
+	// Pad the missing parts with January 1st, midnight
+	const defaults = "00000101000000"
+	stamp := digits + defaults[len(digits):]
+	date, err := time.Parse("20060102150405", stamp)
+	if err != nil {
+		return "", false
+	}
+
+	zone := strings.ReplaceAll(strings.TrimSuffix(value[len(digits):], "'"), "'", "")
+	if len(zone) == 5 && (zone[0] == '+' || zone[0] == '-') {
+		if offset, err := time.Parse("-0700", zone); err == nil {
+			date = time.Date(date.Year(), date.Month(), date.Day(), date.Hour(), date.Minute(), date.Second(), 0, offset.Location())
+		}
+	}
+	return date.Format(time.RFC3339), true
+}
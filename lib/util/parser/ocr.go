@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// OCR reads the text of an image. Implementations live in lib/client/ocr.
+type OCR interface {
+	// Recognize returns the text of an image and the engine's confidence in it, from 0 to 100.
+	// mediaType is the image's MIME type, such as "image/png".
+	Recognize(ctx context.Context, image []byte, mediaType string) (text string, confidence float64, err error)
+}
+
+// lowOCRConfidence is the confidence below which recognized text is reported in the warnings
+const lowOCRConfidence = 60
+
+type ocrContextKey struct{}
+
+// WithOCR returns a context that lets parsers read scanned pages and images with ocr.
+// Without it, PDF pages that have no text layer are skipped and images are rejected.
+func WithOCR(ctx context.Context, ocr OCR) context.Context {
+	if ocr == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, ocrContextKey{}, ocr)
+}
+
+// ocrFrom returns the OCR engine set with WithOCR, or nil
+func ocrFrom(ctx context.Context) OCR {
+	ocr, _ := ctx.Value(ocrContextKey{}).(OCR)
+	return ocr
+}
+
+// withOCRTimeout returns a context for the OCR of one file that is done after limits.OCRTimeout
+func withOCRTimeout(ctx context.Context, limits Limits) (context.Context, context.CancelFunc) {
+	if limits.OCRTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, limits.OCRTimeout, fmt.Errorf("%w: OCR took longer than %s", ErrLimitExceeded, limits.OCRTimeout))
+}
+
+// ocrMarkdown turns recognized text into paragraphs. Engines break lines where the image does,
+// so the lines of a paragraph are joined; blank lines separate paragraphs.
+func ocrMarkdown(text string) string {
+	var paragraphs []string
+	for _, block := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		var lines []string
+		for _, line := range strings.Split(block, "\n") {
+			if line = collapseSpace(line); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) > 0 {
+			paragraphs = append(paragraphs, joinPDFLines(lines))
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// formatConfidence formats an OCR confidence for the metadata
+func formatConfidence(confidence float64) string {
+	return strconv.FormatFloat(confidence, 'f', 1, 64)
+}
+
+// lowConfidenceWarning describes recognized text whose confidence is low enough that it should be
+// checked, or returns "" when it is not
+func lowConfidenceWarning(what string, confidence float64) string {
+	if confidence >= lowOCRConfidence {
+		return ""
+	}
+	return fmt.Sprintf("%s: OCR confidence is low (%s), the text may contain errors", what, formatConfidence(confidence))
+}
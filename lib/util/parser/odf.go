@@ -0,0 +1,279 @@
+package parser
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// odfMaxRepeat caps repeated spreadsheet rows and columns, which files use to pad sheets
+	// to their full size
+	odfMaxRepeat = 1000
+	// odfContentPart holds the body of an OpenDocument file
+	odfContentPart = "content.xml"
+)
+
+// odfSkippedElements hold comments, change tracking and footnote markers rather than document text
+var odfSkippedElements = map[string]bool{
+	"annotation": true, "tracked-changes": true, "note-citation": true, "sequence-decls": true,
+	"variable-decls": true, "user-field-decls": true, "forms": true, "scripts": true,
+}
+
+func init() {
+	Register(zipFormatParser{
+		formatParser: formatParser{TypeOdt, []string{".odt"}, []string{"application/vnd.oasis.opendocument.text"}, ParseOdt},
+	})
+	Register(zipFormatParser{
+		formatParser: formatParser{TypeOds, []string{".ods"}, []string{"application/vnd.oasis.opendocument.spreadsheet"}, ParseOds},
+	})
+}
+
+// ParseOdt extracts text from an OpenDocument text file as Markdown with headings, lists and tables
+func ParseOdt(ctx context.Context, r io.ReaderAt, size int64, filename string, limits Limits) (*Document, error) {
+	content, err := readODFContent(ctx, r, size, limits, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse odt: %w", err)
+	}
+	return &Document{
+		Title:    strings.TrimSuffix(filename, ".odt"),
+		Content:  content,
+		Metadata: map[string]string{"type": "odt"},
+	}, nil
+}
+
+// ParseOds extracts an OpenDocument spreadsheet as one Markdown table per sheet
+func ParseOds(ctx context.Context, r io.ReaderAt, size int64, filename string, limits Limits) (*Document, error) {
+	content, err := readODFContent(ctx, r, size, limits, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ods: %w", err)
+	}
+	return &Document{
+		Title:    strings.TrimSuffix(filename, ".ods"),
+		Content:  content,
+		Metadata: map[string]string{"type": "ods"},
+	}, nil
+}
+
+// odfTable collects the rows of a table while content.xml is read
+type odfTable struct {
+	name       string
+	rows       [][]string
+	row        []string
+	emptyCols  int // Empty cells not yet added to row; dropped when nothing follows them
+	cell       []string
+	cellRepeat int
+	rowRepeat  int
+}
+
+// readODFContent converts content.xml of an OpenDocument file to Markdown.
+// Spreadsheets get a heading per sheet, as Excel files do.
+func readODFContent(ctx context.Context, r io.ReaderAt, size int64, limits Limits, spreadsheet bool) (string, error) {
+	zipReader, budget, err := openZip(r, size, limits)
+	if err != nil {
+		return "", err
+	}
+	f := findZipFile(zipReader, odfContentPart)
+	if f == nil {
+		return "", fmt.Errorf("%s not found", odfContentPart)
+	}
+	rc, err := budget.open(f)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	var (
+		decoder = xml.NewDecoder(rc)
+		blocks  []string
+
+		paragraphs []*strings.Builder // Paragraphs can nest through frames and notes
+		heading    int
+
+		listDepth     int
+		listLines     []string
+		pendingBullet bool
+
+		tables []*odfTable
+	)
+
+	emit := func(text string) {
+		switch {
+		case len(tables) > 0:
+			table := tables[len(tables)-1]
+			table.cell = append(table.cell, strings.ReplaceAll(text, "\n", " "))
+		case listDepth > 0:
+			indent := strings.Repeat("  ", listDepth-1)
+			if pendingBullet {
+				listLines = append(listLines, indent+"- "+text)
+				pendingBullet = false
+			} else {
+				listLines = append(listLines, indent+"  "+text)
+			}
+		case heading > 0:
+			blocks = append(blocks, strings.Repeat("#", min(heading, 6))+" "+strings.ReplaceAll(text, "\n", " "))
+		default:
+			blocks = append(blocks, text)
+		}
+	}
+
+	for {
+		t, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", odfContentPart, err)
+		}
+
+		switch se := t.(type) {
+		case xml.StartElement:
+			if odfSkippedElements[se.Name.Local] {
+				if err := decoder.Skip(); err != nil {
+					return "", fmt.Errorf("failed to read %s: %w", odfContentPart, err)
+				}
+				continue
+			}
+			switch se.Name.Local {
+			case "h":
+				heading, _ = strconv.Atoi(xmlAttr(se, "outline-level"))
+				heading = max(heading, 1)
+				paragraphs = append(paragraphs, &strings.Builder{})
+			case "p":
+				paragraphs = append(paragraphs, &strings.Builder{})
+			case "s":
+				if len(paragraphs) > 0 {
+					count, err := strconv.Atoi(xmlAttr(se, "c"))
+					if err != nil || count < 1 {
+						count = 1
+					}
+					paragraphs[len(paragraphs)-1].WriteString(strings.Repeat(" ", min(count, odfMaxRepeat)))
+				}
+			case "tab":
+				if len(paragraphs) > 0 {
+					paragraphs[len(paragraphs)-1].WriteString(" ")
+				}
+			case "line-break":
+				if len(paragraphs) > 0 {
+					paragraphs[len(paragraphs)-1].WriteString("\n")
+				}
+			case "list":
+				listDepth++
+			case "list-item", "list-header":
+				pendingBullet = se.Name.Local == "list-item"
+			case "table":
+				if err := context.Cause(ctx); err != nil {
+					return "", err
+				}
+				tables = append(tables, &odfTable{name: xmlAttr(se, "name")})
+			case "table-row":
+				if len(tables) > 0 {
+					table := tables[len(tables)-1]
+					table.row, table.emptyCols = nil, 0
+					table.rowRepeat = odfRepeat(se, "number-rows-repeated")
+				}
+			case "table-cell", "covered-table-cell":
+				if len(tables) > 0 {
+					table := tables[len(tables)-1]
+					table.cell = nil
+					table.cellRepeat = odfRepeat(se, "number-columns-repeated")
+				}
+			}
+		case xml.CharData:
+			if len(paragraphs) > 0 {
+				paragraphs[len(paragraphs)-1].WriteString(spaceRun.ReplaceAllString(string(se), " "))
+			}
+		case xml.EndElement:
+			switch se.Name.Local {
+			case "h", "p":
+				if len(paragraphs) == 0 {
+					continue
+				}
+				text := cleanInline(paragraphs[len(paragraphs)-1].String())
+				paragraphs = paragraphs[:len(paragraphs)-1]
+				if text != "" {
+					if len(paragraphs) > 0 {
+						// A nested paragraph, such as a frame caption, joins the paragraph around it
+						paragraphs[len(paragraphs)-1].WriteString(" " + text + " ")
+					} else {
+						emit(text)
+					}
+				}
+				if se.Name.Local == "h" {
+					heading = 0
+				}
+			case "list":
+				listDepth = max(listDepth-1, 0)
+				if listDepth == 0 && len(listLines) > 0 && len(tables) == 0 {
+					blocks = append(blocks, strings.Join(listLines, "\n"))
+					listLines = nil
+				}
+			case "table-cell", "covered-table-cell":
+				if len(tables) == 0 {
+					continue
+				}
+				table := tables[len(tables)-1]
+				value := strings.Join(table.cell, " ")
+				if value == "" {
+					table.emptyCols += table.cellRepeat
+					continue
+				}
+				for range table.emptyCols {
+					table.row = append(table.row, "")
+				}
+				table.emptyCols = 0
+				for range table.cellRepeat {
+					table.row = append(table.row, value)
+				}
+			case "table-row":
+				if len(tables) == 0 {
+					continue
+				}
+				table := tables[len(tables)-1]
+				if len(table.row) == 0 {
+					continue // Empty rows, often repeated to the end of the sheet, carry nothing
+				}
+				for range table.rowRepeat {
+					table.rows = append(table.rows, table.row)
+				}
+			case "table":
+				if len(tables) == 0 {
+					continue
+				}
+				table := tables[len(tables)-1]
+				tables = tables[:len(tables)-1]
+				rendered := markdownTable(table.rows)
+				if rendered == "" {
+					continue
+				}
+				if len(tables) > 0 {
+					// A nested table is flattened into the cell that holds it
+					outer := tables[len(tables)-1]
+					outer.cell = append(outer.cell, strings.ReplaceAll(rendered, "\n", " "))
+					continue
+				}
+				if spreadsheet {
+					blocks = append(blocks, "### Sheet: "+table.name)
+				}
+				blocks = append(blocks, rendered)
+			}
+		}
+	}
+
+	if len(listLines) > 0 {
+		blocks = append(blocks, strings.Join(listLines, "\n"))
+	}
+	return strings.Join(blocks, "\n\n"), nil
+}
+
+// odfRepeat reads a repeat count attribute, capped at odfMaxRepeat
+func odfRepeat(se xml.StartElement, name string) int {
+	n, err := strconv.Atoi(xmlAttr(se, name))
+	if err != nil || n < 1 {
+		return 1
+	}
+	return min(n, odfMaxRepeat)
+}
@@ -0,0 +1,78 @@
+package parser
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Helpers shared by the zip-based formats: Office Open XML, OpenDocument and EPUB
+
+// findZipFile returns the archive entry with the given name, or nil when there is none
+func findZipFile(zr *zip.Reader, name string) *zip.File {
+	name = strings.TrimPrefix(name, "/")
+	for _, f := range zr.File {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// decodeZipXML decodes an XML archive entry into v, counting its bytes toward the budget
+func decodeZipXML(f *zip.File, budget *zipBudget, v interface{}) error {
+	rc, err := budget.open(f)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", f.Name, err)
+	}
+	return nil
+}
+
+// relationship is an entry of an Office Open XML .rels part
+type relationship struct {
+	ID     string `xml:"Id,attr"`
+	Type   string `xml:"Type,attr"`
+	Target string `xml:"Target,attr"`
+	Mode   string `xml:"TargetMode,attr"`
+}
+
+// readRelationships reads the relationships of an Office Open XML part, keyed by ID, with
+// targets resolved to archive entry names. A part without relationships yields an empty map.
+func readRelationships(zr *zip.Reader, part string, budget *zipBudget) (map[string]relationship, error) {
+	relsName := path.Join(path.Dir(part), "_rels", path.Base(part)+".rels")
+	f := findZipFile(zr, relsName)
+	if f == nil {
+		return map[string]relationship{}, nil
+	}
+
+	var rels struct {
+		Relationships []relationship `xml:"Relationship"`
+	}
+	if err := decodeZipXML(f, budget, &rels); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]relationship, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		if rel.Mode != "External" {
+			rel.Target = resolvePartName(part, rel.Target)
+		}
+		result[rel.ID] = rel
+	}
+	return result, nil
+}
+
+// resolvePartName resolves a target relative to the part that references it
+func resolvePartName(part, target string) string {
+	if strings.HasPrefix(target, "/") {
+		return strings.TrimPrefix(path.Clean(target), "/")
+	}
+	return path.Join(path.Dir(part), target)
+}
@@ -0,0 +1,38 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// pageMarkerPattern matches the markers that start each page in the content of paged documents
+var pageMarkerPattern = regexp.MustCompile(`<!-- page (\d+) -->`)
+
+// PageMarker returns the marker written before the content of a page. It is an HTML comment,
+// so rendered Markdown does not show it.
+func PageMarker(page int) string {
+	return fmt.Sprintf("<!-- page %d -->", page)
+}
+
+// PageAt returns the page the character at offset, counted in code points, is on, or 0 when
+// no page marker comes before it
+func PageAt(content string, offset int) int {
+	if offset < 0 {
+		return 0
+	}
+	end := len(content)
+	for i := range content {
+		if offset == 0 {
+			end = i
+			break
+		}
+		offset--
+	}
+
+	page := 0
+	for _, match := range pageMarkerPattern.FindAllStringSubmatchIndex(content[:end], -1) {
+		page, _ = strconv.Atoi(content[match[2]:match[3]])
+	}
+	return page
+}
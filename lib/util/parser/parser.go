@@ -1,10 +1,12 @@
 package parser
 
 import (
+	"context"
 	"fmt"
-	"io"
-	"path/filepath"
-	"strings"
+	"runtime"
+	"time"
+
+	"github.com/snowmerak/open-librarian/lib/util/logger"
 )
 
 // Document represents parsed document content
@@ -12,22 +14,97 @@ type Document struct {
 	Title    string
 	Content  string
 	Metadata map[string]string
+	// Warnings describe parts of the document that could not be read, such as damaged pages
+	Warnings []string
+	// Sections split the document into parts that can be indexed as separate articles, such as
+	// the sheets of a workbook. Content still holds the whole document.
+	Sections []Section
+}
+
+// Section is a part of a document that stands on its own
+type Section struct {
+	Title   string
+	Content string
 }
 
-// Parse parses the content from reader based on file extension
-func Parse(r io.Reader, filename string) (*Document, error) {
-	ext := strings.ToLower(filepath.Ext(filename))
-
-	switch ext {
-	case ".pdf":
-		return ParsePDF(r, filename)
-	case ".docx":
-		return ParseDocx(r, filename)
-	case ".xlsx", ".xls":
-		return ParseExcel(r, filename)
-	case ".md", ".markdown", ".txt":
-		return ParseText(r, filename)
-	default:
-		return nil, fmt.Errorf("unsupported file extension: %s", ext)
+// Types of the built-in parsers
+const (
+	TypePDF   = "pdf"
+	TypeDocx  = "docx"
+	TypeExcel = "excel"
+	TypeText  = "text"
+	TypeHTML  = "html"
+	TypePptx  = "pptx"
+	TypeOdt   = "odt"
+	TypeOds   = "ods"
+	TypeEPUB  = "epub"
+	TypeRTF   = "rtf"
+	TypeCSV   = "csv"
+)
+
+// parseSlots bounds the parses running at once. Parsers only check ctx between units of work,
+// such as the pages of a PDF, and a single page can keep a decoder busy for as long as the file
+// makes it, so a parse that passes its deadline keeps its slot until it really returns. Files
+// that parse forever then queue the next uploads instead of taking every core.
+var parseSlots = make(chan struct{}, max(runtime.GOMAXPROCS(0), 2))
+
+// parseWithLimits runs parse in a parse slot and returns when it finishes, takes longer than
+// limits.Timeout or ctx is done. When an OCR engine is set on ctx, limits.OCRTimeout is added to
+// the timeout, since OCR has a budget of its own that parsers enforce; without an OCR limit there
+// is no overall one either. Time spent waiting for a slot does not count toward the timeout.
+//
+// On timeout the parse is abandoned rather than stopped: it sees ctx done at its next check and
+// reads from a file the caller may already have closed, which fails its remaining reads.
+func parseWithLimits(ctx context.Context, name string, limits Limits, parse func(context.Context) (*Document, error)) (*Document, error) {
+	select {
+	case parseSlots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("parsing %s stopped: %w", name, context.Cause(ctx))
+	}
+
+	timeout := limits.Timeout
+	if timeout > 0 && ocrFrom(ctx) != nil {
+		if limits.OCRTimeout > 0 {
+			timeout += limits.OCRTimeout
+		} else {
+			timeout = 0
+		}
+	}
+	cancel := context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, fmt.Errorf("%w: parsing took longer than %s", ErrLimitExceeded, timeout))
+	}
+
+	type result struct {
+		doc *Document
+		err error
+	}
+	done := make(chan result, 1)
+	started := time.Now()
+	go func() {
+		defer func() { <-parseSlots }()
+		defer cancel()
+		// Malformed files can make the underlying decoders panic
+		defer func() {
+			if p := recover(); p != nil {
+				done <- result{err: fmt.Errorf("failed to parse %s: %v", name, p)}
+			}
+		}()
+		doc, err := parse(ctx)
+		done <- result{doc: doc, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.doc, res.err
+	case <-ctx.Done():
+		err := fmt.Errorf("parsing %s stopped: %w", name, context.Cause(ctx))
+		log := logger.NewLogger("parser")
+		log.Warn().Err(err).Str("file", name).Msg("Abandoned parse still holds its slot until it returns")
+		go func() {
+			<-done
+			log.Info().Str("file", name).Dur("elapsed", time.Since(started)).Msg("Abandoned parse returned")
+		}()
+		return nil, err
 	}
 }
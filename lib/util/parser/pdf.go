@@ -1,50 +1,242 @@
 package parser
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 
 	"github.com/ledongthuc/pdf"
 )
 
-// ParsePDF parses a PDF file and extracts text
-func ParsePDF(r io.Reader, filename string) (*Document, error) {
-	// Read all content to memory to create ReaderAt
-	// Note: For very large files, this might be an issue.
-	content, err := io.ReadAll(r)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read pdf content: %w", err)
-	}
+func init() {
+	Register(signatureParser{
+		formatParser: formatParser{TypePDF, []string{".pdf"}, []string{"application/pdf"}, ParsePDF},
+		signatures:   [][]byte{[]byte("%PDF-")},
+	})
+}
 
-	reader, err := pdf.NewReader(bytes.NewReader(content), int64(len(content)))
+// ParsePDF extracts the text of a PDF as Markdown, using text positions and font sizes to find
+// headings, two-column layouts and tables. Each page starts with a page marker so passages can be
+// traced back to their page. Pages that cannot be read are skipped and reported in the warnings.
+// Pages without a text layer, such as scans, are read with the OCR engine set with WithOCR, and
+// the engine's confidence for each of them is reported in the metadata.
+// Pages are read from r on demand, so the file does not have to fit in memory.
+func ParsePDF(ctx context.Context, r io.ReaderAt, size int64, filename string, limits Limits) (*Document, error) {
+	reader, err := pdf.NewReader(r, size)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create pdf reader: %w", err)
 	}
 
-	var textBuilder strings.Builder
-
-	// Read all pages
 	totalPage := reader.NumPage()
+	if limits.MaxPDFPages > 0 && totalPage > limits.MaxPDFPages {
+		return nil, fmt.Errorf("%w: pdf has %d pages, limit is %d", ErrLimitExceeded, totalPage, limits.MaxPDFPages)
+	}
+
+	// Headings are told apart by comparing font sizes across the whole document, so every page
+	// is read before any is rendered
+	pages := make([][]pdfLine, totalPage)
+	var (
+		failed   []string
+		warnings []string
+		scanned  []int // Pages that draw an image but have no text
+	)
 	for pageIndex := 1; pageIndex <= totalPage; pageIndex++ {
+		if err := context.Cause(ctx); err != nil {
+			return nil, err
+		}
+
 		p := reader.Page(pageIndex)
 		if p.V.IsNull() {
 			continue
 		}
 
-		text, err := p.GetPlainText(nil)
+		lines, err := pdfPageLines(p)
 		if err != nil {
-			// Iterate even if one page fails?
+			failed = append(failed, strconv.Itoa(pageIndex))
+			warnings = append(warnings, fmt.Sprintf("page %d: %v", pageIndex, err))
 			continue
 		}
-		textBuilder.WriteString(text)
-		textBuilder.WriteString("\n")
+		pages[pageIndex-1] = lines
+		if len(lines) == 0 && pdfHasImage(p) {
+			scanned = append(scanned, pageIndex)
+		}
+	}
+	if totalPage > 0 && len(failed) == totalPage {
+		return nil, fmt.Errorf("failed to read any page of the pdf: %s", warnings[0])
+	}
+
+	body := pdfBodySize(pages)
+	levels := pdfHeadingLevels(pages, body)
+
+	recognized, ocrWarnings, err := ocrPDFPages(ctx, reader, r, scanned, limits)
+	if err != nil {
+		return nil, err
+	}
+	warnings = append(warnings, ocrWarnings...)
+
+	var sections []string
+	for i, lines := range pages {
+		text := renderPDFPage(orderPDFColumns(lines), body, levels)
+		if page, ok := recognized[i+1]; ok {
+			text = page.text
+		}
+		if text != "" {
+			sections = append(sections, PageMarker(i+1)+"\n\n"+text)
+		}
 	}
 
+	metadata := pdfMetadata(reader)
+	metadata["pages"] = strconv.Itoa(totalPage)
+	if len(recognized) > 0 {
+		var pageNumbers, confidences []string
+		for _, pageIndex := range scanned {
+			if page, ok := recognized[pageIndex]; ok {
+				pageNumbers = append(pageNumbers, strconv.Itoa(pageIndex))
+				confidences = append(confidences, fmt.Sprintf("%d: %s", pageIndex, formatConfidence(page.confidence)))
+			}
+		}
+		metadata["ocr_pages"] = strings.Join(pageNumbers, ", ")
+		metadata["ocr_confidence"] = strings.Join(confidences, ", ")
+	}
+	if len(failed) > 0 {
+		metadata["failed_pages"] = strings.Join(failed, ", ")
+	}
 	return &Document{
-		Title:    strings.TrimSuffix(filename, ".pdf"),
-		Content:  strings.TrimSpace(textBuilder.String()),
-		Metadata: map[string]string{"type": "pdf"},
+		Title:    documentTitle(metadata, filename),
+		Content:  strings.Join(sections, "\n\n"),
+		Metadata: metadata,
+		Warnings: warnings,
 	}, nil
 }
+
+// ocrPage is the text recognized on a page without a text layer
+type ocrPage struct {
+	text       string
+	confidence float64
+}
+
+// ocrPDFPages reads the given pages with the OCR engine on ctx, at most limits.MaxOCRPages of
+// them within limits.OCRTimeout. Pages that cannot be recognized are reported in the warnings
+// rather than failing the document, as are all of them when there is no engine and the pages
+// left when the time runs out, so a long scan keeps the text read so far.
+func ocrPDFPages(ctx context.Context, reader *pdf.Reader, r io.ReaderAt, scanned []int, limits Limits) (map[int]ocrPage, []string, error) {
+	if len(scanned) == 0 {
+		return nil, nil, nil
+	}
+	ocr := ocrFrom(ctx)
+	if ocr == nil {
+		return nil, []string{fmt.Sprintf("%s without a text layer skipped: OCR is not configured", pageList(scanned))}, nil
+	}
+	// Image data is read from the file as stored, so encrypted streams cannot be decoded
+	if reader.Trailer().Key("Encrypt").Kind() != pdf.Null {
+		return nil, []string{fmt.Sprintf("%s without a text layer skipped: encrypted PDFs cannot be read with OCR", pageList(scanned))}, nil
+	}
+
+	var warnings []string
+	if limits.MaxOCRPages > 0 && len(scanned) > limits.MaxOCRPages {
+		warnings = append(warnings, fmt.Sprintf("%s without a text layer skipped: only %d pages are read with OCR", pageList(scanned[limits.MaxOCRPages:]), limits.MaxOCRPages))
+		scanned = scanned[:limits.MaxOCRPages]
+	}
+
+	ocrCtx, cancel := withOCRTimeout(ctx, limits)
+	defer cancel()
+
+	recognized := make(map[int]ocrPage, len(scanned))
+	for i, pageIndex := range scanned {
+		if err := context.Cause(ctx); err != nil {
+			return nil, nil, err
+		}
+		if ocrCtx.Err() != nil {
+			warnings = append(warnings, fmt.Sprintf("%s without a text layer skipped: %v", pageList(scanned[i:]), context.Cause(ocrCtx)))
+			break
+		}
+
+		text, confidence, err := ocrPDFPage(ocrCtx, ocr, reader.Page(pageIndex), r)
+		if err != nil {
+			if ctx.Err() == nil && ocrCtx.Err() != nil {
+				warnings = append(warnings, fmt.Sprintf("%s without a text layer skipped: %v", pageList(scanned[i:]), context.Cause(ocrCtx)))
+				break
+			}
+			warnings = append(warnings, fmt.Sprintf("page %d: OCR failed: %v", pageIndex, err))
+			continue
+		}
+		recognized[pageIndex] = ocrPage{text: ocrMarkdown(text), confidence: confidence}
+		if warning := lowConfidenceWarning(fmt.Sprintf("page %d", pageIndex), confidence); warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+	return recognized, warnings, nil
+}
+
+// pageList names a list of pages in a warning, such as "pages 3, 4"
+func pageList(pages []int) string {
+	if len(pages) == 1 {
+		return fmt.Sprintf("page %d", pages[0])
+	}
+	numbers := make([]string, len(pages))
+	for i, page := range pages {
+		numbers[i] = strconv.Itoa(page)
+	}
+	return "pages " + strings.Join(numbers, ", ")
+}
+
+// pdfMetadata reads the document properties from the XMP metadata stream and the Info
+// dictionary. XMP is preferred since it is newer and always Unicode.
+func pdfMetadata(reader *pdf.Reader) map[string]string {
+	metadata := map[string]string{"type": "pdf"}
+	trailer := reader.Trailer()
+
+	if xmp := pdfXMP(trailer.Key("Root").Key("Metadata")); xmp != nil {
+		first := func(property string) string {
+			if values := xmp[property]; len(values) > 0 {
+				return values[0]
+			}
+			return ""
+		}
+		if title := first("title"); usableTitle(title) {
+			setMeta(metadata, MetaTitle, title)
+		}
+		setMetaList(metadata, MetaAuthor, xmp["creator"])
+		setMeta(metadata, MetaDescription, first("description"))
+		setMetaDate(metadata, MetaPublishedDate, first("CreateDate"))
+		setMetaList(metadata, MetaTags, append(xmp["subject"], splitKeywords(first("Keywords"))...))
+	}
+
+	info := trailer.Key("Info")
+	if title := info.Key("Title").Text(); usableTitle(title) {
+		setMeta(metadata, MetaTitle, title)
+	}
+	setMeta(metadata, MetaAuthor, info.Key("Author").Text())
+	setMeta(metadata, MetaDescription, info.Key("Subject").Text())
+	if created, ok := parsePDFDate(info.Key("CreationDate").Text()); ok {
+		setMeta(metadata, MetaPublishedDate, created)
+	}
+	setMetaList(metadata, MetaTags, splitKeywords(info.Key("Keywords").Text()))
+	return metadata
+}
+
+// maxXMPSize bounds the metadata stream read from a PDF
+const maxXMPSize = 1 << 20
+
+// pdfXMP reads the XMP metadata stream, or returns nil when the PDF has none or it cannot be read
+func pdfXMP(stream pdf.Value) (values map[string][]string) {
+	if stream.Kind() != pdf.Stream {
+		return nil
+	}
+	// The pdf package panics on stream filters it does not know
+	defer func() {
+		if recover() != nil {
+			values = nil
+		}
+	}()
+
+	rc := stream.Reader()
+	defer rc.Close()
+	values, err := readXMP(io.LimitReader(rc, maxXMPSize))
+	if err != nil && len(values) == 0 {
+		return nil
+	}
+	return values
+}
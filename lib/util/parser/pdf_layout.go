@@ -0,0 +1,498 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// Layout analysis of PDF pages: glyphs are grouped into lines by baseline, lines are split into
+// segments at wide gaps, two-column pages are read column by column, and font sizes larger than
+// the body text mark headings.
+
+const (
+	// pdfLineTolerance is the baseline difference, in font sizes, within which glyphs share a line
+	pdfLineTolerance = 0.4
+	// pdfSpaceGap is the gap between glyphs, in font sizes, read as a word break
+	pdfSpaceGap = 0.2
+	// pdfSegmentGap is the gap between glyphs, in font sizes, that separates columns and table cells
+	pdfSegmentGap = 1.5
+	// pdfParagraphGap is the distance between baselines, in font sizes, above which a paragraph ends
+	pdfParagraphGap = 1.8
+	// pdfHeadingRatio is the size relative to body text from which a short line is a heading
+	pdfHeadingRatio = 1.2
+	// pdfMaxHeadingRunes is the length above which a large line is not taken as a heading
+	pdfMaxHeadingRunes = 200
+	// pdfMaxHeadingLevel is the deepest heading level; smaller heading sizes share it
+	pdfMaxHeadingLevel = 4
+	// pdfGutterBuckets is the resolution of the column gutter search across the page width
+	pdfGutterBuckets = 100
+)
+
+var (
+	// pdfPageNumberPattern matches running page numbers such as "37", "- 37 -" or "Page 3 of 10"
+	pdfPageNumberPattern = regexp.MustCompile(`(?i)^[\s\-–—|]*(?:page\s*)?\d{1,4}(?:\s*(?:/|of)\s*\d{1,4})?[\s\-–—|]*$`)
+	// pdfBulletPattern matches the bullet characters that start list items
+	pdfBulletPattern = regexp.MustCompile(`^[•●○◦▪■□‣∙·]\s*`)
+)
+
+// pdfSegment is a run of text on a line, separated from the next run by a wide gap
+type pdfSegment struct {
+	x0, x1 float64
+	size   float64 // Average font size of its characters
+	text   string
+}
+
+// pdfLine is a row of text. Within a column a line holds one segment unless it is a table row.
+type pdfLine struct {
+	y        float64
+	segments []pdfSegment
+}
+
+// size returns the largest font size of the line's segments
+func (l pdfLine) size() float64 {
+	size := 0.0
+	for _, s := range l.segments {
+		size = max(size, s.size)
+	}
+	return size
+}
+
+// pdfPageLines reads the positioned text of a page as lines from top to bottom, without running
+// page numbers. The pdf package panics on content streams it cannot interpret.
+func pdfPageLines(page pdf.Page) (lines []pdfLine, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			lines, err = nil, fmt.Errorf("failed to read page content: %v", r)
+		}
+	}()
+
+	var glyphs []pdf.Text
+	for _, glyph := range page.Content().Text {
+		glyph.FontSize = math.Abs(glyph.FontSize)
+		if glyph.S == "" || glyph.FontSize == 0 {
+			continue
+		}
+		glyphs = append(glyphs, glyph)
+	}
+	lines = groupPDFLines(glyphs)
+
+	// Page numbers in the header or footer would interrupt text running across pages
+	for len(lines) > 0 && isPDFPageNumber(lines[len(lines)-1]) {
+		lines = lines[:len(lines)-1]
+	}
+	for len(lines) > 0 && isPDFPageNumber(lines[0]) {
+		lines = lines[1:]
+	}
+	return lines, nil
+}
+
+// isPDFPageNumber reports whether a line holds nothing but a page number
+func isPDFPageNumber(line pdfLine) bool {
+	return len(line.segments) == 1 && pdfPageNumberPattern.MatchString(line.segments[0].text)
+}
+
+// groupPDFLines groups glyphs that share a baseline into lines, ordered from the top of the page
+func groupPDFLines(glyphs []pdf.Text) []pdfLine {
+	sort.SliceStable(glyphs, func(i, j int) bool {
+		return glyphs[i].Y > glyphs[j].Y
+	})
+
+	var lines []pdfLine
+	start := 0
+	for i := 1; i <= len(glyphs); i++ {
+		if i < len(glyphs) && glyphs[start].Y-glyphs[i].Y <= pdfLineTolerance*max(glyphs[start].FontSize, glyphs[i].FontSize) {
+			continue
+		}
+		if line := newPDFLine(glyphs[start:i]); len(line.segments) > 0 {
+			lines = append(lines, line)
+		}
+		start = i
+	}
+	return lines
+}
+
+// newPDFLine joins the glyphs of a line from left to right, inserting spaces at word gaps and
+// starting a new segment at wide gaps
+func newPDFLine(glyphs []pdf.Text) pdfLine {
+	sort.SliceStable(glyphs, func(i, j int) bool {
+		return glyphs[i].X < glyphs[j].X
+	})
+
+	line := pdfLine{y: glyphs[0].Y}
+	var (
+		text    strings.Builder
+		x0, end float64
+		sizeSum float64
+		chars   int
+	)
+	flush := func() {
+		if s := strings.TrimSpace(text.String()); s != "" {
+			line.segments = append(line.segments, pdfSegment{x0: x0, x1: end, size: sizeSum / float64(max(chars, 1)), text: s})
+		}
+		text.Reset()
+		sizeSum, chars = 0, 0
+	}
+
+	for i, glyph := range glyphs {
+		blank := strings.TrimSpace(glyph.S) == ""
+		if i > 0 {
+			prev := glyphs[i-1]
+			// Bold text is often faked by drawing the same glyph twice, slightly offset
+			if glyph.S == prev.S && math.Abs(glyph.X-prev.X) < 0.1*glyph.FontSize {
+				continue
+			}
+			gap := glyph.X - end
+			switch {
+			case gap > pdfSegmentGap*glyph.FontSize:
+				flush()
+			case (gap > pdfSpaceGap*glyph.FontSize || blank) && text.Len() > 0 && !strings.HasSuffix(text.String(), " "):
+				text.WriteByte(' ')
+			}
+		}
+
+		if !blank {
+			if text.Len() == 0 {
+				x0 = glyph.X
+			}
+			text.WriteString(glyph.S)
+			sizeSum += glyph.FontSize
+			chars++
+		}
+		// Fonts without width tables report no width; assume half an em
+		width := glyph.W
+		if width <= 0 {
+			width = glyph.FontSize / 2
+		}
+		end = glyph.X + width
+	}
+	flush()
+	return line
+}
+
+// orderPDFColumns puts the lines of a two-column page in reading order: the left column before
+// the right one, with lines that span both columns, such as titles, breaking the page into bands
+// that are read separately. Pages without a column gutter are returned as they are.
+func orderPDFColumns(lines []pdfLine) []pdfLine {
+	left, right := math.Inf(1), math.Inf(-1)
+	for _, line := range lines {
+		for _, s := range line.segments {
+			left, right = min(left, s.x0), max(right, s.x1)
+		}
+	}
+	gutter, ok := findPDFGutter(lines, left, right-left)
+	if !ok {
+		return lines
+	}
+
+	var ordered, leftColumn, rightColumn []pdfLine
+	flushBand := func() {
+		ordered = append(append(ordered, leftColumn...), rightColumn...)
+		leftColumn, rightColumn = nil, nil
+	}
+	for _, line := range lines {
+		var leftSegments, rightSegments []pdfSegment
+		spanning := false
+		for _, s := range line.segments {
+			switch {
+			case s.x1 <= gutter:
+				leftSegments = append(leftSegments, s)
+			case s.x0 >= gutter:
+				rightSegments = append(rightSegments, s)
+			default:
+				spanning = true
+			}
+		}
+		if spanning {
+			flushBand()
+			ordered = append(ordered, line)
+			continue
+		}
+		if len(leftSegments) > 0 {
+			leftColumn = append(leftColumn, pdfLine{y: line.y, segments: leftSegments})
+		}
+		if len(rightSegments) > 0 {
+			rightColumn = append(rightColumn, pdfLine{y: line.y, segments: rightSegments})
+		}
+	}
+	flushBand()
+	return ordered
+}
+
+// findPDFGutter looks for a vertical gap in the middle of the page that almost no text crosses,
+// with wide text on both sides of it. Narrow text on both sides is a table rather than columns.
+func findPDFGutter(lines []pdfLine, left, width float64) (float64, bool) {
+	if width <= 0 || len(lines) < 6 {
+		return 0, false
+	}
+
+	var coverage [pdfGutterBuckets]int
+	for _, line := range lines {
+		for _, s := range line.segments {
+			from := int((s.x0 - left) / width * pdfGutterBuckets)
+			to := int((s.x1 - left) / width * pdfGutterBuckets)
+			for b := max(from, 0); b <= min(to, pdfGutterBuckets-1); b++ {
+				coverage[b]++
+			}
+		}
+	}
+
+	// Up to one line in ten may cross the gutter, such as titles and figure captions
+	best, bestCoverage := -1, len(lines)/10
+	for b := pdfGutterBuckets * 3 / 10; b <= pdfGutterBuckets*7/10; b++ {
+		distance := func(b int) int { return max(b-pdfGutterBuckets/2, pdfGutterBuckets/2-b) }
+		if coverage[b] < bestCoverage || (coverage[b] == bestCoverage && (best < 0 || distance(b) < distance(best))) {
+			best, bestCoverage = b, coverage[b]
+		}
+	}
+	if best < 0 {
+		return 0, false
+	}
+	gutter := left + (float64(best)+0.5)/pdfGutterBuckets*width
+
+	var leftWidths, rightWidths []float64
+	for _, line := range lines {
+		for _, s := range line.segments {
+			switch {
+			case s.x1 <= gutter:
+				leftWidths = append(leftWidths, s.x1-s.x0)
+			case s.x0 >= gutter:
+				rightWidths = append(rightWidths, s.x1-s.x0)
+			}
+		}
+	}
+	minLines := max(3, len(lines)/5)
+	if len(leftWidths) < minLines || len(rightWidths) < minLines {
+		return 0, false
+	}
+	if median(leftWidths) < width/4 || median(rightWidths) < width/4 {
+		return 0, false
+	}
+	return gutter, true
+}
+
+// median returns the middle value of values, which it sorts
+func median(values []float64) float64 {
+	sort.Float64s(values)
+	return values[len(values)/2]
+}
+
+// roundSize rounds a font size to half a point so sizes from different text matrices compare equal
+func roundSize(size float64) float64 {
+	return math.Round(size*2) / 2
+}
+
+// pdfBodySize returns the font size of most of the document's text
+func pdfBodySize(pages [][]pdfLine) float64 {
+	counts := make(map[float64]int)
+	for _, lines := range pages {
+		for _, line := range lines {
+			for _, s := range line.segments {
+				counts[roundSize(s.size)] += utf8.RuneCountInString(s.text)
+			}
+		}
+	}
+	body, bodyCount := 0.0, 0
+	for size, count := range counts {
+		if count > bodyCount || (count == bodyCount && size < body) {
+			body, bodyCount = size, count
+		}
+	}
+	return body
+}
+
+// isPDFHeading reports whether a segment is set large enough, and is short enough, to be a heading
+func isPDFHeading(s pdfSegment, body float64) bool {
+	return body > 0 && s.size >= body*pdfHeadingRatio && utf8.RuneCountInString(s.text) <= pdfMaxHeadingRunes
+}
+
+// pdfHeadingLevels maps the font sizes of headings to heading levels, the largest size first
+func pdfHeadingLevels(pages [][]pdfLine, body float64) map[float64]int {
+	seen := make(map[float64]bool)
+	for _, lines := range pages {
+		for _, line := range lines {
+			for _, s := range line.segments {
+				if isPDFHeading(s, body) {
+					seen[roundSize(s.size)] = true
+				}
+			}
+		}
+	}
+
+	sizes := make([]float64, 0, len(seen))
+	for size := range seen {
+		sizes = append(sizes, size)
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(sizes)))
+
+	levels := make(map[float64]int, len(sizes))
+	for i, size := range sizes {
+		levels[size] = min(i+1, pdfMaxHeadingLevel)
+	}
+	return levels
+}
+
+// renderPDFPage writes the lines of a page, in reading order, as Markdown headings, paragraphs,
+// list items and tables
+func renderPDFPage(lines []pdfLine, body float64, levels map[float64]int) string {
+	var (
+		blocks    []string
+		paragraph []string
+		rows      []pdfLine
+		prev      *pdfLine
+		// lastHeading is the index of the last block when it is a heading that may continue
+		lastHeading = -1
+	)
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			blocks = append(blocks, joinPDFLines(paragraph))
+			paragraph = nil
+		}
+	}
+	flushTable := func() {
+		switch {
+		case len(rows) > 1:
+			blocks = append(blocks, pdfTable(rows))
+		case len(rows) == 1:
+			// A single line with a wide gap, such as a table of contents entry, is text
+			var cells []string
+			for _, s := range rows[0].segments {
+				cells = append(cells, s.text)
+			}
+			blocks = append(blocks, strings.Join(cells, " "))
+		}
+		rows = nil
+	}
+
+	for i := range lines {
+		line := lines[i]
+		size := line.size()
+		gap := 0.0
+		if prev != nil {
+			gap = prev.y - line.y
+		}
+		// A line above the previous one starts a new column; a wide gap or a size change a new block
+		continues := prev != nil && gap > 0 && gap <= pdfParagraphGap*size && math.Abs(prev.size()-size) <= 0.15*size
+
+		if len(line.segments) > 1 {
+			flushParagraph()
+			if !continues {
+				flushTable()
+			}
+			rows = append(rows, line)
+			prev, lastHeading = &lines[i], -1
+			continue
+		}
+		flushTable()
+
+		segment := line.segments[0]
+		if isPDFHeading(segment, body) {
+			flushParagraph()
+			// Headings set over several lines are joined
+			if lastHeading == len(blocks)-1 && continues {
+				blocks[lastHeading] += " " + segment.text
+			} else {
+				level := levels[roundSize(segment.size)]
+				blocks = append(blocks, strings.Repeat("#", max(level, 1))+" "+segment.text)
+				lastHeading = len(blocks) - 1
+			}
+			prev = &lines[i]
+			continue
+		}
+		lastHeading = -1
+
+		text := segment.text
+		if bullet := pdfBulletPattern.FindString(text); bullet != "" {
+			flushParagraph()
+			text = "- " + text[len(bullet):]
+		} else if !continues {
+			flushParagraph()
+		}
+		paragraph = append(paragraph, text)
+		prev = &lines[i]
+	}
+	flushTable()
+	flushParagraph()
+	return strings.Join(blocks, "\n\n")
+}
+
+// pdfTable renders table rows as a Markdown table, placing each segment in the column whose
+// left edge it is closest to
+func pdfTable(rows []pdfLine) string {
+	var starts []float64
+	tolerance := 0.0
+	for _, row := range rows {
+		for _, s := range row.segments {
+			starts = append(starts, s.x0)
+			tolerance = max(tolerance, s.size)
+		}
+	}
+	sort.Float64s(starts)
+	var anchors []float64
+	for _, x := range starts {
+		if len(anchors) == 0 || x-anchors[len(anchors)-1] > tolerance {
+			anchors = append(anchors, x)
+		}
+	}
+
+	cells := make([][]string, len(rows))
+	for i, row := range rows {
+		cells[i] = make([]string, len(anchors))
+		for _, s := range row.segments {
+			column := 0
+			for j, anchor := range anchors {
+				if math.Abs(s.x0-anchor) < math.Abs(s.x0-anchors[column]) {
+					column = j
+				}
+			}
+			cells[i][column] = strings.TrimSpace(cells[i][column] + " " + s.text)
+		}
+	}
+	return markdownTable(cells)
+}
+
+// joinPDFLines joins the lines of a paragraph, rejoining words hyphenated at line ends and
+// leaving no space between lines of CJK text
+func joinPDFLines(lines []string) string {
+	var sb strings.Builder
+	for i, line := range lines {
+		if i == 0 {
+			sb.WriteString(line)
+			continue
+		}
+		prev := sb.String()
+		last, _ := utf8.DecodeLastRuneInString(prev)
+		next, _ := utf8.DecodeRuneInString(line)
+		switch {
+		case last == '-' && len(prev) > 1 && unicode.IsLetter(lastRuneBefore(prev, 1)) && unicode.IsLower(next):
+			sb.Reset()
+			sb.WriteString(strings.TrimSuffix(prev, "-"))
+		case isCJK(last) && isCJK(next):
+		default:
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
+
+// lastRuneBefore returns the rune n runes before the end of s
+func lastRuneBefore(s string, n int) rune {
+	for range n {
+		_, size := utf8.DecodeLastRuneInString(s)
+		s = s[:len(s)-size]
+	}
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
+
+// isCJK reports whether r is written without spaces between words
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+}
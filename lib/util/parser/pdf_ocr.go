@@ -0,0 +1,482 @@
+package parser
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+	"golang.org/x/image/ccitt"
+)
+
+const (
+	// maxPDFImageSize bounds the encoded data of an image read from a PDF for OCR
+	maxPDFImageSize = 64 << 20
+	// maxPDFImagePixels bounds the decoded size of an image read from a PDF for OCR
+	maxPDFImagePixels = 100_000_000
+	// maxPDFFormDepth bounds how deeply form XObjects are searched for images
+	maxPDFFormDepth = 4
+)
+
+// errNoPageImage is returned for pages that do not draw any image
+var errNoPageImage = errors.New("page has no image")
+
+// ocrPDFPage recognizes the text of a page without a text layer from the largest image it draws,
+// which on a scanned page is the scan itself
+func ocrPDFPage(ctx context.Context, ocr OCR, page pdf.Page, r io.ReaderAt) (string, float64, error) {
+	data, mediaType, err := pdfPageImage(page, r)
+	if err != nil {
+		return "", 0, err
+	}
+	return ocr.Recognize(ctx, data, mediaType)
+}
+
+// pdfHasImage reports whether a page draws an image
+func pdfHasImage(page pdf.Page) (found bool) {
+	defer func() {
+		if recover() != nil {
+			found = false
+		}
+	}()
+	_, found = largestPDFImage(page.Resources(), 0)
+	return found
+}
+
+// pdfPageImage returns the largest image of a page encoded as JPEG or PNG
+func pdfPageImage(page pdf.Page, r io.ReaderAt) (data []byte, mediaType string, err error) {
+	// Malformed objects make the pdf package panic
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("failed to read page image: %v", p)
+		}
+	}()
+
+	stream, ok := largestPDFImage(page.Resources(), 0)
+	if !ok {
+		return nil, "", errNoPageImage
+	}
+	return decodePDFImage(stream, r)
+}
+
+// largestPDFImage finds the image XObject with the most pixels in resources and the forms they use
+func largestPDFImage(resources pdf.Value, depth int) (pdf.Value, bool) {
+	var (
+		best     pdf.Value
+		bestArea int64
+	)
+	xobjects := resources.Key("XObject")
+	for _, name := range xobjects.Keys() {
+		xobject := xobjects.Key(name)
+		if xobject.Kind() != pdf.Stream {
+			continue
+		}
+
+		candidate := xobject
+		switch xobject.Key("Subtype").Name() {
+		case "Image":
+		case "Form":
+			if depth >= maxPDFFormDepth {
+				continue
+			}
+			var ok bool
+			if candidate, ok = largestPDFImage(xobject.Key("Resources"), depth+1); !ok {
+				continue
+			}
+		default:
+			continue
+		}
+
+		if area := candidate.Key("Width").Int64() * candidate.Key("Height").Int64(); area > bestArea {
+			best, bestArea = candidate, area
+		}
+	}
+	return best, bestArea > 0
+}
+
+// decodePDFImage converts an image XObject to a format OCR engines read. JPEG data is passed
+// through; CCITT fax and Flate-compressed or raw samples are decoded and encoded as PNG.
+func decodePDFImage(stream pdf.Value, r io.ReaderAt) ([]byte, string, error) {
+	width, height := int(stream.Key("Width").Int64()), int(stream.Key("Height").Int64())
+	if width <= 0 || height <= 0 {
+		return nil, "", fmt.Errorf("image has invalid size %dx%d", width, height)
+	}
+	if int64(width)*int64(height) > maxPDFImagePixels {
+		return nil, "", fmt.Errorf("%w: image is %dx%d pixels", ErrLimitExceeded, width, height)
+	}
+
+	filter, params, err := pdfImageFilter(stream)
+	if err != nil {
+		return nil, "", err
+	}
+	raw, err := pdfRawStream(stream, r)
+	if err != nil {
+		return nil, "", err
+	}
+
+	bits := int(stream.Key("BitsPerComponent").Int64())
+	mask := stream.Key("ImageMask").Bool()
+	if mask || filter == "CCITTFaxDecode" {
+		bits = 1
+	}
+	colors := pdfColorSpace{components: 1}
+	if !mask {
+		if colors, err = readPDFColorSpace(stream.Key("ColorSpace")); err != nil {
+			return nil, "", err
+		}
+	}
+	rowBytes := (width*colors.components*bits + 7) / 8
+
+	var samples []byte
+	switch filter {
+	case "DCTDecode":
+		return raw, "image/jpeg", nil
+	case "CCITTFaxDecode":
+		samples, err = decodePDFCCITT(raw, params, width, height)
+	case "FlateDecode":
+		samples, err = inflatePDFImage(raw, params, rowBytes*height)
+	case "":
+		samples = raw
+	default:
+		return nil, "", fmt.Errorf("images encoded with %s are not supported", filter)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if len(samples) < rowBytes*height {
+		return nil, "", fmt.Errorf("image data is truncated")
+	}
+
+	img, err := pdfSamplesImage(samples, width, height, bits, colors, stream.Key("Decode"))
+	if err != nil {
+		return nil, "", err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", fmt.Errorf("failed to encode page image: %w", err)
+	}
+	return buf.Bytes(), "image/png", nil
+}
+
+// pdfImageFilter returns the single filter of an image stream and its parameters. Images are
+// compressed once in practice; chained filters are not supported.
+func pdfImageFilter(stream pdf.Value) (string, pdf.Value, error) {
+	filter, params := stream.Key("Filter"), stream.Key("DecodeParms")
+	switch filter.Kind() {
+	case pdf.Null:
+		return "", params, nil
+	case pdf.Name:
+		return filter.Name(), params, nil
+	case pdf.Array:
+		switch filter.Len() {
+		case 0:
+			return "", params, nil
+		case 1:
+			return filter.Index(0).Name(), params.Index(0), nil
+		}
+		names := make([]string, filter.Len())
+		for i := range names {
+			names[i] = filter.Index(i).Name()
+		}
+		return "", params, fmt.Errorf("images encoded with %s are not supported", strings.Join(names, "+"))
+	}
+	return "", params, fmt.Errorf("image has an invalid filter")
+}
+
+// pdfRawStream reads the encoded data of a stream. The pdf package only decodes the filters used
+// for text, but a stream's string form ends with the offset of its data, so the data can be read
+// from the file directly.
+func pdfRawStream(stream pdf.Value, r io.ReaderAt) ([]byte, error) {
+	s := stream.String()
+	at := strings.LastIndexByte(s, '@')
+	if at < 0 {
+		return nil, fmt.Errorf("failed to locate image data")
+	}
+	offset, err := strconv.ParseInt(s[at+1:], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate image data: %w", err)
+	}
+
+	length := stream.Key("Length").Int64()
+	if length <= 0 {
+		return nil, fmt.Errorf("image has no data")
+	}
+	if length > maxPDFImageSize {
+		return nil, fmt.Errorf("%w: image data is larger than %d MB", ErrLimitExceeded, maxPDFImageSize>>20)
+	}
+
+	data := make([]byte, length)
+	if n, err := r.ReadAt(data, offset); n < len(data) {
+		return nil, fmt.Errorf("failed to read image data: %w", err)
+	}
+	return data, nil
+}
+
+// inflatePDFImage decompresses Flate-encoded samples, undoing PNG predictors, and reads at most
+// size bytes of samples
+func inflatePDFImage(raw []byte, params pdf.Value, size int) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress image: %w", err)
+	}
+	defer zr.Close()
+
+	predictor := params.Key("Predictor").Int64()
+	if predictor <= 1 {
+		samples := make([]byte, size)
+		if _, err := io.ReadFull(zr, samples); err != nil {
+			return nil, fmt.Errorf("failed to decompress image: %w", err)
+		}
+		return samples, nil
+	}
+	if predictor < 10 {
+		return nil, fmt.Errorf("images with TIFF predictors are not supported")
+	}
+
+	// The predictor describes its own rows; the defaults are those of the Flate filter
+	colors, bits, columns := pdfInt(params.Key("Colors"), 1), pdfInt(params.Key("BitsPerComponent"), 8), pdfInt(params.Key("Columns"), 1)
+	rowBytes := int((colors*bits*columns + 7) / 8)
+	pixelBytes := int(max((colors*bits+7)/8, 1))
+	return unpredictPNG(zr, rowBytes, pixelBytes, size)
+}
+
+// unpredictPNG reverses the PNG row filters of Flate-encoded data, where each row starts with a
+// byte naming its filter
+func unpredictPNG(r io.Reader, rowBytes, pixelBytes, size int) ([]byte, error) {
+	samples := make([]byte, 0, size)
+	row := make([]byte, rowBytes+1)
+	prev := make([]byte, rowBytes)
+	for len(samples) < size {
+		if _, err := io.ReadFull(r, row); err != nil {
+			return nil, fmt.Errorf("failed to decompress image: %w", err)
+		}
+		cur := row[1:]
+		for i := range cur {
+			var left, upLeft byte
+			if i >= pixelBytes {
+				left, upLeft = cur[i-pixelBytes], prev[i-pixelBytes]
+			}
+			up := prev[i]
+			switch row[0] {
+			case 0:
+			case 1:
+				cur[i] += left
+			case 2:
+				cur[i] += up
+			case 3:
+				cur[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				cur[i] += paeth(left, up, upLeft)
+			default:
+				return nil, fmt.Errorf("image has an invalid PNG filter %d", row[0])
+			}
+		}
+		samples = append(samples, cur...)
+		copy(prev, cur)
+	}
+	return samples[:size], nil
+}
+
+// paeth is the PNG Paeth predictor
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+// pdfInt returns an integer value, or def when it is missing
+func pdfInt(v pdf.Value, def int64) int64 {
+	if v.Kind() == pdf.Null {
+		return def
+	}
+	return v.Int64()
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// decodePDFCCITT decodes fax-compressed bilevel samples, one bit per pixel with rows starting on
+// byte boundaries, as the CCITTFaxDecode filter would produce them
+func decodePDFCCITT(raw []byte, params pdf.Value, width, height int) ([]byte, error) {
+	var format ccitt.SubFormat
+	switch k := params.Key("K").Int64(); {
+	case k < 0:
+		format = ccitt.Group4
+	case k == 0:
+		format = ccitt.Group3
+	default:
+		return nil, fmt.Errorf("images encoded with two-dimensional CCITT Group 3 are not supported")
+	}
+	if columns := params.Key("Columns"); columns.Kind() != pdf.Null && int(columns.Int64()) != width {
+		return nil, fmt.Errorf("fax image has %d columns but is %d pixels wide", columns.Int64(), width)
+	}
+
+	// The decoder writes 1 for white; the filter writes 1 for black when BlackIs1 is set
+	reader := ccitt.NewReader(bytes.NewReader(raw), ccitt.MSB, format, width, height, &ccitt.Options{
+		Align:  params.Key("EncodedByteAlign").Bool(),
+		Invert: params.Key("BlackIs1").Bool(),
+	})
+	samples := make([]byte, (width+7)/8*height)
+	if _, err := io.ReadFull(reader, samples); err != nil {
+		return nil, fmt.Errorf("failed to decode fax image: %w", err)
+	}
+	return samples, nil
+}
+
+// pdfColorSpace describes how the samples of an image make up its colors
+type pdfColorSpace struct {
+	components int // Samples per pixel
+	cmyk       bool
+	// palette holds the color of each index of an Indexed color space, paletteComponents
+	// bytes per color in the base color space
+	palette           []byte
+	paletteComponents int
+}
+
+// readPDFColorSpace reads the color spaces scanned images use. Lab, separation and pattern
+// color spaces are not supported.
+func readPDFColorSpace(cs pdf.Value) (pdfColorSpace, error) {
+	name := cs.Name()
+	if cs.Kind() == pdf.Array {
+		name = cs.Index(0).Name()
+	}
+
+	switch name {
+	case "DeviceGray", "CalGray", "G":
+		return pdfColorSpace{components: 1}, nil
+	case "DeviceRGB", "CalRGB", "RGB":
+		return pdfColorSpace{components: 3}, nil
+	case "DeviceCMYK", "CMYK":
+		return pdfColorSpace{components: 4, cmyk: true}, nil
+	case "ICCBased":
+		switch n := cs.Index(1).Key("N").Int64(); n {
+		case 1, 3:
+			return pdfColorSpace{components: int(n)}, nil
+		case 4:
+			return pdfColorSpace{components: 4, cmyk: true}, nil
+		}
+	case "Indexed", "I":
+		base, err := readPDFColorSpace(cs.Index(1))
+		if err != nil || base.palette != nil {
+			break
+		}
+		lookup := cs.Index(3)
+		var palette []byte
+		switch lookup.Kind() {
+		case pdf.String:
+			palette = []byte(lookup.RawString())
+		case pdf.Stream:
+			rc := lookup.Reader()
+			palette, err = io.ReadAll(io.LimitReader(rc, 256*4))
+			rc.Close()
+			if err != nil {
+				return pdfColorSpace{}, fmt.Errorf("failed to read image palette: %w", err)
+			}
+		}
+		return pdfColorSpace{components: 1, cmyk: base.cmyk, palette: palette, paletteComponents: base.components}, nil
+	}
+	return pdfColorSpace{}, fmt.Errorf("images in the %s color space are not supported", cs)
+}
+
+// pdfSamplesImage builds an image from rows of samples, applying the image's Decode array
+func pdfSamplesImage(samples []byte, width, height, bits int, colors pdfColorSpace, decode pdf.Value) (image.Image, error) {
+	switch bits {
+	case 1, 2, 4, 8:
+	default:
+		return nil, fmt.Errorf("images with %d bits per component are not supported", bits)
+	}
+	maxValue := float64(int(1)<<bits - 1)
+	rowBytes := (width*colors.components*bits + 7) / 8
+
+	// Decode maps each sample from [0, maxValue] to [min, max]; indexes are mapped as is
+	ranges := make([][2]float64, colors.components)
+	for c := range ranges {
+		ranges[c] = [2]float64{0, 1}
+		if colors.palette != nil {
+			ranges[c] = [2]float64{0, maxValue}
+		}
+		if decode.Len() == 2*colors.components {
+			ranges[c] = [2]float64{decode.Index(2 * c).Float64(), decode.Index(2*c + 1).Float64()}
+		}
+	}
+
+	outComponents := colors.components
+	if colors.palette != nil {
+		outComponents = colors.paletteComponents
+	}
+	var (
+		gray *image.Gray
+		rgb  *image.RGBA
+	)
+	if outComponents == 1 {
+		gray = image.NewGray(image.Rect(0, 0, width, height))
+	} else {
+		rgb = image.NewRGBA(image.Rect(0, 0, width, height))
+	}
+
+	values := make([]float64, max(outComponents, colors.components))
+	for y := 0; y < height; y++ {
+		row := samples[y*rowBytes : (y+1)*rowBytes]
+		for x := 0; x < width; x++ {
+			for c := 0; c < colors.components; c++ {
+				v := float64(sampleAt(row, x*colors.components+c, bits)) / maxValue
+				values[c] = ranges[c][0] + v*(ranges[c][1]-ranges[c][0])
+			}
+			if colors.palette != nil {
+				index := int(values[0])
+				for c := 0; c < colors.paletteComponents; c++ {
+					values[c] = 0
+					if i := index*colors.paletteComponents + c; i < len(colors.palette) {
+						values[c] = float64(colors.palette[i]) / 255
+					}
+				}
+			}
+
+			switch {
+			case gray != nil:
+				gray.SetGray(x, y, color.Gray{Y: unit(values[0])})
+			case colors.cmyk:
+				k := 1 - values[3]
+				rgb.SetRGBA(x, y, color.RGBA{unit((1 - values[0]) * k), unit((1 - values[1]) * k), unit((1 - values[2]) * k), 0xff})
+			default:
+				rgb.SetRGBA(x, y, color.RGBA{unit(values[0]), unit(values[1]), unit(values[2]), 0xff})
+			}
+		}
+	}
+	if gray != nil {
+		return gray, nil
+	}
+	return rgb, nil
+}
+
+// sampleAt returns the i-th sample of bits bits in a row
+func sampleAt(row []byte, i, bits int) int {
+	if bits == 8 {
+		return int(row[i])
+	}
+	bit := i * bits
+	shift := 8 - bits - bit%8
+	return int(row[bit/8]>>shift) & (1<<bits - 1)
+}
+
+// unit converts a color component from [0, 1] to a byte
+func unit(v float64) uint8 {
+	return uint8(min(max(v, 0), 1)*255 + 0.5)
+}
@@ -0,0 +1,278 @@
+package parser
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	pptxSlideRelType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide"
+	pptxNotesRelType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/notesSlide"
+)
+
+func init() {
+	Register(zipFormatParser{
+		formatParser: formatParser{TypePptx, []string{".pptx"}, []string{"application/vnd.openxmlformats-officedocument.presentationml.presentation"}, ParsePptx},
+		marker:       "ppt/presentation.xml",
+	})
+}
+
+// ParsePptx extracts the text of a PowerPoint .pptx file as Markdown, one section per slide
+// in presentation order, with the slide's speaker notes after its content
+func ParsePptx(ctx context.Context, r io.ReaderAt, size int64, filename string, limits Limits) (*Document, error) {
+	zipReader, budget, err := openZip(r, size, limits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pptx: %w", err)
+	}
+
+	slides, err := pptxSlideOrder(zipReader, budget)
+	if err != nil {
+		return nil, err
+	}
+
+	var sections []string
+	for i, slidePart := range slides {
+		if err := context.Cause(ctx); err != nil {
+			return nil, err
+		}
+
+		f := findZipFile(zipReader, slidePart)
+		if f == nil {
+			continue
+		}
+		slide, err := readPptxPart(f, budget, false)
+		if err != nil {
+			return nil, err
+		}
+
+		heading := fmt.Sprintf("## Slide %d", i+1)
+		if slide.title != "" {
+			heading += ": " + slide.title
+		}
+		blocks := append([]string{heading}, slide.blocks...)
+
+		if notes, err := pptxNotes(zipReader, slidePart, budget); err != nil {
+			return nil, err
+		} else if len(notes) > 0 {
+			blocks = append(blocks, "### Notes")
+			blocks = append(blocks, notes...)
+		}
+		sections = append(sections, strings.Join(blocks, "\n\n"))
+	}
+
+	metadata := map[string]string{"type": "pptx", "slides": strconv.Itoa(len(slides))}
+	if err := readCoreProperties(zipReader, budget, metadata); err != nil {
+		return nil, err
+	}
+
+	return &Document{
+		Title:    documentTitle(metadata, filename),
+		Content:  strings.Join(sections, "\n\n"),
+		Metadata: metadata,
+	}, nil
+}
+
+// pptxSlideOrder returns the slide parts in the order of the presentation's slide list
+func pptxSlideOrder(zr *zip.Reader, budget *zipBudget) ([]string, error) {
+	const presentationPart = "ppt/presentation.xml"
+	f := findZipFile(zr, presentationPart)
+	if f == nil {
+		return nil, fmt.Errorf("invalid pptx: %s not found", presentationPart)
+	}
+
+	var presentation struct {
+		Slides []struct {
+			Attrs []xml.Attr `xml:",any,attr"`
+		} `xml:"sldIdLst>sldId"`
+	}
+	if err := decodeZipXML(f, budget, &presentation); err != nil {
+		return nil, err
+	}
+	rels, err := readRelationships(zr, presentationPart, budget)
+	if err != nil {
+		return nil, err
+	}
+
+	var slides []string
+	for _, slide := range presentation.Slides {
+		// The relationship ID is the namespaced r:id attribute; the plain id is a slide number
+		for _, a := range slide.Attrs {
+			if a.Name.Local != "id" || a.Name.Space == "" {
+				continue
+			}
+			if rel, ok := rels[a.Value]; ok && rel.Type == pptxSlideRelType {
+				slides = append(slides, rel.Target)
+			}
+		}
+	}
+	return slides, nil
+}
+
+// pptxNotes returns the speaker notes of a slide, or nil when it has none
+func pptxNotes(zr *zip.Reader, slidePart string, budget *zipBudget) ([]string, error) {
+	rels, err := readRelationships(zr, slidePart, budget)
+	if err != nil {
+		return nil, err
+	}
+	for _, rel := range rels {
+		if rel.Type != pptxNotesRelType {
+			continue
+		}
+		f := findZipFile(zr, rel.Target)
+		if f == nil {
+			return nil, nil
+		}
+		notes, err := readPptxPart(f, budget, true)
+		if err != nil {
+			return nil, err
+		}
+		return notes.blocks, nil
+	}
+	return nil, nil
+}
+
+// pptxPart is the text of a slide or notes page
+type pptxPart struct {
+	title  string
+	blocks []string
+}
+
+// pptxSkippedPlaceholders hold slide numbers, dates, footers and the slide image on notes pages
+var pptxSkippedPlaceholders = map[string]bool{
+	"sldNum": true, "dt": true, "ftr": true, "hdr": true, "sldImg": true,
+}
+
+// readPptxPart extracts titles, text boxes, bulleted placeholders and tables from a slide or
+// notes part. Notes are rendered as plain paragraphs.
+func readPptxPart(f *zip.File, budget *zipBudget, notes bool) (*pptxPart, error) {
+	rc, err := budget.open(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	type paragraph struct {
+		text  string
+		level int
+	}
+
+	var (
+		part    = &pptxPart{}
+		decoder = xml.NewDecoder(rc)
+
+		inShape       bool
+		isPlaceholder bool
+		placeholder   string
+		paragraphs    []paragraph
+		para          strings.Builder
+		level         int
+
+		inTable bool
+		rows    [][]string
+		row     []string
+		cell    []string
+	)
+
+	for {
+		t, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+
+		switch se := t.(type) {
+		case xml.StartElement:
+			switch se.Name.Local {
+			case "sp":
+				inShape, isPlaceholder, placeholder, paragraphs = true, false, "", nil
+			case "ph":
+				isPlaceholder = true
+				placeholder = xmlAttr(se, "type")
+			case "p":
+				para.Reset()
+				level = 0
+			case "pPr":
+				level, _ = strconv.Atoi(xmlAttr(se, "lvl"))
+			case "t":
+				var text string
+				if err := decoder.DecodeElement(&text, &se); err == nil {
+					para.WriteString(text)
+				}
+			case "br":
+				para.WriteString("\n")
+			case "tbl":
+				inTable, rows = true, nil
+			case "tr":
+				row = nil
+			case "tc":
+				cell = nil
+			}
+		case xml.EndElement:
+			switch se.Name.Local {
+			case "p":
+				text := strings.TrimSpace(para.String())
+				if text == "" {
+					continue
+				}
+				if inTable {
+					cell = append(cell, text)
+				} else if inShape {
+					paragraphs = append(paragraphs, paragraph{text: text, level: level})
+				}
+			case "tc":
+				row = append(row, strings.Join(cell, " "))
+			case "tr":
+				rows = append(rows, row)
+			case "tbl":
+				inTable = false
+				if table := markdownTable(rows); table != "" {
+					part.blocks = append(part.blocks, table)
+				}
+			case "sp":
+				inShape = false
+				if len(paragraphs) == 0 || pptxSkippedPlaceholders[placeholder] {
+					continue
+				}
+
+				var lines []string
+				for _, p := range paragraphs {
+					lines = append(lines, strings.ReplaceAll(p.text, "\n", " "))
+				}
+				switch {
+				case placeholder == "title" || placeholder == "ctrTitle":
+					if part.title == "" && !notes {
+						part.title = strings.Join(lines, " ")
+						continue
+					}
+					part.blocks = append(part.blocks, "**"+strings.Join(lines, " ")+"**")
+				case isPlaceholder && !notes && placeholder != "subTitle":
+					// Body placeholders hold bullet lists, indented by paragraph level
+					for i, p := range paragraphs {
+						lines[i] = strings.Repeat("  ", p.level) + "- " + lines[i]
+					}
+					part.blocks = append(part.blocks, strings.Join(lines, "\n"))
+				default:
+					part.blocks = append(part.blocks, strings.Join(lines, "\n\n"))
+				}
+			}
+		}
+	}
+	return part, nil
+}
+
+// xmlAttr returns the value of an attribute by local name, or "" when it is missing
+func xmlAttr(se xml.StartElement, local string) string {
+	for _, a := range se.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,283 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Readability-style main content detection: paragraphs score their ancestors by length and
+// punctuation, scores are discounted by link density, and the best container is kept together
+// with siblings that score close to it.
+
+var (
+	// unlikelyCandidates match class and id values of page chrome
+	unlikelyCandidates = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|gdpr|header|legends|menu|modal|nav|pager|pagination|popup|promo|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|toolbar|advert|ad-break|agegate`)
+	// maybeCandidates keep an element that also matches unlikelyCandidates
+	maybeCandidates = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow|post|entry|text|blog|story`)
+	// positiveWeight and negativeWeight adjust the score of class and id values
+	positiveWeight = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|pagination|post|text|blog|story`)
+	negativeWeight = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|footer|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|widget`)
+)
+
+// boilerplateTags are removed wherever they appear
+var boilerplateTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Iframe: true, atom.Nav: true,
+	atom.Footer: true, atom.Aside: true, atom.Form: true, atom.Button: true, atom.Svg: true,
+	atom.Template: true, atom.Select: true, atom.Input: true, atom.Textarea: true, atom.Object: true,
+	atom.Embed: true, atom.Canvas: true, atom.Dialog: true, atom.Link: true, atom.Meta: true,
+}
+
+// boilerplateRoles are ARIA landmark roles of page chrome
+var boilerplateRoles = map[string]bool{
+	"navigation": true, "banner": true, "contentinfo": true, "complementary": true,
+	"dialog": true, "alertdialog": true, "search": true, "menu": true, "menubar": true,
+}
+
+// blockTags start a new block in the rendered Markdown
+var blockTags = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true, atom.Details: true,
+	atom.Dd: true, atom.Div: true, atom.Dl: true, atom.Dt: true, atom.Figcaption: true, atom.Figure: true,
+	atom.Footer: true, atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Header: true, atom.Hr: true, atom.Li: true, atom.Main: true, atom.Ol: true, atom.P: true,
+	atom.Pre: true, atom.Section: true, atom.Summary: true, atom.Table: true, atom.Ul: true,
+}
+
+const (
+	// minParagraphRunes is the shortest text that scores its ancestors
+	minParagraphRunes = 25
+	// siblingScoreShare is the share of the top score a sibling needs to be kept
+	siblingScoreShare = 0.2
+)
+
+// removeBoilerplate removes scripts, navigation, hidden elements and containers whose
+// class or id marks them as page chrome
+func removeBoilerplate(root *html.Node) {
+	var remove []*html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.CommentNode {
+				remove = append(remove, c)
+				continue
+			}
+			if c.Type != html.ElementNode {
+				continue
+			}
+			if isBoilerplate(c) {
+				remove = append(remove, c)
+				continue
+			}
+			walk(c)
+		}
+	}
+	walk(root)
+
+	for _, n := range remove {
+		n.Parent.RemoveChild(n)
+	}
+}
+
+func isBoilerplate(n *html.Node) bool {
+	if boilerplateTags[n.DataAtom] {
+		return true
+	}
+	if boilerplateRoles[strings.ToLower(attr(n, "role"))] {
+		return true
+	}
+	if attr(n, "aria-hidden") == "true" || hasAttr(n, "hidden") {
+		return true
+	}
+	if style := strings.ReplaceAll(strings.ToLower(attr(n, "style")), " ", ""); strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") {
+		return true
+	}
+
+	switch n.DataAtom {
+	case atom.Body, atom.Article, atom.Main, atom.Table, atom.Tbody, atom.Thead, atom.Tr, atom.Td, atom.Th, atom.A, atom.Code, atom.Pre:
+		return false
+	case atom.Header:
+		// A page header holds the site's navigation; an article header holds its title
+		return !hasAncestor(n, atom.Article, atom.Main)
+	}
+
+	match := attr(n, "class") + " " + attr(n, "id")
+	return unlikelyCandidates.MatchString(match) && !maybeCandidates.MatchString(match)
+}
+
+// readableContent returns the elements holding the page's main content, in document order
+func readableContent(body *html.Node) []*html.Node {
+	scores := make(map[*html.Node]float64)
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = initialScore(n)
+		}
+		scores[n] += score
+	}
+
+	for n := range body.Descendants() {
+		if n.Type != html.ElementNode || !isParagraphLike(n) {
+			continue
+		}
+		text := collapseSpace(textContent(n))
+		length := utf8.RuneCountInString(text)
+		if length < minParagraphRunes {
+			continue
+		}
+
+		score := 1 + float64(strings.Count(text, ",")+strings.Count(text, "，")+strings.Count(text, "、"))
+		score += min(float64(length)/100, 3)
+		addScore(n.Parent, score)
+		if n.Parent != nil {
+			addScore(n.Parent.Parent, score/2)
+		}
+	}
+
+	var top *html.Node
+	topScore := 0.0
+	for n, score := range scores {
+		score *= 1 - linkDensity(n)
+		scores[n] = score
+		if top == nil || score > topScore || (score == topScore && precedes(n, top)) {
+			top, topScore = n, score
+		}
+	}
+	if top == nil || top == body {
+		return []*html.Node{body}
+	}
+
+	// Siblings of the top candidate often hold the rest of the article
+	threshold := max(10, topScore*siblingScoreShare)
+	topClass := attr(top, "class")
+	var content []*html.Node
+	for sibling := top.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+		if sibling.Type != html.ElementNode {
+			continue
+		}
+		if sibling == top {
+			content = append(content, sibling)
+			continue
+		}
+
+		bonus := 0.0
+		if topClass != "" && attr(sibling, "class") == topClass {
+			bonus = topScore * 0.2
+		}
+		if score, ok := scores[sibling]; ok && score+bonus >= threshold {
+			content = append(content, sibling)
+			continue
+		}
+		if sibling.DataAtom == atom.P {
+			text := collapseSpace(textContent(sibling))
+			length := utf8.RuneCountInString(text)
+			density := linkDensity(sibling)
+			if (length > 80 && density < 0.25) || (length > 0 && density == 0 && strings.Contains(text, ". ")) {
+				content = append(content, sibling)
+			}
+		}
+	}
+	return content
+}
+
+// isParagraphLike reports whether an element's text is scored: paragraphs, preformatted text,
+// quotes, table cells and divs without block children
+func isParagraphLike(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.P, atom.Pre, atom.Td, atom.Blockquote:
+		return true
+	case atom.Div, atom.Section:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && blockTags[c.DataAtom] {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// initialScore is the score a container starts with, from its tag and its class and id
+func initialScore(n *html.Node) float64 {
+	score := 0.0
+	switch n.DataAtom {
+	case atom.Div, atom.Article, atom.Main, atom.Section:
+		score = 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score = 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+		score = -3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score = -5
+	}
+
+	for _, value := range []string{attr(n, "class"), attr(n, "id")} {
+		if value == "" {
+			continue
+		}
+		if negativeWeight.MatchString(value) {
+			score -= 25
+		}
+		if positiveWeight.MatchString(value) {
+			score += 25
+		}
+	}
+	return score
+}
+
+// linkDensity is the share of an element's text that is inside links
+func linkDensity(n *html.Node) float64 {
+	total := utf8.RuneCountInString(collapseSpace(textContent(n)))
+	if total == 0 {
+		return 0
+	}
+	linked := 0
+	for d := range n.Descendants() {
+		if d.Type == html.ElementNode && d.DataAtom == atom.A {
+			linked += utf8.RuneCountInString(collapseSpace(textContent(d)))
+		}
+	}
+	return min(1, float64(linked)/float64(total))
+}
+
+// precedes reports whether a comes before b in document order
+func precedes(a, b *html.Node) bool {
+	root := a
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	for n := range root.Descendants() {
+		switch n {
+		case a:
+			return true
+		case b:
+			return false
+		}
+	}
+	return false
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAncestor reports whether an element is inside one of the given tags
+func hasAncestor(n *html.Node, tags ...atom.Atom) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		for _, tag := range tags {
+			if p.DataAtom == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,352 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ErrUnsupportedFormat is returned when no registered parser handles a file
+var ErrUnsupportedFormat = errors.New("unsupported file format")
+
+// sniffLength is how much of a file is read to detect its format, as http.DetectContentType considers
+const sniffLength = 512
+
+// oleSignature starts the compound files of legacy Office and HWP documents
+var oleSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// zipSignature starts zip archives, and with them every zip-based document format
+var zipSignature = []byte("PK\x03\x04")
+
+// Parser extracts a Document from one file format
+type Parser interface {
+	Type() string         // Name of the format, such as "pdf"
+	Extensions() []string // File extensions including the dot, such as ".pdf"
+	MediaTypes() []string // MIME types, such as "application/pdf"
+	Parse(ctx context.Context, r io.ReaderAt, size int64, filename string, limits Limits) (*Document, error)
+}
+
+// Sniffer is implemented by parsers of formats that start with a signature. Files of these
+// formats are recognized by content alone, and a file with their extension but without their
+// signature is not handed to them.
+type Sniffer interface {
+	Sniff(header []byte) bool
+}
+
+// ZipSniffer is implemented by parsers of zip-based formats, which are told apart by the
+// entries of the archive
+type ZipSniffer interface {
+	SniffZip(zr *zip.Reader) bool
+}
+
+// Registry maps file formats to parsers. Parsers without a Sniffer or ZipSniffer are text
+// formats, chosen by extension or MIME type when the content is text.
+type Registry struct {
+	mu          sync.RWMutex
+	parsers     map[string]Parser // By type
+	order       []string          // Types in registration order, the order signatures are checked in
+	extensions  map[string]string // Extension to type
+	mediaTypes  map[string]string // MIME type to type
+	unsupported map[string]string // Extension to the reason it is not supported
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		parsers:     make(map[string]Parser),
+		extensions:  make(map[string]string),
+		mediaTypes:  make(map[string]string),
+		unsupported: make(map[string]string),
+	}
+}
+
+// Register adds a parser, replacing any parser registered for the same type, extensions or MIME types
+func (r *Registry) Register(p Parser) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fileType := p.Type()
+	if _, ok := r.parsers[fileType]; !ok {
+		r.order = append(r.order, fileType)
+	}
+	r.parsers[fileType] = p
+	for _, ext := range p.Extensions() {
+		ext = strings.ToLower(ext)
+		r.extensions[ext] = fileType
+		delete(r.unsupported, ext)
+	}
+	for _, mediaType := range p.MediaTypes() {
+		r.mediaTypes[strings.ToLower(mediaType)] = fileType
+	}
+}
+
+// RegisterUnsupported declares an extension that is recognized but cannot be parsed, so
+// files with it are rejected with reason instead of a generic error
+func (r *Registry) RegisterUnsupported(extension, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	extension = strings.ToLower(extension)
+	if _, ok := r.extensions[extension]; !ok {
+		r.unsupported[extension] = reason
+	}
+}
+
+// Lookup returns the parser of a type
+func (r *Registry) Lookup(fileType string) (Parser, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.parsers[fileType]
+	return p, ok
+}
+
+// Types returns the registered types in registration order
+func (r *Registry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.order)
+}
+
+// Extensions returns the registered extensions, sorted
+func (r *Registry) Extensions() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	exts := make([]string, 0, len(r.extensions))
+	for ext := range r.extensions {
+		exts = append(exts, ext)
+	}
+	slices.Sort(exts)
+	return exts
+}
+
+// FileType returns the type registered for a file's extension, or an empty string if there is none
+func (r *Registry) FileType(filename string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.extensions[strings.ToLower(filepath.Ext(filename))]
+}
+
+// MediaFileType returns the type registered for a MIME type, or an empty string if there is none
+func (r *Registry) MediaFileType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.mediaTypes[mediaType]
+}
+
+// Detect finds the parser for a file from its content. Signatures and zip entries decide first;
+// the extension of filename and the MIME type mediaType, both optional, only choose between
+// text formats, which have no signature.
+func (r *Registry) Detect(ra io.ReaderAt, size int64, filename, mediaType string) (Parser, error) {
+	header := make([]byte, min(size, sniffLength))
+	n, err := ra.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read file header: %w", err)
+	}
+	header = header[:n]
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	unsupported := func(detected string) error {
+		if reason, ok := r.unsupported[ext]; ok {
+			return fmt.Errorf("%w: %s", ErrUnsupportedFormat, reason)
+		}
+		return fmt.Errorf("%w: %s", ErrUnsupportedFormat, detected)
+	}
+
+	for _, fileType := range r.order {
+		if sniffer, ok := r.parsers[fileType].(Sniffer); ok && sniffer.Sniff(header) {
+			return r.parsers[fileType], nil
+		}
+	}
+
+	switch {
+	case bytes.HasPrefix(header, oleSignature):
+		return nil, unsupported("legacy binary Office and HWP documents (.doc, .xls, .ppt, .hwp) cannot be read; save them as .docx, .xlsx, .pptx or PDF")
+	case bytes.HasPrefix(header, zipSignature):
+		zr, err := zip.NewReader(ra, size)
+		if err != nil {
+			return nil, fmt.Errorf("%w: damaged zip archive: %v", ErrUnsupportedFormat, err)
+		}
+		for _, fileType := range r.order {
+			if sniffer, ok := r.parsers[fileType].(ZipSniffer); ok && sniffer.SniffZip(zr) {
+				return r.parsers[fileType], nil
+			}
+		}
+		return nil, unsupported("zip archive that is not a supported document")
+	}
+
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(header))
+	if !strings.HasPrefix(sniffed, "text/") {
+		// A binary format that registered no signature is trusted by its extension
+		if p, ok := r.parsers[r.extensions[ext]]; ok && !isSniffing(p) {
+			return p, nil
+		}
+		return nil, unsupported(fmt.Sprintf("unrecognized content (%s)", sniffed))
+	}
+
+	// Text: the extension or declared type decide, since Markdown with inline HTML sniffs as HTML
+	hinted, _, _ := mime.ParseMediaType(mediaType)
+	for _, fileType := range []string{r.extensions[ext], r.mediaTypes[hinted], r.mediaTypes[sniffed]} {
+		if p, ok := r.parsers[fileType]; ok && !isSniffing(p) {
+			return p, nil
+		}
+	}
+	if p, ok := r.parsers[TypeText]; ok && sniffed == "text/plain" {
+		return p, nil
+	}
+	return nil, unsupported(fmt.Sprintf("unrecognized content (%s)", sniffed))
+}
+
+// Parse detects the format of size bytes of r and parses them.
+// It returns an error when parsing takes longer than limits.Timeout or ctx is done; see parseWithLimits.
+func (r *Registry) Parse(ctx context.Context, ra io.ReaderAt, size int64, filename string, limits Limits) (*Document, error) {
+	p, err := r.Detect(ra, size, filename, "")
+	if err != nil {
+		return nil, err
+	}
+	return parseWithLimits(ctx, filename, limits, func(ctx context.Context) (*Document, error) {
+		return p.Parse(ctx, ra, size, filename, limits)
+	})
+}
+
+// ParseAs parses size bytes of r with the parser of fileType, without detecting the format
+func (r *Registry) ParseAs(ctx context.Context, fileType string, ra io.ReaderAt, size int64, filename string, limits Limits) (*Document, error) {
+	p, ok := r.Lookup(fileType)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, fileType)
+	}
+	return parseWithLimits(ctx, filename, limits, func(ctx context.Context) (*Document, error) {
+		return p.Parse(ctx, ra, size, filename, limits)
+	})
+}
+
+func isSniffing(p Parser) bool {
+	_, signature := p.(Sniffer)
+	_, zipped := p.(ZipSniffer)
+	return signature || zipped
+}
+
+// registry holds the built-in parsers, which register themselves in init
+var registry = NewRegistry()
+
+func init() {
+	registry.RegisterUnsupported(".xls", "legacy Excel .xls files cannot be read; save them as .xlsx")
+	registry.RegisterUnsupported(".doc", "legacy Word .doc files cannot be read; save them as .docx")
+	registry.RegisterUnsupported(".ppt", "legacy PowerPoint .ppt files cannot be read; save them as .pptx")
+	registry.RegisterUnsupported(".hwp", "HWP documents cannot be read; export them as PDF or .docx")
+}
+
+// DefaultRegistry returns the registry used by the package-level functions
+func DefaultRegistry() *Registry {
+	return registry
+}
+
+// Register adds a parser to the default registry, replacing any parser of the same type.
+// Programs embedding the library call it from init to support additional formats.
+func Register(p Parser) {
+	registry.Register(p)
+}
+
+// RegisterUnsupported declares an extension in the default registry that is recognized but cannot be parsed
+func RegisterUnsupported(extension, reason string) {
+	registry.RegisterUnsupported(extension, reason)
+}
+
+// FileType returns the type registered for a file's extension, or an empty string if it is not supported
+func FileType(filename string) string {
+	return registry.FileType(filename)
+}
+
+// MediaFileType returns the type registered for a MIME type, or an empty string if it is not supported
+func MediaFileType(contentType string) string {
+	return registry.MediaFileType(contentType)
+}
+
+// Detect finds the parser for a file in the default registry from its content
+func Detect(r io.ReaderAt, size int64, filename, mediaType string) (Parser, error) {
+	return registry.Detect(r, size, filename, mediaType)
+}
+
+// Parse detects the format of size bytes of r and parses them with the default registry.
+// It returns an error when parsing takes longer than limits.Timeout or ctx is done; see parseWithLimits.
+func Parse(ctx context.Context, r io.ReaderAt, size int64, filename string, limits Limits) (*Document, error) {
+	return registry.Parse(ctx, r, size, filename, limits)
+}
+
+// ParseAs parses size bytes of r as the given file type, for files whose type is already known
+func ParseAs(ctx context.Context, fileType string, r io.ReaderAt, size int64, filename string, limits Limits) (*Document, error) {
+	return registry.ParseAs(ctx, fileType, r, size, filename, limits)
+}
+
+// formatParser is the Parser of a built-in format
+type formatParser struct {
+	fileType   string
+	extensions []string
+	mediaTypes []string
+	parse      func(ctx context.Context, r io.ReaderAt, size int64, filename string, limits Limits) (*Document, error)
+}
+
+func (p formatParser) Type() string         { return p.fileType }
+func (p formatParser) Extensions() []string { return p.extensions }
+func (p formatParser) MediaTypes() []string { return p.mediaTypes }
+
+func (p formatParser) Parse(ctx context.Context, r io.ReaderAt, size int64, filename string, limits Limits) (*Document, error) {
+	return p.parse(ctx, r, size, filename, limits)
+}
+
+// signatureParser is a built-in format recognized by the bytes it starts with
+type signatureParser struct {
+	formatParser
+	signatures [][]byte
+}
+
+func (p signatureParser) Sniff(header []byte) bool {
+	for _, signature := range p.signatures {
+		if bytes.HasPrefix(header, signature) {
+			return true
+		}
+	}
+	return false
+}
+
+// zipFormatParser is a built-in zip-based format, recognized by a marker entry or by the
+// "mimetype" entry OpenDocument and EPUB files start with
+type zipFormatParser struct {
+	formatParser
+	marker string
+}
+
+func (p zipFormatParser) SniffZip(zr *zip.Reader) bool {
+	if p.marker != "" {
+		return findZipFile(zr, p.marker) != nil
+	}
+
+	f := findZipFile(zr, "mimetype")
+	if f == nil {
+		return false
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return false
+	}
+	defer rc.Close()
+	declared, err := io.ReadAll(io.LimitReader(rc, 256))
+	if err != nil {
+		return false
+	}
+	return slices.Contains(p.mediaTypes, strings.TrimSpace(string(declared)))
+}
@@ -0,0 +1,457 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// rtfSkippedDestinations hold formatting tables, embedded objects, headers and footers rather than body text
+var rtfSkippedDestinations = map[string]bool{
+	"colortbl": true, "stylesheet": true, "listtable": true, "listoverridetable": true, "revtbl": true,
+	"rsidtbl": true, "generator": true, "pict": true, "object": true, "shppict": true, "nonshppict": true,
+	"header": true, "headerl": true, "headerr": true, "headerf": true, "footer": true, "footerl": true,
+	"footerr": true, "footerf": true, "footnote": true, "annotation": true, "xmlnstbl": true,
+	"themedata": true, "colorschememapping": true, "datastore": true, "latentstyles": true,
+	"pgdsctbl": true, "filetbl": true, "fldinst": true, "mmathPr": true, "bkmkstart": true, "bkmkend": true,
+}
+
+// rtfSymbols are control words that stand for a character
+var rtfSymbols = map[string]string{
+	"emdash": "—", "endash": "–", "bullet": "•", "lquote": "‘", "rquote": "’",
+	"ldblquote": "“", "rdblquote": "”", "emspace": " ", "enspace": " ", "qmspace": " ",
+}
+
+// rtfCharsetCodepages maps font charsets to the code page their \'hh bytes are in
+var rtfCharsetCodepages = map[int]int{
+	77: 10000, 128: 932, 129: 949, 134: 936, 136: 950, 161: 1253, 162: 1254, 163: 1258,
+	177: 1255, 178: 1256, 186: 1257, 204: 1251, 222: 874, 238: 1250,
+}
+
+func init() {
+	Register(signatureParser{
+		formatParser: formatParser{TypeRTF, []string{".rtf"}, []string{"application/rtf", "text/rtf"}, ParseRtf},
+		signatures:   [][]byte{[]byte(`{\rtf`)},
+	})
+}
+
+// ParseRtf extracts the text of a Rich Text Format file as Markdown, with paragraphs, tables
+// and headings from outline levels
+func ParseRtf(ctx context.Context, r io.ReaderAt, size int64, filename string, limits Limits) (*Document, error) {
+	data, err := io.ReadAll(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(string(data[:min(len(data), 5)]), `{\rtf`) {
+		return nil, fmt.Errorf("invalid rtf: missing {\\rtf header")
+	}
+
+	p := &rtfParser{
+		data:         data,
+		codepage:     1252,
+		fontCharsets: make(map[int]int),
+		state:        rtfState{uc: 1, font: -1},
+		outline:      -1,
+		metadata:     map[string]*strings.Builder{"title": {}, "author": {}},
+	}
+	if err := p.parse(ctx); err != nil {
+		return nil, fmt.Errorf("failed to parse rtf: %w", err)
+	}
+
+	metadata := map[string]string{"type": "rtf"}
+	title := strings.TrimSuffix(filename, ".rtf")
+	if value := collapseSpace(p.metadata["title"].String()); value != "" {
+		metadata[MetaTitle] = value
+		title = value
+	}
+	if value := collapseSpace(p.metadata["author"].String()); value != "" {
+		metadata[MetaAuthor] = value
+	}
+
+	return &Document{
+		Title:    title,
+		Content:  strings.Join(p.blocks, "\n\n"),
+		Metadata: metadata,
+	}, nil
+}
+
+// rtfState is the part of the reader state scoped to a group
+type rtfState struct {
+	dest string // "" for body text, a metadata field, or a destination whose text is dropped
+	uc   int    // Fallback characters following each \u
+	font int
+}
+
+type rtfParser struct {
+	data []byte
+	pos  int
+
+	state        rtfState
+	stack        []rtfState
+	codepage     int
+	fontCharsets map[int]int
+	defineFont   int // Font being declared in the font table
+	defaultFont  int
+
+	pending       []byte // \'hh bytes not yet decoded, kept together for double-byte code pages
+	skipFallback  int
+	highSurrogate rune
+
+	para    strings.Builder
+	inTable bool
+	outline int // Outline level of the current paragraph, -1 for body text
+
+	rows   [][]string
+	row    []string
+	cell   []string
+	blocks []string
+
+	metadata map[string]*strings.Builder
+}
+
+func (p *rtfParser) parse(ctx context.Context) error {
+	nextCheck := 0
+	for p.pos < len(p.data) {
+		if p.pos >= nextCheck {
+			if err := context.Cause(ctx); err != nil {
+				return err
+			}
+			nextCheck = p.pos + 64<<10
+		}
+
+		c := p.data[p.pos]
+		switch c {
+		case '{':
+			p.flushBytes()
+			p.skipFallback = 0
+			p.stack = append(p.stack, p.state)
+			p.pos++
+		case '}':
+			p.flushBytes()
+			p.skipFallback = 0
+			if len(p.stack) > 0 {
+				p.state = p.stack[len(p.stack)-1]
+				p.stack = p.stack[:len(p.stack)-1]
+			}
+			p.pos++
+		case '\\':
+			p.pos++
+			if err := p.control(); err != nil {
+				return err
+			}
+		case '\r', '\n':
+			p.pos++
+		default:
+			p.pos++
+			if p.fallback() {
+				continue
+			}
+			if c >= 0x80 {
+				// Some writers emit 8-bit text without escaping it
+				p.pending = append(p.pending, c)
+				continue
+			}
+			p.write(string(c))
+		}
+	}
+
+	p.endParagraph()
+	p.flushTable()
+	return nil
+}
+
+// control reads a control word or symbol after a backslash
+func (p *rtfParser) control() error {
+	if p.pos >= len(p.data) {
+		return nil
+	}
+	c := p.data[p.pos]
+	if !isASCIILetter(c) {
+		p.pos++
+		p.symbol(c)
+		return nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.data) && isASCIILetter(p.data[p.pos]) {
+		p.pos++
+	}
+	word := string(p.data[start:p.pos])
+
+	param, hasParam := 0, false
+	numStart := p.pos
+	if p.pos < len(p.data) && p.data[p.pos] == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '9' {
+		p.pos++
+	}
+	if p.pos > numStart {
+		if n, err := strconv.Atoi(string(p.data[numStart:p.pos])); err == nil {
+			param, hasParam = n, true
+		}
+	}
+	if p.pos < len(p.data) && p.data[p.pos] == ' ' {
+		p.pos++
+	}
+
+	if word == "bin" && hasParam && param > 0 {
+		// Binary data follows as raw bytes
+		p.pos = min(p.pos+param, len(p.data))
+		return nil
+	}
+	p.word(word, param, hasParam)
+	return nil
+}
+
+// symbol handles a control symbol such as \' or \~
+func (p *rtfParser) symbol(c byte) {
+	switch c {
+	case '\'':
+		if p.pos+2 > len(p.data) {
+			return
+		}
+		b, err := strconv.ParseUint(string(p.data[p.pos:p.pos+2]), 16, 8)
+		p.pos += 2
+		if err != nil || p.fallback() {
+			return
+		}
+		p.pending = append(p.pending, byte(b))
+	case '*':
+		p.state.dest = "skip"
+	case '~':
+		p.write(" ")
+	case '_':
+		p.write("-")
+	case '\\', '{', '}':
+		if !p.fallback() {
+			p.write(string(c))
+		}
+	case '\r', '\n':
+		if p.state.dest == "" {
+			p.endParagraph()
+		}
+	}
+}
+
+// word handles a control word
+func (p *rtfParser) word(word string, param int, hasParam bool) {
+	if p.state.dest == "skip" {
+		return
+	}
+	if rtfSkippedDestinations[word] {
+		p.state.dest = "skip"
+		return
+	}
+	if symbol, ok := rtfSymbols[word]; ok {
+		p.write(symbol)
+		return
+	}
+
+	switch word {
+	case "fonttbl":
+		p.state.dest = "fonttbl"
+	case "info":
+		p.state.dest = "info"
+	case "title", "author":
+		if p.state.dest == "info" {
+			p.state.dest = word
+		}
+	case "ansicpg":
+		p.codepage = param
+	case "deff":
+		p.defaultFont = param
+	case "f":
+		if p.state.dest == "fonttbl" {
+			p.defineFont = param
+		} else {
+			p.flushBytes()
+			p.state.font = param
+		}
+	case "fcharset":
+		if p.state.dest == "fonttbl" {
+			p.fontCharsets[p.defineFont] = param
+		}
+	case "uc":
+		p.state.uc = max(param, 0)
+	case "u":
+		if !hasParam {
+			return
+		}
+		p.flushBytes()
+		p.writeUnicode(rune(uint16(int16(param))))
+		p.skipFallback = p.state.uc
+	case "par", "sect", "page":
+		p.endParagraph()
+	case "line":
+		p.write("\n")
+	case "tab":
+		p.write(" ")
+	case "pard":
+		p.inTable = false
+		p.outline = -1
+	case "intbl":
+		p.inTable = true
+	case "outlinelevel":
+		p.outline = param
+	case "cell", "nestcell":
+		p.endParagraph()
+		p.row = append(p.row, strings.Join(p.cell, " "))
+		p.cell = nil
+	case "row", "nestrow":
+		if len(p.row) > 0 {
+			p.rows = append(p.rows, p.row)
+		}
+		p.row = nil
+	}
+}
+
+// fallback consumes one fallback character after \u, reporting whether the character was dropped
+func (p *rtfParser) fallback() bool {
+	if p.skipFallback > 0 {
+		p.skipFallback--
+		return true
+	}
+	return false
+}
+
+// writeUnicode writes a \u character, joining surrogate pairs written as two escapes
+func (p *rtfParser) writeUnicode(r rune) {
+	switch {
+	case utf16.IsSurrogate(r) && r < 0xDC00:
+		p.highSurrogate = r
+	case utf16.IsSurrogate(r):
+		if p.highSurrogate != 0 {
+			p.write(string(utf16.DecodeRune(p.highSurrogate, r)))
+		}
+		p.highSurrogate = 0
+	default:
+		p.highSurrogate = 0
+		p.write(string(r))
+	}
+}
+
+// write appends text to the destination of the current group
+func (p *rtfParser) write(s string) {
+	p.flushBytes()
+	switch p.state.dest {
+	case "":
+		p.para.WriteString(s)
+	case "title", "author":
+		p.metadata[p.state.dest].WriteString(s)
+	}
+}
+
+// flushBytes decodes pending \'hh bytes with the code page of the current font
+func (p *rtfParser) flushBytes() {
+	if len(p.pending) == 0 {
+		return
+	}
+	data := p.pending
+	p.pending = nil
+
+	codepage := p.codepage
+	font := p.state.font
+	if font < 0 {
+		font = p.defaultFont
+	}
+	if fontCodepage, ok := rtfCharsetCodepages[p.fontCharsets[font]]; ok {
+		codepage = fontCodepage
+	}
+
+	decoded, err := rtfEncoding(codepage).NewDecoder().Bytes(data)
+	if err != nil {
+		decoded = data
+	}
+	p.write(string(decoded))
+}
+
+// endParagraph finishes the current paragraph as a block, heading or table cell text
+func (p *rtfParser) endParagraph() {
+	p.flushBytes()
+	text := cleanInline(p.para.String())
+	p.para.Reset()
+
+	if p.inTable {
+		if text != "" {
+			p.cell = append(p.cell, strings.ReplaceAll(text, "\n", " "))
+		}
+		return
+	}
+	p.flushTable()
+	if text == "" {
+		return
+	}
+	if p.outline >= 0 && p.outline < 6 {
+		text = strings.Repeat("#", p.outline+1) + " " + strings.ReplaceAll(text, "\n", " ")
+	}
+	p.blocks = append(p.blocks, text)
+}
+
+// flushTable adds the rows read so far as a table block
+func (p *rtfParser) flushTable() {
+	if len(p.row) > 0 {
+		p.rows = append(p.rows, p.row)
+		p.row = nil
+	}
+	if table := markdownTable(p.rows); table != "" {
+		p.blocks = append(p.blocks, table)
+	}
+	p.rows = nil
+}
+
+// rtfEncoding returns the decoder for a Windows code page, defaulting to Windows-1252
+func rtfEncoding(codepage int) encoding.Encoding {
+	switch codepage {
+	case 437:
+		return charmap.CodePage437
+	case 850:
+		return charmap.CodePage850
+	case 866:
+		return charmap.CodePage866
+	case 874:
+		return charmap.Windows874
+	case 932:
+		return japanese.ShiftJIS
+	case 936:
+		return simplifiedchinese.GBK
+	case 949:
+		return korean.EUCKR
+	case 950:
+		return traditionalchinese.Big5
+	case 1250:
+		return charmap.Windows1250
+	case 1251:
+		return charmap.Windows1251
+	case 1253:
+		return charmap.Windows1253
+	case 1254:
+		return charmap.Windows1254
+	case 1255:
+		return charmap.Windows1255
+	case 1256:
+		return charmap.Windows1256
+	case 1257:
+		return charmap.Windows1257
+	case 1258:
+		return charmap.Windows1258
+	case 10000:
+		return charmap.Macintosh
+	default:
+		return charmap.Windows1252
+	}
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,443 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Split modes of spreadsheets and CSV files
+const (
+	SheetSplitNone  = "none"  // The whole workbook is one document
+	SheetSplitSheet = "sheet" // Each sheet becomes a section
+	SheetSplitRows  = "rows"  // Each window of rows becomes a section
+)
+
+// maxHeaderLength is the longest cell, in characters, that can be a column header
+const maxHeaderLength = 80
+
+// SheetOptions controls how spreadsheets and CSV files are turned into Markdown
+type SheetOptions struct {
+	Split     string // SheetSplitNone, SheetSplitSheet or SheetSplitRows
+	ChunkRows int    // Data rows per table window, each repeating the header; 0 keeps tables whole
+}
+
+// DefaultSheetOptions returns options that keep a workbook in one document with tables
+// broken into windows of 200 rows
+func DefaultSheetOptions() SheetOptions {
+	return SheetOptions{
+		Split:     SheetSplitNone,
+		ChunkRows: 200,
+	}
+}
+
+// Validate checks the split mode and window size
+func (o SheetOptions) Validate() error {
+	switch o.Split {
+	case SheetSplitNone, SheetSplitSheet:
+	case SheetSplitRows:
+		if o.ChunkRows <= 0 {
+			return fmt.Errorf("splitting by rows requires a positive row count")
+		}
+	default:
+		return fmt.Errorf("invalid sheet split mode %q: use %s, %s or %s", o.Split, SheetSplitNone, SheetSplitSheet, SheetSplitRows)
+	}
+	if o.ChunkRows < 0 {
+		return fmt.Errorf("sheet chunk rows must not be negative")
+	}
+	return nil
+}
+
+type sheetOptionsContextKey struct{}
+
+// WithSheetOptions returns a context that makes the spreadsheet parsers use options.
+// Without it, they use DefaultSheetOptions.
+func WithSheetOptions(ctx context.Context, options SheetOptions) context.Context {
+	return context.WithValue(ctx, sheetOptionsContextKey{}, options)
+}
+
+// sheetOptionsFrom returns the options set with WithSheetOptions, or the defaults
+func sheetOptionsFrom(ctx context.Context) SheetOptions {
+	if options, ok := ctx.Value(sheetOptionsContextKey{}).(SheetOptions); ok {
+		return options
+	}
+	return DefaultSheetOptions()
+}
+
+// sheetRow is a non-empty row of a sheet with its 1-based row number
+type sheetRow struct {
+	num   int
+	cells []string
+}
+
+// sheet collects the rows of one sheet within the row and column limits
+type sheet struct {
+	name      string
+	rows      []sheetRow
+	maxRows   int
+	maxCols   int
+	truncated bool // Columns past the limit were dropped
+	full      bool // The row limit was reached
+}
+
+func newSheet(name string, limits Limits) *sheet {
+	return &sheet{name: name, maxRows: limits.MaxSheetRows, maxCols: limits.MaxSheetColumns}
+}
+
+// add keeps a row unless all of its cells are empty. It reports false once the row limit
+// has been reached, after which the caller stops reading the sheet.
+func (s *sheet) add(num int, cells []string) bool {
+	if s.maxRows > 0 && len(s.rows) >= s.maxRows {
+		s.full = true
+		return false
+	}
+	if s.maxCols > 0 && len(cells) > s.maxCols {
+		for _, cell := range cells[s.maxCols:] {
+			if strings.TrimSpace(cell) != "" {
+				s.truncated = true
+				break
+			}
+		}
+		cells = cells[:s.maxCols]
+	}
+	for _, cell := range cells {
+		if strings.TrimSpace(cell) != "" {
+			s.rows = append(s.rows, sheetRow{num: num, cells: cells})
+			break
+		}
+	}
+	return true
+}
+
+// warnings describes the parts of the sheet that were left out
+func (s *sheet) warnings() []string {
+	prefix := ""
+	if s.name != "" {
+		prefix = fmt.Sprintf("sheet %q: ", s.name)
+	}
+	var warnings []string
+	if s.full {
+		warnings = append(warnings, fmt.Sprintf("%sonly the first %d rows were read", prefix, s.maxRows))
+	}
+	if s.truncated {
+		warnings = append(warnings, fmt.Sprintf("%sonly the first %d columns were read", prefix, s.maxCols))
+	}
+	return warnings
+}
+
+// sheetTable is a block of rows between empty rows. Single-cell rows above it, such as a
+// title, are its captions; those below the last table of a sheet are its notes.
+type sheetTable struct {
+	captions []string
+	header   []string
+	rows     []sheetRow
+	notes    []string
+}
+
+// tables splits the sheet into tables at empty rows, drops columns that are empty throughout
+// a table and finds each table's header row. Tables without one get column letters as headers.
+func (s *sheet) tables() []sheetTable {
+	var (
+		tables  []sheetTable
+		pending []string
+	)
+	for start := 0; start < len(s.rows); {
+		end := start + 1
+		for end < len(s.rows) && s.rows[end].num == s.rows[end-1].num+1 {
+			end++
+		}
+		block := s.rows[start:end]
+		start = end
+
+		// Leading rows with a single value are titles rather than data
+		for len(block) > 0 {
+			value, single := singleCell(block[0].cells)
+			if !single {
+				break
+			}
+			pending = append(pending, value)
+			block = block[1:]
+		}
+		if len(block) == 0 {
+			continue
+		}
+		if len(block) == 1 {
+			pending = append(pending, strings.Join(nonEmptyCells(block[0].cells), " "))
+			continue
+		}
+
+		columns := usedColumns(block)
+		table := sheetTable{captions: pending}
+		pending = nil
+		for _, row := range block {
+			cells := make([]string, len(columns))
+			for i, col := range columns {
+				if col < len(row.cells) {
+					cells[i] = strings.TrimSpace(row.cells[col])
+				}
+			}
+			table.rows = append(table.rows, sheetRow{num: row.num, cells: cells})
+		}
+		if isHeaderRow(table.rows[0].cells, table.rows[1].cells) {
+			table.header = table.rows[0].cells
+			table.rows = table.rows[1:]
+		} else {
+			table.header = make([]string, len(columns))
+			for i, col := range columns {
+				table.header[i] = columnName(col)
+			}
+		}
+		tables = append(tables, table)
+	}
+
+	if len(pending) > 0 {
+		if len(tables) == 0 {
+			return []sheetTable{{captions: pending}}
+		}
+		tables[len(tables)-1].notes = pending
+	}
+	return tables
+}
+
+// singleCell returns the only non-empty cell of a row, if it has exactly one
+func singleCell(cells []string) (string, bool) {
+	values := nonEmptyCells(cells)
+	if len(values) != 1 {
+		return "", false
+	}
+	return values[0], true
+}
+
+func nonEmptyCells(cells []string) []string {
+	var values []string
+	for _, cell := range cells {
+		if cell = strings.TrimSpace(cell); cell != "" {
+			values = append(values, cell)
+		}
+	}
+	return values
+}
+
+// usedColumns returns the indexes of the columns that hold a value in any row
+func usedColumns(rows []sheetRow) []int {
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row.cells))
+	}
+	var columns []int
+	for col := range width {
+		for _, row := range rows {
+			if col < len(row.cells) && strings.TrimSpace(row.cells[col]) != "" {
+				columns = append(columns, col)
+				break
+			}
+		}
+	}
+	return columns
+}
+
+// isHeaderRow guesses whether row labels the columns of the rows below it, starting with next.
+// A header fills most columns with distinct, short labels that are mostly not numbers; a row
+// with numbers in the same places as the row below it is data.
+func isHeaderRow(row, next []string) bool {
+	filled, numeric := 0, 0
+	seen := make(map[string]bool, len(row))
+	for _, cell := range row {
+		if cell == "" {
+			continue
+		}
+		if seen[cell] || utf8.RuneCountInString(cell) > maxHeaderLength {
+			return false
+		}
+		seen[cell] = true
+		filled++
+		if isNumericCell(cell) {
+			numeric++
+		}
+	}
+	if filled*2 < len(row) || numeric*2 >= filled {
+		return false
+	}
+	if numeric == 0 {
+		return true
+	}
+
+	for i, cell := range row {
+		nextCell := ""
+		if i < len(next) {
+			nextCell = next[i]
+		}
+		if (cell == "") != (nextCell == "") || isNumericCell(cell) != isNumericCell(nextCell) {
+			return true
+		}
+	}
+	return false
+}
+
+// isNumericCell reports whether a cell holds a number, allowing for grouping separators,
+// signs, percentages and currency symbols
+func isNumericCell(cell string) bool {
+	cell = strings.Trim(cell, " %$€£¥₩+-()")
+	cell = strings.ReplaceAll(cell, ",", "")
+	if cell == "" {
+		return false
+	}
+	_, err := strconv.ParseFloat(cell, 64)
+	return err == nil
+}
+
+// columnName returns the spreadsheet letters of a 0-based column index: A, B, ..., Z, AA
+func columnName(col int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name
+}
+
+// sheetWindow is a run of at most SheetOptions.ChunkRows data rows of a table
+type sheetWindow struct {
+	table *sheetTable
+	rows  []sheetRow
+	first bool // The window starts the table, so its captions precede it
+	last  bool // The window ends the table, so its notes follow it
+	split bool // The table spans several windows, so each is headed by its row numbers
+}
+
+// windows breaks the table into runs of at most size rows; size 0 keeps it whole
+func (t *sheetTable) windows(size int) []sheetWindow {
+	if size <= 0 || len(t.rows) <= size {
+		return []sheetWindow{{table: t, rows: t.rows, first: true, last: true}}
+	}
+	var windows []sheetWindow
+	for start := 0; start < len(t.rows); start += size {
+		end := min(start+size, len(t.rows))
+		windows = append(windows, sheetWindow{
+			table: t,
+			rows:  t.rows[start:end],
+			first: start == 0,
+			last:  end == len(t.rows),
+			split: true,
+		})
+	}
+	return windows
+}
+
+// label names the sheet rows the window covers, such as "Rows 2-201"
+func (w sheetWindow) label() string {
+	if len(w.rows) == 0 {
+		return ""
+	}
+	first, last := w.rows[0].num, w.rows[len(w.rows)-1].num
+	if first == last {
+		return fmt.Sprintf("Row %d", first)
+	}
+	return fmt.Sprintf("Rows %d-%d", first, last)
+}
+
+// markdown renders the window as a table under the header, with the captions of the table
+// before its first window, or every window when standalone is set, and the notes after its last
+func (w sheetWindow) markdown(standalone bool) []string {
+	var blocks []string
+	if w.first || standalone {
+		blocks = append(blocks, w.table.captions...)
+	}
+	if len(w.rows) > 0 {
+		if w.split {
+			blocks = append(blocks, "#### "+w.label())
+		}
+		table := make([][]string, 0, len(w.rows)+1)
+		table = append(table, w.table.header)
+		for _, row := range w.rows {
+			table = append(table, row.cells)
+		}
+		blocks = append(blocks, markdownTable(table))
+	}
+	if w.last {
+		blocks = append(blocks, w.table.notes...)
+	}
+	return blocks
+}
+
+// workbook renders the sheets of a spreadsheet or CSV file as Markdown and splits them into
+// sections as the options ask
+type workbook struct {
+	options  SheetOptions
+	blocks   []string
+	sections []Section
+	warnings []string
+	sheets   int
+	rows     int
+}
+
+func newWorkbook(options SheetOptions) *workbook {
+	return &workbook{options: options}
+}
+
+// addSheet renders a sheet that has been read. Sheets without values are skipped.
+func (w *workbook) addSheet(s *sheet) {
+	w.warnings = append(w.warnings, s.warnings()...)
+	tables := s.tables()
+	if len(tables) == 0 {
+		return
+	}
+	w.sheets++
+
+	heading := ""
+	if s.name != "" {
+		heading = "### Sheet: " + s.name
+	}
+	var sheetBlocks []string
+	if heading != "" {
+		sheetBlocks = append(sheetBlocks, heading)
+	}
+
+	for i := range tables {
+		w.rows += len(tables[i].rows)
+		for _, window := range tables[i].windows(w.options.ChunkRows) {
+			sheetBlocks = append(sheetBlocks, window.markdown(false)...)
+			if w.options.Split != SheetSplitRows {
+				continue
+			}
+
+			// Each window is read on its own, so it keeps the sheet heading and table captions
+			title := s.name
+			if label := window.label(); label != "" && s.name != "" {
+				title = fmt.Sprintf("%s (%s)", s.name, strings.ToLower(label))
+			} else if label != "" {
+				title = label
+			}
+			blocks := window.markdown(true)
+			if heading != "" {
+				blocks = append([]string{heading}, blocks...)
+			}
+			w.sections = append(w.sections, Section{Title: title, Content: strings.Join(blocks, "\n\n")})
+		}
+	}
+
+	content := strings.Join(sheetBlocks, "\n\n")
+	w.blocks = append(w.blocks, content)
+	if w.options.Split == SheetSplitSheet {
+		w.sections = append(w.sections, Section{Title: s.name, Content: content})
+	}
+}
+
+// document returns the rendered sheets. A single section is not worth splitting, so the
+// document only has sections when there are several.
+func (w *workbook) document(title string, metadata map[string]string) *Document {
+	metadata["sheets"] = strconv.Itoa(w.sheets)
+	metadata["rows"] = strconv.Itoa(w.rows)
+
+	doc := &Document{
+		Title:    title,
+		Content:  strings.Join(w.blocks, "\n\n"),
+		Metadata: metadata,
+		Warnings: w.warnings,
+	}
+	if len(w.sections) > 1 {
+		doc.Sections = w.sections
+	}
+	return doc
+}
@@ -1,51 +1,43 @@
 package parser
 
 import (
-	"bufio"
+	"context"
+	"fmt"
 	"io"
 	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
-// ParseText parses plain text or markdown files
-func ParseText(r io.Reader, filename string) (*Document, error) {
-	contentBytes, err := io.ReadAll(r)
+func init() {
+	Register(formatParser{TypeText, []string{".md", ".markdown", ".txt"}, []string{"text/plain", "text/markdown", "text/x-markdown"}, ParseText})
+}
+
+// ParseText parses plain text or markdown files. YAML front matter supplies the title, author,
+// date, tags, source URL and aliases.
+func ParseText(ctx context.Context, r io.ReaderAt, size int64, filename string, limits Limits) (*Document, error) {
+	contentBytes, err := io.ReadAll(io.NewSectionReader(r, 0, size))
 	if err != nil {
 		return nil, err
 	}
 	content := string(contentBytes)
 
-	// Check for YAML Frontmatter
+	metadata := make(map[string]string)
+
 	// ---
 	// title: ...
 	// ---
+	if frontMatter, body, ok := splitFrontMatter(content); ok {
+		// Malformed front matter is dropped rather than indexed as text
+		content = strings.TrimSpace(body)
+		readFrontMatter(frontMatter, metadata)
+	}
 
-	title := strings.TrimSuffix(filename, ".md")
-	title = strings.TrimSuffix(title, ".txt")
-	metadata := make(map[string]string)
-
-	if strings.HasPrefix(content, "---\n") || strings.HasPrefix(content, "---\r\n") {
-		parts := strings.SplitN(content, "---", 3)
-		if len(parts) >= 3 {
-			// parts[0] is empty
-			// parts[1] is frontmatter
-			// parts[2] is content
-			frontmatter := parts[1]
-			content = strings.TrimSpace(parts[2])
-
-			// Simple Yaml Parser for Title
-			scanner := bufio.NewScanner(strings.NewReader(frontmatter))
-			for scanner.Scan() {
-				line := scanner.Text()
-				if strings.HasPrefix(strings.ToLower(line), "title:") {
-					t := strings.TrimPrefix(line[6:], " ")
-					t = strings.Trim(t, ` "'`)
-					if t != "" {
-						title = t
-					}
-				}
-				// Can parse other metadata here
-			}
-		}
+	title := metadata[MetaTitle]
+	if title == "" {
+		title = strings.TrimSuffix(filename, ".md")
+		title = strings.TrimSuffix(title, ".txt")
 	}
 
 	return &Document{
@@ -54,3 +46,128 @@ func ParseText(r io.Reader, filename string) (*Document, error) {
 		Metadata: metadata,
 	}, nil
 }
+
+// splitFrontMatter separates a front matter block delimited by "---" lines from the text after it.
+// The closing line may also be "...".
+func splitFrontMatter(content string) (frontMatter, body string, ok bool) {
+	content = strings.TrimPrefix(content, "\ufeff")
+	rest, found := strings.CutPrefix(content, "---\n")
+	if !found {
+		if rest, found = strings.CutPrefix(content, "---\r\n"); !found {
+			return "", content, false
+		}
+	}
+
+	offset := 0
+	for offset <= len(rest) {
+		end := strings.IndexByte(rest[offset:], '\n')
+		line := rest[offset:]
+		if end >= 0 {
+			line = rest[offset : offset+end]
+		}
+		if trimmed := strings.TrimRight(line, " \t\r"); trimmed == "---" || trimmed == "..." {
+			if end < 0 {
+				return rest[:offset], "", true
+			}
+			return rest[:offset], rest[offset+end+1:], true
+		}
+		if end < 0 {
+			break
+		}
+		offset += end + 1
+	}
+	return "", content, false
+}
+
+// frontMatterKeys maps front matter fields, by lowercase name, to metadata keys. Earlier fields
+// win when a file sets several of them.
+var frontMatterKeys = []struct {
+	field string
+	key   string
+}{
+	{"title", MetaTitle},
+	{"author", MetaAuthor},
+	{"authors", MetaAuthor},
+	{"creator", MetaAuthor},
+	{"date", MetaPublishedDate},
+	{"published", MetaPublishedDate},
+	{"created", MetaPublishedDate},
+	{"description", MetaDescription},
+	{"summary", MetaDescription},
+	{"url", MetaURL},
+	{"source", MetaURL},
+	{"link", MetaURL},
+	{"original_url", MetaURL},
+	{"canonical_url", MetaCanonicalURL},
+	{"tags", MetaTags},
+	{"keywords", MetaTags},
+	{"categories", MetaTags},
+	{"aliases", MetaAliases},
+	{"alias", MetaAliases},
+}
+
+// readFrontMatter adds the recognised fields of YAML front matter to metadata; front matter that
+// is not valid YAML adds nothing
+func readFrontMatter(frontMatter string, metadata map[string]string) {
+	var fields map[string]any
+	if err := yaml.Unmarshal([]byte(frontMatter), &fields); err != nil {
+		return
+	}
+
+	lowered := make(map[string]any, len(fields))
+	for field, value := range fields {
+		lowered[strings.ToLower(field)] = value
+	}
+
+	for _, m := range frontMatterKeys {
+		value, ok := lowered[m.field]
+		if !ok {
+			continue
+		}
+		values := frontMatterValues(value)
+		switch m.key {
+		case MetaTags, MetaAliases:
+			// A single string may list several tags: "tags: go, search"
+			if len(values) == 1 {
+				values = splitKeywords(values[0])
+			}
+			setMetaList(metadata, m.key, values)
+		case MetaAuthor:
+			setMetaList(metadata, m.key, values)
+		case MetaPublishedDate:
+			if len(values) > 0 {
+				setMetaDate(metadata, m.key, values[0])
+			}
+		default:
+			if len(values) > 0 {
+				setMeta(metadata, m.key, values[0])
+			}
+		}
+	}
+}
+
+// frontMatterValues flattens a front matter value into strings. Lists yield their scalar items;
+// maps, such as an author written as {name: ..., email: ...}, yield their name.
+func frontMatterValues(value any) []string {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return []string{v}
+	case time.Time:
+		return []string{v.Format(time.RFC3339)}
+	case []any:
+		var values []string
+		for _, item := range v {
+			values = append(values, frontMatterValues(item)...)
+		}
+		return values
+	case map[string]any:
+		if name, ok := v["name"]; ok {
+			return frontMatterValues(name)
+		}
+		return nil
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
@@ -1,7 +1,9 @@
 package parser
 
 import (
-	"bytes"
+	"archive/zip"
+	"context"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"strings"
@@ -9,99 +11,235 @@ import (
 	"github.com/xuri/excelize/v2"
 )
 
-// ParseExcel parses an Excel file and converts sheets to Markdown tables
-func ParseExcel(r io.Reader, filename string) (*Document, error) {
-	// Read content to support ReaderAt if needed, or OpenReader
-	content, err := io.ReadAll(r)
+const (
+	xlsxWorkbookPart     = "xl/workbook.xml"
+	xlsxWorksheetRelType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet"
+
+	// maxFormulaCalcs bounds the formulas computed for cells saved without their result
+	maxFormulaCalcs = 10000
+)
+
+func init() {
+	Register(zipFormatParser{
+		formatParser: formatParser{TypeExcel, []string{".xlsx", ".xlsm"}, []string{"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "application/vnd.ms-excel.sheet.macroenabled.12"}, ParseExcel},
+		marker:       xlsxWorkbookPart,
+	})
+}
+
+// ParseExcel parses an Excel file and converts its sheets to Markdown tables. Rows are streamed
+// within limits.MaxSheetRows and limits.MaxSheetColumns, formulas give their results, and the
+// sheets are split into tables, windows and sections as the SheetOptions on ctx ask.
+func ParseExcel(ctx context.Context, r io.ReaderAt, size int64, filename string, limits Limits) (*Document, error) {
+	// excelize reads the whole archive into memory, so check it is safe to expand first
+	zipReader, budget, err := openZip(r, size, limits)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read excel content: %w", err)
+		return nil, fmt.Errorf("failed to open excel file: %w", err)
+	}
+	metadata := map[string]string{"type": "excel"}
+	if err := readCoreProperties(zipReader, budget, metadata); err != nil {
+		return nil, err
+	}
+	parts, err := xlsxSheetParts(zipReader, budget)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read excel workbook: %w", err)
 	}
 
-	f, err := excelize.OpenReader(bytes.NewReader(content))
+	opts := excelize.Options{}
+	if limits.MaxUncompressedSize > 0 {
+		opts.UnzipSizeLimit = limits.MaxUncompressedSize
+		opts.UnzipXMLSizeLimit = min(excelize.StreamChunkSize, limits.MaxUncompressedSize)
+	}
+	f, err := excelize.OpenReader(io.NewSectionReader(r, 0, size), opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open excel file: %w", err)
 	}
 	defer f.Close()
 
-	var sb strings.Builder
-
-	sheets := f.GetSheetList()
-	for _, sheet := range sheets {
-		rows, err := f.GetRows(sheet)
-		if err != nil {
-			continue // Skip unreadable sheets
+	book := newWorkbook(sheetOptionsFrom(ctx))
+	calcs := 0
+	for _, name := range f.GetSheetList() {
+		if err := context.Cause(ctx); err != nil {
+			return nil, err
+		}
+		part, ok := parts[name]
+		if !ok {
+			continue // Chart and dialog sheets hold no cells
 		}
 
-		if len(rows) == 0 {
+		s, err := readExcelSheet(ctx, f, zipReader, budget, name, part, limits, &calcs)
+		if err != nil {
+			if ctxErr := context.Cause(ctx); ctxErr != nil {
+				return nil, ctxErr
+			}
+			book.warnings = append(book.warnings, fmt.Sprintf("sheet %q could not be read: %v", name, err))
 			continue
 		}
+		book.addSheet(s)
+	}
+	if calcs > maxFormulaCalcs {
+		book.warnings = append(book.warnings, fmt.Sprintf("only the first %d formulas saved without results were calculated; the rest are empty", maxFormulaCalcs))
+	}
 
-		sb.WriteString(fmt.Sprintf("\n### Sheet: %s\n\n", sheet))
+	return book.document(documentTitle(metadata, filename), metadata), nil
+}
 
-		// Convert to Markdown Table
-		// Find max columns to align the table
-		maxCols := 0
-		for _, row := range rows {
-			if len(row) > maxCols {
-				maxCols = len(row)
+// readExcelSheet streams the rows of a worksheet. Cells whose formula was saved without its
+// result, as by generators that leave calculation to Excel, are calculated while calcs, shared
+// across the workbook, stays within maxFormulaCalcs.
+func readExcelSheet(ctx context.Context, f *excelize.File, zr *zip.Reader, budget *zipBudget, name, part string, limits Limits, calcs *int) (*sheet, error) {
+	uncached, err := xlsxUncachedFormulas(zr, part, budget)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := f.Rows(name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	s := newSheet(name, limits)
+	// The iterator also yields the rows missing between stored ones, so the count is the row number
+	for num := 1; rows.Next(); num++ {
+		if num%1000 == 0 {
+			if err := context.Cause(ctx); err != nil {
+				return nil, err
 			}
 		}
-
-		if maxCols == 0 {
-			continue
+		cells, err := rows.Columns()
+		if err != nil {
+			return nil, err
 		}
 
-		// Header (first row)
-		// If explicit header doesn't exist, we might treat first row as header
-		// or if empty, generating generic headers (A, B, C...) is complex.
-		// Let's assume Row 1 is header.
-
-		// Header Row
-		sb.WriteString("|")
-		for i := 0; i < maxCols; i++ {
-			val := ""
-			if i < len(rows[0]) {
-				val = normalizeCell(rows[0][i])
+		for _, col := range uncached[num] {
+			if limits.MaxSheetColumns > 0 && col >= limits.MaxSheetColumns {
+				continue
+			}
+			if *calcs++; *calcs > maxFormulaCalcs {
+				break
+			}
+			cell, err := excelize.CoordinatesToCellName(col+1, num)
+			if err != nil {
+				continue
+			}
+			value, err := f.CalcCellValue(name, cell)
+			if err != nil || value == "" {
+				continue
 			}
-			sb.WriteString(fmt.Sprintf(" %s |", val))
+			for len(cells) <= col {
+				cells = append(cells, "")
+			}
+			cells[col] = value
 		}
-		sb.WriteString("\n|")
 
-		// Separator Row
-		for i := 0; i < maxCols; i++ {
-			sb.WriteString(" --- |")
+		if !s.add(num, cells) {
+			break
 		}
-		sb.WriteString("\n")
-
-		// Data Rows (from index 1)
-		for i := 1; i < len(rows); i++ {
-			sb.WriteString("|")
-			for j := 0; j < maxCols; j++ {
-				val := ""
-				if j < len(rows[i]) {
-					val = normalizeCell(rows[i][j])
-				}
-				sb.WriteString(fmt.Sprintf(" %s |", val))
+	}
+	if err := rows.Error(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// xlsxSheetParts maps the names of a workbook's worksheets to their archive entries
+func xlsxSheetParts(zr *zip.Reader, budget *zipBudget) (map[string]string, error) {
+	f := findZipFile(zr, xlsxWorkbookPart)
+	if f == nil {
+		return nil, fmt.Errorf("%s not found", xlsxWorkbookPart)
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			Name  string     `xml:"name,attr"`
+			Attrs []xml.Attr `xml:",any,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodeZipXML(f, budget, &workbook); err != nil {
+		return nil, err
+	}
+	rels, err := readRelationships(zr, xlsxWorkbookPart, budget)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make(map[string]string, len(workbook.Sheets))
+	for _, sheet := range workbook.Sheets {
+		// The relationship ID is the namespaced r:id attribute
+		for _, a := range sheet.Attrs {
+			if a.Name.Local != "id" || a.Name.Space == "" {
+				continue
+			}
+			if rel, ok := rels[a.Value]; ok && rel.Type == xlsxWorksheetRelType {
+				parts[sheet.Name] = rel.Target
 			}
-			sb.WriteString("\n")
 		}
-		sb.WriteString("\n")
 	}
-
-	return &Document{
-		Title:    strings.TrimSuffix(filename, ".xlsx"),
-		Content:  strings.TrimSpace(sb.String()),
-		Metadata: map[string]string{"type": "excel"},
-	}, nil
+	return parts, nil
 }
 
-func normalizeCell(val string) string {
-	// Escape pipes and newlines for markdown table
-	val = strings.ReplaceAll(val, "|", "\\|")
-	val = strings.ReplaceAll(val, "\n", " ")
-	val = strings.ReplaceAll(val, "\r", "")
-	if val == "" {
-		return " "
+// xlsxUncachedFormulas finds the cells of a worksheet that have a formula but no saved value,
+// keyed by row number, with 0-based column indexes
+func xlsxUncachedFormulas(zr *zip.Reader, part string, budget *zipBudget) (map[int][]int, error) {
+	f := findZipFile(zr, part)
+	if f == nil {
+		return nil, nil
+	}
+	rc, err := budget.open(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", part, err)
+	}
+	defer rc.Close()
+
+	var (
+		decoder  = xml.NewDecoder(rc)
+		uncached = make(map[int][]int)
+		ref      string
+		formula  bool
+		value    bool
+		inValue  bool
+	)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", part, err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "c":
+				ref, formula, value = "", false, false
+				for _, a := range t.Attr {
+					if a.Name.Local == "r" {
+						ref = a.Value
+					}
+				}
+			case "f":
+				formula = true
+			case "v":
+				inValue = true
+			}
+		case xml.CharData:
+			if inValue && strings.TrimSpace(string(t)) != "" {
+				value = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v":
+				inValue = false
+			case "c":
+				if !formula || value || ref == "" {
+					continue
+				}
+				if col, row, err := excelize.CellNameToCoordinates(ref); err == nil {
+					uncached[row] = append(uncached[row], col-1)
+				}
+			}
+		}
 	}
-	return val
+	return uncached, nil
 }
@@ -131,6 +131,7 @@ $indexMapping = @{
                 type = "text"
                 analyzer = "standard"
                 fields = @{
+                    keyword = @{ type = "keyword"; ignore_above = 256 }
                     ko = @{ type = "text"; analyzer = "nori" }
                     en = @{ type = "text"; analyzer = "english" }
                     ja = @{ type = "text"; analyzer = "kuromoji" }
@@ -192,6 +193,7 @@ $indexMapping = @{
             author = @{ type = "keyword" }
             created_date = @{ type = "date" }
             registrar = @{ type = "keyword" }
+            topic_id = @{ type = "keyword" }
         }
     }
 } | ConvertTo-Json -Depth 10
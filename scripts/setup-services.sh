@@ -118,6 +118,7 @@ curl_result=$(curl -X PUT "http://localhost:9200/open-librarian-articles" -H "Co
         "type": "text",
         "analyzer": "standard",
         "fields": {
+          "keyword": { "type": "keyword", "ignore_above": 256 },
           "ko": { "type": "text", "analyzer": "nori" },
           "en": { "type": "text", "analyzer": "english" },
           "ja": { "type": "text", "analyzer": "kuromoji" },
@@ -178,7 +179,8 @@ curl_result=$(curl -X PUT "http://localhost:9200/open-librarian-articles" -H "Co
       "original_url": { "type": "keyword" },
       "author": { "type": "keyword" },
       "created_date": { "type": "date" },
-      "registrar": { "type": "keyword" }
+      "registrar": { "type": "keyword" },
+      "topic_id": { "type": "keyword" }
     }
   }
 }')